@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 
 	"github.com/PauloHFS/elenchus/internal/config"
 	"github.com/PauloHFS/elenchus/internal/db"
@@ -16,15 +15,13 @@ func initDB() (*sql.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
-	// Aplicando os mesmos pragmas de performance e resiliência
-	dsn := cfg.DatabaseURL
-	if strings.Contains(dsn, "?") {
-		dsn += "&_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
-	} else {
-		dsn += "?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
+	// Driver e pragmas de performance/resiliência vêm do DATABASE_URL
+	conn, err := db.ParseDatabaseURL(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_URL: %w", err)
 	}
 
-	dbConn, err := sql.Open("sqlite3", dsn)
+	dbConn, err := sql.Open(conn.Driver, conn.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
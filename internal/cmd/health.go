@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+
+	"github.com/PauloHFS/elenchus/internal/config"
+	"github.com/PauloHFS/elenchus/internal/health"
+	"github.com/PauloHFS/elenchus/internal/mailer"
+	"github.com/PauloHFS/elenchus/internal/service"
+	"github.com/PauloHFS/elenchus/internal/worker"
+)
+
+// minFreeDiskBytes abaixo disso o SQLite corre risco de falhar em escritas
+const minFreeDiskBytes = 100 * 1024 * 1024
+
+// healthChecks monta as dependências verificadas pelo readiness probe.
+// Banco e disco são críticos; fila, SMTP e Gemini apenas degradam o status.
+func healthChecks(cfg *config.Config, dbConn *sql.DB, gemini *service.GeminiClient, w *worker.Processor, logger *slog.Logger) []health.Check {
+	m := mailer.New(cfg)
+
+	checks := []health.Check{
+		{Name: "database", Critical: true, Run: dbConn.PingContext},
+		{Name: "disk", Critical: true, Run: func(ctx context.Context) error {
+			var stat syscall.Statfs_t
+			wd, _ := os.Getwd()
+			if err := syscall.Statfs(wd, &stat); err != nil {
+				return nil // Sem informação de disco não derrubamos o probe
+			}
+			if free := stat.Bavail * uint64(stat.Bsize); free < minFreeDiskBytes {
+				return fmt.Errorf("low disk space: %d bytes free", free)
+			}
+			return nil
+		}},
+		{Name: "job_queue", Run: func(ctx context.Context) error {
+			var failedJobs, pendingJobs int
+			if err := dbConn.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs WHERE status = 'failed'").Scan(&failedJobs); err != nil {
+				return err
+			}
+			if err := dbConn.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs WHERE status = 'pending'").Scan(&pendingJobs); err != nil {
+				return err
+			}
+			if failedJobs > 50 || pendingJobs > 1000 {
+				return fmt.Errorf("job queue issues: %d failed, %d pending", failedJobs, pendingJobs)
+			}
+			return nil
+		}},
+		{Name: "mailer", Run: m.Ping},
+		// Pausa é intencional (manutenção): aparece como degraded, não derruba o probe
+		{Name: "worker", Run: func(ctx context.Context) error {
+			if w.Paused() {
+				return errors.New("worker paused")
+			}
+			return nil
+		}},
+	}
+
+	if cfg.HealthCheckGemini {
+		// Sem API key o cliente compartilhado é nil: o check falha, mas o boot não
+		checks = append(checks, health.Check{Name: "gemini", Run: func(ctx context.Context) error {
+			if gemini == nil {
+				return service.ErrGeminiUnavailable
+			}
+			return gemini.HealthCheck(ctx)
+		}})
+	}
+
+	// Falhas aparecem no JSON do probe; o log facilita alertas
+	for i := range checks {
+		check := checks[i]
+		checks[i].Run = func(ctx context.Context) error {
+			err := check.Run(ctx)
+			if err != nil {
+				logger.WarnContext(ctx, "health check failed",
+					slog.String("check", check.Name),
+					slog.Bool("critical", check.Critical),
+					slog.String("error", err.Error()),
+				)
+			}
+			return err
+		}
+	}
+
+	return checks
+}
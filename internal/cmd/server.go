@@ -8,16 +8,19 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	_ "github.com/PauloHFS/elenchus/docs"
 	"github.com/PauloHFS/elenchus/internal/config"
 	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/health"
 	"github.com/PauloHFS/elenchus/internal/logging"
 	"github.com/PauloHFS/elenchus/internal/middleware"
+	"github.com/PauloHFS/elenchus/internal/policies"
+	"github.com/PauloHFS/elenchus/internal/service"
 	"github.com/PauloHFS/elenchus/internal/sse"
+	"github.com/PauloHFS/elenchus/internal/storage"
 	"github.com/PauloHFS/elenchus/internal/web"
 	"github.com/PauloHFS/elenchus/internal/webhook"
 	"github.com/PauloHFS/elenchus/internal/worker"
@@ -42,24 +45,35 @@ func RunServer(assetsFS embed.FS) {
 	logger := logging.Get()
 
 	// 1. DB (Hardening para Produção)
-	dsn := cfg.DatabaseURL
-	if strings.Contains(dsn, "?") {
-		dsn += "&_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
-	} else {
-		dsn += "?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
+	conn, err := db.ParseDatabaseURL(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("invalid DATABASE_URL", "error", err)
+		panic(err)
 	}
 
-	dbConn, err := sql.Open("sqlite3", dsn)
+	dbConn, err := sql.Open(conn.Driver, conn.DSN)
 	if err != nil {
 		logger.Error("failed to open database", "error", err)
 		panic(err)
 	}
 	defer dbConn.Close()
 
-	// 1.1 Garantir diretórios de storage
-	if err := os.MkdirAll("storage/avatars", 0755); err != nil {
-		logger.Error("failed to create storage directories", "error", err)
-		panic(err)
+	// 1.1 Storage dos arquivos enviados; o local garante os diretórios no boot
+	var blobs storage.BlobStorage
+	if cfg.StorageBackend == storage.BackendLocal {
+		if err := os.MkdirAll(cfg.AvatarDir(), 0755); err != nil {
+			logger.Error("failed to create storage directories", "error", err)
+			panic(err)
+		}
+		blobs = storage.NewLocalStorage(cfg.StorageDir, web.StorageURLPrefix)
+	} else {
+		objects, err := storage.NewObjectStorage(cfg.StorageBackend, cfg.StorageBucket, cfg.StoragePublicURL)
+		if err != nil {
+			logger.Error("failed to configure storage", "error", err)
+			panic(err)
+		}
+		logger.Warn("storage backend not implemented yet: avatar uploads will fail", "backend", cfg.StorageBackend)
+		blobs = objects
 	}
 
 	queries := db.New(dbConn)
@@ -69,65 +83,121 @@ func RunServer(assetsFS embed.FS) {
 		panic(err)
 	}
 
+	// Eventos de log com evaluation_id também vão para evaluation_logs (trace por avaliação)
+	logger = logging.EnableEvaluationSink(func(ctx context.Context, entry logging.EvaluationLog) error {
+		return queries.CreateEvaluationLog(ctx, db.CreateEvaluationLogParams{
+			EvaluationID: entry.EvaluationID,
+			Level:        entry.Level,
+			Message:      entry.Message,
+			Attrs:        entry.Attrs,
+			CreatedAt:    sql.NullTime{Time: entry.Time.UTC(), Valid: true},
+		})
+	})
+
+	policies.SetMonthlyEvaluationQuota(int64(cfg.MonthlyEvaluationQuota))
+	policies.SetRoleLevels(cfg.RoleLevels)
+
+	// Negações das policies: log estruturado e, se habilitado, access_denied_log
+	policies.SetDenialHook(func(ctx context.Context, d policies.Denial) {
+		policies.LogDenial(ctx, d)
+		if !cfg.AccessDeniedLog {
+			return
+		}
+		if err := queries.CreateAccessDenied(context.WithoutCancel(ctx), db.CreateAccessDeniedParams{
+			UserID:     d.UserID,
+			TenantID:   d.TenantID,
+			Resource:   string(d.Resource),
+			ResourceID: d.ResourceID,
+			Action:     string(d.Action),
+			Reason:     d.Reason,
+		}); err != nil {
+			logger.Warn("failed to record access denial", "error", err)
+		}
+	})
+
 	sessionManager := scs.New()
 	sessionManager.Store = sqlite3store.New(dbConn)
 
-	// Create SSE Broker
-	broker := sse.NewBroker()
+	// Create SSE Broker (Redis propaga eventos entre réplicas)
+	var bus sse.EventBus = sse.NewLocalBus()
+	if cfg.SSEBus == "redis" {
+		redisBus, err := sse.NewRedisBus(context.Background(), cfg.RedisURL)
+		if err != nil {
+			logger.Error("failed to connect sse event bus", "error", err)
+			panic(err)
+		}
+		bus = redisBus
+	}
+	broker, err := sse.NewBrokerWithBus(bus)
+	if err != nil {
+		logger.Error("failed to subscribe sse event bus", "error", err)
+		panic(err)
+	}
+	defer func() { _ = broker.Close() }()
+	broker.SetMaxLifetime(cfg.SSEMaxLifetime)
+	broker.SetAllowedOrigins(cfg.SSEAllowedOrigins)
+	broker.SetHeartbeatInterval(cfg.SSEHeartbeatInterval)
+
+	// Um único cliente Gemini para handlers e worker. Sem API key o servidor
+	// sobe mesmo assim; só as operações que dependem do Gemini falham.
+	geminiClient, err := service.NewGeminiClient(context.Background(), service.NewGeminiClientConfig())
+	if err != nil {
+		logger.Warn("gemini client unavailable", "error", err)
+	}
+	if geminiClient != nil && geminiClient.DryRun() {
+		logger.Warn("gemini dry-run enabled: evaluations use simulated responses")
+	}
+
+	// Prompts do protocolo lidos uma vez; arquivo inválido impede o boot
+	prompts, err := service.ProtocolPromptsFromEnv()
+	if err != nil {
+		logger.Error("failed to load protocol prompts", "error", err)
+		panic(err)
+	}
+	logger.Info("protocol prompts loaded", "prompt_set", prompts.Name)
 
 	workerCtx, cancelWorker := context.WithCancel(context.Background())
 	defer cancelWorker()
 
-	w := worker.New(cfg, dbConn, queries, logger, broker)
+	w := worker.New(cfg, dbConn, queries, logger, broker, geminiClient)
+	w.SetProtocolPrompts(prompts)
 	if err := w.RescueZombies(workerCtx); err != nil {
 		logger.Error("zombie hunter failed", "error", err)
 	}
 	go w.Start(workerCtx)
 
+	// SIGUSR1 pausa e SIGUSR2 retoma o worker, como POST /admin/worker/{pause,resume}
+	workerSignals := make(chan os.Signal, 1)
+	signal.Notify(workerSignals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range workerSignals {
+			if sig == syscall.SIGUSR1 {
+				w.Pause()
+			} else {
+				w.Resume()
+			}
+		}
+	}()
+
 	mux := http.NewServeMux()
 	mux.Handle("GET /assets/", http.StripPrefix("/assets/", http.FileServer(http.FS(assetsFS))))
-	mux.Handle("GET /storage/", http.StripPrefix("/storage/", http.FileServer(http.Dir("storage"))))
-	mux.Handle("GET /metrics", promhttp.Handler())
+	if cfg.StorageBackend == storage.BackendLocal {
+		mux.Handle("GET "+web.Avatar, web.AvatarHandler(cfg.AvatarDir()))
+	}
+	// Métricas: Bearer METRICS_TOKEN quando configurado, aberto em dev
+	if cfg.MetricsToken == "" && cfg.IsProduction() {
+		logger.Warn("METRICS_TOKEN not set: /metrics is publicly accessible")
+	}
+	mux.Handle("GET "+web.Metrics, middleware.RequireBearerToken(cfg.MetricsToken, promhttp.Handler()))
 	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
 
 	mux.Handle("POST /webhooks/{source}", webhook.NewHandler(queries))
 
-	mux.HandleFunc("GET "+web.Health, func(w http.ResponseWriter, r *http.Request) {
-		// 1. Ping DB
-		if err := dbConn.PingContext(r.Context()); err != nil {
-			logger.Error("health check failed: db unreachable", "error", err)
-			w.WriteHeader(http.StatusServiceUnavailable)
-			return
-		}
-
-		// 2. Check Jobs Health
-		var failedJobs int
-		_ = dbConn.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM jobs WHERE status = 'failed'").Scan(&failedJobs)
-
-		var pendingJobs int
-		_ = dbConn.QueryRowContext(r.Context(), "SELECT COUNT(*) FROM jobs WHERE status = 'pending'").Scan(&pendingJobs)
-
-		if failedJobs > 50 || pendingJobs > 1000 {
-			logger.Warn("health check warning: job queue issues", "failed", failedJobs, "pending", pendingJobs)
-		}
-
-		// 3. Disk Space Check
-		var stat syscall.Statfs_t
-		wd, _ := os.Getwd()
-		err := syscall.Statfs(wd, &stat)
-		if err == nil {
-			freeSpace := stat.Bavail * uint64(stat.Bsize)
-			if freeSpace < 100*1024*1024 { // Menos de 100MB
-				logger.Error("health check failed: low disk space", "free_bytes", freeSpace)
-				w.WriteHeader(http.StatusServiceUnavailable)
-				fmt.Fprintf(w, "Low disk space")
-				return
-			}
-		}
-
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+	// Liveness não depende de nada externo; readiness verifica as dependências
+	checks := healthChecks(cfg, dbConn, geminiClient, w, logger)
+	mux.Handle("GET "+web.HealthLive, health.LiveHandler())
+	mux.Handle("GET "+web.HealthReady, health.ReadyHandler(checks, health.DefaultTimeout))
+	mux.Handle("GET "+web.Health, health.ReadyHandler(checks, health.DefaultTimeout))
 
 	// Registrar handlers de negócio
 	web.RegisterRoutes(mux, web.HandlerDeps{
@@ -137,18 +207,27 @@ func RunServer(assetsFS embed.FS) {
 		Logger:         logger,
 		Config:         cfg,
 		SSEBroker:      broker,
+		GeminiClient:   geminiClient,
+		Worker:         w,
+		Storage:        blobs,
+		Prompts:        &prompts,
 	})
 
 	// Ordem dos middlewares (de fora para dentro):
-	// Recovery -> Logger -> RateLimit -> SecurityHeaders -> Locale -> Session -> CSRF
-	// Logger vem cedo para capturar TUDO, incluindo falhas CSRF e rate limit
+	// Recovery -> Logger -> Gzip -> RateLimit -> SecurityHeaders -> Locale -> Session -> CSRF -> HTMXOnly
+	// Logger vem cedo para capturar TUDO, incluindo falhas CSRF e rate limit.
+	// Gzip nunca envolve os streams (/sse e /metrics).
 	handler := middleware.Recovery(
 		middleware.Logger(
-			middleware.RateLimit(
-				middleware.SecurityHeaders(cfg.Env == "production")(
-					middleware.Locale(
-						sessionManager.LoadAndSave(
-							middleware.CSRFWithContext(mux),
+			middleware.Gzip("/sse", web.Metrics)(
+				middleware.NewRateLimiter("global", cfg.RateLimitGlobalPerMinute, cfg.RateLimitGlobalBurst, middleware.ByIP).Middleware(
+					middleware.SecurityHeaders(cfg.IsProduction())(
+						middleware.Locale(
+							sessionManager.LoadAndSave(
+								middleware.CSRFWithContext(
+									middleware.HTMXOnly(cfg.HTMXOnly)(mux),
+								),
+							),
 						),
 					),
 				),
@@ -157,8 +236,12 @@ func RunServer(assetsFS embed.FS) {
 	)
 
 	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: handler,
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
 	}
 
 	done := make(chan os.Signal, 1)
@@ -175,8 +258,12 @@ func RunServer(assetsFS embed.FS) {
 	<-done
 	logger.Info("server stopping")
 
+	// Para de pegar jobs e dá aos que estão rodando até WorkerShutdownTimeout;
+	// os que não terminarem são cancelados e voltam para a fila
 	cancelWorker()
-	w.Wait()
+	if !w.Shutdown(cfg.WorkerShutdownTimeout) {
+		logger.Warn("worker jobs still running after cancellation")
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/PauloHFS/elenchus/internal/db"
 	"golang.org/x/crypto/bcrypt"
@@ -11,11 +12,15 @@ import (
 
 func RunCreateUser() {
 	if len(os.Args) < 4 {
-		fmt.Println("Usage: create-user <email> <password>")
+		fmt.Println("Usage: create-user <email> <password> [tenant]")
 		os.Exit(1)
 	}
-	email := os.Args[2]
+	email := strings.ToLower(strings.TrimSpace(os.Args[2]))
 	password := os.Args[3]
+	tenantID := "default"
+	if len(os.Args) > 4 {
+		tenantID = os.Args[4]
+	}
 
 	dbConn, err := initDB()
 	if err != nil {
@@ -30,7 +35,7 @@ func RunCreateUser() {
 	}
 
 	_, err = queries.CreateUser(context.Background(), db.CreateUserParams{
-		TenantID:     "default",
+		TenantID:     tenantID,
 		Email:        email,
 		PasswordHash: string(hash),
 		RoleID:       "user",
@@ -39,5 +44,5 @@ func RunCreateUser() {
 		fmt.Printf("failed to create user: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("User %s created successfully\n", email)
+	fmt.Printf("User %s created successfully in tenant %s\n", email, tenantID)
 }
@@ -2,7 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +22,123 @@ type Config struct {
 	SMTPFrom      string
 	SessionSecret string
 	Env           string // "dev" or "prod"
+
+	// Bloqueia login de contas que ainda não verificaram o email
+	RequireEmailVerification bool
+
+	// Domínio base para resolver o tenant pelo subdomínio (acme.<domínio> → "acme").
+	// Vazio desativa a resolução por host; vale o campo do formulário ou "default".
+	TenantBaseDomain string
+
+	// Inclui o Gemini no readiness probe (cada probe consome uma requisição da quota)
+	HealthCheckGemini bool
+
+	// Token Bearer exigido em /metrics (vazio = aberto)
+	MetricsToken string
+
+	// Grava as decisões de autorização negadas em access_denied_log (além do log)
+	AccessDeniedLog bool
+
+	// Hierarquia de roles das policies (nil = policies.DefaultRoleLevels)
+	RoleLevels map[string]int
+
+	// Redireciona acessos diretos (sem HX-Request) a /htmx/* para a página cheia
+	HTMXOnly bool
+
+	// Diretório base dos arquivos enviados (avatars em <StorageDir>/avatars);
+	// relativo ao diretório de trabalho ou absoluto, ex: um volume montado
+	StorageDir string
+	// Backend dos arquivos enviados: "local" (StorageDir), "s3" ou "gcs".
+	// StoragePublicURL é a base das URLs servidas (vazio = endpoint público do bucket)
+	StorageBackend   string
+	StorageBucket    string
+	StoragePublicURL string
+
+	// SSE pub/sub: "local" (réplica única) ou "redis" (múltiplas réplicas)
+	SSEBus   string
+	RedisURL string
+	// Duração máxima de uma conexão SSE; ao atingir, o servidor envia
+	// "reconnect" e fecha, e o browser reabre
+	SSEMaxLifetime time.Duration
+	// Intervalo do evento SSE "heartbeat" para indicadores de conexão na UI
+	// (0 = desativado)
+	SSEHeartbeatInterval time.Duration
+	// Origens (scheme://host[:port]) de outros domínios que podem abrir o SSE
+	// com o cookie de sessão; vazio = só same-origin
+	SSEAllowedOrigins []string
+
+	// Avaliações em "processing" sem progresso no checkpoint além deste limite
+	// são consideradas órfãs e re-enfileiradas pelo worker
+	StuckEvaluationThreshold time.Duration
+
+	// Tempo que o shutdown espera os jobs em andamento antes de cancelá-los
+	WorkerShutdownTimeout time.Duration
+
+	// Intervalo de polling da fila de jobs e de busca por avaliações a retomar
+	WorkerPollInterval  time.Duration
+	WorkerRetryInterval time.Duration
+
+	// Limpeza periódica do worker: tokens expirados (verificação de email e
+	// reset de senha) e checkpoints de avaliações terminadas há mais de CheckpointRetention
+	CleanupInterval     time.Duration
+	CheckpointRetention time.Duration
+
+	// Destinatário dos alertas de jobs movidos para o DLQ (vazio = só log) e
+	// intervalo mínimo entre alertas do mesmo tipo de job
+	AdminAlertEmail         string
+	DeadLetterAlertInterval time.Duration
+
+	// Janela em que uma avaliação determinística idêntica é reaproveitada (0 desabilita)
+	EvaluationCacheTTL time.Duration
+
+	// Tamanho máximo do prompt base em caracteres, checado antes de gastar quota
+	MaxPromptChars int
+
+	// Importação em massa (CSV): linhas por upload, espaçamento entre os jobs
+	// (rate limit do Gemini) e limite de avaliações em andamento por tenant
+	BulkImportMaxRows    int
+	BulkImportInterval   time.Duration
+	MaxActiveEvaluations int
+	// Avaliações que cada tenant pode iniciar por mês (0 = sem limite);
+	// settings.monthly_evaluation_quota do tenant sobrescreve
+	MonthlyEvaluationQuota int
+
+	// Rate limiting HTTP (token bucket: requisições por minuto + burst).
+	// Global por IP; auth por IP nas rotas públicas; evaluations por usuário.
+	RateLimitGlobalPerMinute      int
+	RateLimitGlobalBurst          int
+	RateLimitAuthPerMinute        int
+	RateLimitAuthBurst            int
+	RateLimitEvaluationsPerMinute int
+	RateLimitEvaluationsBurst     int
+
+	// Bloqueio de conta: LoginMaxAttempts falhas dentro de LoginAttemptWindow
+	// bloqueiam o email por LoginLockoutDuration
+	LoginMaxAttempts     int
+	LoginAttemptWindow   time.Duration
+	LoginLockoutDuration time.Duration
+
+	// Força de senha no registro e no reset: comprimento mínimo e quantas
+	// classes de caracteres (minúscula, maiúscula, dígito, símbolo) são exigidas
+	PasswordMinLength  int
+	PasswordMinClasses int
+
+	// Timeouts do http.Server (proteção contra slowloris e conexões penduradas).
+	// O handler SSE remove o write deadline da própria conexão.
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+}
+
+// IsProduction reports whether the app runs with production settings
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// AvatarDir é o diretório onde os avatars são gravados e de onde são servidos
+func (c *Config) AvatarDir() string {
+	return filepath.Join(c.StorageDir, "avatars")
 }
 
 func Load() (*Config, error) {
@@ -32,10 +154,101 @@ func Load() (*Config, error) {
 		SMTPFrom:      getEnv("SMTP_FROM", "noreply@elenchus.com"),
 		SessionSecret: os.Getenv("SESSION_SECRET"),
 		Env:           getEnv("ENV", "development"),
+		SSEBus:        getEnv("SSE_BUS", "local"),
+		RedisURL:      os.Getenv("REDIS_URL"),
+		MetricsToken:  os.Getenv("METRICS_TOKEN"),
+		StorageDir:    getEnv("STORAGE_DIR", "storage"),
+
+		StorageBackend:   getEnv("STORAGE_BACKEND", "local"),
+		StorageBucket:    os.Getenv("STORAGE_BUCKET"),
+		StoragePublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+
+		SSEMaxLifetime:    getEnvDuration("SSE_MAX_LIFETIME", 30*time.Minute),
+		SSEAllowedOrigins: getEnvList("SSE_ALLOWED_ORIGINS"),
+
+		SSEHeartbeatInterval: getEnvDuration("SSE_HEARTBEAT_INTERVAL", 0),
+
+		TenantBaseDomain: os.Getenv("TENANT_BASE_DOMAIN"),
+
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		HealthCheckGemini:        getEnvBool("HEALTH_CHECK_GEMINI", false),
+		AccessDeniedLog:          getEnvBool("ACCESS_DENIED_LOG", true),
+		HTMXOnly:                 getEnvBool("HTMX_ONLY", false),
+
+		StuckEvaluationThreshold: time.Duration(getEnvInt("STUCK_EVALUATION_MINUTES", 15)) * time.Minute,
+		WorkerShutdownTimeout:    time.Duration(getEnvInt("WORKER_SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+		WorkerPollInterval:       getEnvDuration("WORKER_POLL_INTERVAL", time.Second),
+		WorkerRetryInterval:      getEnvDuration("WORKER_RETRY_INTERVAL", 30*time.Second),
+		CleanupInterval:          time.Duration(getEnvInt("CLEANUP_INTERVAL_MINUTES", 60)) * time.Minute,
+		CheckpointRetention:      time.Duration(getEnvInt("CHECKPOINT_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		AdminAlertEmail:          os.Getenv("ADMIN_ALERT_EMAIL"),
+		DeadLetterAlertInterval:  time.Duration(getEnvInt("DLQ_ALERT_INTERVAL_MINUTES", 15)) * time.Minute,
+		EvaluationCacheTTL:       time.Duration(getEnvInt("EVALUATION_CACHE_TTL_MINUTES", 60)) * time.Minute,
+
+		MaxPromptChars: getEnvInt("MAX_PROMPT_CHARS", 20000),
+
+		BulkImportMaxRows:    getEnvInt("BULK_IMPORT_MAX_ROWS", 100),
+		BulkImportInterval:   time.Duration(getEnvInt("BULK_IMPORT_INTERVAL_SECONDS", 20)) * time.Second,
+		MaxActiveEvaluations: getEnvInt("MAX_ACTIVE_EVALUATIONS", 200),
+
+		MonthlyEvaluationQuota: getEnvInt("MONTHLY_EVALUATION_QUOTA", 0),
+
+		RateLimitGlobalPerMinute:      getEnvInt("RATE_LIMIT_GLOBAL_PER_MINUTE", 300),
+		RateLimitGlobalBurst:          getEnvInt("RATE_LIMIT_GLOBAL_BURST", 10),
+		RateLimitAuthPerMinute:        getEnvInt("RATE_LIMIT_AUTH_PER_MINUTE", 10),
+		RateLimitAuthBurst:            getEnvInt("RATE_LIMIT_AUTH_BURST", 5),
+		RateLimitEvaluationsPerMinute: getEnvInt("RATE_LIMIT_EVALUATIONS_PER_MINUTE", 10),
+		RateLimitEvaluationsBurst:     getEnvInt("RATE_LIMIT_EVALUATIONS_BURST", 3),
+
+		LoginMaxAttempts:     getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginAttemptWindow:   time.Duration(getEnvInt("LOGIN_ATTEMPT_WINDOW_MINUTES", 15)) * time.Minute,
+		LoginLockoutDuration: time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+
+		PasswordMinLength:  getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinClasses: getEnvInt("PASSWORD_MIN_CLASSES", 2),
+
+		HTTPReadHeaderTimeout: time.Duration(getEnvInt("HTTP_READ_HEADER_TIMEOUT", 5)) * time.Second,
+		HTTPReadTimeout:       time.Duration(getEnvInt("HTTP_READ_TIMEOUT", 15)) * time.Second,
+		HTTPWriteTimeout:      time.Duration(getEnvInt("HTTP_WRITE_TIMEOUT", 30)) * time.Second,
+		HTTPIdleTimeout:       time.Duration(getEnvInt("HTTP_IDLE_TIMEOUT", 120)) * time.Second,
+	}
+
+	roleLevels, err := parseRoleLevels(os.Getenv("ROLE_LEVELS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.RoleLevels = roleLevels
+
+	// getEnvInt ignora zero; aqui zero é um valor válido (cache desabilitado)
+	if os.Getenv("EVALUATION_CACHE_TTL_MINUTES") == "0" {
+		cfg.EvaluationCacheTTL = 0
+	}
+
+	if cfg.SSEBus != "local" && cfg.SSEBus != "redis" {
+		return nil, fmt.Errorf("SSE_BUS inválido: %q (use local ou redis)", cfg.SSEBus)
+	}
+	if cfg.SSEBus == "redis" && cfg.RedisURL == "" {
+		return nil, fmt.Errorf("SSE_BUS=redis requer REDIS_URL")
+	}
+	for _, origin := range cfg.SSEAllowedOrigins {
+		// "*" com credenciais é recusado pelos browsers e abriria o stream a qualquer site
+		if u, err := url.Parse(origin); origin == "*" || err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return nil, fmt.Errorf("SSE_ALLOWED_ORIGINS inválido: %q (use scheme://host[:port])", origin)
+		}
+	}
+
+	switch cfg.StorageBackend {
+	case "local":
+	case "s3", "gcs":
+		if cfg.StorageBucket == "" {
+			return nil, fmt.Errorf("STORAGE_BACKEND=%s requer STORAGE_BUCKET", cfg.StorageBackend)
+		}
+	default:
+		return nil, fmt.Errorf("STORAGE_BACKEND inválido: %q (use local, s3 ou gcs)", cfg.StorageBackend)
 	}
 
 	// Validação Estrita para Produção
-	if cfg.Env == "production" {
+	if cfg.IsProduction() {
 		if cfg.SMTPPass == "" {
 			return nil, fmt.Errorf("produção: SMTP_PASS é obrigatório")
 		}
@@ -62,6 +275,47 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		switch value {
+		case "true", "1", "yes":
+			return true
+		case "false", "0", "no":
+			return false
+		}
+	}
+	return fallback
+}
+
+// getEnvList lê uma lista separada por vírgula, sem itens vazios
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRoleLevels lê ROLE_LEVELS no formato "role=nível,..." (vazio = nil)
+func parseRoleLevels(value string) (map[string]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	levels := make(map[string]int)
+	for _, item := range strings.Split(value, ",") {
+		role, level, ok := strings.Cut(strings.TrimSpace(item), "=")
+		n, err := strconv.Atoi(strings.TrimSpace(level))
+		role = strings.TrimSpace(role)
+		if !ok || role == "" || err != nil || n <= 0 {
+			return nil, fmt.Errorf("ROLE_LEVELS inválido: %q (use role=nível,...)", item)
+		}
+		levels[role] = n
+	}
+	return levels, nil
+}
+
 func getEnvInt(key string, fallback int) int {
 	if value, ok := os.LookupEnv(key); ok {
 		var result int
@@ -72,3 +326,14 @@ func getEnvInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// getEnvDuration lê uma duração no formato do Go ("500ms", "2s", "1m");
+// valores inválidos ou não positivos caem no fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
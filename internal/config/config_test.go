@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -19,13 +21,152 @@ func TestLoad(t *testing.T) {
 
 	t.Run("ProductionValidation", func(t *testing.T) {
 		os.Clearenv()
-		os.Setenv("APP_ENV", "prod")
+		os.Setenv("ENV", "production")
 		_, err := Load()
 		if err == nil {
 			t.Error("expected error when SMTP_PASS is missing in production")
 		}
 	})
 
+	t.Run("WorkerIntervals", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("WORKER_POLL_INTERVAL", "250ms")
+		os.Setenv("WORKER_RETRY_INTERVAL", "abc")
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.WorkerPollInterval != 250*time.Millisecond {
+			t.Errorf("expected poll interval 250ms, got %v", cfg.WorkerPollInterval)
+		}
+		if cfg.WorkerRetryInterval != 30*time.Second {
+			t.Errorf("expected invalid retry interval to fall back to 30s, got %v", cfg.WorkerRetryInterval)
+		}
+	})
+
+	t.Run("StorageDir", func(t *testing.T) {
+		os.Clearenv()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.AvatarDir() != filepath.Join("storage", "avatars") {
+			t.Errorf("expected default avatar dir storage/avatars, got %s", cfg.AvatarDir())
+		}
+
+		os.Setenv("STORAGE_DIR", "/var/lib/elenchus")
+		cfg, err = Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.AvatarDir() != filepath.Join("/var/lib/elenchus", "avatars") {
+			t.Errorf("expected avatar dir under STORAGE_DIR, got %s", cfg.AvatarDir())
+		}
+	})
+
+	t.Run("StorageBackend", func(t *testing.T) {
+		os.Clearenv()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.StorageBackend != "local" {
+			t.Errorf("expected default storage backend local, got %s", cfg.StorageBackend)
+		}
+
+		os.Setenv("STORAGE_BACKEND", "s3")
+		if _, err := Load(); err == nil {
+			t.Error("expected error when STORAGE_BUCKET is missing for s3")
+		}
+		os.Setenv("STORAGE_BUCKET", "avatars")
+		if _, err := Load(); err != nil {
+			t.Errorf("expected no error with bucket set, got %v", err)
+		}
+
+		os.Setenv("STORAGE_BACKEND", "ftp")
+		if _, err := Load(); err == nil {
+			t.Error("expected error for unknown storage backend")
+		}
+	})
+
+	t.Run("SSEMaxLifetime", func(t *testing.T) {
+		os.Clearenv()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.SSEMaxLifetime != 30*time.Minute {
+			t.Errorf("expected default SSE max lifetime 30m, got %v", cfg.SSEMaxLifetime)
+		}
+
+		os.Setenv("SSE_MAX_LIFETIME", "5m")
+		cfg, err = Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.SSEMaxLifetime != 5*time.Minute {
+			t.Errorf("expected SSE max lifetime 5m, got %v", cfg.SSEMaxLifetime)
+		}
+
+		if cfg.SSEHeartbeatInterval != 0 {
+			t.Errorf("heartbeat should be disabled by default, got %v", cfg.SSEHeartbeatInterval)
+		}
+		os.Setenv("SSE_HEARTBEAT_INTERVAL", "15s")
+		cfg, err = Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.SSEHeartbeatInterval != 15*time.Second {
+			t.Errorf("expected heartbeat interval 15s, got %v", cfg.SSEHeartbeatInterval)
+		}
+	})
+
+	t.Run("SSEAllowedOrigins", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("SSE_ALLOWED_ORIGINS", "https://app.example.com, http://localhost:3000")
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(cfg.SSEAllowedOrigins) != 2 || cfg.SSEAllowedOrigins[1] != "http://localhost:3000" {
+			t.Errorf("unexpected origins: %v", cfg.SSEAllowedOrigins)
+		}
+
+		for _, invalid := range []string{"*", "app.example.com", "https://app.example.com/sse"} {
+			os.Setenv("SSE_ALLOWED_ORIGINS", invalid)
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for SSE_ALLOWED_ORIGINS=%q", invalid)
+			}
+		}
+	})
+
+	t.Run("RoleLevels", func(t *testing.T) {
+		os.Clearenv()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cfg.RoleLevels != nil {
+			t.Errorf("expected nil role levels by default, got %v", cfg.RoleLevels)
+		}
+
+		os.Setenv("ROLE_LEVELS", "viewer=10, owner=100")
+		cfg, err = Load()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(cfg.RoleLevels) != 2 || cfg.RoleLevels["owner"] != 100 {
+			t.Errorf("unexpected role levels: %v", cfg.RoleLevels)
+		}
+
+		for _, invalid := range []string{"owner", "owner=x", "=10", "owner=0"} {
+			os.Setenv("ROLE_LEVELS", invalid)
+			if _, err := Load(); err == nil {
+				t.Errorf("expected error for ROLE_LEVELS=%q", invalid)
+			}
+		}
+	})
+
 	t.Run("CustomValues", func(t *testing.T) {
 		os.Clearenv()
 		os.Setenv("PORT", "9000")
@@ -5,3 +5,4 @@ type contextKey string
 const UserContextKey contextKey = "user"
 const LocaleKey contextKey = "locale"
 const CSRFTokenKey contextKey = "csrf_token"
+const EvaluationContextKey contextKey = "evaluation"
@@ -4,15 +4,36 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"sort"
 	"strings"
 
 	"github.com/PauloHFS/elenchus/migrations"
 )
 
-// RunMigrations executa todos os arquivos .sql do FS embutido em ordem alfabética.
+// RunMigrations executa os arquivos .sql do FS embutido em ordem alfabética.
+// Cada arquivo é aplicado uma única vez e registrado em schema_migrations,
+// o que permite migrações não idempotentes (ex: ALTER TABLE ADD COLUMN).
+// Cada arquivo roda numa transação junto com o registro: se um comando
+// falha, nada do arquivo fica aplicado e o próximo boot tenta de novo.
 func RunMigrations(ctx context.Context, db *sql.DB) error {
-	entries, err := migrations.FS.ReadDir(".")
+	return runMigrations(ctx, db, migrations.FS)
+}
+
+func runMigrations(ctx context.Context, db *sql.DB, fsys fs.FS) error {
+	// Antes ficava a cargo do 001_schema.sql, que agora roda apenas uma vez
+	if _, err := db.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		return fmt.Errorf("falha ao habilitar foreign keys: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("falha ao criar schema_migrations: %w", err)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return fmt.Errorf("falha ao ler diretório de migrações: %w", err)
 	}
@@ -26,15 +47,50 @@ func RunMigrations(ctx context.Context, db *sql.DB) error {
 	sort.Strings(filenames)
 
 	for _, name := range filenames {
-		content, err := migrations.FS.ReadFile(name)
+		var applied int
+		if err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM schema_migrations WHERE name = ?", name,
+		).Scan(&applied); err != nil {
+			return fmt.Errorf("falha ao verificar migração %s: %w", name, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, name)
 		if err != nil {
 			return fmt.Errorf("falha ao ler arquivo %s: %w", name, err)
 		}
 
-		if _, err := db.ExecContext(ctx, string(content)); err != nil {
-			return fmt.Errorf("falha ao executar migração %s: %w", name, err)
+		if err := applyMigration(ctx, db, name, string(content)); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// applyMigration executa o arquivo e o registra em schema_migrations na mesma
+// transação (DDL é transacional no SQLite)
+func applyMigration(ctx context.Context, db *sql.DB, name, content string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar migração %s: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, content); err != nil {
+		return fmt.Errorf("falha ao executar migração %s: %w", name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (name) VALUES (?)", name,
+	); err != nil {
+		return fmt.Errorf("falha ao registrar migração %s: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("falha ao confirmar migração %s: %w", name, err)
+	}
+	return nil
+}
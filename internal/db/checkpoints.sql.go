@@ -11,6 +11,26 @@ import (
 	"encoding/json"
 )
 
+const cancelEvaluation = `-- name: CancelEvaluation :execrows
+UPDATE evaluations
+SET status = 'cancelled',
+    error_message = ?
+WHERE id = ? AND status IN ('pending', 'processing', 'retrying')
+`
+
+type CancelEvaluationParams struct {
+	ErrorMessage sql.NullString `json:"error_message"`
+	ID           string         `json:"id"`
+}
+
+func (q *Queries) CancelEvaluation(ctx context.Context, arg CancelEvaluationParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelEvaluation, arg.ErrorMessage, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const clearCheckpointRetry = `-- name: ClearCheckpointRetry :exec
 UPDATE evaluation_checkpoints
 SET next_retry_at = NULL,
@@ -57,8 +77,41 @@ func (q *Queries) DeleteCheckpoint(ctx context.Context, evaluationID string) err
 	return err
 }
 
+const deleteFinishedCheckpoints = `-- name: DeleteFinishedCheckpoints :execrows
+DELETE FROM evaluation_checkpoints
+WHERE updated_at < datetime('now', '-' || ?1 || ' minutes')
+  AND evaluation_id IN (
+    SELECT id FROM evaluations WHERE status IN ('completed', 'failed', 'cancelled')
+  )
+`
+
+func (q *Queries) DeleteFinishedCheckpoints(ctx context.Context, retentionMinutes sql.NullString) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFinishedCheckpoints, retentionMinutes)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const failEvaluation = `-- name: FailEvaluation :exec
+UPDATE evaluations
+SET status = 'failed',
+    error_message = ?
+WHERE id = ?
+`
+
+type FailEvaluationParams struct {
+	ErrorMessage sql.NullString `json:"error_message"`
+	ID           string         `json:"id"`
+}
+
+func (q *Queries) FailEvaluation(ctx context.Context, arg FailEvaluationParams) error {
+	_, err := q.db.ExecContext(ctx, failEvaluation, arg.ErrorMessage, arg.ID)
+	return err
+}
+
 const getCheckpoint = `-- name: GetCheckpoint :one
-SELECT evaluation_id, current_phase, messages, embedding_inicial, embedding_confronto, divergencia_calculada, diagnostico_final, retry_count, last_retry_at, next_retry_at, created_at, updated_at FROM evaluation_checkpoints
+SELECT evaluation_id, current_phase, messages, embedding_inicial, embedding_confronto, divergencia_calculada, diagnostico_final, retry_count, last_retry_at, next_retry_at, created_at, updated_at, progress_step, progress_phase FROM evaluation_checkpoints
 WHERE evaluation_id = ?
 `
 
@@ -78,14 +131,16 @@ func (q *Queries) GetCheckpoint(ctx context.Context, evaluationID string) (Evalu
 		&i.NextRetryAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProgressStep,
+		&i.ProgressPhase,
 	)
 	return i, err
 }
 
 const getEvaluationsToRetry = `-- name: GetEvaluationsToRetry :many
-SELECT e.id, e.tenant_id, e.user_id, e.prompt_base, e.status, e.idempotency_key, e.error_message, e.retry_count, e.created_at FROM evaluations e
+SELECT e.id, e.tenant_id, e.user_id, e.prompt_base, e.status, e.idempotency_key, e.error_message, e.retry_count, e.created_at, e.sampling_params, e.config_hash, e.experiment_id, e.parent_evaluation_id, e.total_tokens FROM evaluations e
 INNER JOIN evaluation_checkpoints c ON e.id = c.evaluation_id
-WHERE e.status = 'processing'
+WHERE e.status = 'retrying'
   AND c.next_retry_at IS NOT NULL
   AND c.next_retry_at <= CURRENT_TIMESTAMP
 `
@@ -109,6 +164,11 @@ func (q *Queries) GetEvaluationsToRetry(ctx context.Context) ([]Evaluation, erro
 			&i.ErrorMessage,
 			&i.RetryCount,
 			&i.CreatedAt,
+			&i.SamplingParams,
+			&i.ConfigHash,
+			&i.ExperimentID,
+			&i.ParentEvaluationID,
+			&i.TotalTokens,
 		); err != nil {
 			return nil, err
 		}
@@ -124,15 +184,16 @@ func (q *Queries) GetEvaluationsToRetry(ctx context.Context) ([]Evaluation, erro
 }
 
 const getStuckEvaluations = `-- name: GetStuckEvaluations :many
-SELECT e.id, e.tenant_id, e.user_id, e.prompt_base, e.status, e.idempotency_key, e.error_message, e.retry_count, e.created_at FROM evaluations e
-INNER JOIN evaluation_checkpoints c ON e.id = c.evaluation_id
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations e
 WHERE e.status = 'processing'
-  AND c.next_retry_at IS NULL
-  AND e.updated_at < datetime('now', '-5 minutes')
+  AND COALESCE(
+    (SELECT c.updated_at FROM evaluation_checkpoints c WHERE c.evaluation_id = e.id),
+    e.created_at
+  ) < datetime('now', '-' || ?1 || ' minutes')
 `
 
-func (q *Queries) GetStuckEvaluations(ctx context.Context) ([]Evaluation, error) {
-	rows, err := q.db.QueryContext(ctx, getStuckEvaluations)
+func (q *Queries) GetStuckEvaluations(ctx context.Context, thresholdMinutes sql.NullString) ([]Evaluation, error) {
+	rows, err := q.db.QueryContext(ctx, getStuckEvaluations, thresholdMinutes)
 	if err != nil {
 		return nil, err
 	}
@@ -150,6 +211,11 @@ func (q *Queries) GetStuckEvaluations(ctx context.Context) ([]Evaluation, error)
 			&i.ErrorMessage,
 			&i.RetryCount,
 			&i.CreatedAt,
+			&i.SamplingParams,
+			&i.ConfigHash,
+			&i.ExperimentID,
+			&i.ParentEvaluationID,
+			&i.TotalTokens,
 		); err != nil {
 			return nil, err
 		}
@@ -164,6 +230,18 @@ func (q *Queries) GetStuckEvaluations(ctx context.Context) ([]Evaluation, error)
 	return items, nil
 }
 
+const requeueStuckEvaluation = `-- name: RequeueStuckEvaluation :exec
+UPDATE evaluations
+SET status = 'pending',
+    retry_count = retry_count + 1
+WHERE id = ? AND status = 'processing'
+`
+
+func (q *Queries) RequeueStuckEvaluation(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, requeueStuckEvaluation, id)
+	return err
+}
+
 const updateCheckpointDivergence = `-- name: UpdateCheckpointDivergence :exec
 UPDATE evaluation_checkpoints
 SET divergencia_calculada = ?,
@@ -235,6 +313,25 @@ func (q *Queries) UpdateCheckpointPhase(ctx context.Context, arg UpdateCheckpoin
 	return err
 }
 
+const updateCheckpointProgress = `-- name: UpdateCheckpointProgress :exec
+UPDATE evaluation_checkpoints
+SET progress_step = ?,
+    progress_phase = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE evaluation_id = ?
+`
+
+type UpdateCheckpointProgressParams struct {
+	ProgressStep  int64          `json:"progress_step"`
+	ProgressPhase sql.NullString `json:"progress_phase"`
+	EvaluationID  string         `json:"evaluation_id"`
+}
+
+func (q *Queries) UpdateCheckpointProgress(ctx context.Context, arg UpdateCheckpointProgressParams) error {
+	_, err := q.db.ExecContext(ctx, updateCheckpointProgress, arg.ProgressStep, arg.ProgressPhase, arg.EvaluationID)
+	return err
+}
+
 const updateCheckpointRetry = `-- name: UpdateCheckpointRetry :exec
 UPDATE evaluation_checkpoints
 SET retry_count = retry_count + 1,
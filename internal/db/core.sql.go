@@ -12,6 +12,48 @@ import (
 	"time"
 )
 
+const addEvaluationTokens = `-- name: AddEvaluationTokens :exec
+UPDATE evaluations SET total_tokens = total_tokens + ?1 WHERE id = ?2
+`
+
+type AddEvaluationTokensParams struct {
+	Tokens int64  `json:"tokens"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) AddEvaluationTokens(ctx context.Context, arg AddEvaluationTokensParams) error {
+	_, err := q.db.ExecContext(ctx, addEvaluationTokens, arg.Tokens, arg.ID)
+	return err
+}
+
+const cancelPendingJob = `-- name: CancelPendingJob :one
+UPDATE jobs
+SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND status = 'pending'
+RETURNING id, tenant_id, type, payload, status, idempotency_key, attempt_count, max_attempts, last_error, run_at, created_at, updated_at, evaluation_id
+`
+
+func (q *Queries) CancelPendingJob(ctx context.Context, id int64) (Job, error) {
+	row := q.db.QueryRowContext(ctx, cancelPendingJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Type,
+		&i.Payload,
+		&i.Status,
+		&i.IdempotencyKey,
+		&i.AttemptCount,
+		&i.MaxAttempts,
+		&i.LastError,
+		&i.RunAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EvaluationID,
+	)
+	return i, err
+}
+
 const completeJob = `-- name: CompleteJob :exec
 UPDATE jobs 
 SET status = 'completed', updated_at = CURRENT_TIMESTAMP 
@@ -39,6 +81,24 @@ func (q *Queries) CountEvaluations(ctx context.Context, arg CountEvaluationsPara
 	return count, err
 }
 
+const countJobsFiltered = `-- name: CountJobsFiltered :one
+SELECT COUNT(*) FROM jobs
+WHERE (CAST(?1 AS TEXT) = '' OR type = CAST(?1 AS TEXT))
+  AND (CAST(?2 AS TEXT) = '' OR status = CAST(?2 AS TEXT))
+`
+
+type CountJobsFilteredParams struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) CountJobsFiltered(ctx context.Context, arg CountJobsFilteredParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countJobsFiltered, arg.Type, arg.Status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUsers = `-- name: CountUsers :one
 SELECT COUNT(*) FROM users WHERE tenant_id = ?
 `
@@ -51,8 +111,8 @@ func (q *Queries) CountUsers(ctx context.Context, tenantID string) (int64, error
 }
 
 const createAudit = `-- name: CreateAudit :one
-INSERT INTO audits (id, evaluation_id, divergencia, diagnostico) 
-VALUES (?, ?, ?, ?) RETURNING id, evaluation_id, divergencia, diagnostico, created_at
+INSERT INTO audits (id, evaluation_id, divergencia, diagnostico, metrica, prompt_set, severidade)
+VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id, evaluation_id, divergencia, diagnostico, created_at, metrica, prompt_set, severidade
 `
 
 type CreateAuditParams struct {
@@ -60,6 +120,9 @@ type CreateAuditParams struct {
 	EvaluationID string  `json:"evaluation_id"`
 	Divergencia  float64 `json:"divergencia"`
 	Diagnostico  string  `json:"diagnostico"`
+	Metrica      string  `json:"metrica"`
+	PromptSet    string  `json:"prompt_set"`
+	Severidade   string  `json:"severidade"`
 }
 
 func (q *Queries) CreateAudit(ctx context.Context, arg CreateAuditParams) (Audit, error) {
@@ -68,6 +131,9 @@ func (q *Queries) CreateAudit(ctx context.Context, arg CreateAuditParams) (Audit
 		arg.EvaluationID,
 		arg.Divergencia,
 		arg.Diagnostico,
+		arg.Metrica,
+		arg.PromptSet,
+		arg.Severidade,
 	)
 	var i Audit
 	err := row.Scan(
@@ -76,21 +142,29 @@ func (q *Queries) CreateAudit(ctx context.Context, arg CreateAuditParams) (Audit
 		&i.Divergencia,
 		&i.Diagnostico,
 		&i.CreatedAt,
+		&i.Metrica,
+		&i.PromptSet,
+		&i.Severidade,
 	)
 	return i, err
 }
 
 const createEvaluation = `-- name: CreateEvaluation :one
-INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status) 
-VALUES (?, ?, ?, ?, ?) RETURNING id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at
+INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, sampling_params, config_hash, experiment_id, idempotency_key, parent_evaluation_id) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens
 `
 
 type CreateEvaluationParams struct {
-	ID         string `json:"id"`
-	TenantID   string `json:"tenant_id"`
-	UserID     int64  `json:"user_id"`
-	PromptBase string `json:"prompt_base"`
-	Status     string `json:"status"`
+	ID                 string         `json:"id"`
+	TenantID           string         `json:"tenant_id"`
+	UserID             int64          `json:"user_id"`
+	PromptBase         string         `json:"prompt_base"`
+	Status             string         `json:"status"`
+	SamplingParams     []byte         `json:"sampling_params"`
+	ConfigHash         sql.NullString `json:"config_hash"`
+	ExperimentID       sql.NullString `json:"experiment_id"`
+	IdempotencyKey     sql.NullString `json:"idempotency_key"`
+	ParentEvaluationID sql.NullString `json:"parent_evaluation_id"`
 }
 
 func (q *Queries) CreateEvaluation(ctx context.Context, arg CreateEvaluationParams) (Evaluation, error) {
@@ -100,6 +174,11 @@ func (q *Queries) CreateEvaluation(ctx context.Context, arg CreateEvaluationPara
 		arg.UserID,
 		arg.PromptBase,
 		arg.Status,
+		arg.SamplingParams,
+		arg.ConfigHash,
+		arg.ExperimentID,
+		arg.IdempotencyKey,
+		arg.ParentEvaluationID,
 	)
 	var i Evaluation
 	err := row.Scan(
@@ -112,19 +191,43 @@ func (q *Queries) CreateEvaluation(ctx context.Context, arg CreateEvaluationPara
 		&i.ErrorMessage,
 		&i.RetryCount,
 		&i.CreatedAt,
+		&i.SamplingParams,
+		&i.ConfigHash,
+		&i.ExperimentID,
+		&i.ParentEvaluationID,
+		&i.TotalTokens,
 	)
 	return i, err
 }
 
+const createEvaluationAttachment = `-- name: CreateEvaluationAttachment :exec
+INSERT INTO evaluation_attachments (evaluation_id, mime_type, data)
+VALUES (?, ?, ?)
+`
+
+type CreateEvaluationAttachmentParams struct {
+	EvaluationID string `json:"evaluation_id"`
+	MimeType     string `json:"mime_type"`
+	Data         []byte `json:"data"`
+}
+
+func (q *Queries) CreateEvaluationAttachment(ctx context.Context, arg CreateEvaluationAttachmentParams) error {
+	_, err := q.db.ExecContext(ctx, createEvaluationAttachment, arg.EvaluationID, arg.MimeType, arg.Data)
+	return err
+}
+
 const createIteration = `-- name: CreateIteration :one
-INSERT INTO iterations (id, evaluation_id, fase, resposta, embedding) 
-VALUES (?, ?, ?, ?, ?) RETURNING id, evaluation_id, fase, resposta, embedding, created_at
+INSERT INTO iterations (id, evaluation_id, fase, prompt, resposta, embedding) 
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(evaluation_id, fase) DO UPDATE SET prompt = excluded.prompt, resposta = excluded.resposta, embedding = excluded.embedding
+RETURNING id, evaluation_id, fase, resposta, embedding, created_at, prompt
 `
 
 type CreateIterationParams struct {
 	ID           string `json:"id"`
 	EvaluationID string `json:"evaluation_id"`
 	Fase         string `json:"fase"`
+	Prompt       string `json:"prompt"`
 	Resposta     string `json:"resposta"`
 	Embedding    []byte `json:"embedding"`
 }
@@ -134,6 +237,7 @@ func (q *Queries) CreateIteration(ctx context.Context, arg CreateIterationParams
 		arg.ID,
 		arg.EvaluationID,
 		arg.Fase,
+		arg.Prompt,
 		arg.Resposta,
 		arg.Embedding,
 	)
@@ -145,19 +249,21 @@ func (q *Queries) CreateIteration(ctx context.Context, arg CreateIterationParams
 		&i.Resposta,
 		&i.Embedding,
 		&i.CreatedAt,
+		&i.Prompt,
 	)
 	return i, err
 }
 
 const createJob = `-- name: CreateJob :one
-INSERT INTO jobs (tenant_id, type, payload, run_at) VALUES (?, ?, ?, ?) RETURNING id, tenant_id, type, payload, status, idempotency_key, attempt_count, max_attempts, last_error, run_at, created_at, updated_at
+INSERT INTO jobs (tenant_id, type, payload, run_at, evaluation_id) VALUES (?, ?, ?, ?, ?) RETURNING id, tenant_id, type, payload, status, idempotency_key, attempt_count, max_attempts, last_error, run_at, created_at, updated_at, evaluation_id
 `
 
 type CreateJobParams struct {
-	TenantID sql.NullString  `json:"tenant_id"`
-	Type     string          `json:"type"`
-	Payload  json.RawMessage `json:"payload"`
-	RunAt    sql.NullTime    `json:"run_at"`
+	TenantID     sql.NullString  `json:"tenant_id"`
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload"`
+	RunAt        sql.NullTime    `json:"run_at"`
+	EvaluationID sql.NullString  `json:"evaluation_id"`
 }
 
 func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, error) {
@@ -166,6 +272,7 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, erro
 		arg.Type,
 		arg.Payload,
 		arg.RunAt,
+		arg.EvaluationID,
 	)
 	var i Job
 	err := row.Scan(
@@ -181,6 +288,7 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, erro
 		&i.RunAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EvaluationID,
 	)
 	return i, err
 }
@@ -251,20 +359,54 @@ func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (W
 }
 
 const deleteEmailVerification = `-- name: DeleteEmailVerification :exec
-DELETE FROM email_verifications WHERE email = ?
+DELETE FROM email_verifications WHERE tenant_id = ? AND email = ?
 `
 
-func (q *Queries) DeleteEmailVerification(ctx context.Context, email string) error {
-	_, err := q.db.ExecContext(ctx, deleteEmailVerification, email)
+type DeleteEmailVerificationParams struct {
+	TenantID string `json:"tenant_id"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) DeleteEmailVerification(ctx context.Context, arg DeleteEmailVerificationParams) error {
+	_, err := q.db.ExecContext(ctx, deleteEmailVerification, arg.TenantID, arg.Email)
 	return err
 }
 
+const deleteExpiredEmailVerifications = `-- name: DeleteExpiredEmailVerifications :execrows
+DELETE FROM email_verifications WHERE expires_at < ?
+`
+
+func (q *Queries) DeleteExpiredEmailVerifications(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredEmailVerifications, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredPasswordResets = `-- name: DeleteExpiredPasswordResets :execrows
+DELETE FROM password_resets WHERE expires_at < ?
+`
+
+func (q *Queries) DeleteExpiredPasswordResets(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredPasswordResets, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deletePasswordReset = `-- name: DeletePasswordReset :exec
-DELETE FROM password_resets WHERE email = ?
+DELETE FROM password_resets WHERE tenant_id = ? AND email = ?
 `
 
-func (q *Queries) DeletePasswordReset(ctx context.Context, email string) error {
-	_, err := q.db.ExecContext(ctx, deletePasswordReset, email)
+type DeletePasswordResetParams struct {
+	TenantID string `json:"tenant_id"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) DeletePasswordReset(ctx context.Context, arg DeletePasswordResetParams) error {
+	_, err := q.db.ExecContext(ctx, deletePasswordReset, arg.TenantID, arg.Email)
 	return err
 }
 
@@ -283,7 +425,7 @@ func (q *Queries) FailJob(ctx context.Context, arg FailJobParams) error {
 }
 
 const getAuditByEvaluation = `-- name: GetAuditByEvaluation :one
-SELECT id, evaluation_id, divergencia, diagnostico, created_at FROM audits WHERE evaluation_id = ? LIMIT 1
+SELECT id, evaluation_id, divergencia, diagnostico, created_at, metrica, prompt_set, severidade FROM audits WHERE evaluation_id = ? LIMIT 1
 `
 
 func (q *Queries) GetAuditByEvaluation(ctx context.Context, evaluationID string) (Audit, error) {
@@ -295,18 +437,22 @@ func (q *Queries) GetAuditByEvaluation(ctx context.Context, evaluationID string)
 		&i.Divergencia,
 		&i.Diagnostico,
 		&i.CreatedAt,
+		&i.Metrica,
+		&i.PromptSet,
+		&i.Severidade,
 	)
 	return i, err
 }
 
 const getEmailVerificationByToken = `-- name: GetEmailVerificationByToken :one
-SELECT email, token, expires_at, created_at FROM email_verifications WHERE token = ? LIMIT 1
+SELECT tenant_id, email, token, expires_at, created_at FROM email_verifications WHERE token = ? LIMIT 1
 `
 
 func (q *Queries) GetEmailVerificationByToken(ctx context.Context, token string) (EmailVerification, error) {
 	row := q.db.QueryRowContext(ctx, getEmailVerificationByToken, token)
 	var i EmailVerification
 	err := row.Scan(
+		&i.TenantID,
 		&i.Email,
 		&i.Token,
 		&i.ExpiresAt,
@@ -316,7 +462,7 @@ func (q *Queries) GetEmailVerificationByToken(ctx context.Context, token string)
 }
 
 const getEvaluationByID = `-- name: GetEvaluationByID :one
-SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at FROM evaluations WHERE id = ? LIMIT 1
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetEvaluationByID(ctx context.Context, id string) (Evaluation, error) {
@@ -332,12 +478,72 @@ func (q *Queries) GetEvaluationByID(ctx context.Context, id string) (Evaluation,
 		&i.ErrorMessage,
 		&i.RetryCount,
 		&i.CreatedAt,
+		&i.SamplingParams,
+		&i.ConfigHash,
+		&i.ExperimentID,
+		&i.ParentEvaluationID,
+		&i.TotalTokens,
+	)
+	return i, err
+}
+
+const getEvaluationJobAttempts = `-- name: GetEvaluationJobAttempts :one
+SELECT CAST(COUNT(*) + COALESCE(SUM(attempt_count), 0) AS INTEGER) AS attempts
+FROM jobs
+WHERE type = 'run_evaluation'
+  AND status != 'cancelled'
+  AND evaluation_id = ?
+`
+
+func (q *Queries) GetEvaluationJobAttempts(ctx context.Context, evaluationID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getEvaluationJobAttempts, evaluationID)
+	var attempts int64
+	err := row.Scan(&attempts)
+	return attempts, err
+}
+
+const getEvaluationLastJobError = `-- name: GetEvaluationLastJobError :one
+SELECT last_error FROM jobs
+WHERE type = 'run_evaluation'
+  AND last_error IS NOT NULL
+  AND evaluation_id = ?
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetEvaluationLastJobError(ctx context.Context, evaluationID sql.NullString) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, getEvaluationLastJobError, evaluationID)
+	var last_error sql.NullString
+	err := row.Scan(&last_error)
+	return last_error, err
+}
+
+const getIterationByPhase = `-- name: GetIterationByPhase :one
+SELECT id, evaluation_id, fase, resposta, embedding, created_at, prompt FROM iterations WHERE evaluation_id = ? AND fase = ? ORDER BY created_at ASC LIMIT 1
+`
+
+type GetIterationByPhaseParams struct {
+	EvaluationID string `json:"evaluation_id"`
+	Fase         string `json:"fase"`
+}
+
+func (q *Queries) GetIterationByPhase(ctx context.Context, arg GetIterationByPhaseParams) (Iteration, error) {
+	row := q.db.QueryRowContext(ctx, getIterationByPhase, arg.EvaluationID, arg.Fase)
+	var i Iteration
+	err := row.Scan(
+		&i.ID,
+		&i.EvaluationID,
+		&i.Fase,
+		&i.Resposta,
+		&i.Embedding,
+		&i.CreatedAt,
+		&i.Prompt,
 	)
 	return i, err
 }
 
 const getIterationsByEvaluation = `-- name: GetIterationsByEvaluation :many
-SELECT id, evaluation_id, fase, resposta, embedding, created_at FROM iterations WHERE evaluation_id = ? ORDER BY created_at ASC
+SELECT id, evaluation_id, fase, resposta, embedding, created_at, prompt FROM iterations WHERE evaluation_id = ? ORDER BY created_at ASC
 `
 
 func (q *Queries) GetIterationsByEvaluation(ctx context.Context, evaluationID string) ([]Iteration, error) {
@@ -356,6 +562,7 @@ func (q *Queries) GetIterationsByEvaluation(ctx context.Context, evaluationID st
 			&i.Resposta,
 			&i.Embedding,
 			&i.CreatedAt,
+			&i.Prompt,
 		); err != nil {
 			return nil, err
 		}
@@ -370,8 +577,19 @@ func (q *Queries) GetIterationsByEvaluation(ctx context.Context, evaluationID st
 	return items, nil
 }
 
+const getJobStatus = `-- name: GetJobStatus :one
+SELECT status FROM jobs WHERE id = ?
+`
+
+func (q *Queries) GetJobStatus(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getJobStatus, id)
+	var status string
+	err := row.Scan(&status)
+	return status, err
+}
+
 const getPasswordResetByToken = `-- name: GetPasswordResetByToken :one
-SELECT email, token_hash, expires_at, created_at FROM password_resets WHERE token_hash = ? LIMIT 1
+SELECT email, token_hash, expires_at, created_at, tenant_id FROM password_resets WHERE token_hash = ? LIMIT 1
 `
 
 func (q *Queries) GetPasswordResetByToken(ctx context.Context, tokenHash string) (PasswordReset, error) {
@@ -382,10 +600,44 @@ func (q *Queries) GetPasswordResetByToken(ctx context.Context, tokenHash string)
 		&i.TokenHash,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
+const getPhaseTimings = `-- name: GetPhaseTimings :many
+SELECT evaluation_id, phase, duration_ms, created_at FROM phase_timings WHERE evaluation_id = ?
+ORDER BY CASE phase WHEN 'inicial' THEN 1 WHEN 'inversao' THEN 2 WHEN 'confronto' THEN 3 WHEN 'calculo' THEN 4 WHEN 'purga' THEN 5 ELSE 6 END
+`
+
+func (q *Queries) GetPhaseTimings(ctx context.Context, evaluationID string) ([]PhaseTiming, error) {
+	rows, err := q.db.QueryContext(ctx, getPhaseTimings, evaluationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PhaseTiming
+	for rows.Next() {
+		var i PhaseTiming
+		if err := rows.Scan(
+			&i.EvaluationID,
+			&i.Phase,
+			&i.DurationMs,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTenantByID = `-- name: GetTenantByID :one
 SELECT id, name, settings, created_at FROM tenants WHERE id = ? LIMIT 1
 `
@@ -447,6 +699,23 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 	return i, err
 }
 
+const hasActiveEvaluationJob = `-- name: HasActiveEvaluationJob :one
+SELECT EXISTS(
+    SELECT 1 FROM jobs
+    WHERE type = 'run_evaluation'
+      AND status IN ('pending', 'processing')
+      AND evaluation_id = ?
+)
+`
+
+// processing conta: um worker lento ainda pode estar rodando o protocolo
+func (q *Queries) HasActiveEvaluationJob(ctx context.Context, evaluationID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, hasActiveEvaluationJob, evaluationID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const isJobProcessed = `-- name: IsJobProcessed :one
 SELECT EXISTS(SELECT 1 FROM processed_jobs WHERE job_id = ?)
 `
@@ -458,8 +727,42 @@ func (q *Queries) IsJobProcessed(ctx context.Context, jobID int64) (int64, error
 	return column_1, err
 }
 
+const listEvaluationAttachments = `-- name: ListEvaluationAttachments :many
+SELECT mime_type, data FROM evaluation_attachments
+WHERE evaluation_id = ?
+ORDER BY id ASC
+`
+
+type ListEvaluationAttachmentsRow struct {
+	MimeType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+func (q *Queries) ListEvaluationAttachments(ctx context.Context, evaluationID string) ([]ListEvaluationAttachmentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEvaluationAttachments, evaluationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEvaluationAttachmentsRow
+	for rows.Next() {
+		var i ListEvaluationAttachmentsRow
+		if err := rows.Scan(&i.MimeType, &i.Data); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEvaluationsPaginated = `-- name: ListEvaluationsPaginated :many
-SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at FROM evaluations 
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations 
 WHERE tenant_id = ? AND user_id = ? 
 ORDER BY created_at DESC 
 LIMIT ? OFFSET ?
@@ -496,6 +799,79 @@ func (q *Queries) ListEvaluationsPaginated(ctx context.Context, arg ListEvaluati
 			&i.ErrorMessage,
 			&i.RetryCount,
 			&i.CreatedAt,
+			&i.SamplingParams,
+			&i.ConfigHash,
+			&i.ExperimentID,
+			&i.ParentEvaluationID,
+			&i.TotalTokens,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJobsPaginated = `-- name: ListJobsPaginated :many
+SELECT id, tenant_id, type, status, attempt_count, max_attempts, last_error, run_at, created_at, updated_at
+FROM jobs
+WHERE (CAST(?1 AS TEXT) = '' OR type = CAST(?1 AS TEXT))
+  AND (CAST(?2 AS TEXT) = '' OR status = CAST(?2 AS TEXT))
+ORDER BY created_at DESC, id DESC
+LIMIT ?4 OFFSET ?3
+`
+
+type ListJobsPaginatedParams struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Offset int64  `json:"offset"`
+	Limit  int64  `json:"limit"`
+}
+
+type ListJobsPaginatedRow struct {
+	ID           int64          `json:"id"`
+	TenantID     sql.NullString `json:"tenant_id"`
+	Type         string         `json:"type"`
+	Status       string         `json:"status"`
+	AttemptCount sql.NullInt64  `json:"attempt_count"`
+	MaxAttempts  sql.NullInt64  `json:"max_attempts"`
+	LastError    sql.NullString `json:"last_error"`
+	RunAt        sql.NullTime   `json:"run_at"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+}
+
+func (q *Queries) ListJobsPaginated(ctx context.Context, arg ListJobsPaginatedParams) ([]ListJobsPaginatedRow, error) {
+	rows, err := q.db.QueryContext(ctx, listJobsPaginated,
+		arg.Type,
+		arg.Status,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListJobsPaginatedRow
+	for rows.Next() {
+		var i ListJobsPaginatedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Type,
+			&i.Status,
+			&i.AttemptCount,
+			&i.MaxAttempts,
+			&i.LastError,
+			&i.RunAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -571,7 +947,7 @@ WHERE id = (
     SELECT id FROM jobs 
     WHERE status = 'pending' AND run_at <= CURRENT_TIMESTAMP 
     ORDER BY run_at ASC LIMIT 1
-) RETURNING id, tenant_id, type, payload, status, idempotency_key, attempt_count, max_attempts, last_error, run_at, created_at, updated_at
+) RETURNING id, tenant_id, type, payload, status, idempotency_key, attempt_count, max_attempts, last_error, run_at, created_at, updated_at, evaluation_id
 `
 
 func (q *Queries) PickNextJob(ctx context.Context) (Job, error) {
@@ -590,6 +966,7 @@ func (q *Queries) PickNextJob(ctx context.Context) (Job, error) {
 		&i.RunAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EvaluationID,
 	)
 	return i, err
 }
@@ -604,6 +981,43 @@ func (q *Queries) RecordJobProcessed(ctx context.Context, jobID int64) error {
 	return err
 }
 
+const releaseJob = `-- name: ReleaseJob :exec
+UPDATE jobs
+SET status = 'pending', last_error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND status = 'processing'
+`
+
+type ReleaseJobParams struct {
+	LastError sql.NullString `json:"last_error"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) ReleaseJob(ctx context.Context, arg ReleaseJobParams) error {
+	_, err := q.db.ExecContext(ctx, releaseJob, arg.LastError, arg.ID)
+	return err
+}
+
+const rescheduleJob = `-- name: RescheduleJob :exec
+UPDATE jobs
+SET status = 'pending',
+    attempt_count = attempt_count + 1,
+    last_error = ?1,
+    run_at = datetime('now', '+' || ?2 || ' seconds'),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?3
+`
+
+type RescheduleJobParams struct {
+	LastError    sql.NullString `json:"last_error"`
+	DelaySeconds sql.NullString `json:"delay_seconds"`
+	ID           int64          `json:"id"`
+}
+
+func (q *Queries) RescheduleJob(ctx context.Context, arg RescheduleJobParams) error {
+	_, err := q.db.ExecContext(ctx, rescheduleJob, arg.LastError, arg.DelaySeconds, arg.ID)
+	return err
+}
+
 const rescueZombies = `-- name: RescueZombies :exec
 UPDATE jobs 
 SET status = 'pending', attempt_count = attempt_count + 1 
@@ -615,6 +1029,17 @@ func (q *Queries) RescueZombies(ctx context.Context) error {
 	return err
 }
 
+const tenantExists = `-- name: TenantExists :one
+SELECT EXISTS(SELECT 1 FROM tenants WHERE id = ?)
+`
+
+func (q *Queries) TenantExists(ctx context.Context, id string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, tenantExists, id)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const updateEvaluationStatus = `-- name: UpdateEvaluationStatus :exec
 UPDATE evaluations SET status = ? WHERE id = ?
 `
@@ -629,6 +1054,21 @@ func (q *Queries) UpdateEvaluationStatus(ctx context.Context, arg UpdateEvaluati
 	return err
 }
 
+const updateIterationEmbedding = `-- name: UpdateIterationEmbedding :exec
+UPDATE iterations SET embedding = ? WHERE evaluation_id = ? AND fase = ?
+`
+
+type UpdateIterationEmbeddingParams struct {
+	Embedding    []byte `json:"embedding"`
+	EvaluationID string `json:"evaluation_id"`
+	Fase         string `json:"fase"`
+}
+
+func (q *Queries) UpdateIterationEmbedding(ctx context.Context, arg UpdateIterationEmbeddingParams) error {
+	_, err := q.db.ExecContext(ctx, updateIterationEmbedding, arg.Embedding, arg.EvaluationID, arg.Fase)
+	return err
+}
+
 const updateUserAvatar = `-- name: UpdateUserAvatar :exec
 UPDATE users SET avatar_url = ? WHERE id = ?
 `
@@ -643,63 +1083,113 @@ func (q *Queries) UpdateUserAvatar(ctx context.Context, arg UpdateUserAvatarPara
 	return err
 }
 
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+UPDATE users SET email = ?, is_verified = FALSE WHERE id = ?
+`
+
+type UpdateUserEmailParams struct {
+	Email string `json:"email"`
+	ID    int64  `json:"id"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserEmail, arg.Email, arg.ID)
+	return err
+}
+
 const updateUserPassword = `-- name: UpdateUserPassword :exec
-UPDATE users SET password_hash = ? WHERE email = ?
+UPDATE users SET password_hash = ? WHERE tenant_id = ? AND email = ?
 `
 
 type UpdateUserPasswordParams struct {
 	PasswordHash string `json:"password_hash"`
+	TenantID     string `json:"tenant_id"`
 	Email        string `json:"email"`
 }
 
 func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
-	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.Email)
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.TenantID, arg.Email)
 	return err
 }
 
 const upsertEmailVerification = `-- name: UpsertEmailVerification :exec
-INSERT INTO email_verifications (email, token, expires_at) 
-VALUES (?, ?, ?)
-ON CONFLICT(email) DO UPDATE SET 
+INSERT INTO email_verifications (tenant_id, email, token, expires_at) 
+VALUES (?, ?, ?, ?)
+ON CONFLICT(tenant_id, email) DO UPDATE SET 
     token = excluded.token,
     expires_at = excluded.expires_at
 `
 
 type UpsertEmailVerificationParams struct {
+	TenantID  string    `json:"tenant_id"`
 	Email     string    `json:"email"`
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
 func (q *Queries) UpsertEmailVerification(ctx context.Context, arg UpsertEmailVerificationParams) error {
-	_, err := q.db.ExecContext(ctx, upsertEmailVerification, arg.Email, arg.Token, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, upsertEmailVerification,
+		arg.TenantID,
+		arg.Email,
+		arg.Token,
+		arg.ExpiresAt,
+	)
 	return err
 }
 
 const upsertPasswordReset = `-- name: UpsertPasswordReset :exec
-INSERT INTO password_resets (email, token_hash, expires_at) 
-VALUES (?, ?, ?)
+INSERT INTO password_resets (tenant_id, email, token_hash, expires_at) 
+VALUES (?, ?, ?, ?)
 ON CONFLICT(token_hash) DO UPDATE SET 
+    tenant_id = excluded.tenant_id,
     email = excluded.email,
     expires_at = excluded.expires_at
 `
 
 type UpsertPasswordResetParams struct {
+	TenantID  string    `json:"tenant_id"`
 	Email     string    `json:"email"`
 	TokenHash string    `json:"token_hash"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
 func (q *Queries) UpsertPasswordReset(ctx context.Context, arg UpsertPasswordResetParams) error {
-	_, err := q.db.ExecContext(ctx, upsertPasswordReset, arg.Email, arg.TokenHash, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, upsertPasswordReset,
+		arg.TenantID,
+		arg.Email,
+		arg.TokenHash,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const upsertPhaseTiming = `-- name: UpsertPhaseTiming :exec
+INSERT INTO phase_timings (evaluation_id, phase, duration_ms)
+VALUES (?, ?, ?)
+ON CONFLICT(evaluation_id, phase) DO UPDATE SET duration_ms = excluded.duration_ms, created_at = CURRENT_TIMESTAMP
+`
+
+type UpsertPhaseTimingParams struct {
+	EvaluationID string `json:"evaluation_id"`
+	Phase        string `json:"phase"`
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+func (q *Queries) UpsertPhaseTiming(ctx context.Context, arg UpsertPhaseTimingParams) error {
+	_, err := q.db.ExecContext(ctx, upsertPhaseTiming, arg.EvaluationID, arg.Phase, arg.DurationMs)
 	return err
 }
 
 const verifyUser = `-- name: VerifyUser :exec
-UPDATE users SET is_verified = TRUE WHERE email = ?
+UPDATE users SET is_verified = TRUE WHERE tenant_id = ? AND email = ?
 `
 
-func (q *Queries) VerifyUser(ctx context.Context, email string) error {
-	_, err := q.db.ExecContext(ctx, verifyUser, email)
+type VerifyUserParams struct {
+	TenantID string `json:"tenant_id"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUser(ctx context.Context, arg VerifyUserParams) error {
+	_, err := q.db.ExecContext(ctx, verifyUser, arg.TenantID, arg.Email)
 	return err
 }
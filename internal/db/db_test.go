@@ -4,8 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -103,3 +110,653 @@ func TestJobQueueAtomic(t *testing.T) {
 		t.Errorf("esperado sql.ErrNoRows, obtido: %v", err)
 	}
 }
+
+func TestGetStuckEvaluations(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, created_at)
+		VALUES ('old', 't1', 1, 'p', 'processing', datetime('now', '-1 hour')),
+		       ('fresh', 't1', 1, 'p', 'processing', datetime('now', '-1 hour')),
+		       ('done', 't1', 1, 'p', 'completed', datetime('now', '-1 hour'));
+		INSERT INTO evaluation_checkpoints (evaluation_id, updated_at) VALUES ('fresh', CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stuck, err := queries.GetStuckEvaluations(ctx, sql.NullString{String: "15", Valid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stuck) != 1 || stuck[0].ID != "old" {
+		t.Fatalf("esperado apenas 'old' travada, obtido %+v", stuck)
+	}
+
+	// Job pendente para a avaliação evita re-enfileirar em duplicidade
+	_, err = queries.CreateJob(ctx, CreateJobParams{
+		TenantID:     sql.NullString{String: "t1", Valid: true},
+		Type:         "run_evaluation",
+		Payload:      json.RawMessage(`{"evaluation_id":"old"}`),
+		EvaluationID: sql.NullString{String: "old", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := queries.HasActiveEvaluationJob(ctx, sql.NullString{String: "old", Valid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 {
+		t.Error("esperado job pendente para 'old'")
+	}
+
+	// Em execução (worker lento) também conta; concluído não
+	for _, tc := range []struct {
+		status string
+		want   int64
+	}{{"processing", 1}, {"completed", 0}} {
+		if _, err := dbConn.Exec("UPDATE jobs SET status = ? WHERE evaluation_id = 'old'", tc.status); err != nil {
+			t.Fatal(err)
+		}
+		active, err := queries.HasActiveEvaluationJob(ctx, sql.NullString{String: "old", Valid: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != tc.want {
+			t.Errorf("job %s: HasActiveEvaluationJob = %d, esperado %d", tc.status, active, tc.want)
+		}
+	}
+}
+
+func TestRunMigrationsIsIdempotent(t *testing.T) {
+	dbConn, _ := setupTestDB(t)
+	defer dbConn.Close()
+
+	// Migrações com ALTER TABLE falhariam se fossem reaplicadas
+	if err := RunMigrations(context.Background(), dbConn); err != nil {
+		t.Fatalf("segunda execução das migrações falhou: %v", err)
+	}
+}
+
+func TestRunMigrationsRollsBackFailedFile(t *testing.T) {
+	dbConn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"001_base.sql": {Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY);")},
+		// Falha no terceiro comando, depois do ALTER e do CREATE
+		"002_extend.sql": {Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT;
+CREATE TABLE items_extra (id INTEGER);
+INSERT INTO tabela_inexistente VALUES (1);`)},
+	}
+	if err := runMigrations(ctx, dbConn, fsys); err == nil {
+		t.Fatal("expected the failing migration to return an error")
+	}
+
+	var applied []string
+	rows, err := dbConn.QueryContext(ctx, "SELECT name FROM schema_migrations ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		applied = append(applied, name)
+	}
+	rows.Close()
+	if len(applied) != 1 || applied[0] != "001_base.sql" {
+		t.Errorf("migrações registradas = %v, esperado só 001_base.sql", applied)
+	}
+	if _, err := dbConn.ExecContext(ctx, "SELECT name FROM items"); err == nil {
+		t.Error("ALTER TABLE da migração falha ficou aplicado")
+	}
+	if _, err := dbConn.ExecContext(ctx, "SELECT id FROM items_extra"); err == nil {
+		t.Error("CREATE TABLE da migração falha ficou aplicado")
+	}
+
+	// Corrigido o arquivo, o próximo boot aplica a migração inteira
+	fsys["002_extend.sql"] = &fstest.MapFile{Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT;
+CREATE TABLE items_extra (id INTEGER);`)}
+	if err := runMigrations(ctx, dbConn, fsys); err != nil {
+		t.Fatalf("retry da migração falhou: %v", err)
+	}
+	if _, err := dbConn.ExecContext(ctx, "SELECT name FROM items"); err != nil {
+		t.Errorf("coluna da migração reaplicada ausente: %v", err)
+	}
+}
+
+func TestLoginAttempts(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		attempt, err := queries.RecordFailedLogin(ctx, "a@b.c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempt.FailedCount != int64(i) {
+			t.Fatalf("esperado %d falhas, obtido %d", i, attempt.FailedCount)
+		}
+	}
+
+	lockedUntil := time.Now().Add(15 * time.Minute).UTC()
+	if err := queries.LockLogin(ctx, LockLoginParams{
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		Email:       "a@b.c",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	attempt, err := queries.GetLoginAttempt(ctx, "a@b.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !attempt.LockedUntil.Valid || !attempt.LockedUntil.Time.Equal(lockedUntil) {
+		t.Errorf("locked_until não persistido corretamente: %+v", attempt.LockedUntil)
+	}
+
+	// Falhas antigas saem da janela
+	if _, err := dbConn.Exec(`UPDATE login_attempts SET first_failed_at = datetime('now', '-1 hour')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := queries.ExpireLoginAttempts(ctx, ExpireLoginAttemptsParams{
+		Email:         "a@b.c",
+		WindowMinutes: sql.NullString{String: "15", Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queries.GetLoginAttempt(ctx, "a@b.c"); err != sql.ErrNoRows {
+		t.Errorf("esperado contador expirado, obtido err=%v", err)
+	}
+}
+
+func TestFindEvaluationByIdempotencyKey(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user'), (2, 't1', 'd@e.f', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, idempotency_key, created_at)
+		VALUES ('recent', 't1', 1, 'p', 'pending', 'key:abc', CURRENT_TIMESTAMP),
+		       ('expired', 't1', 1, 'p', 'completed', 'key:old', datetime('now', '-2 hours'));
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(userID int64, key string, windowSeconds int) (Evaluation, error) {
+		return queries.FindEvaluationByIdempotencyKey(ctx, FindEvaluationByIdempotencyKeyParams{
+			TenantID:       "t1",
+			UserID:         userID,
+			IdempotencyKey: sql.NullString{String: key, Valid: true},
+			WindowSeconds:  sql.NullString{String: strconv.Itoa(windowSeconds), Valid: true},
+		})
+	}
+
+	eval, err := find(1, "key:abc", 60)
+	if err != nil || eval.ID != "recent" {
+		t.Fatalf("esperado 'recent', obtido %+v (err=%v)", eval, err)
+	}
+	if _, err := find(2, "key:abc", 60); err != sql.ErrNoRows {
+		t.Errorf("chave de outro usuário não deve ser reaproveitada, err=%v", err)
+	}
+	if _, err := find(1, "key:old", 3600); err != sql.ErrNoRows {
+		t.Errorf("chave fora da janela não deve ser reaproveitada, err=%v", err)
+	}
+}
+
+func TestParsePagingParams(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantPage    int
+		wantPerPage int
+	}{
+		{"", 1, 5},
+		{"page=3&per_page=20", 3, 20},
+		{"page=-1&per_page=abc", 1, 5},
+		{"per_page=0", 1, 5},
+		{"per_page=5000", 1, MaxPerPage},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		p := ParsePagingParams(q, 5)
+		if p.Page != tt.wantPage || p.PerPage != tt.wantPerPage {
+			t.Errorf("ParsePagingParams(%q) = %+v, want page=%d per_page=%d", tt.query, p, tt.wantPage, tt.wantPerPage)
+		}
+	}
+}
+
+func TestListEvaluationsFiltered(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, created_at)
+		VALUES ('go', 't1', 1, 'Explique goroutines', 'completed', '2025-01-10 12:00:00'),
+		       ('rust', 't1', 1, 'Explique ownership em Rust', 'failed', '2025-02-01 08:00:00'),
+		       ('sql', 't1', 1, 'Normalize este schema', 'completed', '2025-02-15 23:30:00');
+		INSERT INTO audits (id, evaluation_id, divergencia, diagnostico)
+		VALUES ('a1', 'sql', 0.5, 'Alucinação Detectada');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(filter ListEvaluationsFilteredParams) []string {
+		t.Helper()
+		filter.TenantID, filter.UserID, filter.Limit = "t1", 1, 10
+		evals, err := queries.ListEvaluationsFiltered(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total, err := queries.CountEvaluationsFiltered(ctx, CountEvaluationsFilteredParams{
+			TenantID:    "t1",
+			UserID:      1,
+			Search:      filter.Search,
+			Status:      filter.Status,
+			CreatedFrom: filter.CreatedFrom,
+			CreatedTo:   filter.CreatedTo,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(total) != len(evals) {
+			t.Errorf("count %d difere da listagem %d", total, len(evals))
+		}
+		ids := make([]string, len(evals))
+		for i, e := range evals {
+			ids[i] = e.ID
+		}
+		return ids
+	}
+
+	if got := list(ListEvaluationsFilteredParams{}); len(got) != 3 {
+		t.Errorf("sem filtro esperado 3, obtido %v", got)
+	}
+	if got := list(ListEvaluationsFilteredParams{Search: "Explique", Status: "completed"}); len(got) != 1 || got[0] != "go" {
+		t.Errorf("prefixo + status: obtido %v", got)
+	}
+	if got := list(ListEvaluationsFilteredParams{Search: "Alucinação"}); len(got) != 1 || got[0] != "sql" {
+		t.Errorf("busca por diagnóstico: obtido %v", got)
+	}
+	if got := list(ListEvaluationsFilteredParams{Search: "goroutines"}); len(got) != 0 {
+		t.Errorf("busca é por prefixo, obtido %v", got)
+	}
+	// Curingas do LIKE digitados na busca são literais
+	if got := list(ListEvaluationsFilteredParams{Search: EscapeLike("%")}); len(got) != 0 {
+		t.Errorf("%% deveria ser literal, obtido %v", got)
+	}
+	if got := list(ListEvaluationsFilteredParams{Search: EscapeLike("Explique_")}); len(got) != 0 {
+		t.Errorf("_ deveria ser literal, obtido %v", got)
+	}
+	// Limite superior exclusivo: o handler passa o início do dia seguinte
+	got := list(ListEvaluationsFilteredParams{CreatedFrom: "2025-02-01 00:00:00", CreatedTo: "2025-02-16 00:00:00"})
+	if len(got) != 2 || got[0] != "sql" || got[1] != "rust" {
+		t.Errorf("intervalo de datas: obtido %v", got)
+	}
+	if got := list(ListEvaluationsFilteredParams{Status: "completed", CreatedTo: "2025-02-01 00:00:00"}); len(got) != 1 || got[0] != "go" {
+		t.Errorf("status + data final: obtido %v", got)
+	}
+}
+
+func TestCreateIterationUpsertsByPhase(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status) VALUES ('e1', 't1', 1, 'p', 'processing');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simula a falha entre saveIteration e o avanço de fase: a retomada
+	// executa a fase "inicial" de novo
+	for i, resposta := range []string{"primeira", "retomada"} {
+		if _, err := queries.CreateIteration(ctx, CreateIterationParams{
+			ID:           "it" + strconv.Itoa(i),
+			EvaluationID: "e1",
+			Fase:         "inicial",
+			Resposta:     resposta,
+		}); err != nil {
+			t.Fatalf("tentativa %d: %v", i+1, err)
+		}
+	}
+
+	its, err := queries.GetIterationsByEvaluation(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(its) != 1 {
+		t.Fatalf("esperada 1 iteração, obtidas %d", len(its))
+	}
+	if its[0].ID != "it0" || its[0].Resposta != "retomada" {
+		t.Errorf("esperado upsert da iteração original, obtido %+v", its[0])
+	}
+
+	it, err := queries.GetIterationByPhase(ctx, GetIterationByPhaseParams{EvaluationID: "e1", Fase: "inicial"})
+	if err != nil || it.ID != "it0" {
+		t.Errorf("GetIterationByPhase = %+v (err=%v)", it, err)
+	}
+}
+
+func TestPhaseTimings(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status) VALUES ('e1', 't1', 1, 'p', 'processing');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "purga" gravada antes e "inicial" medida de novo após uma retomada
+	for _, pt := range []UpsertPhaseTimingParams{
+		{EvaluationID: "e1", Phase: "purga", DurationMs: 300},
+		{EvaluationID: "e1", Phase: "inicial", DurationMs: 100},
+		{EvaluationID: "e1", Phase: "inicial", DurationMs: 150},
+	} {
+		if err := queries.UpsertPhaseTiming(ctx, pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timings, err := queries.GetPhaseTimings(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timings) != 2 {
+		t.Fatalf("esperadas 2 fases, obtidas %d", len(timings))
+	}
+	if timings[0].Phase != "inicial" || timings[0].DurationMs != 150 || timings[1].Phase != "purga" {
+		t.Errorf("ordem/valores inesperados: %+v", timings)
+	}
+}
+
+func TestListJobsPaginated(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO jobs (type, payload, status, last_error) VALUES
+			('send_email', '{}', 'failed', 'smtp timeout'),
+			('send_email', '{}', 'completed', NULL),
+			('run_evaluation', '{}', 'failed', 'quota'),
+			('run_evaluation', '{}', 'pending', NULL);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := queries.ListJobsPaginated(ctx, ListJobsPaginatedParams{Type: "send_email", Status: "failed", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].LastError.String != "smtp timeout" {
+		t.Errorf("filtro por tipo e status: %+v", jobs)
+	}
+
+	count, err := queries.CountJobsFiltered(ctx, CountJobsFilteredParams{Status: "failed"})
+	if err != nil || count != 2 {
+		t.Errorf("esperados 2 jobs falhos, obtido %d (err=%v)", count, err)
+	}
+
+	page, err := queries.ListJobsPaginated(ctx, ListJobsPaginatedParams{Limit: 3, Offset: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Type != "send_email" || page[0].Status != "failed" {
+		t.Errorf("última página deveria conter o job mais antigo: %+v", page)
+	}
+}
+
+func TestCancelPendingJob(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	job, err := queries.CreateJob(ctx, CreateJobParams{
+		Type:    "send_email",
+		Payload: json.RawMessage(`{}`),
+		RunAt:   sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, err := queries.CancelPendingJob(ctx, job.ID)
+	if err != nil || cancelled.Status != "cancelled" {
+		t.Fatalf("esperado job cancelado, obtido %+v (err=%v)", cancelled, err)
+	}
+	if _, err := queries.PickNextJob(ctx); err != sql.ErrNoRows {
+		t.Errorf("PickNextJob não deve pegar job cancelado, err=%v", err)
+	}
+	if _, err := queries.CancelPendingJob(ctx, job.ID); err != sql.ErrNoRows {
+		t.Errorf("job que não está pendente não deve ser cancelado de novo, err=%v", err)
+	}
+}
+
+func TestCancelEvaluation(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status) VALUES
+			('running', 't1', 1, 'p', 'retrying'),
+			('done', 't1', 1, 'p', 'completed');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reason := sql.NullString{String: "cancelada", Valid: true}
+	if n, err := queries.CancelEvaluation(ctx, CancelEvaluationParams{ErrorMessage: reason, ID: "running"}); err != nil || n != 1 {
+		t.Fatalf("avaliação em andamento não cancelada: n=%d err=%v", n, err)
+	}
+	if n, err := queries.CancelEvaluation(ctx, CancelEvaluationParams{ErrorMessage: reason, ID: "done"}); err != nil || n != 0 {
+		t.Errorf("avaliação terminada não deve ser cancelada: n=%d err=%v", n, err)
+	}
+
+	eval, err := queries.GetEvaluationByID(ctx, "running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Status != "cancelled" || eval.ErrorMessage != reason {
+		t.Errorf("esperado status cancelled com motivo, obtido %q %+v", eval.Status, eval.ErrorMessage)
+	}
+
+	// Cancelada não é ativa, mas continua no histórico
+	active, err := queries.ListEvaluationsByStatus(ctx, ListEvaluationsByStatusParams{
+		TenantID: "t1", UserID: 1, Status: "processing", Status_2: "retrying",
+	})
+	if err != nil || len(active) != 0 {
+		t.Errorf("avaliações ativas = %d (err=%v), esperado 0", len(active), err)
+	}
+	history, err := queries.ListEvaluationsPaginated(ctx, ListEvaluationsPaginatedParams{
+		TenantID: "t1", UserID: 1, Limit: 10,
+	})
+	if err != nil || len(history) != 2 {
+		t.Errorf("histórico = %d (err=%v), esperado 2", len(history), err)
+	}
+}
+
+func TestAuthTokensScopedByTenant(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1'), ('t2', 'Tenant 2');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user'), (2, 't2', 'a@b.c', 'x', 'user');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := queries.TenantExists(ctx, "t2"); err != nil || exists != 1 {
+		t.Fatalf("tenant t2 deveria existir: %d (err=%v)", exists, err)
+	}
+	if exists, _ := queries.TenantExists(ctx, "nope"); exists != 0 {
+		t.Error("tenant inexistente não deveria existir")
+	}
+
+	// O mesmo email tem um token de verificação por tenant
+	for _, tenant := range []string{"t1", "t2"} {
+		if err := queries.UpsertEmailVerification(ctx, UpsertEmailVerificationParams{
+			TenantID:  tenant,
+			Email:     "a@b.c",
+			Token:     "token-" + tenant,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}); err != nil {
+			t.Fatalf("falha ao gravar verificação de %s: %v", tenant, err)
+		}
+	}
+
+	verification, err := queries.GetEmailVerificationByToken(ctx, "token-t2")
+	if err != nil || verification.TenantID != "t2" {
+		t.Fatalf("esperada verificação do tenant t2, obtido %+v (err=%v)", verification, err)
+	}
+	if err := queries.VerifyUser(ctx, VerifyUserParams{TenantID: verification.TenantID, Email: verification.Email}); err != nil {
+		t.Fatal(err)
+	}
+
+	u1, _ := queries.GetUserByID(ctx, 1)
+	u2, _ := queries.GetUserByID(ctx, 2)
+	if u1.IsVerified || !u2.IsVerified {
+		t.Errorf("apenas o usuário do tenant t2 deveria estar verificado: t1=%v t2=%v", u1.IsVerified, u2.IsVerified)
+	}
+
+	if err := queries.UpdateUserPassword(ctx, UpdateUserPasswordParams{PasswordHash: "nova", TenantID: "t1", Email: "a@b.c"}); err != nil {
+		t.Fatal(err)
+	}
+	u1, _ = queries.GetUserByID(ctx, 1)
+	u2, _ = queries.GetUserByID(ctx, 2)
+	if u1.PasswordHash != "nova" || u2.PasswordHash != "x" {
+		t.Errorf("senha deveria mudar só no tenant t1: t1=%q t2=%q", u1.PasswordHash, u2.PasswordHash)
+	}
+}
+
+func TestCleanupQueries(t *testing.T) {
+	dbConn, queries := setupTestDB(t)
+	defer dbConn.Close()
+	ctx := context.Background()
+
+	_, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status) VALUES
+			('old-done', 't1', 1, 'p', 'completed'),
+			('old-running', 't1', 1, 'p', 'processing'),
+			('old-cancelled', 't1', 1, 'p', 'cancelled'),
+			('new-done', 't1', 1, 'p', 'failed');
+		INSERT INTO evaluation_checkpoints (evaluation_id, updated_at) VALUES
+			('old-done', datetime('now', '-10 days')),
+			('old-cancelled', datetime('now', '-10 days')),
+			('old-running', datetime('now', '-10 days')),
+			('new-done', CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for i, expires := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		email := fmt.Sprintf("u%d@b.c", i)
+		if err := queries.UpsertEmailVerification(ctx, UpsertEmailVerificationParams{TenantID: "t1", Email: email, Token: email, ExpiresAt: expires}); err != nil {
+			t.Fatal(err)
+		}
+		if err := queries.UpsertPasswordReset(ctx, UpsertPasswordResetParams{TenantID: "t1", Email: email, TokenHash: email, ExpiresAt: expires}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := queries.DeleteExpiredEmailVerifications(ctx, now); err != nil || n != 1 {
+		t.Errorf("verificações expiradas removidas = %d (err=%v), esperado 1", n, err)
+	}
+	if n, err := queries.DeleteExpiredPasswordResets(ctx, now); err != nil || n != 1 {
+		t.Errorf("resets expirados removidos = %d (err=%v), esperado 1", n, err)
+	}
+
+	n, err := queries.DeleteFinishedCheckpoints(ctx, sql.NullString{String: fmt.Sprintf("%d", 7*24*60), Valid: true})
+	if err != nil || n != 2 {
+		t.Fatalf("checkpoints removidos = %d (err=%v), esperado 2", n, err)
+	}
+	var remaining string
+	if err := dbConn.QueryRow("SELECT group_concat(evaluation_id) FROM (SELECT evaluation_id FROM evaluation_checkpoints ORDER BY evaluation_id)").Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != "new-done,old-running" {
+		t.Errorf("checkpoints restantes = %q, esperado só os recentes ou em andamento", remaining)
+	}
+}
+
+func TestParseDatabaseURL(t *testing.T) {
+	const pragmas = "_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
+
+	tests := []struct {
+		url     string
+		dialect string
+		driver  string
+		dsn     string
+	}{
+		{"./elenchus.db", DialectSQLite, "sqlite3", "./elenchus.db?" + pragmas},
+		{"file:test.db?cache=shared", DialectSQLite, "sqlite3", "file:test.db?cache=shared&" + pragmas},
+		{"sqlite://data/app.db", DialectSQLite, "sqlite3", "data/app.db?" + pragmas},
+	}
+	for _, tt := range tests {
+		conn, err := ParseDatabaseURL(tt.url)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.url, err)
+		}
+		if conn.Dialect != tt.dialect || conn.Driver != tt.driver || conn.DSN != tt.dsn {
+			t.Errorf("%s: got %+v, want %s/%s/%s", tt.url, conn, tt.dialect, tt.driver, tt.dsn)
+		}
+	}
+
+	for _, url := range []string{"postgres://u:p@localhost/elenchus", "PostgreSQL://localhost/db"} {
+		conn, err := ParseDatabaseURL(url)
+		if !errors.Is(err, ErrUnsupportedDialect) {
+			t.Errorf("%s: expected ErrUnsupportedDialect, got %v", url, err)
+		}
+		if conn.Dialect != DialectPostgres {
+			t.Errorf("%s: expected postgres dialect, got %s", url, conn.Dialect)
+		}
+	}
+}
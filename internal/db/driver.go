@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Dialetos reconhecidos em DATABASE_URL
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// ErrUnsupportedDialect indica um DATABASE_URL de um dialeto ainda sem queries
+// geradas (ver docs/database.md)
+var ErrUnsupportedDialect = errors.New("unsupported database dialect")
+
+// sqlitePragmas são os pragmas de performance e resiliência aplicados a toda
+// conexão SQLite
+const sqlitePragmas = "_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
+
+// Connection é o resultado de ParseDatabaseURL, pronto para sql.Open
+type Connection struct {
+	Dialect string
+	Driver  string
+	DSN     string
+}
+
+// ParseDatabaseURL escolhe o driver a partir do DATABASE_URL. "postgres://" e
+// "postgresql://" selecionam Postgres; qualquer outro valor (caminho,
+// "file:" ou "sqlite://") é tratado como arquivo SQLite e recebe os pragmas
+// padrão. O Postgres é reconhecido mas ainda não suportado: as queries do sqlc
+// usam placeholders e funções de data do SQLite.
+func ParseDatabaseURL(url string) (Connection, error) {
+	lower := strings.ToLower(url)
+	if strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://") {
+		return Connection{Dialect: DialectPostgres, Driver: "pgx", DSN: url},
+			fmt.Errorf("%w: %s", ErrUnsupportedDialect, DialectPostgres)
+	}
+
+	dsn := url
+	if strings.HasPrefix(lower, "sqlite://") {
+		dsn = url[len("sqlite://"):]
+	}
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqlitePragmas
+	} else {
+		dsn += "?" + sqlitePragmas
+	}
+	return Connection{Dialect: DialectSQLite, Driver: "sqlite3", DSN: dsn}, nil
+}
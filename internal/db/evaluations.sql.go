@@ -7,10 +7,259 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
+const aggregateAuditsByTenantDiagnosis = `-- name: AggregateAuditsByTenantDiagnosis :many
+SELECT e.tenant_id, a.diagnostico, COUNT(*) AS total,
+       CAST(AVG(a.divergencia) AS REAL) AS avg_divergencia
+FROM audits a
+JOIN evaluations e ON e.id = a.evaluation_id
+WHERE (CAST(?1 AS TEXT) = '' OR e.tenant_id = CAST(?1 AS TEXT))
+  AND (CAST(?2 AS TEXT) = '' OR e.created_at >= datetime(CAST(?2 AS TEXT)))
+  AND (CAST(?3 AS TEXT) = '' OR e.created_at < datetime(CAST(?3 AS TEXT)))
+GROUP BY e.tenant_id, a.diagnostico
+ORDER BY e.tenant_id, a.diagnostico
+`
+
+type AggregateAuditsByTenantDiagnosisParams struct {
+	TenantID    string `json:"tenant_id"`
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+}
+
+type AggregateAuditsByTenantDiagnosisRow struct {
+	TenantID       string  `json:"tenant_id"`
+	Diagnostico    string  `json:"diagnostico"`
+	Total          int64   `json:"total"`
+	AvgDivergencia float64 `json:"avg_divergencia"`
+}
+
+func (q *Queries) AggregateAuditsByTenantDiagnosis(ctx context.Context, arg AggregateAuditsByTenantDiagnosisParams) ([]AggregateAuditsByTenantDiagnosisRow, error) {
+	rows, err := q.db.QueryContext(ctx, aggregateAuditsByTenantDiagnosis, arg.TenantID, arg.CreatedFrom, arg.CreatedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateAuditsByTenantDiagnosisRow
+	for rows.Next() {
+		var i AggregateAuditsByTenantDiagnosisRow
+		if err := rows.Scan(
+			&i.TenantID,
+			&i.Diagnostico,
+			&i.Total,
+			&i.AvgDivergencia,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countEvaluationsByTenantStatus = `-- name: CountEvaluationsByTenantStatus :many
+SELECT tenant_id, status, COUNT(*) AS total,
+       CAST(COALESCE(SUM(total_tokens), 0) AS INTEGER) AS tokens
+FROM evaluations
+WHERE (CAST(?1 AS TEXT) = '' OR tenant_id = CAST(?1 AS TEXT))
+  AND (CAST(?2 AS TEXT) = '' OR created_at >= datetime(CAST(?2 AS TEXT)))
+  AND (CAST(?3 AS TEXT) = '' OR created_at < datetime(CAST(?3 AS TEXT)))
+GROUP BY tenant_id, status
+ORDER BY tenant_id, status
+`
+
+type CountEvaluationsByTenantStatusParams struct {
+	TenantID    string `json:"tenant_id"`
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+}
+
+type CountEvaluationsByTenantStatusRow struct {
+	TenantID string `json:"tenant_id"`
+	Status   string `json:"status"`
+	Total    int64  `json:"total"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// tenant_id vazio agrega todos os tenants
+func (q *Queries) CountEvaluationsByTenantStatus(ctx context.Context, arg CountEvaluationsByTenantStatusParams) ([]CountEvaluationsByTenantStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countEvaluationsByTenantStatus, arg.TenantID, arg.CreatedFrom, arg.CreatedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountEvaluationsByTenantStatusRow
+	for rows.Next() {
+		var i CountEvaluationsByTenantStatusRow
+		if err := rows.Scan(
+			&i.TenantID,
+			&i.Status,
+			&i.Total,
+			&i.Tokens,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countEvaluationsFiltered = `-- name: CountEvaluationsFiltered :one
+SELECT COUNT(*) FROM evaluations e
+WHERE e.tenant_id = ?1
+  AND e.user_id = ?2
+  AND (CAST(?3 AS TEXT) = ''
+       OR (e.prompt_base LIKE CAST(?3 AS TEXT) || '%' ESCAPE '\')
+       OR EXISTS (SELECT 1 FROM audits a WHERE a.evaluation_id = e.id AND (a.diagnostico LIKE CAST(?3 AS TEXT) || '%' ESCAPE '\')))
+  AND (CAST(?4 AS TEXT) = '' OR e.status = CAST(?4 AS TEXT))
+  AND (CAST(?5 AS TEXT) = '' OR e.created_at >= datetime(CAST(?5 AS TEXT)))
+  AND (CAST(?6 AS TEXT) = '' OR e.created_at < datetime(CAST(?6 AS TEXT)))
+`
+
+type CountEvaluationsFilteredParams struct {
+	TenantID    string `json:"tenant_id"`
+	UserID      int64  `json:"user_id"`
+	Search      string `json:"search"`
+	Status      string `json:"status"`
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+}
+
+func (q *Queries) CountEvaluationsFiltered(ctx context.Context, arg CountEvaluationsFilteredParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEvaluationsFiltered,
+		arg.TenantID,
+		arg.UserID,
+		arg.Search,
+		arg.Status,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTenantEvaluationsSince = `-- name: CountTenantEvaluationsSince :one
+SELECT COUNT(*) FROM evaluations
+WHERE tenant_id = ?1
+  AND created_at >= datetime(CAST(?2 AS TEXT))
+`
+
+type CountTenantEvaluationsSinceParams struct {
+	TenantID string `json:"tenant_id"`
+	Since    string `json:"since"`
+}
+
+func (q *Queries) CountTenantEvaluationsSince(ctx context.Context, arg CountTenantEvaluationsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTenantEvaluationsSince, arg.TenantID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const findCachedEvaluation = `-- name: FindCachedEvaluation :one
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations
+WHERE tenant_id = ?1
+  AND user_id = ?2
+  AND config_hash = ?3
+  AND status = 'completed'
+  AND created_at >= datetime('now', '-' || ?4 || ' minutes')
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type FindCachedEvaluationParams struct {
+	TenantID   string         `json:"tenant_id"`
+	UserID     int64          `json:"user_id"`
+	ConfigHash sql.NullString `json:"config_hash"`
+	TtlMinutes sql.NullString `json:"ttl_minutes"`
+}
+
+func (q *Queries) FindCachedEvaluation(ctx context.Context, arg FindCachedEvaluationParams) (Evaluation, error) {
+	row := q.db.QueryRowContext(ctx, findCachedEvaluation,
+		arg.TenantID,
+		arg.UserID,
+		arg.ConfigHash,
+		arg.TtlMinutes,
+	)
+	var i Evaluation
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.UserID,
+		&i.PromptBase,
+		&i.Status,
+		&i.IdempotencyKey,
+		&i.ErrorMessage,
+		&i.RetryCount,
+		&i.CreatedAt,
+		&i.SamplingParams,
+		&i.ConfigHash,
+		&i.ExperimentID,
+		&i.ParentEvaluationID,
+		&i.TotalTokens,
+	)
+	return i, err
+}
+
+const findEvaluationByIdempotencyKey = `-- name: FindEvaluationByIdempotencyKey :one
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations
+WHERE tenant_id = ?1
+  AND user_id = ?2
+  AND idempotency_key = ?3
+  AND created_at >= datetime('now', '-' || ?4 || ' seconds')
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type FindEvaluationByIdempotencyKeyParams struct {
+	TenantID       string         `json:"tenant_id"`
+	UserID         int64          `json:"user_id"`
+	IdempotencyKey sql.NullString `json:"idempotency_key"`
+	WindowSeconds  sql.NullString `json:"window_seconds"`
+}
+
+func (q *Queries) FindEvaluationByIdempotencyKey(ctx context.Context, arg FindEvaluationByIdempotencyKeyParams) (Evaluation, error) {
+	row := q.db.QueryRowContext(ctx, findEvaluationByIdempotencyKey,
+		arg.TenantID,
+		arg.UserID,
+		arg.IdempotencyKey,
+		arg.WindowSeconds,
+	)
+	var i Evaluation
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.UserID,
+		&i.PromptBase,
+		&i.Status,
+		&i.IdempotencyKey,
+		&i.ErrorMessage,
+		&i.RetryCount,
+		&i.CreatedAt,
+		&i.SamplingParams,
+		&i.ConfigHash,
+		&i.ExperimentID,
+		&i.ParentEvaluationID,
+		&i.TotalTokens,
+	)
+	return i, err
+}
+
 const listEvaluationsByStatus = `-- name: ListEvaluationsByStatus :many
-SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at FROM evaluations
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations
 WHERE tenant_id = ?
   AND user_id = ?
   AND status IN (?, ?)
@@ -48,6 +297,84 @@ func (q *Queries) ListEvaluationsByStatus(ctx context.Context, arg ListEvaluatio
 			&i.ErrorMessage,
 			&i.RetryCount,
 			&i.CreatedAt,
+			&i.SamplingParams,
+			&i.ConfigHash,
+			&i.ExperimentID,
+			&i.ParentEvaluationID,
+			&i.TotalTokens,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEvaluationsFiltered = `-- name: ListEvaluationsFiltered :many
+SELECT id, tenant_id, user_id, prompt_base, status, idempotency_key, error_message, retry_count, created_at, sampling_params, config_hash, experiment_id, parent_evaluation_id, total_tokens FROM evaluations e
+WHERE e.tenant_id = ?1
+  AND e.user_id = ?2
+  AND (CAST(?3 AS TEXT) = ''
+       OR (e.prompt_base LIKE CAST(?3 AS TEXT) || '%' ESCAPE '\')
+       OR EXISTS (SELECT 1 FROM audits a WHERE a.evaluation_id = e.id AND (a.diagnostico LIKE CAST(?3 AS TEXT) || '%' ESCAPE '\')))
+  AND (CAST(?4 AS TEXT) = '' OR e.status = CAST(?4 AS TEXT))
+  AND (CAST(?5 AS TEXT) = '' OR e.created_at >= datetime(CAST(?5 AS TEXT)))
+  AND (CAST(?6 AS TEXT) = '' OR e.created_at < datetime(CAST(?6 AS TEXT)))
+ORDER BY e.created_at DESC
+LIMIT ?8 OFFSET ?7
+`
+
+type ListEvaluationsFilteredParams struct {
+	TenantID    string `json:"tenant_id"`
+	UserID      int64  `json:"user_id"`
+	Search      string `json:"search"`
+	Status      string `json:"status"`
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+	Offset      int64  `json:"offset"`
+	Limit       int64  `json:"limit"`
+}
+
+// search chega escapado (db.EscapeLike) e casa como prefixo literal
+func (q *Queries) ListEvaluationsFiltered(ctx context.Context, arg ListEvaluationsFilteredParams) ([]Evaluation, error) {
+	rows, err := q.db.QueryContext(ctx, listEvaluationsFiltered,
+		arg.TenantID,
+		arg.UserID,
+		arg.Search,
+		arg.Status,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Evaluation
+	for rows.Next() {
+		var i Evaluation
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.UserID,
+			&i.PromptBase,
+			&i.Status,
+			&i.IdempotencyKey,
+			&i.ErrorMessage,
+			&i.RetryCount,
+			&i.CreatedAt,
+			&i.SamplingParams,
+			&i.ConfigHash,
+			&i.ExperimentID,
+			&i.ParentEvaluationID,
+			&i.TotalTokens,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,109 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: experiments.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const countActiveEvaluations = `-- name: CountActiveEvaluations :one
+SELECT COUNT(*) FROM evaluations
+WHERE tenant_id = ?
+  AND status IN ('pending', 'processing', 'retrying')
+`
+
+func (q *Queries) CountActiveEvaluations(ctx context.Context, tenantID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveEvaluations, tenantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countExperimentEvaluationsByStatus = `-- name: CountExperimentEvaluationsByStatus :many
+SELECT status, COUNT(*) AS total FROM evaluations
+WHERE experiment_id = ?
+GROUP BY status
+`
+
+type CountExperimentEvaluationsByStatusRow struct {
+	Status string `json:"status"`
+	Total  int64  `json:"total"`
+}
+
+func (q *Queries) CountExperimentEvaluationsByStatus(ctx context.Context, experimentID sql.NullString) ([]CountExperimentEvaluationsByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countExperimentEvaluationsByStatus, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountExperimentEvaluationsByStatusRow
+	for rows.Next() {
+		var i CountExperimentEvaluationsByStatusRow
+		if err := rows.Scan(&i.Status, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createExperiment = `-- name: CreateExperiment :one
+INSERT INTO experiments (id, tenant_id, user_id, name, total)
+VALUES (?, ?, ?, ?, ?) RETURNING id, tenant_id, user_id, name, total, created_at
+`
+
+type CreateExperimentParams struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	UserID   int64  `json:"user_id"`
+	Name     string `json:"name"`
+	Total    int64  `json:"total"`
+}
+
+func (q *Queries) CreateExperiment(ctx context.Context, arg CreateExperimentParams) (Experiment, error) {
+	row := q.db.QueryRowContext(ctx, createExperiment,
+		arg.ID,
+		arg.TenantID,
+		arg.UserID,
+		arg.Name,
+		arg.Total,
+	)
+	var i Experiment
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.UserID,
+		&i.Name,
+		&i.Total,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getExperimentByID = `-- name: GetExperimentByID :one
+SELECT id, tenant_id, user_id, name, total, created_at FROM experiments WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetExperimentByID(ctx context.Context, id string) (Experiment, error) {
+	row := q.db.QueryRowContext(ctx, getExperimentByID, id)
+	var i Experiment
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.UserID,
+		&i.Name,
+		&i.Total,
+		&i.CreatedAt,
+	)
+	return i, err
+}
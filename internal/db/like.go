@@ -0,0 +1,11 @@
+package db
+
+import "strings"
+
+// likeEscaper escapa os curingas do LIKE; as queries declaram ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike faz o texto de busca casar literalmente num LIKE ... ESCAPE '\'
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,85 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login_attempts.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const expireLoginAttempts = `-- name: ExpireLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE email = ?1
+  AND first_failed_at < datetime('now', '-' || ?2 || ' minutes')
+`
+
+type ExpireLoginAttemptsParams struct {
+	Email         string         `json:"email"`
+	WindowMinutes sql.NullString `json:"window_minutes"`
+}
+
+func (q *Queries) ExpireLoginAttempts(ctx context.Context, arg ExpireLoginAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, expireLoginAttempts, arg.Email, arg.WindowMinutes)
+	return err
+}
+
+const getLoginAttempt = `-- name: GetLoginAttempt :one
+SELECT email, failed_count, first_failed_at, locked_until FROM login_attempts WHERE email = ? LIMIT 1
+`
+
+func (q *Queries) GetLoginAttempt(ctx context.Context, email string) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, getLoginAttempt, email)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Email,
+		&i.FailedCount,
+		&i.FirstFailedAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const lockLogin = `-- name: LockLogin :exec
+UPDATE login_attempts SET locked_until = ?, failed_count = 0 WHERE email = ?
+`
+
+type LockLoginParams struct {
+	LockedUntil sql.NullTime `json:"locked_until"`
+	Email       string       `json:"email"`
+}
+
+func (q *Queries) LockLogin(ctx context.Context, arg LockLoginParams) error {
+	_, err := q.db.ExecContext(ctx, lockLogin, arg.LockedUntil, arg.Email)
+	return err
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+INSERT INTO login_attempts (email, failed_count, first_failed_at)
+VALUES (?, 1, CURRENT_TIMESTAMP)
+ON CONFLICT(email) DO UPDATE SET failed_count = login_attempts.failed_count + 1
+RETURNING email, failed_count, first_failed_at, locked_until
+`
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, email string) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, recordFailedLogin, email)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Email,
+		&i.FailedCount,
+		&i.FirstFailedAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const resetLoginAttempts = `-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts WHERE email = ?
+`
+
+func (q *Queries) ResetLoginAttempts(ctx context.Context, email string) error {
+	_, err := q.db.ExecContext(ctx, resetLoginAttempts, email)
+	return err
+}
@@ -0,0 +1,135 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: logs.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createAccessDenied = `-- name: CreateAccessDenied :exec
+INSERT INTO access_denied_log (user_id, tenant_id, resource, resource_id, action, reason)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type CreateAccessDeniedParams struct {
+	UserID     int64  `json:"user_id"`
+	TenantID   string `json:"tenant_id"`
+	Resource   string `json:"resource"`
+	ResourceID string `json:"resource_id"`
+	Action     string `json:"action"`
+	Reason     string `json:"reason"`
+}
+
+func (q *Queries) CreateAccessDenied(ctx context.Context, arg CreateAccessDeniedParams) error {
+	_, err := q.db.ExecContext(ctx, createAccessDenied,
+		arg.UserID,
+		arg.TenantID,
+		arg.Resource,
+		arg.ResourceID,
+		arg.Action,
+		arg.Reason,
+	)
+	return err
+}
+
+const createEvaluationLog = `-- name: CreateEvaluationLog :exec
+INSERT INTO evaluation_logs (evaluation_id, level, message, attrs, created_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateEvaluationLogParams struct {
+	EvaluationID string       `json:"evaluation_id"`
+	Level        string       `json:"level"`
+	Message      string       `json:"message"`
+	Attrs        []byte       `json:"attrs"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+}
+
+func (q *Queries) CreateEvaluationLog(ctx context.Context, arg CreateEvaluationLogParams) error {
+	_, err := q.db.ExecContext(ctx, createEvaluationLog,
+		arg.EvaluationID,
+		arg.Level,
+		arg.Message,
+		arg.Attrs,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const listAccessDenied = `-- name: ListAccessDenied :many
+SELECT id, user_id, tenant_id, resource, resource_id, "action", reason, created_at FROM access_denied_log
+ORDER BY id DESC
+LIMIT ?
+`
+
+func (q *Queries) ListAccessDenied(ctx context.Context, limit int64) ([]AccessDeniedLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAccessDenied, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AccessDeniedLog
+	for rows.Next() {
+		var i AccessDeniedLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.TenantID,
+			&i.Resource,
+			&i.ResourceID,
+			&i.Action,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEvaluationLogs = `-- name: ListEvaluationLogs :many
+SELECT id, evaluation_id, level, message, attrs, created_at FROM evaluation_logs
+WHERE evaluation_id = ?
+ORDER BY id ASC
+`
+
+func (q *Queries) ListEvaluationLogs(ctx context.Context, evaluationID string) ([]EvaluationLog, error) {
+	rows, err := q.db.QueryContext(ctx, listEvaluationLogs, evaluationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EvaluationLog
+	for rows.Next() {
+		var i EvaluationLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.EvaluationID,
+			&i.Level,
+			&i.Message,
+			&i.Attrs,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
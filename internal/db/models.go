@@ -10,15 +10,30 @@ import (
 	"time"
 )
 
+type AccessDeniedLog struct {
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	TenantID   string       `json:"tenant_id"`
+	Resource   string       `json:"resource"`
+	ResourceID string       `json:"resource_id"`
+	Action     string       `json:"action"`
+	Reason     string       `json:"reason"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+}
+
 type Audit struct {
 	ID           string       `json:"id"`
 	EvaluationID string       `json:"evaluation_id"`
 	Divergencia  float64      `json:"divergencia"`
 	Diagnostico  string       `json:"diagnostico"`
 	CreatedAt    sql.NullTime `json:"created_at"`
+	Metrica      string       `json:"metrica"`
+	PromptSet    string       `json:"prompt_set"`
+	Severidade   string       `json:"severidade"`
 }
 
 type EmailVerification struct {
+	TenantID  string       `json:"tenant_id"`
 	Email     string       `json:"email"`
 	Token     string       `json:"token"`
 	ExpiresAt time.Time    `json:"expires_at"`
@@ -26,15 +41,28 @@ type EmailVerification struct {
 }
 
 type Evaluation struct {
-	ID             string         `json:"id"`
-	TenantID       string         `json:"tenant_id"`
-	UserID         int64          `json:"user_id"`
-	PromptBase     string         `json:"prompt_base"`
-	Status         string         `json:"status"`
-	IdempotencyKey sql.NullString `json:"idempotency_key"`
-	ErrorMessage   sql.NullString `json:"error_message"`
-	RetryCount     int64          `json:"retry_count"`
-	CreatedAt      sql.NullTime   `json:"created_at"`
+	ID                 string         `json:"id"`
+	TenantID           string         `json:"tenant_id"`
+	UserID             int64          `json:"user_id"`
+	PromptBase         string         `json:"prompt_base"`
+	Status             string         `json:"status"`
+	IdempotencyKey     sql.NullString `json:"idempotency_key"`
+	ErrorMessage       sql.NullString `json:"error_message"`
+	RetryCount         int64          `json:"retry_count"`
+	CreatedAt          sql.NullTime   `json:"created_at"`
+	SamplingParams     []byte         `json:"sampling_params"`
+	ConfigHash         sql.NullString `json:"config_hash"`
+	ExperimentID       sql.NullString `json:"experiment_id"`
+	ParentEvaluationID sql.NullString `json:"parent_evaluation_id"`
+	TotalTokens        int64          `json:"total_tokens"`
+}
+
+type EvaluationAttachment struct {
+	ID           int64        `json:"id"`
+	EvaluationID string       `json:"evaluation_id"`
+	MimeType     string       `json:"mime_type"`
+	Data         []byte       `json:"data"`
+	CreatedAt    sql.NullTime `json:"created_at"`
 }
 
 type EvaluationCheckpoint struct {
@@ -50,6 +78,26 @@ type EvaluationCheckpoint struct {
 	NextRetryAt          sql.NullTime    `json:"next_retry_at"`
 	CreatedAt            sql.NullTime    `json:"created_at"`
 	UpdatedAt            sql.NullTime    `json:"updated_at"`
+	ProgressStep         int64           `json:"progress_step"`
+	ProgressPhase        sql.NullString  `json:"progress_phase"`
+}
+
+type EvaluationLog struct {
+	ID           int64        `json:"id"`
+	EvaluationID string       `json:"evaluation_id"`
+	Level        string       `json:"level"`
+	Message      string       `json:"message"`
+	Attrs        []byte       `json:"attrs"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+}
+
+type Experiment struct {
+	ID        string       `json:"id"`
+	TenantID  string       `json:"tenant_id"`
+	UserID    int64        `json:"user_id"`
+	Name      string       `json:"name"`
+	Total     int64        `json:"total"`
+	CreatedAt sql.NullTime `json:"created_at"`
 }
 
 type Iteration struct {
@@ -59,6 +107,7 @@ type Iteration struct {
 	Resposta     string       `json:"resposta"`
 	Embedding    []byte       `json:"embedding"`
 	CreatedAt    sql.NullTime `json:"created_at"`
+	Prompt       string       `json:"prompt"`
 }
 
 type Job struct {
@@ -74,6 +123,14 @@ type Job struct {
 	RunAt          sql.NullTime    `json:"run_at"`
 	CreatedAt      sql.NullTime    `json:"created_at"`
 	UpdatedAt      sql.NullTime    `json:"updated_at"`
+	EvaluationID   sql.NullString  `json:"evaluation_id"`
+}
+
+type LoginAttempt struct {
+	Email         string       `json:"email"`
+	FailedCount   int64        `json:"failed_count"`
+	FirstFailedAt time.Time    `json:"first_failed_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
 }
 
 type PasswordReset struct {
@@ -81,6 +138,14 @@ type PasswordReset struct {
 	TokenHash string       `json:"token_hash"`
 	ExpiresAt time.Time    `json:"expires_at"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	TenantID  string       `json:"tenant_id"`
+}
+
+type PhaseTiming struct {
+	EvaluationID string       `json:"evaluation_id"`
+	Phase        string       `json:"phase"`
+	DurationMs   int64        `json:"duration_ms"`
+	CreatedAt    sql.NullTime `json:"created_at"`
 }
 
 type ProcessedJob struct {
@@ -126,3 +191,17 @@ type Webhook struct {
 	Status     string          `json:"status"`
 	CreatedAt  sql.NullTime    `json:"created_at"`
 }
+
+type WebhookDelivery struct {
+	ID         int64          `json:"id"`
+	DeliveryID string         `json:"delivery_id"`
+	TenantID   sql.NullString `json:"tenant_id"`
+	Event      string         `json:"event"`
+	Url        string         `json:"url"`
+	Attempt    int64          `json:"attempt"`
+	StatusCode sql.NullInt64  `json:"status_code"`
+	Outcome    string         `json:"outcome"`
+	Error      sql.NullString `json:"error"`
+	DurationMs int64          `json:"duration_ms"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
+}
@@ -1,11 +1,38 @@
 package db
 
+import (
+	"net/url"
+	"strconv"
+)
+
+// MaxPerPage limita o per_page aceito da query string
+const MaxPerPage = 100
+
 // PagingParams define os parâmetros básicos de entrada
 type PagingParams struct {
 	Page    int
 	PerPage int
 }
 
+// ParsePagingParams lê page e per_page da query string. per_page ausente ou
+// inválido usa defaultPerPage; acima de MaxPerPage é limitado ao máximo.
+func ParsePagingParams(q url.Values, defaultPerPage int) PagingParams {
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(q.Get("per_page"))
+	switch {
+	case err != nil || perPage < 1:
+		perPage = defaultPerPage
+	case perPage > MaxPerPage:
+		perPage = MaxPerPage
+	}
+
+	return PagingParams{Page: page, PerPage: perPage}
+}
+
 func (p PagingParams) Offset() int {
 	if p.Page < 1 {
 		p.Page = 1
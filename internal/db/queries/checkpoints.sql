@@ -32,6 +32,13 @@ SET embedding_inicial = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE evaluation_id = ?;
 
+-- name: UpdateCheckpointProgress :exec
+UPDATE evaluation_checkpoints
+SET progress_step = ?,
+    progress_phase = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE evaluation_id = ?;
+
 -- name: UpdateCheckpointDivergence :exec
 UPDATE evaluation_checkpoints
 SET divergencia_calculada = ?,
@@ -60,13 +67,39 @@ DELETE FROM evaluation_checkpoints WHERE evaluation_id = ?;
 -- name: GetEvaluationsToRetry :many
 SELECT e.* FROM evaluations e
 INNER JOIN evaluation_checkpoints c ON e.id = c.evaluation_id
-WHERE e.status = 'processing'
+WHERE e.status = 'retrying'
   AND c.next_retry_at IS NOT NULL
   AND c.next_retry_at <= CURRENT_TIMESTAMP;
 
 -- name: GetStuckEvaluations :many
-SELECT e.* FROM evaluations e
-INNER JOIN evaluation_checkpoints c ON e.id = c.evaluation_id
+SELECT * FROM evaluations e
 WHERE e.status = 'processing'
-  AND c.next_retry_at IS NULL
-  AND e.updated_at < datetime('now', '-5 minutes');
+  AND COALESCE(
+    (SELECT c.updated_at FROM evaluation_checkpoints c WHERE c.evaluation_id = e.id),
+    e.created_at
+  ) < datetime('now', '-' || sqlc.arg(threshold_minutes) || ' minutes');
+
+-- name: RequeueStuckEvaluation :exec
+UPDATE evaluations
+SET status = 'pending',
+    retry_count = retry_count + 1
+WHERE id = ? AND status = 'processing';
+
+-- name: FailEvaluation :exec
+UPDATE evaluations
+SET status = 'failed',
+    error_message = ?
+WHERE id = ?;
+
+-- name: CancelEvaluation :execrows
+UPDATE evaluations
+SET status = 'cancelled',
+    error_message = ?
+WHERE id = ? AND status IN ('pending', 'processing', 'retrying');
+
+-- name: DeleteFinishedCheckpoints :execrows
+DELETE FROM evaluation_checkpoints
+WHERE updated_at < datetime('now', '-' || sqlc.arg(retention_minutes) || ' minutes')
+  AND evaluation_id IN (
+    SELECT id FROM evaluations WHERE status IN ('completed', 'failed', 'cancelled')
+  );
@@ -1,6 +1,9 @@
 -- name: GetTenantByID :one
 SELECT * FROM tenants WHERE id = ? LIMIT 1;
 
+-- name: TenantExists :one
+SELECT EXISTS(SELECT 1 FROM tenants WHERE id = ?);
+
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE tenant_id = ? AND email = ? LIMIT 1;
 
@@ -12,7 +15,7 @@ INSERT INTO users (tenant_id, email, password_hash, role_id)
 VALUES (?, ?, ?, ?) RETURNING *;
 
 -- name: CreateJob :one
-INSERT INTO jobs (tenant_id, type, payload, run_at) VALUES (?, ?, ?, ?) RETURNING *;
+INSERT INTO jobs (tenant_id, type, payload, run_at, evaluation_id) VALUES (?, ?, ?, ?, ?) RETURNING *;
 
 -- name: PickNextJob :one
 UPDATE jobs 
@@ -31,11 +34,43 @@ WHERE id = ?;
 -- name: FailJob :exec
 UPDATE jobs SET status = 'failed', last_error = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?;
 
+-- name: RescheduleJob :exec
+UPDATE jobs
+SET status = 'pending',
+    attempt_count = attempt_count + 1,
+    last_error = sqlc.arg(last_error),
+    run_at = datetime('now', '+' || sqlc.arg(delay_seconds) || ' seconds'),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id);
+
+-- name: CancelPendingJob :one
+UPDATE jobs
+SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND status = 'pending'
+RETURNING *;
+
+-- name: GetJobStatus :one
+SELECT status FROM jobs WHERE id = ?;
+
+-- name: ReleaseJob :exec
+UPDATE jobs
+SET status = 'pending', last_error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND status = 'processing';
+
 -- name: RescueZombies :exec
 UPDATE jobs 
 SET status = 'pending', attempt_count = attempt_count + 1 
 WHERE status = 'processing' AND updated_at < datetime('now', '-5 minutes');
 
+-- name: HasActiveEvaluationJob :one
+-- processing conta: um worker lento ainda pode estar rodando o protocolo
+SELECT EXISTS(
+    SELECT 1 FROM jobs
+    WHERE type = 'run_evaluation'
+      AND status IN ('pending', 'processing')
+      AND evaluation_id = ?
+);
+
 -- name: RecordJobProcessed :exec
 INSERT INTO processed_jobs (job_id) VALUES (?)
 ON CONFLICT(job_id) DO UPDATE SET processed_at = CURRENT_TIMESTAMP;
@@ -43,25 +78,45 @@ ON CONFLICT(job_id) DO UPDATE SET processed_at = CURRENT_TIMESTAMP;
 -- name: IsJobProcessed :one
 SELECT EXISTS(SELECT 1 FROM processed_jobs WHERE job_id = ?);
 
+-- name: ListJobsPaginated :many
+SELECT id, tenant_id, type, status, attempt_count, max_attempts, last_error, run_at, created_at, updated_at
+FROM jobs
+WHERE (CAST(sqlc.arg(type) AS TEXT) = '' OR type = CAST(sqlc.arg(type) AS TEXT))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR status = CAST(sqlc.arg(status) AS TEXT))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: CountJobsFiltered :one
+SELECT COUNT(*) FROM jobs
+WHERE (CAST(sqlc.arg(type) AS TEXT) = '' OR type = CAST(sqlc.arg(type) AS TEXT))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR status = CAST(sqlc.arg(status) AS TEXT));
+
 -- name: CreateWebhook :one
 INSERT INTO webhooks (source, external_id, payload, headers) 
 VALUES (?, ?, ?, ?) RETURNING *;
 
 -- name: UpsertPasswordReset :exec
-INSERT INTO password_resets (email, token_hash, expires_at) 
-VALUES (?, ?, ?)
+INSERT INTO password_resets (tenant_id, email, token_hash, expires_at) 
+VALUES (?, ?, ?, ?)
 ON CONFLICT(token_hash) DO UPDATE SET 
+    tenant_id = excluded.tenant_id,
     email = excluded.email,
     expires_at = excluded.expires_at;
 
+-- name: DeleteExpiredPasswordResets :execrows
+DELETE FROM password_resets WHERE expires_at < ?;
+
 -- name: GetPasswordResetByToken :one
 SELECT * FROM password_resets WHERE token_hash = ? LIMIT 1;
 
 -- name: DeletePasswordReset :exec
-DELETE FROM password_resets WHERE email = ?;
+DELETE FROM password_resets WHERE tenant_id = ? AND email = ?;
 
 -- name: UpdateUserPassword :exec
-UPDATE users SET password_hash = ? WHERE email = ?;
+UPDATE users SET password_hash = ? WHERE tenant_id = ? AND email = ?;
+
+-- name: UpdateUserEmail :exec
+UPDATE users SET email = ?, is_verified = FALSE WHERE id = ?;
 
 -- name: UpdateUserAvatar :exec
 UPDATE users SET avatar_url = ? WHERE id = ?;
@@ -77,24 +132,27 @@ LIMIT ? OFFSET ?;
 SELECT COUNT(*) FROM users WHERE tenant_id = ?;
 
 -- name: UpsertEmailVerification :exec
-INSERT INTO email_verifications (email, token, expires_at) 
-VALUES (?, ?, ?)
-ON CONFLICT(email) DO UPDATE SET 
+INSERT INTO email_verifications (tenant_id, email, token, expires_at) 
+VALUES (?, ?, ?, ?)
+ON CONFLICT(tenant_id, email) DO UPDATE SET 
     token = excluded.token,
     expires_at = excluded.expires_at;
 
+-- name: DeleteExpiredEmailVerifications :execrows
+DELETE FROM email_verifications WHERE expires_at < ?;
+
 -- name: GetEmailVerificationByToken :one
 SELECT * FROM email_verifications WHERE token = ? LIMIT 1;
 
 -- name: DeleteEmailVerification :exec
-DELETE FROM email_verifications WHERE email = ?;
+DELETE FROM email_verifications WHERE tenant_id = ? AND email = ?;
 
 -- name: VerifyUser :exec
-UPDATE users SET is_verified = TRUE WHERE email = ?;
+UPDATE users SET is_verified = TRUE WHERE tenant_id = ? AND email = ?;
 
 -- name: CreateEvaluation :one
-INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status) 
-VALUES (?, ?, ?, ?, ?) RETURNING *;
+INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, sampling_params, config_hash, experiment_id, idempotency_key, parent_evaluation_id) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING *;
 
 -- name: GetEvaluationByID :one
 SELECT * FROM evaluations WHERE id = ? LIMIT 1;
@@ -102,16 +160,36 @@ SELECT * FROM evaluations WHERE id = ? LIMIT 1;
 -- name: UpdateEvaluationStatus :exec
 UPDATE evaluations SET status = ? WHERE id = ?;
 
+-- name: AddEvaluationTokens :exec
+UPDATE evaluations SET total_tokens = total_tokens + sqlc.arg(tokens) WHERE id = sqlc.arg(id);
+
 -- name: CreateIteration :one
-INSERT INTO iterations (id, evaluation_id, fase, resposta, embedding) 
-VALUES (?, ?, ?, ?, ?) RETURNING *;
+INSERT INTO iterations (id, evaluation_id, fase, prompt, resposta, embedding) 
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(evaluation_id, fase) DO UPDATE SET prompt = excluded.prompt, resposta = excluded.resposta, embedding = excluded.embedding
+RETURNING *;
 
 -- name: GetIterationsByEvaluation :many
 SELECT * FROM iterations WHERE evaluation_id = ? ORDER BY created_at ASC;
 
+-- name: GetIterationByPhase :one
+SELECT * FROM iterations WHERE evaluation_id = ? AND fase = ? ORDER BY created_at ASC LIMIT 1;
+
+-- name: UpdateIterationEmbedding :exec
+UPDATE iterations SET embedding = ? WHERE evaluation_id = ? AND fase = ?;
+
+-- name: UpsertPhaseTiming :exec
+INSERT INTO phase_timings (evaluation_id, phase, duration_ms)
+VALUES (?, ?, ?)
+ON CONFLICT(evaluation_id, phase) DO UPDATE SET duration_ms = excluded.duration_ms, created_at = CURRENT_TIMESTAMP;
+
+-- name: GetPhaseTimings :many
+SELECT * FROM phase_timings WHERE evaluation_id = ?
+ORDER BY CASE phase WHEN 'inicial' THEN 1 WHEN 'inversao' THEN 2 WHEN 'confronto' THEN 3 WHEN 'calculo' THEN 4 WHEN 'purga' THEN 5 ELSE 6 END;
+
 -- name: CreateAudit :one
-INSERT INTO audits (id, evaluation_id, divergencia, diagnostico) 
-VALUES (?, ?, ?, ?) RETURNING *;
+INSERT INTO audits (id, evaluation_id, divergencia, diagnostico, metrica, prompt_set, severidade)
+VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING *;
 
 -- name: GetAuditByEvaluation :one
 SELECT * FROM audits WHERE evaluation_id = ? LIMIT 1;
@@ -124,3 +202,27 @@ LIMIT ? OFFSET ?;
 
 -- name: CountEvaluations :one
 SELECT COUNT(*) FROM evaluations WHERE tenant_id = ? AND user_id = ?;
+
+-- name: CreateEvaluationAttachment :exec
+INSERT INTO evaluation_attachments (evaluation_id, mime_type, data)
+VALUES (?, ?, ?);
+
+-- name: ListEvaluationAttachments :many
+SELECT mime_type, data FROM evaluation_attachments
+WHERE evaluation_id = ?
+ORDER BY id ASC;
+
+-- name: GetEvaluationJobAttempts :one
+SELECT CAST(COUNT(*) + COALESCE(SUM(attempt_count), 0) AS INTEGER) AS attempts
+FROM jobs
+WHERE type = 'run_evaluation'
+  AND status != 'cancelled'
+  AND evaluation_id = ?;
+
+-- name: GetEvaluationLastJobError :one
+SELECT last_error FROM jobs
+WHERE type = 'run_evaluation'
+  AND last_error IS NOT NULL
+  AND evaluation_id = ?
+ORDER BY id DESC
+LIMIT 1;
@@ -4,3 +4,74 @@ WHERE tenant_id = ?
   AND user_id = ?
   AND status IN (?, ?)
 ORDER BY created_at DESC;
+
+-- name: FindCachedEvaluation :one
+SELECT * FROM evaluations
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND user_id = sqlc.arg(user_id)
+  AND config_hash = sqlc.arg(config_hash)
+  AND status = 'completed'
+  AND created_at >= datetime('now', '-' || sqlc.arg(ttl_minutes) || ' minutes')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: FindEvaluationByIdempotencyKey :one
+SELECT * FROM evaluations
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND user_id = sqlc.arg(user_id)
+  AND idempotency_key = sqlc.arg(idempotency_key)
+  AND created_at >= datetime('now', '-' || sqlc.arg(window_seconds) || ' seconds')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ListEvaluationsFiltered :many
+-- search chega escapado (db.EscapeLike) e casa como prefixo literal
+SELECT * FROM evaluations e
+WHERE e.tenant_id = sqlc.arg(tenant_id)
+  AND e.user_id = sqlc.arg(user_id)
+  AND (CAST(sqlc.arg(search) AS TEXT) = ''
+       OR (e.prompt_base LIKE CAST(sqlc.arg(search) AS TEXT) || '%' ESCAPE '\')
+       OR EXISTS (SELECT 1 FROM audits a WHERE a.evaluation_id = e.id AND (a.diagnostico LIKE CAST(sqlc.arg(search) AS TEXT) || '%' ESCAPE '\')))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR e.status = CAST(sqlc.arg(status) AS TEXT))
+  AND (CAST(sqlc.arg(created_from) AS TEXT) = '' OR e.created_at >= datetime(CAST(sqlc.arg(created_from) AS TEXT)))
+  AND (CAST(sqlc.arg(created_to) AS TEXT) = '' OR e.created_at < datetime(CAST(sqlc.arg(created_to) AS TEXT)))
+ORDER BY e.created_at DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: CountEvaluationsFiltered :one
+SELECT COUNT(*) FROM evaluations e
+WHERE e.tenant_id = sqlc.arg(tenant_id)
+  AND e.user_id = sqlc.arg(user_id)
+  AND (CAST(sqlc.arg(search) AS TEXT) = ''
+       OR (e.prompt_base LIKE CAST(sqlc.arg(search) AS TEXT) || '%' ESCAPE '\')
+       OR EXISTS (SELECT 1 FROM audits a WHERE a.evaluation_id = e.id AND (a.diagnostico LIKE CAST(sqlc.arg(search) AS TEXT) || '%' ESCAPE '\')))
+  AND (CAST(sqlc.arg(status) AS TEXT) = '' OR e.status = CAST(sqlc.arg(status) AS TEXT))
+  AND (CAST(sqlc.arg(created_from) AS TEXT) = '' OR e.created_at >= datetime(CAST(sqlc.arg(created_from) AS TEXT)))
+  AND (CAST(sqlc.arg(created_to) AS TEXT) = '' OR e.created_at < datetime(CAST(sqlc.arg(created_to) AS TEXT)));
+
+-- name: CountEvaluationsByTenantStatus :many
+-- tenant_id vazio agrega todos os tenants
+SELECT tenant_id, status, COUNT(*) AS total,
+       CAST(COALESCE(SUM(total_tokens), 0) AS INTEGER) AS tokens
+FROM evaluations
+WHERE (CAST(sqlc.arg(tenant_id) AS TEXT) = '' OR tenant_id = CAST(sqlc.arg(tenant_id) AS TEXT))
+  AND (CAST(sqlc.arg(created_from) AS TEXT) = '' OR created_at >= datetime(CAST(sqlc.arg(created_from) AS TEXT)))
+  AND (CAST(sqlc.arg(created_to) AS TEXT) = '' OR created_at < datetime(CAST(sqlc.arg(created_to) AS TEXT)))
+GROUP BY tenant_id, status
+ORDER BY tenant_id, status;
+
+-- name: AggregateAuditsByTenantDiagnosis :many
+SELECT e.tenant_id, a.diagnostico, COUNT(*) AS total,
+       CAST(AVG(a.divergencia) AS REAL) AS avg_divergencia
+FROM audits a
+JOIN evaluations e ON e.id = a.evaluation_id
+WHERE (CAST(sqlc.arg(tenant_id) AS TEXT) = '' OR e.tenant_id = CAST(sqlc.arg(tenant_id) AS TEXT))
+  AND (CAST(sqlc.arg(created_from) AS TEXT) = '' OR e.created_at >= datetime(CAST(sqlc.arg(created_from) AS TEXT)))
+  AND (CAST(sqlc.arg(created_to) AS TEXT) = '' OR e.created_at < datetime(CAST(sqlc.arg(created_to) AS TEXT)))
+GROUP BY e.tenant_id, a.diagnostico
+ORDER BY e.tenant_id, a.diagnostico;
+
+-- name: CountTenantEvaluationsSince :one
+SELECT COUNT(*) FROM evaluations
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND created_at >= datetime(CAST(sqlc.arg(since) AS TEXT));
@@ -0,0 +1,16 @@
+-- name: CreateExperiment :one
+INSERT INTO experiments (id, tenant_id, user_id, name, total)
+VALUES (?, ?, ?, ?, ?) RETURNING *;
+
+-- name: GetExperimentByID :one
+SELECT * FROM experiments WHERE id = ? LIMIT 1;
+
+-- name: CountExperimentEvaluationsByStatus :many
+SELECT status, COUNT(*) AS total FROM evaluations
+WHERE experiment_id = ?
+GROUP BY status;
+
+-- name: CountActiveEvaluations :one
+SELECT COUNT(*) FROM evaluations
+WHERE tenant_id = ?
+  AND status IN ('pending', 'processing', 'retrying');
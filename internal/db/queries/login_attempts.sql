@@ -0,0 +1,19 @@
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts WHERE email = ? LIMIT 1;
+
+-- name: ExpireLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE email = sqlc.arg(email)
+  AND first_failed_at < datetime('now', '-' || sqlc.arg(window_minutes) || ' minutes');
+
+-- name: RecordFailedLogin :one
+INSERT INTO login_attempts (email, failed_count, first_failed_at)
+VALUES (?, 1, CURRENT_TIMESTAMP)
+ON CONFLICT(email) DO UPDATE SET failed_count = login_attempts.failed_count + 1
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_attempts SET locked_until = ?, failed_count = 0 WHERE email = ?;
+
+-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts WHERE email = ?;
@@ -0,0 +1,17 @@
+-- name: CreateEvaluationLog :exec
+INSERT INTO evaluation_logs (evaluation_id, level, message, attrs, created_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: ListEvaluationLogs :many
+SELECT * FROM evaluation_logs
+WHERE evaluation_id = ?
+ORDER BY id ASC;
+
+-- name: CreateAccessDenied :exec
+INSERT INTO access_denied_log (user_id, tenant_id, resource, resource_id, action, reason)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: ListAccessDenied :many
+SELECT * FROM access_denied_log
+ORDER BY id DESC
+LIMIT ?;
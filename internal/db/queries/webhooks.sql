@@ -0,0 +1,25 @@
+-- name: GetTenantSettings :one
+SELECT CAST(settings AS BLOB) AS settings FROM tenants WHERE id = ?;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+    delivery_id,
+    tenant_id,
+    event,
+    url,
+    attempt,
+    status_code,
+    outcome,
+    error,
+    duration_ms
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: CountWebhookDeliveryAttempts :one
+SELECT COUNT(*) FROM webhook_deliveries WHERE delivery_id = ?;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE tenant_id = ?
+ORDER BY created_at DESC, id DESC
+LIMIT ? OFFSET ?;
@@ -0,0 +1,137 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhooks.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const countWebhookDeliveryAttempts = `-- name: CountWebhookDeliveryAttempts :one
+SELECT COUNT(*) FROM webhook_deliveries WHERE delivery_id = ?
+`
+
+func (q *Queries) CountWebhookDeliveryAttempts(ctx context.Context, deliveryID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countWebhookDeliveryAttempts, deliveryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+    delivery_id,
+    tenant_id,
+    event,
+    url,
+    attempt,
+    status_code,
+    outcome,
+    error,
+    duration_ms
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, delivery_id, tenant_id, event, url, attempt, status_code, outcome, error, duration_ms, created_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	DeliveryID string         `json:"delivery_id"`
+	TenantID   sql.NullString `json:"tenant_id"`
+	Event      string         `json:"event"`
+	Url        string         `json:"url"`
+	Attempt    int64          `json:"attempt"`
+	StatusCode sql.NullInt64  `json:"status_code"`
+	Outcome    string         `json:"outcome"`
+	Error      sql.NullString `json:"error"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery,
+		arg.DeliveryID,
+		arg.TenantID,
+		arg.Event,
+		arg.Url,
+		arg.Attempt,
+		arg.StatusCode,
+		arg.Outcome,
+		arg.Error,
+		arg.DurationMs,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.DeliveryID,
+		&i.TenantID,
+		&i.Event,
+		&i.Url,
+		&i.Attempt,
+		&i.StatusCode,
+		&i.Outcome,
+		&i.Error,
+		&i.DurationMs,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTenantSettings = `-- name: GetTenantSettings :one
+SELECT CAST(settings AS BLOB) AS settings FROM tenants WHERE id = ?
+`
+
+func (q *Queries) GetTenantSettings(ctx context.Context, id string) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getTenantSettings, id)
+	var settings []byte
+	err := row.Scan(&settings)
+	return settings, err
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, delivery_id, tenant_id, event, url, attempt, status_code, outcome, error, duration_ms, created_at FROM webhook_deliveries
+WHERE tenant_id = ?
+ORDER BY created_at DESC, id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListWebhookDeliveriesParams struct {
+	TenantID sql.NullString `json:"tenant_id"`
+	Limit    int64          `json:"limit"`
+	Offset   int64          `json:"offset"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookDeliveries, arg.TenantID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeliveryID,
+			&i.TenantID,
+			&i.Event,
+			&i.Url,
+			&i.Attempt,
+			&i.StatusCode,
+			&i.Outcome,
+			&i.Error,
+			&i.DurationMs,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout limita cada verificação para o probe não pendurar
+const DefaultTimeout = 3 * time.Second
+
+// Check é uma dependência verificada pelo readiness probe. Falhas de checks
+// críticos tornam a aplicação não pronta (503); as demais aparecem só no relatório.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Result é o resultado de um Check
+type Result struct {
+	Status     string  `json:"status"` // "ok" ou "fail"
+	Critical   bool    `json:"critical"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Report é o corpo JSON do readiness probe
+type Report struct {
+	Status string            `json:"status"` // "ok", "degraded" ou "fail"
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run executa os checks em paralelo, cada um com o timeout informado
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	report := Report{Status: "ok", Checks: make(map[string]Result, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Run(checkCtx)
+			result := Result{
+				Status:     "ok",
+				Critical:   check.Critical,
+				DurationMs: float64(time.Since(start).Nanoseconds()) / 1e6,
+			}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			switch {
+			case err != nil && check.Critical:
+				report.Status = "fail"
+			case err != nil && report.Status == "ok":
+				report.Status = "degraded"
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// ReadyHandler responde 200 com o relatório, ou 503 se algum check crítico falhar
+func ReadyHandler(checks []Check, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), checks, timeout)
+
+		status := http.StatusOK
+		if report.Status == "fail" {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	}
+}
+
+// LiveHandler indica apenas que o processo está de pé (liveness): não verifica
+// dependências, para o orquestrador não reiniciar o pod por falha externa
+func LiveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadyHandler(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("down") }
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantCode   int
+		wantStatus string
+	}{
+		{"all healthy", []Check{{Name: "db", Critical: true, Run: ok}}, http.StatusOK, "ok"},
+		{"non-critical failure", []Check{{Name: "db", Critical: true, Run: ok}, {Name: "mailer", Run: fail}}, http.StatusOK, "degraded"},
+		{"critical failure", []Check{{Name: "db", Critical: true, Run: fail}, {Name: "mailer", Run: ok}}, http.StatusServiceUnavailable, "fail"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ReadyHandler(tt.checks, time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, rr.Code)
+			}
+			var report Report
+			if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, report.Status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("expected %d checks, got %d", len(tt.checks), len(report.Checks))
+			}
+		})
+	}
+}
+
+func TestRun_TimesOutSlowChecks(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	report := Run(context.Background(), []Check{{Name: "gemini", Run: slow}}, 10*time.Millisecond)
+	if report.Checks["gemini"].Status != "fail" {
+		t.Errorf("expected slow check to fail by timeout, got %+v", report.Checks["gemini"])
+	}
+}
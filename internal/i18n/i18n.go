@@ -9,6 +9,7 @@ type Translation struct {
 	Login     string
 	Email     string
 	Password  string
+	Tenant    string
 	Register  string
 	Welcome   string
 	Dashboard string
@@ -18,6 +19,7 @@ var ptBR = Translation{
 	Login:     "Entrar",
 	Email:     "E-mail",
 	Password:  "Senha",
+	Tenant:    "Organização",
 	Register:  "Registrar",
 	Welcome:   "Bem-vindo",
 	Dashboard: "Painel de Controle",
@@ -27,6 +29,7 @@ var enUS = Translation{
 	Login:     "Login",
 	Email:     "Email",
 	Password:  "Password",
+	Tenant:    "Organization",
 	Register:  "Register",
 	Welcome:   "Welcome",
 	Dashboard: "Dashboard",
@@ -9,6 +9,12 @@ import (
 
 var logger *slog.Logger
 
+// baseHandler e baseAttrs permitem reconstruir o logger global (ver EnableEvaluationSink)
+var (
+	baseHandler slog.Handler
+	baseAttrs   []any
+)
+
 type contextKey string
 
 const (
@@ -48,10 +54,13 @@ func Init() {
 		version = "dev"
 	}
 
-	logger = slog.New(handler).With(
+	baseHandler = handler
+	baseAttrs = []any{
 		slog.String("version", version),
 		slog.String("service", "elenchus"),
-	)
+	}
+
+	logger = slog.New(handler).With(baseAttrs...)
 
 	slog.SetDefault(logger)
 }
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// EvaluationIDKey é o atributo usado para associar um evento de log a uma avaliação
+const EvaluationIDKey = "evaluation_id"
+
+// EvaluationLog é um evento de log associado a uma avaliação
+type EvaluationLog struct {
+	EvaluationID string
+	Time         time.Time
+	Level        string
+	Message      string
+	Attrs        []byte // JSON com todos os atributos do evento
+}
+
+// EvaluationSink persiste eventos de log que carregam evaluation_id
+type EvaluationSink func(ctx context.Context, entry EvaluationLog) error
+
+// evaluationHandler repassa todos os registros ao handler base e, quando o
+// registro (ou o logger, via With) carrega evaluation_id, também grava no sink
+type evaluationHandler struct {
+	next  slog.Handler
+	sink  EvaluationSink
+	attrs []slog.Attr
+}
+
+func (h *evaluationHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *evaluationHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.next.Handle(ctx, r)
+
+	fields := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	var evaluationID string
+	collect := func(a slog.Attr) bool {
+		v := a.Value.Resolve()
+		if a.Key == EvaluationIDKey && v.Kind() == slog.KindString {
+			evaluationID = v.String()
+		}
+		fields[a.Key] = v.Any()
+		return true
+	}
+	for _, a := range h.attrs {
+		collect(a)
+	}
+	r.Attrs(collect)
+
+	if evaluationID == "" {
+		return err
+	}
+
+	attrs, jsonErr := json.Marshal(fields)
+	if jsonErr != nil {
+		attrs = nil
+	}
+
+	// O sink não deve herdar o cancelamento do job/request que gerou o log
+	sinkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	if sinkErr := h.sink(sinkCtx, EvaluationLog{
+		EvaluationID: evaluationID,
+		Time:         r.Time,
+		Level:        r.Level.String(),
+		Message:      r.Message,
+		Attrs:        attrs,
+	}); sinkErr != nil {
+		// Não pode logar via slog aqui (recursão); reporta direto no stderr
+		_, _ = os.Stderr.WriteString("evaluation log sink failed: " + sinkErr.Error() + "\n")
+	}
+
+	return err
+}
+
+func (h *evaluationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	combined := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	combined = append(combined, h.attrs...)
+	combined = append(combined, attrs...)
+	return &evaluationHandler{next: h.next.WithAttrs(attrs), sink: h.sink, attrs: combined}
+}
+
+func (h *evaluationHandler) WithGroup(name string) slog.Handler {
+	return &evaluationHandler{next: h.next.WithGroup(name), sink: h.sink, attrs: h.attrs}
+}
+
+// EnableEvaluationSink passa a gravar no sink os eventos com evaluation_id,
+// mantendo a saída padrão. Retorna o novo logger global.
+func EnableEvaluationSink(sink EvaluationSink) *slog.Logger {
+	if logger == nil {
+		Init()
+	}
+
+	logger = slog.New(&evaluationHandler{next: baseHandler, sink: sink}).With(baseAttrs...)
+	slog.SetDefault(logger)
+	return logger
+}
@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+// ErrPermanent marca falhas de envio que não se resolvem com retry: resposta
+// 5xx do servidor SMTP (caixa inexistente, domínio rejeitado, auth recusada)
+// ou endereço de destino inválido. Erros 4xx e de rede continuam transitórios.
+var ErrPermanent = errors.New("permanent smtp failure")
+
+// classify envolve err com ErrPermanent quando a falha é definitiva
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 && smtpErr.Code < 600 {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+	return err
+}
@@ -1,7 +1,10 @@
 package mailer
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/mail"
 	"net/smtp"
 
 	"github.com/PauloHFS/elenchus/internal/config"
@@ -27,9 +30,25 @@ func New(cfg *config.Config) *Mailer {
 	}
 }
 
+// Ping verifica se o servidor SMTP aceita conexões (usado no readiness probe)
+func (m *Mailer) Ping(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("smtp unreachable: %w", err)
+	}
+	return conn.Close()
+}
+
+// Send envia o email. Falhas definitivas (ver ErrPermanent) são marcadas para
+// que o chamador não insista; as demais podem ser repetidas.
 func (m *Mailer) Send(to, subject, body string) error {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("%w: invalid recipient %q: %w", ErrPermanent, to, err)
+	}
+
 	header := fmt.Sprintf("To: %s\r\nSubject: %s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n", to, subject)
 	msg := []byte(header + body)
 
-	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, msg)
+	return classify(smtp.SendMail(m.addr, m.auth, m.from, []string{to}, msg))
 }
@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/config"
+)
+
+// fakeSMTP aceita uma conexão e responde rcptReply ao RCPT TO
+func fakeSMTP(t *testing.T, rcptReply string) *config.Config {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 fake ESMTP\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(line); {
+			case strings.HasPrefix(cmd, "RCPT"):
+				fmt.Fprint(conn, rcptReply+"\r\n")
+			case strings.HasPrefix(cmd, "QUIT"):
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return &config.Config{SMTPHost: host, SMTPPort: port, SMTPFrom: "noreply@example.com"}
+}
+
+func TestSendClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		permanent bool
+	}{
+		{"caixa inexistente", "550 5.1.1 mailbox unavailable", true},
+		{"greylisting", "451 4.7.1 try again later", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(fakeSMTP(t, tt.reply))
+			err := m.Send("user@example.com", "assunto", "corpo")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := errors.Is(err, ErrPermanent); got != tt.permanent {
+				t.Errorf("permanent = %v, want %v (err: %v)", got, tt.permanent, err)
+			}
+		})
+	}
+
+	// Endereço inválido falha antes de conectar
+	m := New(&config.Config{SMTPHost: "127.0.0.1", SMTPPort: "1"})
+	if err := m.Send("não é email", "assunto", "corpo"); !errors.Is(err, ErrPermanent) {
+		t.Errorf("invalid recipient: expected ErrPermanent, got %v", err)
+	}
+}
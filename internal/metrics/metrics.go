@@ -39,6 +39,18 @@ var (
 		Help: "Total number of jobs moved to dead letter queue",
 	}, []string{"type"})
 
+	WorkerPaused = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "worker_paused",
+		Help: "1 while the worker is paused and not picking new jobs",
+	})
+
+	// Evaluation Metrics
+	EvalPhaseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "eval_phase_duration_seconds",
+		Help:    "Time taken by each evaluation protocol phase (API calls and embeddings included)",
+		Buckets: []float64{0.5, 1, 2, 5, 10, 30, 60, 120},
+	}, []string{"phase"})
+
 	// Gemini API Metrics
 	GeminiAPILatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "gemini_api_latency_seconds",
@@ -56,6 +68,26 @@ var (
 		Help: "Remaining Gemini API requests in current window",
 	})
 
+	GeminiQuotaUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gemini_quota_used",
+		Help: "Gemini API requests made by this process in the current window (minute or UTC day)",
+	}, []string{"window"})
+
+	GeminiEmbedRateLimitRemaining = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gemini_embed_rate_limit_remaining",
+		Help: "Remaining Gemini embedding requests in current window",
+	})
+
+	GeminiEmbedQuotaUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gemini_embed_quota_used",
+		Help: "Gemini embedding requests made by this process in the current window (minute or UTC day)",
+	}, []string{"window"})
+
+	GeminiCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gemini_circuit_state",
+		Help: "Gemini circuit breaker state (0=closed, 1=open, 2=half-open)",
+	})
+
 	// SSE Metrics
 	SSEConnections = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "sse_connections_active",
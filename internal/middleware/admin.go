@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/PauloHFS/elenchus/internal/policies"
+)
+
+// RequireAdmin restringe a rota a administradores. Deve ser usado dentro de
+// RequireAuth, que coloca o usuário no contexto.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUser(r.Context())
+		if !ok || !policies.IsAdmin(user) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireBearerToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		code   int
+	}{
+		{"sem token configurado", "", "", http.StatusOK},
+		{"token correto", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"token errado", "s3cret", "Bearer nope", http.StatusUnauthorized},
+		{"sem header", "s3cret", "", http.StatusUnauthorized},
+		{"outro esquema", "s3cret", "Basic s3cret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			RequireBearerToken(tt.token, ok).ServeHTTP(rr, req)
+			if rr.Code != tt.code {
+				t.Errorf("status %d, esperado %d", rr.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/PauloHFS/elenchus/internal/contextkeys"
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/logging"
+	"github.com/PauloHFS/elenchus/internal/policies"
+)
+
+// RequireEvaluationAccess carrega a avaliação do {id} da rota, aplica
+// policies.CheckEvaluationAccess para a ação e coloca a avaliação validada no
+// contexto (ver GetEvaluation). Deve ser usado dentro de RequireAuth.
+func RequireEvaluationAccess(queries *db.Queries, action policies.Action, next http.Handler) http.Handler {
+	return RequireEvaluationAccessOr(queries, action, nil, next)
+}
+
+// RequireEvaluationAccessOr é RequireEvaluationAccess com uma resposta própria
+// para avaliação inexistente (ex: fragmento HTMX de not-found). notFound nil
+// mantém o 404 em texto puro; erros reais do banco continuam 500.
+func RequireEvaluationAccessOr(queries *db.Queries, action policies.Action, notFound http.Handler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUser(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		evalID := r.PathValue("id")
+		if evalID == "" {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+
+		eval, err := queries.GetEvaluationByID(r.Context(), evalID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				if notFound != nil {
+					notFound.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, "Avaliação não encontrada", http.StatusNotFound)
+				return
+			}
+			logging.Get().Error("failed to get evaluation", "evaluation_id", evalID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := policies.CheckEvaluationAccess(r.Context(), user, eval, action); err != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextkeys.EvaluationContextKey, eval)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetEvaluation recupera a avaliação validada por RequireEvaluationAccess
+func GetEvaluation(ctx context.Context) (db.Evaluation, bool) {
+	eval, ok := ctx.Value(contextkeys.EvaluationContextKey).(db.Evaluation)
+	return eval, ok
+}
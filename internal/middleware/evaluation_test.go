@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/contextkeys"
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/policies"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRequireEvaluationAccess(t *testing.T) {
+	dbConn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if err := db.RunMigrations(context.Background(), dbConn); err != nil {
+		t.Fatal(err)
+	}
+	_, err = dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1'), ('t2', 'Tenant 2');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base) VALUES ('e1', 't1', 1, 'p');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base) VALUES ('e2', 't2', 1, 'p');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies.SetDenialHook(func(context.Context, policies.Denial) {})
+	t.Cleanup(func() { policies.SetDenialHook(nil) })
+
+	var got db.Evaluation
+	handler := RequireEvaluationAccess(db.New(dbConn), policies.ActionView, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetEvaluation(r.Context())
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("GET /evaluations/{id}", handler)
+
+	user := db.User{ID: 1, TenantID: "t1", RoleID: "user"}
+	tests := []struct {
+		id   string
+		code int
+	}{
+		{"e1", http.StatusOK},
+		{"e2", http.StatusForbidden},
+		{"missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		got = db.Evaluation{}
+		req := httptest.NewRequest("GET", "/evaluations/"+tt.id, nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserContextKey, user))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tt.code {
+			t.Errorf("%s: status %d, esperado %d", tt.id, rr.Code, tt.code)
+		}
+		if tt.code == http.StatusOK && got.ID != tt.id {
+			t.Errorf("%s: avaliação no contexto = %q", tt.id, got.ID)
+		}
+	}
+}
+
+func TestRequireEvaluationAccessOrNotFound(t *testing.T) {
+	dbConn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if err := db.RunMigrations(context.Background(), dbConn); err != nil {
+		t.Fatal(err)
+	}
+
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("<div>não encontrada</div>"))
+	})
+	called := false
+	handler := RequireEvaluationAccessOr(db.New(dbConn), policies.ActionView, notFound, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("GET /evaluations/{id}", handler)
+
+	req := httptest.NewRequest("GET", "/evaluations/missing", nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserContextKey, db.User{ID: 1, TenantID: "t1", RoleID: "user"}))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status %d, esperado 404", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/html" {
+		t.Errorf("Content-Type = %q, esperado o do handler de not-found", got)
+	}
+	if called {
+		t.Error("handler não deveria ser chamado para avaliação inexistente")
+	}
+
+	// Erro real do banco continua 500, não 404
+	dbConn.Close()
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("banco fechado: status %d, esperado 500", rr.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipContentTypes são os tipos comprimidos; o resto (imagens, assets já
+// comprimidos, text/event-stream) passa direto
+var gzipContentTypes = map[string]bool{
+	"text/html":        true,
+	"application/json": true,
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// Gzip comprime respostas text/html e application/json quando o cliente envia
+// "Accept-Encoding: gzip". Rotas em skipPaths (streaming, como /sse e /metrics)
+// nunca são comprimidas nem bufferizadas.
+func Gzip(skipPaths ...string) func(http.Handler) http.Handler {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{
+				ResponseWriter: w,
+				accepts:        acceptsGzip(r.Header.Get("Accept-Encoding")),
+			}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip verifica se "gzip" aparece em Accept-Encoding sem q=0
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimSpace(params), "=")
+		if !found || strings.TrimSpace(name) != "q" {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	accepts     bool
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+// Unwrap permite que http.ResponseController alcance o writer original
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// WriteHeader decide se a resposta será comprimida: só agora o Content-Type
+// definido pelo handler é conhecido
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+
+	h := gw.Header()
+	if compressible(h, code) {
+		h.Add("Vary", "Accept-Encoding")
+		if gw.accepts {
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+			gw.gz = gzipWriterPool.Get().(*gzip.Writer)
+			gw.gz.Reset(gw.ResponseWriter)
+		}
+	}
+	gw.ResponseWriter.WriteHeader(code)
+}
+
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !gw.wroteHeader {
+		// Mesmo sniffing do net/http, que só aconteceria depois da decisão
+		if gw.Header().Get("Content-Type") == "" {
+			gw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		gw.WriteHeader(http.StatusOK)
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(b)
+	}
+	return gw.ResponseWriter.Write(b)
+}
+
+func (gw *gzipResponseWriter) Flush() {
+	if gw.gz != nil {
+		_ = gw.gz.Flush()
+	}
+	if f, ok := gw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (gw *gzipResponseWriter) close() {
+	if gw.gz == nil {
+		return
+	}
+	_ = gw.gz.Close()
+	gw.gz.Reset(nil)
+	gzipWriterPool.Put(gw.gz)
+	gw.gz = nil
+}
+
+func compressible(h http.Header, code int) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && gzipContentTypes[mediaType]
+}
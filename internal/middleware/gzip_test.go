@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	const body = "<html><body>resultado</body></html>"
+	handler := func(contentType string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			_, _ = io.WriteString(w, body)
+		})
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		contentType    string
+		acceptEncoding string
+		compressed     bool
+		vary           bool
+	}{
+		{"html com gzip", "/evaluations", "text/html; charset=utf-8", "gzip, deflate", true, true},
+		{"json com gzip", "/api/evaluations", "application/json", "br;q=1.0, gzip;q=0.8", true, true},
+		{"content-type detectado", "/", "", "gzip", true, true},
+		{"cliente sem gzip", "/evaluations", "text/html", "", false, true},
+		{"gzip recusado com q=0", "/evaluations", "text/html", "gzip;q=0", false, true},
+		{"tipo não comprimível", "/assets/app.png", "image/png", "gzip", false, false},
+		{"rota ignorada", "/sse", "text/html", "gzip", false, false},
+		{"métricas ignoradas", "/metrics", "text/plain", "gzip", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+			Gzip("/sse", "/metrics")(handler(tt.contentType)).ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Vary") == "Accept-Encoding"; got != tt.vary {
+				t.Errorf("Vary = %q, esperado presente=%v", rr.Header().Get("Vary"), tt.vary)
+			}
+			if !tt.compressed {
+				if enc := rr.Header().Get("Content-Encoding"); enc != "" {
+					t.Fatalf("Content-Encoding = %q, esperado vazio", enc)
+				}
+				if rr.Body.String() != body {
+					t.Errorf("corpo alterado: %q", rr.Body.String())
+				}
+				return
+			}
+
+			if enc := rr.Header().Get("Content-Encoding"); enc != "gzip" {
+				t.Fatalf("Content-Encoding = %q, esperado gzip", enc)
+			}
+			zr, err := gzip.NewReader(rr.Body)
+			if err != nil {
+				t.Fatalf("corpo não é gzip: %v", err)
+			}
+			got, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("erro ao descomprimir: %v", err)
+			}
+			if string(got) != body {
+				t.Errorf("corpo descomprimido %q, esperado %q", got, body)
+			}
+		})
+	}
+}
+
+func TestGzip_NoContent(t *testing.T) {
+	req := httptest.NewRequest("DELETE", "/evaluations/1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(rr, req)
+
+	if enc := rr.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding = %q em 204", enc)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("corpo inesperado em 204: %q", rr.Body.Bytes())
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/PauloHFS/elenchus/internal/routes"
+)
+
+// htmxPrefix agrupa as rotas que devolvem fragmentos HTML
+const htmxPrefix = "/htmx/"
+
+// htmxFullPages mapeia prefixos de rotas HTMX para a página cheia que as
+// contém; o prefixo mais longo vence e o resto cai no dashboard
+var htmxFullPages = []struct {
+	prefix string
+	page   string
+}{
+	{"/htmx/evaluations", routes.EvaluationsPage},
+	{"/htmx/experiments", routes.EvaluationsPage},
+}
+
+// htmxDirectAccess são rotas sob /htmx/ abertas direto pelo browser (download
+// e stream), que não carregam HX-Request
+var htmxDirectAccess = func() *http.ServeMux {
+	mux := http.NewServeMux()
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	mux.Handle("GET "+routes.EvaluationExport, ok)
+	mux.Handle("GET "+routes.EvaluationStatus, ok)
+	return mux
+}()
+
+// HTMXOnly redireciona GETs em /htmx/* sem o header HX-Request para a página
+// cheia correspondente, em vez de servir o fragmento solto. Requisições que
+// aceitam JSON (clientes de API) e as rotas de download/stream passam direto.
+// Desabilitado, apenas repassa a requisição.
+func HTMXOnly(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, htmxPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A mesma URL responde fragmento ou redirect conforme o header
+			w.Header().Add("Vary", "HX-Request")
+
+			if r.Header.Get("HX-Request") != "" || acceptsJSON(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, pattern := htmxDirectAccess.Handler(r); pattern != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			http.Redirect(w, r, htmxFullPage(r.URL.Path), http.StatusSeeOther)
+		})
+	}
+}
+
+func htmxFullPage(path string) string {
+	page, longest := routes.Dashboard, 0
+	for _, p := range htmxFullPages {
+		if strings.HasPrefix(path, p.prefix) && len(p.prefix) > longest {
+			page, longest = p.page, len(p.prefix)
+		}
+	}
+	return page
+}
+
+// acceptsJSON verifica se o cliente pediu explicitamente application/json
+func acceptsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
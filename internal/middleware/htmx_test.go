@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTMXOnly(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		method   string
+		path     string
+		headers  map[string]string
+		code     int
+		location string
+	}{
+		{"desabilitado", false, "GET", "/htmx/evaluations/list", nil, http.StatusOK, ""},
+		{"requisição HTMX", true, "GET", "/htmx/evaluations/list", map[string]string{"HX-Request": "true"}, http.StatusOK, ""},
+		{"acesso direto", true, "GET", "/htmx/evaluations/abc/result", nil, http.StatusSeeOther, "/evaluations"},
+		{"experimento direto", true, "GET", "/htmx/experiments/abc", nil, http.StatusSeeOther, "/evaluations"},
+		{"rota sem página mapeada", true, "GET", "/htmx/other", nil, http.StatusSeeOther, "/dashboard"},
+		{"cliente JSON", true, "GET", "/htmx/evaluations/stats", map[string]string{"Accept": "text/html, application/json;q=0.9"}, http.StatusOK, ""},
+		{"exportação", true, "GET", "/htmx/evaluations/abc/export", nil, http.StatusOK, ""},
+		{"stream SSE", true, "GET", "/htmx/evaluations/abc/events", nil, http.StatusOK, ""},
+		{"POST não redireciona", true, "POST", "/htmx/evaluations", nil, http.StatusOK, ""},
+		{"fora de /htmx", true, "GET", "/evaluations", nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			HTMXOnly(tt.enabled)(ok).ServeHTTP(rr, req)
+			if rr.Code != tt.code {
+				t.Errorf("status %d, esperado %d", rr.Code, tt.code)
+			}
+			if loc := rr.Header().Get("Location"); loc != tt.location {
+				t.Errorf("Location %q, esperado %q", loc, tt.location)
+			}
+		})
+	}
+}
@@ -25,6 +25,12 @@ func (rw *responseWriter) Flush() {
 	}
 }
 
+// Unwrap permite que http.ResponseController alcance o writer original
+// (usado pelo SSE para remover o write deadline)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (rw *responseWriter) WriteHeader(code int) {
 	if rw.wroteHeader {
 		return
@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/PauloHFS/elenchus/internal/logging"
 	"golang.org/x/time/rate"
 )
 
@@ -14,47 +17,109 @@ type client struct {
 	lastSeen time.Time
 }
 
-var (
-	clients = make(map[string]*client)
-	mu      sync.Mutex
-)
+// KeyFunc extrai a chave do bucket de uma requisição (IP, usuário, ...)
+type KeyFunc func(r *http.Request) string
+
+// ByIP agrupa por IP de origem; usado em rotas públicas (login, registro)
+func ByIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return "ip:" + ip
+}
+
+// ByUser agrupa pelo usuário autenticado; deve rodar dentro de RequireAuth.
+// Sem usuário no contexto cai no IP.
+func ByUser(r *http.Request) string {
+	if user, ok := GetUser(r.Context()); ok {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	return ByIP(r)
+}
 
-func init() {
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, c := range clients {
-				if time.Since(c.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
-				}
+// RateLimiter é um token bucket por chave. Cada instância tem seus próprios
+// buckets, então limites de rotas diferentes não interferem entre si.
+type RateLimiter struct {
+	name  string
+	limit rate.Limit
+	burst int
+	key   KeyFunc
+
+	mu          sync.Mutex
+	clients     map[string]*client
+	lastCleanup time.Time
+}
+
+// NewRateLimiter cria um limitador de perMinute requisições por minuto (com burst)
+// por chave. perMinute <= 0 desabilita o limite.
+func NewRateLimiter(name string, perMinute, burst int, key KeyFunc) *RateLimiter {
+	limit := rate.Inf
+	if perMinute > 0 {
+		limit = rate.Limit(float64(perMinute) / 60)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &RateLimiter{
+		name:        name,
+		limit:       limit,
+		burst:       burst,
+		key:         key,
+		clients:     make(map[string]*client),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (l *RateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastCleanup) > time.Minute {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > 3*time.Minute {
+				delete(l.clients, k)
 			}
-			mu.Unlock()
 		}
-	}()
+		l.lastCleanup = now
+	}
+
+	c, found := l.clients[key]
+	if !found {
+		c = &client{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+
+	return c.limiter.Allow()
 }
 
-func RateLimit(next http.Handler) http.Handler {
+// Middleware responde 429 quando o bucket da requisição estoura. A resposta
+// passa pelo Logger, então entra em http_requests_total com status 429.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
+		if l.limit == rate.Inf {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		mu.Lock()
-		if _, found := clients[ip]; !found {
-			clients[ip] = &client{limiter: rate.NewLimiter(5, 10)}
-		}
-		clients[ip].lastSeen = time.Now()
-
-		if !clients[ip].limiter.Allow() {
-			mu.Unlock()
+		if !l.allow(l.key(r)) {
+			logging.AddToEvent(r.Context(), slog.String("rate_limited", l.name))
+			w.Header().Set("Retry-After", strconv.Itoa(l.retryAfterSeconds()))
 			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
-		mu.Unlock()
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// retryAfterSeconds estima quando um novo token estará disponível
+func (l *RateLimiter) retryAfterSeconds() int {
+	seconds := int(1 / float64(l.limit))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
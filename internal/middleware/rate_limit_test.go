@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/contextkeys"
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+func TestRateLimiter_ByIP(t *testing.T) {
+	handler := NewRateLimiter("test", 1, 2, ByIP).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Burst de 2, depois 429
+	for i := 0; i < 2; i++ {
+		if code := do("10.0.0.1:1234"); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := do("10.0.0.1:5678"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 after burst, got %d", code)
+	}
+
+	// Outro IP tem seu próprio bucket
+	if code := do("10.0.0.2:1234"); code != http.StatusOK {
+		t.Errorf("expected 200 for another IP, got %d", code)
+	}
+}
+
+func TestRateLimiter_ByUser(t *testing.T) {
+	handler := NewRateLimiter("test", 1, 1, ByUser).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/htmx/evaluations", nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserContextKey, db.User{ID: userID}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(1); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	rr := do(1)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	// Mesmo IP, usuário diferente
+	if rr := do(2); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 for another user, got %d", rr.Code)
+	}
+}
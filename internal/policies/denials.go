@@ -0,0 +1,67 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// Denial descreve uma decisão de autorização negada
+type Denial struct {
+	UserID     int64
+	TenantID   string // tenant do usuário
+	Resource   ResourceType
+	ResourceID string
+	Action     Action
+	Reason     string
+}
+
+// DenialHook é chamado a cada decisão "forbidden" das funções Check*
+type DenialHook func(ctx context.Context, d Denial)
+
+var (
+	denialHookMu sync.RWMutex
+	denialHook   DenialHook = LogDenial
+)
+
+// SetDenialHook substitui o hook de negações (nil restaura LogDenial)
+func SetDenialHook(h DenialHook) {
+	if h == nil {
+		h = LogDenial
+	}
+	denialHookMu.Lock()
+	defer denialHookMu.Unlock()
+	denialHook = h
+}
+
+// LogDenial é o hook padrão: um log estruturado de warning no logger global
+func LogDenial(ctx context.Context, d Denial) {
+	slog.Default().WarnContext(ctx, "access denied",
+		"user_id", d.UserID,
+		"tenant_id", d.TenantID,
+		"resource", string(d.Resource),
+		"resource_id", d.ResourceID,
+		"action", string(d.Action),
+		"reason", d.Reason,
+	)
+}
+
+// forbid notifica o hook e retorna o erro "forbidden: <reason>"
+func forbid(ctx context.Context, user db.User, resource ResourceType, resourceID string, action Action, reason string) error {
+	denialHookMu.RLock()
+	hook := denialHook
+	denialHookMu.RUnlock()
+
+	hook(ctx, Denial{
+		UserID:     user.ID,
+		TenantID:   user.TenantID,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Action:     action,
+		Reason:     reason,
+	})
+	return fmt.Errorf("forbidden: %s", reason)
+}
@@ -20,10 +20,12 @@ type EvaluationContext struct {
 type Action string
 
 const (
-	ActionView   Action = "view"
-	ActionEdit   Action = "edit"
-	ActionDelete Action = "delete"
-	ActionAudit  Action = "audit"
+	ActionView    Action = "view"
+	ActionEdit    Action = "edit"
+	ActionDelete  Action = "delete"
+	ActionAudit   Action = "audit"
+	ActionCreate  Action = "create"
+	ActionCompare Action = "compare"
 )
 
 // ResourceType representa o tipo de recurso
@@ -33,15 +35,21 @@ const (
 	ResourceEvaluation ResourceType = "evaluation"
 	ResourceIteration  ResourceType = "iteration"
 	ResourceAudit      ResourceType = "audit"
+	ResourceTenant     ResourceType = "tenant"
 )
 
+// IsAdmin verifica se o usuário tem papel de administrador (ou superior na hierarquia)
+func IsAdmin(user db.User) bool {
+	return HasRole(user, RoleAdmin)
+}
+
 // CanAccessEvaluation verifica se o usuário pode acessar uma avaliação
 // Política baseada em atributos (ABAC):
 // - Admins podem acessar todas as avaliações
 // - Usuários podem acessar apenas avaliações do seu tenant
 func CanAccessEvaluation(ctx context.Context, user db.User, evaluation db.Evaluation) bool {
 	// Admin tem acesso total
-	if user.RoleID == "admin" || user.RoleID == "administrator" {
+	if IsAdmin(user) {
 		return true
 	}
 
@@ -49,17 +57,33 @@ func CanAccessEvaluation(ctx context.Context, user db.User, evaluation db.Evalua
 	return user.TenantID == evaluation.TenantID
 }
 
+// CanCompareEvaluations verifica se o usuário pode comparar duas avaliações
+// Política:
+// - O usuário precisa ter acesso a ambas
+// - Não-admins não podem comparar avaliações de tenants diferentes
+func CanCompareEvaluations(ctx context.Context, user db.User, a, b db.Evaluation) bool {
+	if !CanAccessEvaluation(ctx, user, a) || !CanAccessEvaluation(ctx, user, b) {
+		return false
+	}
+
+	return IsAdmin(user) || a.TenantID == b.TenantID
+}
+
 // CanCreateEvaluation verifica se o usuário pode criar uma nova avaliação
 // Política:
-// - Usuários autenticados podem criar avaliações no seu tenant
+// - Usuários autenticados com role "user" ou superior podem criar avaliações no seu tenant
+// - Viewers só visualizam
 // - Admins podem criar em qualquer tenant (se aplicável)
 func CanCreateEvaluation(ctx context.Context, user db.User, tenantID string) bool {
 	if user.ID == 0 {
 		return false // Usuário não autenticado
 	}
+	if !HasRole(user, RoleUser) {
+		return false
+	}
 
 	// Admin pode criar em qualquer tenant
-	if user.RoleID == "admin" || user.RoleID == "administrator" {
+	if IsAdmin(user) {
 		return true
 	}
 
@@ -73,7 +97,7 @@ func CanCreateEvaluation(ctx context.Context, user db.User, tenantID string) boo
 // - Ou o criador da avaliação (se for o mesmo usuário)
 func CanDeleteEvaluation(ctx context.Context, user db.User, evaluation db.Evaluation) bool {
 	// Admin tem acesso total
-	if user.RoleID == "admin" || user.RoleID == "administrator" {
+	if IsAdmin(user) {
 		return true
 	}
 
@@ -90,7 +114,7 @@ func CanDeleteEvaluation(ctx context.Context, user db.User, evaluation db.Evalua
 // - Admins podem visualizar todas as auditorias
 // - Usuários podem visualizar auditorias de avaliações do seu tenant
 func CanViewAudit(ctx context.Context, user db.User, audit db.Audit, evaluation db.Evaluation) bool {
-	if user.RoleID == "admin" || user.RoleID == "administrator" {
+	if IsAdmin(user) {
 		return true
 	}
 
@@ -103,58 +127,92 @@ func CanViewAudit(ctx context.Context, user db.User, audit db.Audit, evaluation
 // - Admins podem visualizar todas as iterações
 // - Usuários podem visualizar iterações de avaliações do seu tenant
 func CanViewIteration(ctx context.Context, user db.User, iteration db.Iteration, evaluation db.Evaluation) bool {
-	if user.RoleID == "admin" || user.RoleID == "administrator" {
+	if IsAdmin(user) {
 		return true
 	}
 
 	return evaluation.TenantID == user.TenantID
 }
 
+// CanViewFailureDetails verifica se o usuário pode ver o detalhe técnico de uma falha
+// (último erro do job, fase do checkpoint)
+// Política:
+// - Apenas admins; os demais veem só a mensagem amigável da avaliação
+func CanViewFailureDetails(ctx context.Context, user db.User, evaluation db.Evaluation) bool {
+	return IsAdmin(user)
+}
+
 // CheckEvaluationAccess é uma função genérica para verificar acesso a avaliações
-// Retorna erro se o acesso for negado
+// Retorna erro se o acesso for negado (negações passam pelo DenialHook)
 func CheckEvaluationAccess(ctx context.Context, user db.User, evaluation db.Evaluation, action Action) error {
 	if user.ID == 0 {
 		return fmt.Errorf("unauthorized: user not authenticated")
 	}
 
+	forbidEval := func(reason string) error {
+		return forbid(ctx, user, ResourceEvaluation, evaluation.ID, action, reason)
+	}
+
 	switch action {
 	case ActionView:
 		if !CanAccessEvaluation(ctx, user, evaluation) {
-			return fmt.Errorf("forbidden: user cannot view this evaluation")
+			return forbidEval("user cannot view this evaluation")
 		}
 	case ActionEdit:
-		if !CanAccessEvaluation(ctx, user, evaluation) {
-			return fmt.Errorf("forbidden: user cannot edit this evaluation")
+		if !HasRole(user, RoleUser) || !CanAccessEvaluation(ctx, user, evaluation) {
+			return forbidEval("user cannot edit this evaluation")
 		}
-		// Não permitir edição de avaliações completadas
-		if evaluation.Status == "completed" || evaluation.Status == "failed" {
-			return fmt.Errorf("forbidden: cannot modify completed/failed evaluations")
+		// Não permitir edição de avaliações terminadas
+		if evaluation.Status == "completed" || evaluation.Status == "failed" || evaluation.Status == "cancelled" {
+			return forbidEval("cannot modify completed/failed/cancelled evaluations")
 		}
 	case ActionDelete:
 		if !CanDeleteEvaluation(ctx, user, evaluation) {
-			return fmt.Errorf("forbidden: user cannot delete this evaluation")
+			return forbidEval("user cannot delete this evaluation")
 		}
 	case ActionAudit:
-		if user.RoleID != "admin" && user.RoleID != "administrator" {
-			return fmt.Errorf("forbidden: only admins can perform audit actions")
+		if !IsAdmin(user) {
+			return forbidEval("only admins can perform audit actions")
 		}
 	}
 
 	return nil
 }
 
+// CheckCreateEvaluation é a versão com erro (e DenialHook) de CanCreateEvaluation
+func CheckCreateEvaluation(ctx context.Context, user db.User, tenantID string) error {
+	if user.ID == 0 {
+		return fmt.Errorf("unauthorized: user not authenticated")
+	}
+	if !CanCreateEvaluation(ctx, user, tenantID) {
+		return forbid(ctx, user, ResourceTenant, tenantID, ActionCreate, "user cannot create evaluations in this tenant")
+	}
+	return nil
+}
+
+// CheckCompareEvaluations é a versão com erro (e DenialHook) de CanCompareEvaluations
+func CheckCompareEvaluations(ctx context.Context, user db.User, a, b db.Evaluation) error {
+	if user.ID == 0 {
+		return fmt.Errorf("unauthorized: user not authenticated")
+	}
+	if !CanCompareEvaluations(ctx, user, a, b) {
+		return forbid(ctx, user, ResourceEvaluation, a.ID+","+b.ID, ActionCompare, "user cannot compare these evaluations")
+	}
+	return nil
+}
+
 // CheckTenantAccess verifica se o usuário tem acesso ao tenant especificado
 func CheckTenantAccess(ctx context.Context, user db.User, tenantID string) error {
 	if user.ID == 0 {
 		return fmt.Errorf("unauthorized: user not authenticated")
 	}
 
-	if user.RoleID == "admin" || user.RoleID == "administrator" {
+	if IsAdmin(user) {
 		return nil // Admin tem acesso a todos os tenants
 	}
 
 	if user.TenantID != tenantID {
-		return fmt.Errorf("forbidden: user does not have access to this tenant")
+		return forbid(ctx, user, ResourceTenant, tenantID, ActionView, "user does not have access to this tenant")
 	}
 
 	return nil
@@ -52,6 +52,49 @@ func TestCanAccessEvaluation(t *testing.T) {
 	}
 }
 
+func TestCanCompareEvaluations(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		user     db.User
+		a        db.Evaluation
+		b        db.Evaluation
+		expected bool
+	}{
+		{
+			name: "user can compare evaluations from own tenant",
+			user: db.User{ID: 1, RoleID: "user", TenantID: "tenant-a"},
+			a: db.Evaluation{ID: "eval-1", TenantID: "tenant-a"},
+			b: db.Evaluation{ID: "eval-2", TenantID: "tenant-a"},
+			expected: true,
+		},
+		{
+			name: "user cannot compare with other tenant evaluation",
+			user: db.User{ID: 1, RoleID: "user", TenantID: "tenant-a"},
+			a: db.Evaluation{ID: "eval-1", TenantID: "tenant-a"},
+			b: db.Evaluation{ID: "eval-2", TenantID: "tenant-b"},
+			expected: false,
+		},
+		{
+			name: "admin can compare across tenants",
+			user: db.User{ID: 1, RoleID: "admin", TenantID: "tenant-a"},
+			a: db.Evaluation{ID: "eval-1", TenantID: "tenant-b"},
+			b: db.Evaluation{ID: "eval-2", TenantID: "tenant-c"},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CanCompareEvaluations(ctx, tt.user, tt.a, tt.b)
+			if result != tt.expected {
+				t.Errorf("CanCompareEvaluations() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestCanCreateEvaluation(t *testing.T) {
 	ctx := context.Background()
 
@@ -292,3 +335,107 @@ func TestCanViewAudit(t *testing.T) {
 		})
 	}
 }
+
+func TestCanViewFailureDetails(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		user       db.User
+		evaluation db.Evaluation
+		expected   bool
+	}{
+		{
+			name:       "admin can view failure details",
+			user:       db.User{ID: 1, RoleID: "admin", TenantID: "tenant-a"},
+			evaluation: db.Evaluation{ID: "eval-1", TenantID: "tenant-b"},
+			expected:   true,
+		},
+		{
+			name:       "user cannot view failure details of own tenant",
+			user:       db.User{ID: 1, RoleID: "user", TenantID: "tenant-a"},
+			evaluation: db.Evaluation{ID: "eval-1", TenantID: "tenant-a", UserID: 1},
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CanViewFailureDetails(ctx, tt.user, tt.evaluation)
+			if result != tt.expected {
+				t.Errorf("CanViewFailureDetails() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRoleHierarchy(t *testing.T) {
+	ctx := context.Background()
+	evaluation := db.Evaluation{ID: "eval-1", TenantID: "tenant-a", Status: "processing"}
+
+	viewer := db.User{ID: 1, RoleID: RoleViewer, TenantID: "tenant-a"}
+	if !CanAccessEvaluation(ctx, viewer, evaluation) {
+		t.Error("viewer should view own tenant evaluation")
+	}
+	if CanCreateEvaluation(ctx, viewer, "tenant-a") {
+		t.Error("viewer should not create evaluations")
+	}
+	if err := CheckEvaluationAccess(ctx, viewer, evaluation, ActionEdit); err == nil {
+		t.Error("viewer should not edit evaluations")
+	}
+
+	superadmin := db.User{ID: 2, RoleID: RoleSuperAdmin, TenantID: "tenant-b"}
+	if !IsAdmin(superadmin) || !CanCreateEvaluation(ctx, superadmin, "tenant-a") {
+		t.Error("superadmin should inherit admin permissions")
+	}
+
+	unknown := db.User{ID: 3, RoleID: "guest", TenantID: "tenant-a"}
+	if HasRole(unknown, RoleViewer) {
+		t.Error("unknown role should not satisfy any requirement")
+	}
+
+	t.Cleanup(func() { SetRoleLevels(nil) })
+	SetRoleLevels(map[string]int{"owner": 100, RoleAdmin: 50, RoleUser: 10})
+	if !IsAdmin(db.User{ID: 4, RoleID: "owner"}) {
+		t.Error("custom hierarchy: owner should be admin")
+	}
+	if HasRole(viewer, RoleViewer) {
+		t.Error("custom hierarchy: viewer was removed and should have no level")
+	}
+}
+
+func TestDenialHook(t *testing.T) {
+	ctx := context.Background()
+	var denials []Denial
+	SetDenialHook(func(ctx context.Context, d Denial) { denials = append(denials, d) })
+	t.Cleanup(func() { SetDenialHook(nil) })
+
+	user := db.User{ID: 7, RoleID: "user", TenantID: "tenant-a"}
+	own := db.Evaluation{ID: "eval-1", TenantID: "tenant-a"}
+	other := db.Evaluation{ID: "eval-2", TenantID: "tenant-b"}
+
+	if err := CheckEvaluationAccess(ctx, user, own, ActionView); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(denials) != 0 {
+		t.Fatalf("allowed access should not call the hook, got %+v", denials)
+	}
+
+	if err := CheckEvaluationAccess(ctx, user, other, ActionView); err == nil {
+		t.Fatal("expected forbidden error")
+	}
+	if err := CheckTenantAccess(ctx, user, "tenant-b"); err == nil {
+		t.Fatal("expected forbidden error")
+	}
+
+	if len(denials) != 2 {
+		t.Fatalf("expected 2 denials, got %d", len(denials))
+	}
+	d := denials[0]
+	if d.UserID != 7 || d.TenantID != "tenant-a" || d.Resource != ResourceEvaluation || d.ResourceID != "eval-2" || d.Action != ActionView || d.Reason == "" {
+		t.Errorf("unexpected denial: %+v", d)
+	}
+	if denials[1].Resource != ResourceTenant || denials[1].ResourceID != "tenant-b" {
+		t.Errorf("unexpected tenant denial: %+v", denials[1])
+	}
+}
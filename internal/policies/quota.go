@@ -0,0 +1,123 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// ErrEvaluationQuotaExceeded indica que o tenant atingiu a quota mensal
+var ErrEvaluationQuotaExceeded = errors.New("monthly evaluation quota exceeded")
+
+// EvaluationQuotaError detalha a quota excedida; ResetAt é o início do próximo mês (UTC)
+type EvaluationQuotaError struct {
+	TenantID string
+	Limit    int64
+	Used     int64
+	ResetAt  time.Time
+}
+
+func (e *EvaluationQuotaError) Error() string {
+	return fmt.Sprintf("%s: tenant %s used %d of %d", ErrEvaluationQuotaExceeded, e.TenantID, e.Used, e.Limit)
+}
+
+func (e *EvaluationQuotaError) Unwrap() error { return ErrEvaluationQuotaExceeded }
+
+// QuotaQueries é o que CheckEvaluationQuota consulta no banco (*db.Queries)
+type QuotaQueries interface {
+	CountTenantEvaluationsSince(ctx context.Context, arg db.CountTenantEvaluationsSinceParams) (int64, error)
+	GetTenantSettings(ctx context.Context, id string) ([]byte, error)
+}
+
+// createdAtLayout é o formato de created_at no SQLite (CURRENT_TIMESTAMP, UTC)
+const createdAtLayout = "2006-01-02 15:04:05"
+
+var (
+	quotaMu          sync.RWMutex
+	monthlyEvalQuota int64
+	// quotaNow é substituído nos testes para fixar o mês corrente
+	quotaNow = time.Now
+)
+
+// SetMonthlyEvaluationQuota define a quota mensal global de avaliações por
+// tenant (<= 0 = sem limite). Tenants podem sobrescrever via settings.
+func SetMonthlyEvaluationQuota(n int64) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	monthlyEvalQuota = n
+}
+
+// TenantEvaluationQuota extrai a quota do JSON de settings do tenant (chave
+// "monthly_evaluation_quota"); ok = false quando o tenant não define quota
+func TenantEvaluationQuota(settings []byte) (quota int64, ok bool, err error) {
+	if len(settings) == 0 {
+		return 0, false, nil
+	}
+	var s struct {
+		MonthlyEvaluationQuota *int64 `json:"monthly_evaluation_quota"`
+	}
+	if err := json.Unmarshal(settings, &s); err != nil {
+		return 0, false, fmt.Errorf("invalid tenant settings: %w", err)
+	}
+	if s.MonthlyEvaluationQuota == nil {
+		return 0, false, nil
+	}
+	return *s.MonthlyEvaluationQuota, true, nil
+}
+
+// CheckEvaluationQuota verifica se o tenant do usuário ainda pode iniciar uma
+// avaliação no mês corrente (UTC). A quota é a do tenant, se definida em
+// settings, senão a global; <= 0 não limita. Admins são isentos.
+func CheckEvaluationQuota(ctx context.Context, q QuotaQueries, user db.User) error {
+	return CheckEvaluationQuotaBatch(ctx, q, user, 1)
+}
+
+// CheckEvaluationQuotaBatch é CheckEvaluationQuota para n avaliações de uma vez
+// (importação CSV): recusa o lote inteiro se ele não couber no saldo do mês
+func CheckEvaluationQuotaBatch(ctx context.Context, q QuotaQueries, user db.User, n int) error {
+	if IsAdmin(user) {
+		return nil
+	}
+
+	quotaMu.RLock()
+	limit := monthlyEvalQuota
+	quotaMu.RUnlock()
+
+	settings, err := q.GetTenantSettings(ctx, user.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to get tenant settings: %w", err)
+	}
+	if tenantLimit, ok, err := TenantEvaluationQuota(settings); err != nil {
+		return err
+	} else if ok {
+		limit = tenantLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	now := quotaNow().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	used, err := q.CountTenantEvaluationsSince(ctx, db.CountTenantEvaluationsSinceParams{
+		TenantID: user.TenantID,
+		Since:    monthStart.Format(createdAtLayout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count tenant evaluations: %w", err)
+	}
+
+	if used+int64(n) > limit {
+		return &EvaluationQuotaError{
+			TenantID: user.TenantID,
+			Limit:    limit,
+			Used:     used,
+			ResetAt:  monthStart.AddDate(0, 1, 0),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+type fakeQuotaQueries struct {
+	settings []byte
+	used     int64
+	since    string
+}
+
+func (f *fakeQuotaQueries) CountTenantEvaluationsSince(ctx context.Context, arg db.CountTenantEvaluationsSinceParams) (int64, error) {
+	f.since = arg.Since
+	return f.used, nil
+}
+
+func (f *fakeQuotaQueries) GetTenantSettings(ctx context.Context, id string) ([]byte, error) {
+	return f.settings, nil
+}
+
+func TestCheckEvaluationQuota(t *testing.T) {
+	ctx := context.Background()
+	user := db.User{ID: 1, RoleID: "user", TenantID: "tenant-a"}
+	admin := db.User{ID: 2, RoleID: "admin", TenantID: "tenant-a"}
+
+	quotaNow = func() time.Time { return time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() {
+		quotaNow = time.Now
+		SetMonthlyEvaluationQuota(0)
+	})
+
+	tests := []struct {
+		name     string
+		global   int64
+		settings string
+		used     int64
+		user     db.User
+		batch    int
+		exceeded bool
+	}{
+		{"sem quota", 0, `{}`, 1000, user, 1, false},
+		{"abaixo da quota global", 10, `{}`, 9, user, 1, false},
+		{"quota global atingida", 10, `{}`, 10, user, 1, true},
+		{"tenant sobrescreve para mais", 10, `{"monthly_evaluation_quota": 50}`, 10, user, 1, false},
+		{"tenant sobrescreve para menos", 100, `{"monthly_evaluation_quota": 5}`, 5, user, 1, true},
+		{"tenant sem limite", 10, `{"monthly_evaluation_quota": 0}`, 10, user, 1, false},
+		{"lote não cabe no saldo", 10, `{}`, 8, user, 3, true},
+		{"admin isento", 10, `{}`, 10, admin, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetMonthlyEvaluationQuota(tt.global)
+			q := &fakeQuotaQueries{settings: []byte(tt.settings), used: tt.used}
+
+			err := CheckEvaluationQuotaBatch(ctx, q, tt.user, tt.batch)
+			if !tt.exceeded {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var quotaErr *EvaluationQuotaError
+			if !errors.As(err, &quotaErr) || !errors.Is(err, ErrEvaluationQuotaExceeded) {
+				t.Fatalf("expected EvaluationQuotaError, got %v", err)
+			}
+			if quotaErr.Used != tt.used || !quotaErr.ResetAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("quota error = %+v", quotaErr)
+			}
+			if q.since != "2026-03-01 00:00:00" {
+				t.Errorf("counted since %q, want start of month", q.since)
+			}
+		})
+	}
+}
+
+func TestTenantEvaluationQuota(t *testing.T) {
+	if _, ok, err := TenantEvaluationQuota(nil); ok || err != nil {
+		t.Errorf("empty settings: ok=%v err=%v", ok, err)
+	}
+	if quota, ok, err := TenantEvaluationQuota([]byte(`{"monthly_evaluation_quota": 25}`)); !ok || err != nil || quota != 25 {
+		t.Errorf("quota=%d ok=%v err=%v, want 25", quota, ok, err)
+	}
+	if _, _, err := TenantEvaluationQuota([]byte(`{"monthly_evaluation_quota": "x"}`)); err == nil {
+		t.Error("expected error for invalid settings")
+	}
+}
@@ -0,0 +1,56 @@
+package policies
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// Roles conhecidos pelas policies (users.role_id)
+const (
+	RoleViewer        = "viewer"
+	RoleUser          = "user"
+	RoleAdmin         = "admin"
+	RoleAdministrator = "administrator" // alias legado de admin
+	RoleSuperAdmin    = "superadmin"
+)
+
+// DefaultRoleLevels é a hierarquia padrão: superadmin > admin > user > viewer.
+// Um role satisfaz qualquer exigência de nível igual ou inferior ao seu.
+var DefaultRoleLevels = map[string]int{
+	RoleViewer:        10,
+	RoleUser:          20,
+	RoleAdmin:         30,
+	RoleAdministrator: 30,
+	RoleSuperAdmin:    40,
+}
+
+var (
+	roleLevelsMu sync.RWMutex
+	roleLevels   = maps.Clone(DefaultRoleLevels)
+)
+
+// SetRoleLevels substitui a hierarquia de roles (nil restaura a padrão).
+// Roles ausentes do mapa não têm nível e não passam em nenhuma exigência.
+func SetRoleLevels(levels map[string]int) {
+	if levels == nil {
+		levels = DefaultRoleLevels
+	}
+	roleLevelsMu.Lock()
+	defer roleLevelsMu.Unlock()
+	roleLevels = maps.Clone(levels)
+}
+
+// RoleLevel retorna o nível do role na hierarquia (0 = desconhecido)
+func RoleLevel(role string) int {
+	roleLevelsMu.RLock()
+	defer roleLevelsMu.RUnlock()
+	return roleLevels[role]
+}
+
+// HasRole verifica se o usuário tem pelo menos o nível do role exigido
+func HasRole(user db.User, required string) bool {
+	level := RoleLevel(user.RoleID)
+	return level > 0 && level >= RoleLevel(required)
+}
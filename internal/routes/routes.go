@@ -1,19 +1,40 @@
 package routes
 
 const (
-	Home             = "/"
-	Login            = "/login"
-	Logout           = "/logout"
-	Register         = "/register"
-	ForgotPassword   = "/forgot-password"
-	ResetPassword    = "/reset-password"
-	VerifyEmail      = "/verify-email"
-	Dashboard        = "/dashboard"
-	Health           = "/health"
-	Metrics          = "/metrics"
-	EvaluationsPage  = "/evaluations"
-	EvaluationStart  = "/htmx/evaluations"
-	EvaluationStatus = "/htmx/evaluations/{id}/events"  // SSE endpoint
-	EvaluationResult = "/htmx/evaluations/{id}/result"
-	EvaluationsList  = "/htmx/evaluations/list"
+	Home              = "/"
+	Login             = "/login"
+	Logout            = "/logout"
+	Register          = "/register"
+	ForgotPassword    = "/forgot-password"
+	ResetPassword     = "/reset-password"
+	VerifyEmail       = "/verify-email"
+	ResendVerify      = "/verify-email/resend"
+	Dashboard         = "/dashboard"
+	Profile           = "/profile"
+	Health            = "/health"
+	Metrics           = "/metrics"
+	EvaluationsPage   = "/evaluations"
+	EvaluationStart   = "/htmx/evaluations"
+	EvaluationBulk    = "/htmx/evaluations/bulk"
+	EvaluationPreview = "/htmx/evaluations/preview"
+	EvaluationStatus  = "/htmx/evaluations/{id}/events" // SSE endpoint
+	EvaluationResult  = "/htmx/evaluations/{id}/result"
+	EvaluationExport  = "/htmx/evaluations/{id}/export" // ?format=md
+	EvaluationRerun   = "/htmx/evaluations/{id}/rerun"
+	EvaluationsList   = "/htmx/evaluations/list"
+	EvaluationCompare = "/htmx/evaluations/compare" // ?a={id1}&b={id2}
+	EvaluationStats   = "/htmx/evaluations/stats"   // ?tenant=&from=&to=
+	ExperimentStatus  = "/htmx/experiments/{id}"
+
+	// API
+	EvaluationEmbeddings = "/api/evaluations/{id}/embeddings"
+
+	// Admin
+	AdminEvaluationLogs    = "/admin/evaluations/{id}/logs"
+	AdminEvaluationReaudit = "/admin/evaluations/{id}/reaudit"
+	AdminJobs              = "/admin/jobs"
+	AdminJobCancel         = "/admin/jobs/{id}/cancel"
+	AdminWorkerPause       = "/admin/worker/pause"
+	AdminWorkerResume      = "/admin/worker/resume"
+	AdminAccessDenied      = "/admin/access-denied"
 )
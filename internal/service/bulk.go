@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBulkEmpty       = errors.New("csv has no prompts")
+	ErrBulkTooManyRows = errors.New("csv exceeds the row limit")
+	ErrQuotaExceeded   = errors.New("evaluation quota exceeded")
+)
+
+// BulkItem é uma linha do CSV de importação: o prompt e a estratégia da linha
+type BulkItem struct {
+	Prompt   string
+	Strategy Strategy
+}
+
+// ParseBulkCSV lê um CSV com cabeçalho contendo a coluna "prompt" e, opcionalmente,
+// "strategy" e "seed" por linha. Linhas com prompt vazio são ignoradas.
+// O arquivo inteiro é validado antes de qualquer avaliação ser criada.
+func ParseBulkCSV(r io.Reader, maxRows int) ([]BulkItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrBulkEmpty
+		}
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	// Planilhas exportadas costumam prefixar o arquivo com BOM UTF-8
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	promptCol, ok := columns["prompt"]
+	if !ok {
+		return nil, fmt.Errorf("csv header must contain a %q column", "prompt")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var items []BulkItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		if promptCol >= len(record) || strings.TrimSpace(record[promptCol]) == "" {
+			continue
+		}
+		if len(items) == maxRows {
+			return nil, fmt.Errorf("%w (%d)", ErrBulkTooManyRows, maxRows)
+		}
+
+		strategy, err := StrategyByName(field(record, "strategy"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if seed := field(record, "seed"); seed != "" {
+			v, err := strconv.ParseInt(seed, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid seed %q", line, seed)
+			}
+			strategy = strategy.WithSeed(int32(v))
+		}
+
+		items = append(items, BulkItem{
+			Prompt:   strings.TrimSpace(record[promptCol]),
+			Strategy: strategy,
+		})
+	}
+
+	if len(items) == 0 {
+		return nil, ErrBulkEmpty
+	}
+	return items, nil
+}
+
+// CheckEvaluationCapacity garante que o tenant comporta mais n avaliações em
+// andamento (pending, processing ou retrying). maxActive <= 0 desabilita o limite.
+func (s *EvaluationService) CheckEvaluationCapacity(ctx context.Context, tenantID string, n, maxActive int) error {
+	if maxActive <= 0 {
+		return nil
+	}
+	active, err := s.q.CountActiveEvaluations(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to count active evaluations: %w", err)
+	}
+	if int(active)+n > maxActive {
+		return fmt.Errorf("%w: %d active, %d requested, limit %d", ErrQuotaExceeded, active, n, maxActive)
+	}
+	return nil
+}
+
+// StartExperiment cria um experimento com uma avaliação por item. Os jobs são
+// escalonados em interval para respeitar o rate limit do Gemini.
+func (s *EvaluationService) StartExperiment(ctx context.Context, tenantID string, userID int64, name string, items []BulkItem, interval time.Duration) (string, error) {
+	experiment, err := s.q.CreateExperiment(ctx, db.CreateExperimentParams{
+		ID:       uuid.New().String(),
+		TenantID: tenantID,
+		UserID:   userID,
+		Name:     name,
+		Total:    int64(len(items)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create experiment: %w", err)
+	}
+
+	experimentID := sql.NullString{String: experiment.ID, Valid: true}
+	start := time.Now()
+	for i, item := range items {
+		runAt := start.Add(time.Duration(i) * interval)
+		if _, err := s.startEvaluation(ctx, tenantID, userID, item.Prompt, nil, item.Strategy, experimentID, sql.NullString{}, sql.NullString{}, runAt); err != nil {
+			return "", fmt.Errorf("failed to start evaluation %d of experiment: %w", i+1, err)
+		}
+	}
+
+	return experiment.ID, nil
+}
+
+// ExperimentProgress resume o andamento das avaliações de um experimento
+type ExperimentProgress struct {
+	Experiment db.Experiment
+	ByStatus   map[string]int64
+}
+
+// GetExperimentProgress carrega o experimento e a contagem de avaliações por status
+func (s *EvaluationService) GetExperimentProgress(ctx context.Context, experimentID string) (*ExperimentProgress, error) {
+	experiment, err := s.q.GetExperimentByID(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+
+	rows, err := s.q.CountExperimentEvaluationsByStatus(ctx, sql.NullString{String: experimentID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count experiment evaluations: %w", err)
+	}
+
+	progress := &ExperimentProgress{Experiment: experiment, ByStatus: make(map[string]int64, len(rows))}
+	for _, row := range rows {
+		progress.ByStatus[row.Status] = row.Total
+	}
+	return progress, nil
+}
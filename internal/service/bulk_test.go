@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseBulkCSV(t *testing.T) {
+	csv := "\ufeffPrompt,strategy,seed\n" +
+		"\"Explique goroutines, com exemplo\",,\n" +
+		",,\n" +
+		"Compare mutex e channels,stochastic,42\n"
+
+	items, err := ParseBulkCSV(strings.NewReader(csv), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items (blank line skipped), got %d", len(items))
+	}
+	if items[0].Prompt != "Explique goroutines, com exemplo" || items[0].Strategy.Name != StrategyDeterministic {
+		t.Errorf("unexpected first item: %+v", items[0])
+	}
+	if p := items[1].Strategy.ParamsFor("inicial"); items[1].Strategy.Name != StrategyStochastic || p.Seed == nil || *p.Seed != 42 {
+		t.Errorf("unexpected per-row config: %+v", items[1].Strategy)
+	}
+}
+
+func TestParseBulkCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		maxRows int
+		wantErr error
+	}{
+		{"empty file", "", 10, ErrBulkEmpty},
+		{"header only", "prompt\n", 10, ErrBulkEmpty},
+		{"too many rows", "prompt\na\nb\nc\n", 2, ErrBulkTooManyRows},
+		{"missing prompt column", "texto\na\n", 10, nil},
+		{"invalid strategy", "prompt,strategy\na,creative\n", 10, nil},
+		{"invalid seed", "prompt,seed\na,abc\n", 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBulkCSV(strings.NewReader(tt.csv), tt.maxRows)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
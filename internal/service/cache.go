@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// ConfigHash identifica uma avaliação pelo prompt (e imagens anexadas) e por tudo
+// que influencia o resultado: modelo, faixas de diagnóstico, métrica, conjunto de
+// prompts do protocolo e estratégia de amostragem. Sem anexos, com as faixas e os
+// prompts padrão o hash é o mesmo de antes do suporte a imagens, a DIAGNOSIS_BANDS
+// e a PROTOCOL_PROMPTS_FILE. Em dry-run o hash muda, para que
+// resultados simulados nunca sirvam de cache para avaliações reais.
+func (s *EvaluationService) ConfigHash(prompt string, strategy Strategy, attachments ...MessagePart) string {
+	strategyJSON, _ := json.Marshal(strategy)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "model=%s\nthreshold=%g\nmetric=%s\nstrategy=%s\nprompt=%s",
+		s.llm.ChatModel(), HallucinationThreshold, s.metricName(), strategyJSON, prompt)
+	if bands := s.bands.String(); bands != DefaultDiagnosisBands().String() {
+		fmt.Fprintf(h, "\nbands=%s", bands)
+	}
+	if !s.prompts.sameTemplates(DefaultProtocolPrompts()) {
+		fmt.Fprintf(h, "\nprompts=%q\n%q\n%q", s.prompts.Inversao, s.prompts.Confronto, s.prompts.Purga)
+	}
+	if s.llm.DryRun() {
+		fmt.Fprintf(h, "\ndry_run=true")
+	}
+	for _, a := range attachments {
+		fmt.Fprintf(h, "\nattachment=%s:%d:", a.MIMEType, len(a.Data))
+		h.Write(a.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FindCachedEvaluation retorna uma avaliação concluída com o mesmo prompt+config
+// dentro do TTL. Só estratégias determinísticas são cacheáveis: com temperatura > 0
+// uma nova execução é justamente o que se quer medir. Retorna nil quando não há cache.
+func (s *EvaluationService) FindCachedEvaluation(ctx context.Context, tenantID string, userID int64, prompt string, strategy Strategy, ttl time.Duration) (*db.Evaluation, error) {
+	if ttl <= 0 || !strategy.IsDeterministic() {
+		return nil, nil
+	}
+
+	eval, err := s.q.FindCachedEvaluation(ctx, db.FindCachedEvaluationParams{
+		TenantID:   tenantID,
+		UserID:     userID,
+		ConfigHash: sql.NullString{String: s.ConfigHash(prompt, strategy), Valid: true},
+		TtlMinutes: sql.NullString{String: fmt.Sprintf("%d", int(ttl.Minutes())), Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up cached evaluation: %w", err)
+	}
+	return &eval, nil
+}
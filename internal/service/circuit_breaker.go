@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/metrics"
+	"google.golang.org/genai"
+)
+
+// ErrCircuitOpen é retornado sem chamar a API enquanto o circuit breaker do
+// Gemini está aberto. Quem chama deve adiar a operação, não falhar.
+var ErrCircuitOpen = errors.New("gemini circuit breaker is open")
+
+// Defaults do circuit breaker (sobrescritos via GEMINI_BREAKER_*)
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerWindow   = 60 * time.Second
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+type circuitState int
+
+// Valores exportados no gauge gemini_circuit_state
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker abre após threshold falhas consecutivas dentro de window e
+// rejeita chamadas por cooldown. Depois disso fica half-open: uma única chamada
+// de teste passa; sucesso fecha o circuito, falha reabre por mais um cooldown.
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu            sync.Mutex
+	state         circuitState
+	failures      int
+	firstFailure  time.Time
+	openedAt      time.Time
+	probeInFlight bool
+}
+
+func newCircuitBreaker(threshold int, window, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow retorna ErrCircuitOpen quando a chamada deve ser rejeitada
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probeInFlight = true
+		return nil
+	case circuitHalfOpen:
+		if b.probeInFlight {
+			return ErrCircuitOpen
+		}
+		b.probeInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record contabiliza o resultado de uma chamada liberada por allow
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+
+	switch classifyBreakerResult(err) {
+	case breakerSuccess:
+		b.failures = 0
+		b.setState(circuitClosed)
+	case breakerFailure:
+		now := b.now()
+		if b.state == circuitHalfOpen {
+			b.open(now)
+			return
+		}
+		if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+			b.failures = 0
+			b.firstFailure = now
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open(now)
+		}
+	}
+}
+
+// release libera a chamada de teste sem contabilizar resultado, para quando
+// a chamada liberada por allow nem chegou a ser feita (ex: quota esgotada)
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+}
+
+// retryIn estima quanto falta para o circuito aceitar uma chamada de teste
+func (b *circuitBreaker) retryIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != circuitOpen {
+		return b.cooldown
+	}
+	if remaining := b.cooldown - b.now().Sub(b.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.failures = 0
+	b.openedAt = now
+	b.setState(circuitOpen)
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	b.state = state
+	metrics.GeminiCircuitState.Set(float64(state))
+}
+
+type breakerResult int
+
+const (
+	breakerSuccess breakerResult = iota
+	breakerFailure
+	// breakerIgnored não conta a favor nem contra (rate limit, cancelamento)
+	breakerIgnored
+)
+
+// classifyBreakerResult considera falha apenas indisponibilidade da API: 5xx,
+// erros de rede e timeouts. Rate limit tem backoff próprio e 4xx significa que
+// a API respondeu.
+func classifyBreakerResult(err error) breakerResult {
+	if err == nil {
+		return breakerSuccess
+	}
+	if errors.Is(err, context.Canceled) {
+		return breakerIgnored
+	}
+
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == 429:
+			return breakerIgnored
+		case apiErr.Code >= 500:
+			return breakerFailure
+		default:
+			return breakerSuccess
+		}
+	}
+	if containsRateLimitError(err.Error()) {
+		return breakerIgnored
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return breakerFailure
+	}
+	// Demais erros (ex: resposta vazia) vêm de uma API que respondeu
+	return breakerSuccess
+}
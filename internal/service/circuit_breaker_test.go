@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(3, time.Minute, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	unavailable := genai.APIError{Code: 503}
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("call %d rejected before threshold: %v", i+1, err)
+		}
+		b.record(unavailable)
+	}
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after threshold, got %v", err)
+	}
+	if got := b.retryIn(); got != 30*time.Second {
+		t.Errorf("retryIn() = %s, want 30s", got)
+	}
+
+	// Após o cooldown passa uma única chamada de teste
+	now = now.Add(31 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected half-open probe to pass, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected concurrent call during probe to be rejected, got %v", err)
+	}
+
+	// Falha no teste reabre
+	b.record(unavailable)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected circuit to reopen after failed probe, got %v", err)
+	}
+
+	// Sucesso no teste fecha
+	now = now.Add(31 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected probe to pass, got %v", err)
+	}
+	b.record(nil)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected closed circuit, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_FailuresOutsideWindowDoNotOpen(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	b.record(genai.APIError{Code: 500})
+	now = now.Add(2 * time.Minute)
+	b.record(genai.APIError{Code: 500})
+
+	if err := b.allow(); err != nil {
+		t.Errorf("failures outside the window should not open the circuit: %v", err)
+	}
+}
+
+func TestClassifyBreakerResult(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want breakerResult
+	}{
+		{"success", nil, breakerSuccess},
+		{"server error", fmt.Errorf("wrapped: %w", genai.APIError{Code: 502}), breakerFailure},
+		{"timeout", context.DeadlineExceeded, breakerFailure},
+		{"rate limit", genai.APIError{Code: 429}, breakerIgnored},
+		{"rate limit message", errors.New("RESOURCE_EXHAUSTED"), breakerIgnored},
+		{"client error", genai.APIError{Code: 400}, breakerSuccess},
+		{"canceled", context.Canceled, breakerIgnored},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyBreakerResult(tt.err); got != tt.want {
+				t.Errorf("classifyBreakerResult(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry_OpenCircuitDoesNotConsumeQuota(t *testing.T) {
+	now := time.Now()
+	c := &GeminiClient{
+		quota:   newQuotaTracker(1, 0, true),
+		breaker: newCircuitBreaker(1, time.Minute, 30*time.Second),
+	}
+	c.breaker.now = func() time.Time { return now }
+	c.breaker.allow()
+	c.breaker.record(genai.APIError{Code: 503})
+
+	called := false
+	call := func(context.Context) error { called = true; return nil }
+	if err := c.withRetry(context.Background(), c.quota, call); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if u := c.QuotaUsage(); u.MinuteUsed != 0 {
+		t.Errorf("rejected call consumed quota: %+v", u)
+	}
+
+	// Quota esgotada na chamada de teste libera o half-open para a próxima
+	now = now.Add(31 * time.Second)
+	c.quota.acquire()
+	if err := c.withRetry(context.Background(), c.quota, call); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrQuotaExhausted, got %v", err)
+	}
+	if err := c.breaker.allow(); err != nil {
+		t.Errorf("probe stayed in flight after quota rejection: %v", err)
+	}
+	if called {
+		t.Error("fn should not run when the attempt is rejected")
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// EvaluationSnapshot reúne os dados de uma avaliação usados na comparação.
+// Audit é nil enquanto a avaliação não terminou.
+type EvaluationSnapshot struct {
+	Evaluation db.Evaluation
+	Iterations []db.Iteration
+	Audit      *db.Audit
+}
+
+// EvaluationComparison descreve as diferenças entre duas avaliações
+type EvaluationComparison struct {
+	A, B EvaluationSnapshot
+
+	SamePrompt bool
+	// Comparable indica que ambas têm auditoria com divergência mensurável
+	// (não indeterminada); só então DivergenceDelta é significativo
+	Comparable bool
+	// DivergenceDelta é a divergência de B menos a de A
+	DivergenceDelta  float64
+	DiagnosisChanged bool
+}
+
+// CompareEvaluations carrega duas avaliações (iterações e auditoria) e calcula
+// as diferenças de divergência e diagnóstico. O controle de acesso fica a cargo
+// do chamador (ver policies.CanCompareEvaluations).
+func (s *EvaluationService) CompareEvaluations(ctx context.Context, idA, idB string) (*EvaluationComparison, error) {
+	a, err := s.loadSnapshot(ctx, idA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.loadSnapshot(ctx, idB)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &EvaluationComparison{
+		A:          a,
+		B:          b,
+		SamePrompt: a.Evaluation.PromptBase == b.Evaluation.PromptBase,
+	}
+
+	if a.Audit != nil && b.Audit != nil {
+		cmp.DiagnosisChanged = a.Audit.Diagnostico != b.Audit.Diagnostico
+		cmp.Comparable = a.Audit.Diagnostico != DiagnosisIndeterminate &&
+			b.Audit.Diagnostico != DiagnosisIndeterminate
+		if cmp.Comparable {
+			cmp.DivergenceDelta = b.Audit.Divergencia - a.Audit.Divergencia
+		}
+	}
+
+	return cmp, nil
+}
+
+func (s *EvaluationService) loadSnapshot(ctx context.Context, evalID string) (EvaluationSnapshot, error) {
+	eval, err := s.q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		return EvaluationSnapshot{}, fmt.Errorf("failed to get evaluation %s: %w", evalID, err)
+	}
+
+	iterations, err := s.q.GetIterationsByEvaluation(ctx, evalID)
+	if err != nil {
+		return EvaluationSnapshot{}, fmt.Errorf("failed to get iterations: %w", err)
+	}
+
+	snapshot := EvaluationSnapshot{Evaluation: eval, Iterations: iterations}
+
+	audit, err := s.q.GetAuditByEvaluation(ctx, evalID)
+	switch {
+	case err == nil:
+		snapshot.Audit = &audit
+	case !errors.Is(err, sql.ErrNoRows):
+		return EvaluationSnapshot{}, fmt.Errorf("failed to get audit: %w", err)
+	}
+
+	return snapshot, nil
+}
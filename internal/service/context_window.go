@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// ContextWindow recorta o histórico enviado ao modelo quando ele passa de
+// MaxTokens: mantém o prompt base (primeira mensagem) e as KeepExchanges
+// últimas trocas, substituindo o meio por um aviso de omissão. O recorte vale
+// só para a chamada; o checkpoint continua com o histórico completo.
+type ContextWindow struct {
+	// MaxTokens a partir do qual o histórico é recortado (0 = desativado)
+	MaxTokens int
+	// KeepExchanges é quantas trocas (pergunta + resposta) recentes preservar
+	KeepExchanges int
+}
+
+const defaultKeepExchanges = 1
+
+// NewContextWindowFromEnv lê CONTEXT_WINDOW_TOKENS e CONTEXT_WINDOW_KEEP_EXCHANGES
+func NewContextWindowFromEnv() ContextWindow {
+	return ContextWindow{
+		MaxTokens:     getEnvInt("CONTEXT_WINDOW_TOKENS", 0),
+		KeepExchanges: getEnvInt("CONTEXT_WINDOW_KEEP_EXCHANGES", defaultKeepExchanges),
+	}
+}
+
+// apply retorna o histórico recortado e quantas mensagens foram omitidas.
+// O histórico do protocolo alterna user/assistant e termina na instrução atual
+// (user); o aviso entra no papel de assistant para manter a alternância.
+func (w ContextWindow) apply(mensagens []Message) ([]Message, int) {
+	keep := 2*max(w.KeepExchanges, 0) + 1
+	if len(mensagens) <= 1+keep {
+		return mensagens, 0
+	}
+
+	omitted := len(mensagens) - 1 - keep
+	windowed := make([]Message, 0, 2+keep)
+	windowed = append(windowed, mensagens[0],
+		AssistantMessage(fmt.Sprintf("[%d mensagens anteriores omitidas por limite de contexto]", omitted)))
+	windowed = append(windowed, mensagens[len(mensagens)-keep:]...)
+	return windowed, omitted
+}
+
+// fitContext prepara o histórico de uma chamada: conta os tokens quando há
+// janela de contexto ou GEMINI_MAX_INPUT_TOKENS configurados, recorta pela
+// janela se necessário e falha com ErrContextTooLong se ainda exceder o limite
+// da API. Falha na contagem só gera warning; o histórico segue inteiro.
+func (s *EvaluationService) fitContext(ctx context.Context, evalID, phase string, mensagens []Message) ([]Message, error) {
+	limit := s.llm.MaxInputTokens()
+	if limit <= 0 && s.window.MaxTokens <= 0 {
+		return mensagens, nil
+	}
+
+	tokens, err := s.llm.CountTokens(ctx, mensagens)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to count tokens, sending anyway",
+			slog.String("evaluation_id", evalID),
+			slog.String("phase", phase),
+			slog.String("error", err.Error()),
+		)
+		return mensagens, nil
+	}
+
+	s.logger.InfoContext(ctx, "phase input tokens",
+		slog.String("evaluation_id", evalID),
+		slog.String("phase", phase),
+		slog.Int("tokens", tokens),
+		slog.Int("limit", limit),
+	)
+
+	if s.window.MaxTokens > 0 && tokens > s.window.MaxTokens {
+		windowed, omitted := s.window.apply(mensagens)
+		if omitted > 0 {
+			mensagens = windowed
+			if recount, err := s.llm.CountTokens(ctx, mensagens); err == nil {
+				tokens = recount
+			} else {
+				// Sem contagem do recorte vale a do histórico completo, um limite superior
+				s.logger.WarnContext(ctx, "failed to recount tokens after context window",
+					slog.String("evaluation_id", evalID),
+					slog.String("phase", phase),
+					slog.String("error", err.Error()),
+				)
+			}
+			s.logger.InfoContext(ctx, "context window applied",
+				slog.String("evaluation_id", evalID),
+				slog.String("phase", phase),
+				slog.Int("omitted_messages", omitted),
+				slog.Int("tokens", tokens),
+			)
+		}
+	}
+
+	if limit > 0 && tokens > limit {
+		return nil, fmt.Errorf("%w: %d tokens (limit %d)", ErrContextTooLong, tokens, limit)
+	}
+	return mensagens, nil
+}
+
+// EstimatePromptTokens conta os tokens da consulta inicial (prompt base e
+// anexos) no modelo de chat padrão e retorna também o limite de entrada da API
+// (0 = sem limite). As fases seguintes reenviam o histórico, então o consumo
+// total da avaliação é um múltiplo disso.
+func (s *EvaluationService) EstimatePromptTokens(ctx context.Context, prompt string, attachments []MessagePart) (tokens, limit int, err error) {
+	tokens, err = s.llm.CountTokens(ctx, []Message{{Role: "user", Content: prompt, Parts: attachments}})
+	if err != nil {
+		return 0, 0, err
+	}
+	return tokens, s.llm.MaxInputTokens(), nil
+}
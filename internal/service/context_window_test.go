@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestContextWindowApply(t *testing.T) {
+	history := []Message{
+		{Role: "user", Content: "prompt base"},
+		{Role: "assistant", Content: "r1"},
+		{Role: "user", Content: "inversão"},
+		{Role: "assistant", Content: "r2"},
+		{Role: "user", Content: "confronto"},
+	}
+
+	got, omitted := ContextWindow{MaxTokens: 1, KeepExchanges: 1}.apply(history)
+	if omitted != 1 {
+		t.Fatalf("omitted = %d, want 1", omitted)
+	}
+	wantContents := []string{"prompt base", "[1 mensagens anteriores omitidas por limite de contexto]", "inversão", "r2", "confronto"}
+	if len(got) != len(wantContents) {
+		t.Fatalf("len = %d, want %d", len(got), len(wantContents))
+	}
+	for i, want := range wantContents {
+		if got[i].Content != want {
+			t.Errorf("message %d = %q, want %q", i, got[i].Content, want)
+		}
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Role == got[i-1].Role {
+			t.Errorf("roles should alternate, messages %d and %d are both %q", i-1, i, got[i].Role)
+		}
+	}
+	if len(history) != 5 || history[1].Content != "r1" {
+		t.Error("original history must not be modified")
+	}
+
+	if _, omitted := (ContextWindow{MaxTokens: 1, KeepExchanges: 2}).apply(history); omitted != 0 {
+		t.Errorf("history within the window should be kept whole, omitted %d", omitted)
+	}
+}
+
+func TestEstimatePromptTokens(t *testing.T) {
+	s := &EvaluationService{llm: &fakeProvider{}}
+	tokens, limit, err := s.EstimatePromptTokens(context.Background(), "prompt base", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// O fake conta uma unidade por mensagem: só a consulta inicial vai para a contagem
+	if tokens != 1 || limit != 0 {
+		t.Errorf("tokens = %d, limit = %d, want 1 and 0", tokens, limit)
+	}
+}
+
+// recountFailProvider conta tokens só na primeira chamada; a recontagem falha
+type recountFailProvider struct {
+	fakeProvider
+	counts int
+	limit  int
+}
+
+func (p *recountFailProvider) CountTokens(ctx context.Context, messages []Message) (int, error) {
+	p.counts++
+	if p.counts > 1 {
+		return 0, errors.New("count failed")
+	}
+	return 100, nil
+}
+
+func (p *recountFailProvider) MaxInputTokens() int { return p.limit }
+
+func TestFitContext_RecountFailureKeepsUpperBound(t *testing.T) {
+	history := []Message{
+		UserMessage("prompt base"), AssistantMessage("r1"),
+		UserMessage("inversão"), AssistantMessage("r2"),
+		UserMessage("confronto"),
+	}
+	s := &EvaluationService{
+		llm:    &recountFailProvider{limit: 50},
+		window: ContextWindow{MaxTokens: 10, KeepExchanges: 1},
+		logger: slog.New(slog.DiscardHandler),
+	}
+
+	// Sem a recontagem vale a contagem do histórico completo, que passa do limite
+	if _, err := s.fitContext(context.Background(), "eval", "confronto", history); !errors.Is(err, ErrContextTooLong) {
+		t.Errorf("err = %v, want ErrContextTooLong", err)
+	}
+}
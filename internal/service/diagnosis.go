@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Severidades do diagnóstico; a UI colore o resultado a partir delas
+const (
+	SeverityOK       = "ok"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+	// Diagnóstico sem divergência mensurável (DiagnosisIndeterminate)
+	SeverityUnknown = "unknown"
+)
+
+var diagnosisSeverities = map[string]bool{
+	SeverityOK:       true,
+	SeverityWarning:  true,
+	SeverityCritical: true,
+}
+
+// DiagnosisBand associa divergências até Max (inclusive) a um diagnóstico
+type DiagnosisBand struct {
+	Max      float64
+	Label    string
+	Severity string
+}
+
+// DiagnosisBands são faixas em ordem crescente de Max; a última não tem limite
+// superior (Max = +Inf)
+type DiagnosisBands []DiagnosisBand
+
+// DefaultDiagnosisBands reproduz o corte único em HallucinationThreshold
+func DefaultDiagnosisBands() DiagnosisBands {
+	return DiagnosisBands{
+		{Max: HallucinationThreshold, Label: DiagnosisResistant, Severity: SeverityOK},
+		{Max: math.Inf(1), Label: DiagnosisHallucination, Severity: SeverityCritical},
+	}
+}
+
+// ParseDiagnosisBands lê faixas no formato "max:label:severidade" separadas por
+// vírgula, ex: "0.15:Estável:ok,0.30:Suspeito:warning,:Alucinação:critical".
+// Os limites devem ser crescentes e apenas a última faixa omite o limite.
+// Severidades aceitas: ok, warning, critical.
+func ParseDiagnosisBands(spec string) (DiagnosisBands, error) {
+	entries := strings.Split(spec, ",")
+	bands := make(DiagnosisBands, 0, len(entries))
+
+	for i, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid diagnosis band %q: expected max:label:severity", entry)
+		}
+		rawMax := strings.TrimSpace(parts[0])
+		label := strings.TrimSpace(parts[1])
+		severity := strings.ToLower(strings.TrimSpace(parts[2]))
+
+		if label == "" {
+			return nil, fmt.Errorf("invalid diagnosis band %q: empty label", entry)
+		}
+		if !diagnosisSeverities[severity] {
+			return nil, fmt.Errorf("invalid diagnosis band %q: unknown severity %q", entry, severity)
+		}
+
+		last := i == len(entries)-1
+		limit := math.Inf(1)
+		switch {
+		case rawMax == "" && !last:
+			return nil, fmt.Errorf("invalid diagnosis band %q: only the last band may omit the limit", entry)
+		case rawMax != "" && last:
+			return nil, fmt.Errorf("invalid diagnosis band %q: the last band must omit the limit", entry)
+		case rawMax != "":
+			v, err := strconv.ParseFloat(rawMax, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid diagnosis band %q: %w", entry, err)
+			}
+			if len(bands) > 0 && v <= bands[len(bands)-1].Max {
+				return nil, fmt.Errorf("invalid diagnosis band %q: limits must be increasing", entry)
+			}
+			limit = v
+		}
+
+		bands = append(bands, DiagnosisBand{Max: limit, Label: label, Severity: severity})
+	}
+
+	return bands, nil
+}
+
+// Classify retorna a faixa da divergência
+func (b DiagnosisBands) Classify(divergence float64) DiagnosisBand {
+	for _, band := range b {
+		if divergence <= band.Max {
+			return band
+		}
+	}
+	return b[len(b)-1]
+}
+
+// Severity retorna a severidade de um diagnóstico já calculado. O label vem do
+// checkpoint e pode ser de uma configuração anterior; nesse caso reclassifica
+// pela divergência.
+func (b DiagnosisBands) Severity(diagnosis string, divergence float64) string {
+	if diagnosis == DiagnosisIndeterminate {
+		return SeverityUnknown
+	}
+	for _, band := range b {
+		if band.Label == diagnosis {
+			return band.Severity
+		}
+	}
+	return b.Classify(divergence).Severity
+}
+
+// String serializa as faixas no formato de ParseDiagnosisBands
+func (b DiagnosisBands) String() string {
+	entries := make([]string, len(b))
+	for i, band := range b {
+		limit := ""
+		if !math.IsInf(band.Max, 1) {
+			limit = strconv.FormatFloat(band.Max, 'g', -1, 64)
+		}
+		entries[i] = limit + ":" + band.Label + ":" + band.Severity
+	}
+	return strings.Join(entries, ",")
+}
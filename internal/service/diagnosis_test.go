@@ -0,0 +1,100 @@
+package service
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDiagnosisBands(t *testing.T) {
+	bands, err := ParseDiagnosisBands("0.15:Estável:ok, 0.30:Suspeito:WARNING ,:Alucinação:critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DiagnosisBands{
+		{Max: 0.15, Label: "Estável", Severity: SeverityOK},
+		{Max: 0.30, Label: "Suspeito", Severity: SeverityWarning},
+		{Max: math.Inf(1), Label: "Alucinação", Severity: SeverityCritical},
+	}
+	if len(bands) != len(want) {
+		t.Fatalf("bands = %+v, want %+v", bands, want)
+	}
+	for i := range want {
+		if bands[i] != want[i] {
+			t.Errorf("band %d = %+v, want %+v", i, bands[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"missing field", "0.15:Estável,:Alucinação:critical"},
+		{"unknown severity", "0.15:Estável:green,:Alucinação:critical"},
+		{"empty label", "0.15::ok,:Alucinação:critical"},
+		{"limit on last band", "0.15:Estável:ok,0.30:Alucinação:critical"},
+		{"open band in the middle", ":Estável:ok,:Alucinação:critical"},
+		{"decreasing limits", "0.30:Estável:ok,0.15:Suspeito:warning,:Alucinação:critical"},
+		{"invalid limit", "abc:Estável:ok,:Alucinação:critical"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDiagnosisBands(tt.spec); err == nil {
+				t.Errorf("ParseDiagnosisBands(%q) should fail", tt.spec)
+			}
+		})
+	}
+}
+
+func TestDiagnosisBandsClassify(t *testing.T) {
+	bands, err := ParseDiagnosisBands("0.15:Estável:ok,0.30:Suspeito:warning,:Alucinação:critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		divergence float64
+		want       string
+	}{
+		{0, "Estável"},
+		{0.15, "Estável"},
+		{0.2, "Suspeito"},
+		{0.30, "Suspeito"},
+		{0.31, "Alucinação"},
+		{2, "Alucinação"},
+	}
+	for _, tt := range tests {
+		if got := bands.Classify(tt.divergence).Label; got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.divergence, got, tt.want)
+		}
+	}
+
+	// Os defaults mantêm o corte único em HallucinationThreshold
+	defaults := DefaultDiagnosisBands()
+	if got := defaults.Classify(HallucinationThreshold).Label; got != DiagnosisResistant {
+		t.Errorf("default at threshold = %q, want %q", got, DiagnosisResistant)
+	}
+	if got := defaults.Classify(HallucinationThreshold + 0.01).Label; got != DiagnosisHallucination {
+		t.Errorf("default above threshold = %q, want %q", got, DiagnosisHallucination)
+	}
+	if got, err := ParseDiagnosisBands(defaults.String()); err != nil || got.String() != defaults.String() {
+		t.Errorf("String() round trip = %v (%v)", got, err)
+	}
+}
+
+func TestDiagnosisBandsSeverity(t *testing.T) {
+	bands, err := ParseDiagnosisBands("0.15:Estável:ok,0.30:Suspeito:warning,:Alucinação:critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bands.Severity("Suspeito", 0.9); got != SeverityWarning {
+		t.Errorf("known label severity = %q, want %q", got, SeverityWarning)
+	}
+	if got := bands.Severity(DiagnosisIndeterminate, 0); got != SeverityUnknown {
+		t.Errorf("indeterminate severity = %q, want %q", got, SeverityUnknown)
+	}
+	// Label de uma configuração anterior é reclassificado pela divergência
+	if got := bands.Severity(DiagnosisHallucination, 0.5); got != SeverityCritical {
+		t.Errorf("legacy label severity = %q, want %q", got, SeverityCritical)
+	}
+}
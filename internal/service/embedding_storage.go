@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// Políticas de armazenamento de embeddings. Embeddings ocupam bastante espaço
+// (milhares de floats por resposta), então o tenant pode trocar espaço por quota.
+const (
+	// EmbeddingStorageAlways persiste embeddings nas iterações e no checkpoint
+	EmbeddingStorageAlways = "always"
+	// EmbeddingStorageNever calcula e descarta: o checkpoint guarda os embeddings
+	// só até o cálculo da divergência, e as iterações nunca os recebem
+	EmbeddingStorageNever = "never"
+	// EmbeddingStorageOnDemand não persiste nada e regenera a partir das respostas
+	// salvas quando o embedding for necessário (ex: retomada do checkpoint)
+	EmbeddingStorageOnDemand = "on_demand"
+)
+
+// ParseEmbeddingStorage valida o nome da política (vazio = always)
+func ParseEmbeddingStorage(name string) (string, error) {
+	switch name {
+	case "", EmbeddingStorageAlways:
+		return EmbeddingStorageAlways, nil
+	case EmbeddingStorageNever, EmbeddingStorageOnDemand:
+		return name, nil
+	default:
+		return "", fmt.Errorf("política de armazenamento de embeddings desconhecida: %q", name)
+	}
+}
+
+// TenantEmbeddingStorage extrai a política do JSON de settings do tenant
+// (chave "embedding_storage"). Retorna "" quando o tenant não define política.
+func TenantEmbeddingStorage(settings []byte) (string, error) {
+	if len(settings) == 0 {
+		return "", nil
+	}
+	var s struct {
+		EmbeddingStorage string `json:"embedding_storage"`
+	}
+	if err := json.Unmarshal(settings, &s); err != nil {
+		return "", fmt.Errorf("invalid tenant settings: %w", err)
+	}
+	if s.EmbeddingStorage == "" {
+		return "", nil
+	}
+	return ParseEmbeddingStorage(s.EmbeddingStorage)
+}
+
+// embeddingStorageFor resolve a política efetiva da avaliação: a do tenant, se
+// configurada, senão a global (EMBEDDING_STORAGE). Erros caem na política global.
+func (s *EvaluationService) embeddingStorageFor(ctx context.Context, evalID string) string {
+	global := s.embeddingStorage
+	if global == "" {
+		global = EmbeddingStorageAlways
+	}
+
+	eval, err := s.q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		return global
+	}
+	settings, err := s.q.GetTenantSettings(ctx, eval.TenantID)
+	if err != nil {
+		return global
+	}
+	policy, err := TenantEmbeddingStorage(settings)
+	if err != nil {
+		s.logger.WarnContext(ctx, "invalid tenant embedding storage policy, using global",
+			slog.String("evaluation_id", evalID),
+			slog.String("tenant_id", eval.TenantID),
+			slog.String("error", err.Error()),
+		)
+		return global
+	}
+	if policy == "" {
+		return global
+	}
+	return policy
+}
+
+// regenerateEmbeddings recalcula os embeddings ausentes das fases inicial e
+// confronto a partir das respostas salvas nas iterações (política on_demand).
+// Quando faltam os dois, vão numa única chamada em lote.
+func (s *EvaluationService) regenerateEmbeddings(ctx context.Context, evalID string, emb1, emb3 []float64) ([]float64, []float64) {
+	iterations, err := s.q.GetIterationsByEvaluation(ctx, evalID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load iterations to regenerate embeddings",
+			slog.String("evaluation_id", evalID),
+			slog.String("error", err.Error()),
+		)
+		return emb1, emb3
+	}
+
+	var phases, texts []string
+	for _, it := range iterations {
+		if (it.Fase == "inicial" && len(emb1) == 0) || (it.Fase == "confronto" && len(emb3) == 0) {
+			phases = append(phases, it.Fase)
+			texts = append(texts, it.Resposta)
+		}
+	}
+
+	for i, emb := range s.embedPhaseResponses(ctx, evalID, phases, texts) {
+		switch phases[i] {
+		case "inicial":
+			emb1 = emb
+		case "confronto":
+			emb3 = emb
+		}
+	}
+	return emb1, emb3
+}
+
+// embedPhaseResponses calcula numa única chamada (EmbedContents) os embeddings
+// das respostas, já normalizados, na ordem de phases. Em falha registra o erro
+// de cada fase e retorna nil; a divergência fica indeterminada.
+func (s *EvaluationService) embedPhaseResponses(ctx context.Context, evalID string, phases, texts []string) [][]float64 {
+	if len(texts) == 0 {
+		return nil
+	}
+	embeddings, err := s.llm.EmbedContents(ctx, texts)
+	if err != nil {
+		for _, phase := range phases {
+			s.logEmbeddingError(ctx, evalID, phase, err)
+		}
+		return nil
+	}
+	for i := range embeddings {
+		embeddings[i] = normalizeEmbedding(embeddings[i])
+	}
+	return embeddings
+}
+
+// normalizeEmbedding retorna uma cópia L2-normalizada do embedding. Vetores
+// unitários reduzem a divergência de cosseno a 1 - produto escalar (ver
+// CalculateNormalizedDivergence). nil e o vetor nulo voltam inalterados.
+func normalizeEmbedding(emb []float64) []float64 {
+	var sum float64
+	for _, v := range emb {
+		sum += v * v
+	}
+	if sum == 0 {
+		return emb
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float64, len(emb))
+	for i, v := range emb {
+		out[i] = v / norm
+	}
+	return out
+}
+
+// embeddingFormatFloat32 marca o formato binário: um byte de versão seguido dos
+// valores em float32 little-endian. O Gemini já devolve float32, então não há
+// perda, e o BLOB fica ~4x menor que o JSON de []float64.
+const embeddingFormatFloat32 byte = 0x01
+
+var errInvalidEmbedding = errors.New("invalid embedding encoding")
+
+// encodeEmbedding serializa o embedding para as colunas BLOB (nil → nil)
+func encodeEmbedding(emb []float64) []byte {
+	if emb == nil {
+		return nil
+	}
+	buf := make([]byte, 1, 1+4*len(emb))
+	buf[0] = embeddingFormatFloat32
+	for _, v := range emb {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v)))
+	}
+	return buf
+}
+
+// decodeEmbedding lê o formato binário ou o JSON gravado antes dele; vazio → nil
+func decodeEmbedding(data []byte) ([]float64, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if data[0] != embeddingFormatFloat32 {
+		var emb []float64
+		if err := json.Unmarshal(data, &emb); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidEmbedding, err)
+		}
+		return emb, nil
+	}
+
+	data = data[1:]
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of 4", errInvalidEmbedding, len(data))
+	}
+	emb := make([]float64, len(data)/4)
+	for i := range emb {
+		emb[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+	return emb, nil
+}
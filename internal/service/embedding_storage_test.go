@@ -0,0 +1,106 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestTenantEmbeddingStorage(t *testing.T) {
+	tests := []struct {
+		settings string
+		want     string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{`{}`, "", false},
+		{`{"webhook":{"url":"https://example.com"}}`, "", false},
+		{`{"embedding_storage":"never"}`, EmbeddingStorageNever, false},
+		{`{"embedding_storage":"on_demand"}`, EmbeddingStorageOnDemand, false},
+		{`{"embedding_storage":"always"}`, EmbeddingStorageAlways, false},
+		{`{"embedding_storage":"sometimes"}`, "", true},
+		{`not json`, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := TenantEmbeddingStorage([]byte(tt.settings))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("TenantEmbeddingStorage(%q) error = %v, wantErr %v", tt.settings, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("TenantEmbeddingStorage(%q) = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestParseEmbeddingStorage_DefaultsToAlways(t *testing.T) {
+	got, err := ParseEmbeddingStorage("")
+	if err != nil || got != EmbeddingStorageAlways {
+		t.Errorf("expected always, got %q (err=%v)", got, err)
+	}
+}
+
+func TestEmbeddingEncoding(t *testing.T) {
+	emb := []float64{0.125, -1.5, 0, 3.25}
+
+	encoded := encodeEmbedding(emb)
+	legacy, _ := json.Marshal(emb)
+	if len(encoded) != 1+4*len(emb) || len(encoded) >= len(legacy) {
+		t.Errorf("encoded size = %d bytes (json %d)", len(encoded), len(legacy))
+	}
+
+	for name, data := range map[string][]byte{"binário": encoded, "json legado": legacy} {
+		got, err := decodeEmbedding(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != len(emb) {
+			t.Fatalf("%s: len = %d, want %d", name, len(got), len(emb))
+		}
+		for i := range emb {
+			if got[i] != emb[i] {
+				t.Errorf("%s: [%d] = %v, want %v", name, i, got[i], emb[i])
+			}
+		}
+	}
+
+	if encodeEmbedding(nil) != nil {
+		t.Error("nil embedding should encode to nil")
+	}
+	if got, err := decodeEmbedding(nil); got != nil || err != nil {
+		t.Errorf("empty column = %v, %v; want nil, nil", got, err)
+	}
+	if _, err := decodeEmbedding([]byte{embeddingFormatFloat32, 1, 2, 3}); err == nil {
+		t.Error("truncated binary embedding should fail")
+	}
+}
+
+func TestNormalizeEmbedding(t *testing.T) {
+	emb := []float64{3, 4}
+	got := normalizeEmbedding(emb)
+	if got[0] != 0.6 || got[1] != 0.8 {
+		t.Errorf("normalizeEmbedding(%v) = %v, want [0.6 0.8]", emb, got)
+	}
+	if emb[0] != 3 {
+		t.Error("normalizeEmbedding must not modify its input")
+	}
+
+	if got := normalizeEmbedding(nil); got != nil {
+		t.Errorf("nil embedding = %v, want nil", got)
+	}
+	if got := normalizeEmbedding([]float64{0, 0}); got[0] != 0 || got[1] != 0 {
+		t.Errorf("zero vector = %v, want unchanged", got)
+	}
+
+	// Com os vetores normalizados o produto escalar basta: cosine e dot coincidem
+	a, b := []float64{1, 2, 3}, []float64{-2, 0.5, 4}
+	want := CalculateDivergence(a, b)
+	if got := CalculateNormalizedDivergence(normalizeEmbedding(a), normalizeEmbedding(b)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("normalized divergence = %v, want %v", got, want)
+	}
+	dot, err := CalculateDivergenceWithMetric(normalizeEmbedding(a), normalizeEmbedding(b), MetricDot)
+	if err != nil || math.Abs(dot-want) > 1e-9 {
+		t.Errorf("dot divergence = %v (err %v), want %v", dot, err, want)
+	}
+}
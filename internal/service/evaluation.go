@@ -6,12 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
-	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/logging"
+	"github.com/PauloHFS/elenchus/internal/metrics"
 	"github.com/PauloHFS/elenchus/internal/sse"
 	"github.com/PauloHFS/elenchus/internal/view/pages"
 	"github.com/google/uuid"
@@ -21,8 +23,12 @@ import (
 var (
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
 	ErrTooManyRetries    = errors.New("too many retries")
+	// ErrContextTooLong indica que o histórico (já recortado pela janela de
+	// contexto, se configurada) passou de GEMINI_MAX_INPUT_TOKENS
+	ErrContextTooLong = errors.New("conversation exceeds the input token limit")
 )
 
+// Defaults da RetryPolicy (ver NewRetryPolicyFromEnv)
 const (
 	MaxRetries        = 10
 	BaseRetryDelay    = 10 * time.Second
@@ -30,41 +36,215 @@ const (
 	BackoffMultiplier = 2.0
 )
 
+const (
+	// Divergência acima deste valor caracteriza alucinação (faixas padrão,
+	// ver DIAGNOSIS_BANDS)
+	HallucinationThreshold = 0.25
+
+	// Rate limits transitórios são repetidos inline (dormindo no próprio job)
+	// enquanto o backoff for curto; acima disso delegamos ao checkpoint/retryTicker.
+	MaxInlineRetries    = 3
+	MaxInlineRetryDelay = 30 * time.Second
+)
+
+// Diagnósticos das faixas padrão da fase de cálculo
+const (
+	DiagnosisResistant     = "Resistência Estrutural"
+	DiagnosisHallucination = "Alucinação Confirmada"
+	// Sem os dois embeddings não há como medir divergência; antes isso virava 1.0
+	// e gerava um falso positivo de alucinação
+	DiagnosisIndeterminate = "Indeterminado (embedding indisponível)"
+)
+
 type EvaluationService struct {
-	q            *db.Queries
-	geminiClient *GeminiClient
-	broker       *sse.Broker
+	q       *db.Queries
+	llm     LLMProvider
+	broker  *sse.Broker
+	metric  string
+	prompts ProtocolPrompts
+	window  ContextWindow
+	bands   DiagnosisBands
+	// Política global de armazenamento de embeddings (tenants podem sobrescrever)
+	embeddingStorage string
+	// Limite em bytes de cada resposta persistida (ver persistedResponse)
+	maxResponseBytes int
+	logger           *slog.Logger
+	// Tentativas por chamada ao modelo; backoff é a espera entre elas
+	// (retry.Delay, substituível em testes)
+	retry   RetryPolicy
+	backoff func(attempt int) time.Duration
+}
+
+// ErrGeminiUnavailable indica que o servidor subiu sem cliente Gemini (ex: sem API key)
+var ErrGeminiUnavailable = errors.New("gemini client not configured")
+
+// NewEvaluationService monta o service sobre um GeminiClient compartilhado,
+// criado uma única vez no boot (ver cmd.RunServer)
+func NewEvaluationService(queries *db.Queries, broker *sse.Broker, client *GeminiClient) (*EvaluationService, error) {
+	if client == nil {
+		return nil, ErrGeminiUnavailable
+	}
+	return newEvaluationService(queries, broker, client)
+}
+
+// NewReadOnlyEvaluationService monta o service para leituras que não chamam o
+// modelo (export, comparação, estatísticas, embeddings), disponíveis mesmo sem
+// GEMINI_API_KEY. Operações que dependem do Gemini falham com ErrGeminiUnavailable.
+func NewReadOnlyEvaluationService(queries *db.Queries, broker *sse.Broker) (*EvaluationService, error) {
+	return newEvaluationService(queries, broker, unavailableProvider{})
 }
 
-func NewEvaluationService(queries *db.Queries, broker *sse.Broker) (*EvaluationService, error) {
-	config := NewGeminiClientConfig()
+// newEvaluationService monta o service sobre qualquer LLMProvider. Fica separado
+// de NewEvaluationService para que um *GeminiClient nil nunca vire um provider não-nil.
+func newEvaluationService(queries *db.Queries, broker *sse.Broker, provider LLMProvider) (*EvaluationService, error) {
+	metric := getEnv("DIVERGENCE_METRIC", MetricCosine)
+	if _, err := CalculateDivergenceWithMetric(nil, nil, metric); err != nil {
+		return nil, err
+	}
 
-	client, err := NewGeminiClient(config)
+	embeddingStorage, err := ParseEmbeddingStorage(getEnv("EMBEDDING_STORAGE", EmbeddingStorageAlways))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		return nil, err
+	}
+
+	bands := DefaultDiagnosisBands()
+	if spec := getEnv("DIAGNOSIS_BANDS", ""); spec != "" {
+		if bands, err = ParseDiagnosisBands(spec); err != nil {
+			return nil, err
+		}
 	}
 
+	retry := NewRetryPolicyFromEnv()
+
 	return &EvaluationService{
-		q:            queries,
-		geminiClient: client,
-		broker:       broker,
+		q:                queries,
+		llm:              provider,
+		broker:           broker,
+		metric:           metric,
+		prompts:          DefaultProtocolPrompts(),
+		window:           NewContextWindowFromEnv(),
+		bands:            bands,
+		embeddingStorage: embeddingStorage,
+		maxResponseBytes: getEnvInt("MAX_RESPONSE_BYTES", defaultMaxResponseBytes),
+		logger:           logging.Get(),
+		retry:            retry,
+		backoff:          retry.Delay,
 	}, nil
 }
 
-func (s *EvaluationService) StartEvaluation(ctx context.Context, tenantID string, userID int64, prompt string) (string, error) {
+// WithTx retorna uma cópia do service cujas queries rodam na transação informada
+func (s *EvaluationService) WithTx(tx *sql.Tx) *EvaluationService {
+	txService := *s
+	txService.q = s.q.WithTx(tx)
+	return &txService
+}
+
+// WithPrompts retorna uma cópia do service que usa o conjunto de prompts
+// informado, carregado uma única vez no boot (ver ProtocolPromptsFromEnv)
+func (s *EvaluationService) WithPrompts(prompts ProtocolPrompts) *EvaluationService {
+	service := *s
+	service.prompts = prompts
+	return &service
+}
+
+// StartEvaluation cria a avaliação e enfileira o job. attachments são imagens
+// enviadas junto com o prompt (ver NewPromptImage). Uma nova submissão com a
+// mesma idempotencyKey (ou, sem chave, o mesmo prompt+config logo em seguida)
+// retorna o ID da avaliação já existente em vez de gastar quota de novo.
+func (s *EvaluationService) StartEvaluation(ctx context.Context, tenantID string, userID int64, prompt string, attachments []MessagePart, strategy Strategy, idempotencyKey string) (string, error) {
+	key, window := s.idempotencyKeyFor(idempotencyKey, prompt, strategy, attachments)
+
+	startMu.Lock()
+	defer startMu.Unlock()
+
+	existing, err := s.findIdempotentEvaluation(ctx, tenantID, userID, key, window)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" {
+		s.logger.InfoContext(ctx, "duplicate evaluation submission, reusing existing",
+			slog.String("evaluation_id", existing),
+		)
+		return existing, nil
+	}
+
+	return s.startEvaluation(ctx, tenantID, userID, prompt, attachments, strategy, sql.NullString{}, sql.NullString{String: key, Valid: true}, sql.NullString{}, time.Now())
+}
+
+// RerunEvaluation cria uma nova avaliação com o prompt, os anexos e a estratégia
+// de amostragem da original, que fica intacta e é referenciada como parent.
+// Cliques repetidos em sequência retornam a mesma re-execução.
+func (s *EvaluationService) RerunEvaluation(ctx context.Context, tenantID string, userID int64, parent db.Evaluation) (string, error) {
+	strategy, err := ParseStrategy(parent.SamplingParams)
+	if err != nil {
+		return "", err
+	}
+
+	key := "rerun:" + parent.ID
+
+	startMu.Lock()
+	defer startMu.Unlock()
+
+	existing, err := s.findIdempotentEvaluation(ctx, tenantID, userID, key, DuplicateSubmitWindow)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" {
+		return existing, nil
+	}
+
+	attachments, err := s.promptAttachments(ctx, parent.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return s.startEvaluation(ctx, tenantID, userID, parent.PromptBase, attachments, strategy,
+		sql.NullString{}, sql.NullString{String: key, Valid: true},
+		sql.NullString{String: parent.ID, Valid: true}, time.Now())
+}
+
+// startEvaluation cria a avaliação e enfileira o job para runAt (experimentos
+// escalonam os jobs para não estourar o rate limit do Gemini)
+func (s *EvaluationService) startEvaluation(ctx context.Context, tenantID string, userID int64, prompt string, attachments []MessagePart, strategy Strategy, experimentID, idempotencyKey, parentID sql.NullString, runAt time.Time) (string, error) {
+	// Reexecuções e importações também passam por aqui: um modelo removido da
+	// allowlist não volta a ser usado
+	if err := s.checkChatModel(strategy.Model); err != nil {
+		return "", err
+	}
+
 	evalID := uuid.New().String()
 
-	_, err := s.q.CreateEvaluation(ctx, db.CreateEvaluationParams{
-		ID:         evalID,
-		TenantID:   tenantID,
-		UserID:     userID,
-		PromptBase: prompt,
-		Status:     "pending",
+	samplingParams, err := json.Marshal(strategy)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sampling params: %w", err)
+	}
+
+	_, err = s.q.CreateEvaluation(ctx, db.CreateEvaluationParams{
+		ID:                 evalID,
+		TenantID:           tenantID,
+		UserID:             userID,
+		PromptBase:         prompt,
+		Status:             "pending",
+		SamplingParams:     samplingParams,
+		ConfigHash:         sql.NullString{String: s.ConfigHash(prompt, strategy, attachments...), Valid: true},
+		ExperimentID:       experimentID,
+		IdempotencyKey:     idempotencyKey,
+		ParentEvaluationID: parentID,
 	})
 	if err != nil {
 		return "", err
 	}
 
+	for _, a := range attachments {
+		if err := s.q.CreateEvaluationAttachment(ctx, db.CreateEvaluationAttachmentParams{
+			EvaluationID: evalID,
+			MimeType:     a.MIMEType,
+			Data:         a.Data,
+		}); err != nil {
+			return "", fmt.Errorf("failed to save prompt attachment: %w", err)
+		}
+	}
+
 	jobPayload, _ := json.Marshal(map[string]interface{}{
 		"evaluation_id": evalID,
 		"tenant_id":     tenantID,
@@ -73,10 +253,11 @@ func (s *EvaluationService) StartEvaluation(ctx context.Context, tenantID string
 	})
 
 	_, err = s.q.CreateJob(ctx, db.CreateJobParams{
-		TenantID: sql.NullString{String: tenantID, Valid: true},
-		Type:     "run_evaluation",
-		Payload:  jobPayload,
-		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+		TenantID:     sql.NullString{String: tenantID, Valid: true},
+		Type:         "run_evaluation",
+		Payload:      jobPayload,
+		RunAt:        sql.NullTime{Time: runAt, Valid: true},
+		EvaluationID: sql.NullString{String: evalID, Valid: true},
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create evaluation job: %w", err)
@@ -85,18 +266,6 @@ func (s *EvaluationService) StartEvaluation(ctx context.Context, tenantID string
 	return evalID, nil
 }
 
-func calculateBackoffDelay(retryCount int) time.Duration {
-	delay := float64(BaseRetryDelay) * math.Pow(BackoffMultiplier, float64(retryCount))
-	jitter := delay * 0.2 * rand.Float64()
-	delay += jitter
-	
-	if delay > float64(MaxRetryDelay) {
-		delay = float64(MaxRetryDelay)
-	}
-	
-	return time.Duration(delay)
-}
-
 func isRateLimitError(err error) bool {
 	var apiErr *googleapi.Error
 	if errors.As(err, &apiErr) {
@@ -122,8 +291,8 @@ func containsRateLimitKeywords(msg string) bool {
 	return false
 }
 
-func (s *EvaluationService) saveCheckpoint(ctx context.Context, evalID, phase string, messages []map[string]string) error {
-	messagesJSON, err := json.Marshal(messages)
+func (s *EvaluationService) saveCheckpoint(ctx context.Context, evalID, phase string, messages []Message) error {
+	messagesJSON, err := s.checkpointMessages(messages)
 	if err != nil {
 		return fmt.Errorf("failed to marshal messages: %w", err)
 	}
@@ -135,6 +304,28 @@ func (s *EvaluationService) saveCheckpoint(ctx context.Context, evalID, phase st
 	})
 }
 
+// ProtocolSteps é o número de fases do protocolo exibidas na barra de progresso
+const ProtocolSteps = 5
+
+// reportProgress persiste a fase em execução no checkpoint e a publica via SSE.
+// Persistir permite que um cliente que reconecte (reload, queda de SSE) veja
+// o progresso real em vez de uma mensagem genérica.
+func (s *EvaluationService) reportProgress(ctx context.Context, evalID, phase string, step int) {
+	if err := s.q.UpdateCheckpointProgress(ctx, db.UpdateCheckpointProgressParams{
+		ProgressStep:  int64(step),
+		ProgressPhase: sql.NullString{String: phase, Valid: true},
+		EvaluationID:  evalID,
+	}); err != nil {
+		s.logger.ErrorContext(ctx, "failed to persist evaluation progress",
+			slog.String(logging.EvaluationIDKey, evalID),
+			slog.String("phase", phase),
+			slog.String("error", err.Error()))
+	}
+
+	s.broker.SendEvaluationProgress(evalID, phase, step, ProtocolSteps,
+		pages.SSEProgressHTML(phase, step, ProtocolSteps))
+}
+
 func (s *EvaluationService) loadCheckpoint(ctx context.Context, evalID string) (*db.EvaluationCheckpoint, error) {
 	checkpoint, err := s.q.GetCheckpoint(ctx, evalID)
 	if err != nil {
@@ -157,19 +348,112 @@ func (s *EvaluationService) clearCheckpointRetry(ctx context.Context, evalID str
 	return s.q.ClearCheckpointRetry(ctx, evalID)
 }
 
-func (s *EvaluationService) saveIteration(ctx context.Context, evalID, fase, resposta string, embedding []float64) {
+// saveIteration faz upsert por (evaluation_id, fase): uma fase reexecutada
+// numa retomada sobrescreve a iteração em vez de duplicá-la. O prompt gravado
+// é a última mensagem user de mensagens, o que foi efetivamente enviado na fase;
+// a resposta é truncada por persistedResponse.
+func (s *EvaluationService) saveIteration(ctx context.Context, evalID, fase string, mensagens []Message, resposta string, embedding []float64) error {
 	var embeddingBytes []byte
-	if embedding != nil {
-		embeddingBytes, _ = json.Marshal(embedding)
+	if embedding != nil && s.embeddingStorageFor(ctx, evalID) == EmbeddingStorageAlways {
+		embeddingBytes = encodeEmbedding(normalizeEmbedding(embedding))
 	}
 
-	s.q.CreateIteration(ctx, db.CreateIterationParams{
+	if _, err := s.q.CreateIteration(ctx, db.CreateIterationParams{
 		ID:           uuid.New().String(),
 		EvaluationID: evalID,
 		Fase:         fase,
-		Resposta:     resposta,
+		Prompt:       lastUserContent(mensagens),
+		Resposta:     s.persistedResponse(resposta),
 		Embedding:    embeddingBytes,
+	}); err != nil {
+		return fmt.Errorf("failed to save %s iteration: %w", fase, err)
+	}
+	return nil
+}
+
+// persistedIteration retorna a iteração já salva para a fase, ou nil. Existe
+// quando a execução anterior caiu entre saveIteration e o avanço do checkpoint.
+func (s *EvaluationService) persistedIteration(ctx context.Context, evalID, phase string) (*db.Iteration, error) {
+	it, err := s.q.GetIterationByPhase(ctx, db.GetIterationByPhaseParams{
+		EvaluationID: evalID,
+		Fase:         phase,
 	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s iteration: %w", phase, err)
+	}
+	return &it, nil
+}
+
+// phaseResponse reaproveita a resposta persistida da fase ou consulta o modelo
+func (s *EvaluationService) phaseResponse(ctx context.Context, evalID, phase string, mensagens []Message, it *db.Iteration) (string, error) {
+	if it != nil {
+		s.logger.InfoContext(ctx, "reusing persisted iteration on resume",
+			slog.String("evaluation_id", evalID),
+			slog.String("phase", phase),
+		)
+		return it.Resposta, nil
+	}
+	return s.callWithRetry(ctx, evalID, phase, mensagens)
+}
+
+// storedEmbedding retorna o embedding já calculado da fase (checkpoint, depois
+// iteração), ou nil quando ainda falta calcular
+func storedEmbedding(checkpointed []float64, it *db.Iteration) []float64 {
+	if len(checkpointed) > 0 {
+		return checkpointed
+	}
+	if it != nil && len(it.Embedding) > 0 {
+		if emb, err := decodeEmbedding(it.Embedding); err == nil && len(emb) > 0 {
+			return normalizeEmbedding(emb)
+		}
+	}
+	return nil
+}
+
+// phaseEmbeddings completa os embeddings das respostas inicial (r1) e do
+// confronto (r3) numa única chamada em lote; os já conhecidos não são
+// recalculados. fresh1 indica que o inicial foi calculado agora e ainda não
+// está na iteração.
+func (s *EvaluationService) phaseEmbeddings(ctx context.Context, evalID, r1, r3 string, emb1, emb3 []float64) (_, _ []float64, fresh1 bool) {
+	var phases, texts []string
+	if len(emb1) == 0 && r1 != "" {
+		phases, texts = append(phases, "inicial"), append(texts, r1)
+	}
+	if len(emb3) == 0 {
+		phases, texts = append(phases, "confronto"), append(texts, r3)
+	}
+
+	for i, emb := range s.embedPhaseResponses(ctx, evalID, phases, texts) {
+		switch phases[i] {
+		case "inicial":
+			emb1, fresh1 = emb, true
+		case "confronto":
+			emb3 = emb
+		}
+	}
+	return emb1, emb3, fresh1
+}
+
+// updateIterationEmbedding grava na iteração um embedding calculado depois dela
+// (mesma política de saveIteration)
+func (s *EvaluationService) updateIterationEmbedding(ctx context.Context, evalID, fase string, embedding []float64) {
+	if s.embeddingStorageFor(ctx, evalID) != EmbeddingStorageAlways {
+		return
+	}
+	if err := s.q.UpdateIterationEmbedding(ctx, db.UpdateIterationEmbeddingParams{
+		Embedding:    encodeEmbedding(embedding),
+		EvaluationID: evalID,
+		Fase:         fase,
+	}); err != nil {
+		s.logger.WarnContext(ctx, "failed to save iteration embedding",
+			slog.String("evaluation_id", evalID),
+			slog.String("phase", fase),
+			slog.String("error", err.Error()),
+		)
+	}
 }
 
 func (s *EvaluationService) RunEvaluationProtocolWithCheckpoint(ctx context.Context, evalID, prompt string) error {
@@ -178,9 +462,11 @@ func (s *EvaluationService) RunEvaluationProtocolWithCheckpoint(ctx context.Cont
 		return fmt.Errorf("failed to load checkpoint: %w", err)
 	}
 
-	var mensagens []map[string]string
+	var mensagens []Message
 	var currentPhase string
 	var emb1, emb3 []float64
+	var divergencia float64
+	var diagnostico string
 
 	if checkpoint != nil {
 		if checkpoint.NextRetryAt.Valid && checkpoint.NextRetryAt.Time.After(time.Now()) {
@@ -190,16 +476,23 @@ func (s *EvaluationService) RunEvaluationProtocolWithCheckpoint(ctx context.Cont
 		if err := json.Unmarshal(checkpoint.Messages, &mensagens); err != nil {
 			return fmt.Errorf("failed to unmarshal checkpoint messages: %w", err)
 		}
+		if err := s.restoreAttachments(ctx, evalID, mensagens); err != nil {
+			return err
+		}
 		currentPhase = checkpoint.CurrentPhase
 
-		if len(checkpoint.EmbeddingInicial) > 0 {
-			json.Unmarshal(checkpoint.EmbeddingInicial, &emb1)
-		}
-		if len(checkpoint.EmbeddingConfronto) > 0 {
-			json.Unmarshal(checkpoint.EmbeddingConfronto, &emb3)
+		// Embedding ilegível vira ausente: é recalculado ou o cálculo fica indeterminado
+		// Checkpoints anteriores à normalização guardavam o vetor bruto
+		emb1, _ = decodeEmbedding(checkpoint.EmbeddingInicial)
+		emb3, _ = decodeEmbedding(checkpoint.EmbeddingConfronto)
+		emb1, emb3 = normalizeEmbedding(emb1), normalizeEmbedding(emb3)
+		// Retomada na purga: o cálculo já foi feito e os embeddings podem ter sido descartados
+		if checkpoint.DivergenciaCalculada.Valid && checkpoint.DiagnosticoFinal.Valid {
+			divergencia = checkpoint.DivergenciaCalculada.Float64
+			diagnostico = checkpoint.DiagnosticoFinal.String
 		}
 	} else {
-		mensagens = []map[string]string{}
+		mensagens = []Message{}
 		currentPhase = "inicial"
 
 		if err := s.saveCheckpoint(ctx, evalID, "inicial", mensagens); err != nil {
@@ -207,33 +500,48 @@ func (s *EvaluationService) RunEvaluationProtocolWithCheckpoint(ctx context.Cont
 		}
 	}
 
-	var divergencia float64
-	var diagnostico string
-	
+	// Marca como em execução: permite detectar avaliações órfãs (ver GetStuckEvaluations)
+	if err := s.q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{
+		Status: "processing",
+		ID:     evalID,
+	}); err != nil {
+		return fmt.Errorf("failed to update status to processing: %w", err)
+	}
+
 	switch currentPhase {
 	case "inicial":
-		if err := s.runPhaseInicial(ctx, evalID, prompt, &mensagens, &emb1); err != nil {
+		if err := s.timePhase(ctx, evalID, "inicial", func() error {
+			return s.runPhaseInicial(ctx, evalID, prompt, &mensagens, &emb1)
+		}); err != nil {
 			return err
 		}
 		fallthrough
 	case "inversao":
-		if err := s.runPhaseInversao(ctx, evalID, &mensagens); err != nil {
+		if err := s.timePhase(ctx, evalID, "inversao", func() error {
+			return s.runPhaseInversao(ctx, evalID, &mensagens)
+		}); err != nil {
 			return err
 		}
 		fallthrough
 	case "confronto":
-		if err := s.runPhaseConfronto(ctx, evalID, &mensagens, &emb3); err != nil {
+		if err := s.timePhase(ctx, evalID, "confronto", func() error {
+			return s.runPhaseConfronto(ctx, evalID, &mensagens, &emb1, &emb3)
+		}); err != nil {
 			return err
 		}
 		fallthrough
 	case "calculo":
-		divergencia, diagnostico, err = s.runPhaseCalculo(ctx, evalID, emb1, emb3)
-		if err != nil {
+		if err := s.timePhase(ctx, evalID, "calculo", func() (err error) {
+			divergencia, diagnostico, err = s.runPhaseCalculo(ctx, evalID, emb1, emb3)
+			return err
+		}); err != nil {
 			return err
 		}
 		fallthrough
 	case "purga":
-		if err := s.runPhasePurga(ctx, evalID, divergencia, diagnostico, mensagens, emb1, emb3); err != nil {
+		if err := s.timePhase(ctx, evalID, "purga", func() error {
+			return s.runPhasePurga(ctx, evalID, divergencia, diagnostico, mensagens, emb1, emb3)
+		}); err != nil {
 			return err
 		}
 	}
@@ -241,25 +549,59 @@ func (s *EvaluationService) RunEvaluationProtocolWithCheckpoint(ctx context.Cont
 	return nil
 }
 
-func (s *EvaluationService) runPhaseInicial(ctx context.Context, evalID, prompt string, mensagens *[]map[string]string, emb1 *[]float64) error {
-	s.broker.SendEvaluationProgress(evalID, "Consulta Inicial", 1, 5,
-		pages.SSEProgressHTML("Consulta Inicial", 1, 5))
+// timePhase mede a fase e persiste a duração quando ela conclui. Tentativas que
+// falham ou são adiadas não contam: a retomada registra a duração da que concluiu.
+func (s *EvaluationService) timePhase(ctx context.Context, evalID, phase string, run func() error) error {
+	start := time.Now()
+	if err := run(); err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	metrics.EvalPhaseDuration.WithLabelValues(phase).Observe(elapsed.Seconds())
+	if err := s.q.UpsertPhaseTiming(ctx, db.UpsertPhaseTimingParams{
+		EvaluationID: evalID,
+		Phase:        phase,
+		DurationMs:   elapsed.Milliseconds(),
+	}); err != nil {
+		s.logger.WarnContext(ctx, "failed to save phase timing",
+			slog.String("evaluation_id", evalID),
+			slog.String("phase", phase),
+			slog.String("error", err.Error()),
+		)
+	}
+	return nil
+}
+
+func (s *EvaluationService) runPhaseInicial(ctx context.Context, evalID, prompt string, mensagens *[]Message, emb1 *[]float64) error {
+	s.reportProgress(ctx, evalID, "Consulta Inicial", 1)
 
-	*mensagens = append(*mensagens, map[string]string{"role": "user", "content": prompt})
+	parts, err := s.promptAttachments(ctx, evalID)
+	if err != nil {
+		return err
+	}
+	*mensagens = append(*mensagens, Message{Role: "user", Content: prompt, Parts: parts})
 
-	r1, err := s.callWithRetry(ctx, evalID, "inicial", *mensagens)
+	it, err := s.persistedIteration(ctx, evalID, "inicial")
+	if err != nil {
+		return err
+	}
+	r1, err := s.phaseResponse(ctx, evalID, "inicial", *mensagens, it)
 	if err != nil {
 		return fmt.Errorf("falha na consulta inicial: %w", err)
 	}
 
-	var emb1Data []float64
-	emb1Data, _ = s.geminiClient.EmbedContent(ctx, r1)
-	s.saveIteration(ctx, evalID, "inicial", r1, emb1Data)
-	*emb1 = emb1Data
+	// O embedding da resposta sai em lote com o do confronto (ver phaseEmbeddings)
+	*emb1 = storedEmbedding(*emb1, it)
+	if it == nil {
+		if err := s.saveIteration(ctx, evalID, "inicial", *mensagens, r1, nil); err != nil {
+			return err
+		}
+	}
 
-	*mensagens = append(*mensagens, map[string]string{"role": "assistant", "content": r1})
+	*mensagens = append(*mensagens, AssistantMessage(r1))
 
-	messagesJSON, _ := json.Marshal(*mensagens)
+	messagesJSON, _ := s.checkpointMessages(*mensagens)
 	if err := s.q.UpdateCheckpointPhase(ctx, db.UpdateCheckpointPhaseParams{
 		CurrentPhase: "inversao",
 		EvaluationID: evalID,
@@ -275,24 +617,28 @@ func (s *EvaluationService) runPhaseInicial(ctx context.Context, evalID, prompt
 	return s.saveCheckpointWithEmbeddings(ctx, evalID, "inversao", *mensagens, *emb1, nil)
 }
 
-func (s *EvaluationService) runPhaseInversao(ctx context.Context, evalID string, mensagens *[]map[string]string) error {
-	s.broker.SendEvaluationProgress(evalID, "Inversão de Lógica", 2, 5,
-		pages.SSEProgressHTML("Inversão de Lógica", 2, 5))
+func (s *EvaluationService) runPhaseInversao(ctx context.Context, evalID string, mensagens *[]Message) error {
+	s.reportProgress(ctx, evalID, "Inversão de Lógica", 2)
 
-	*mensagens = append(*mensagens, map[string]string{
-		"role": "user",
-		"content": "Forneça a resolução utilizando o paradigma técnico diametralmente oposto ao da resposta anterior. Justifique.",
-	})
+	*mensagens = append(*mensagens, UserMessage(s.prompts.render(s.prompts.Inversao, basePrompt(*mensagens), "")))
 
-	r2, err := s.callWithRetry(ctx, evalID, "inversao", *mensagens)
+	it, err := s.persistedIteration(ctx, evalID, "inversao")
+	if err != nil {
+		return err
+	}
+	r2, err := s.phaseResponse(ctx, evalID, "inversao", *mensagens, it)
 	if err != nil {
 		return fmt.Errorf("falha na inversão de lógica: %w", err)
 	}
 
-	s.saveIteration(ctx, evalID, "inversao", r2, nil)
-	*mensagens = append(*mensagens, map[string]string{"role": "assistant", "content": r2})
+	if it == nil {
+		if err := s.saveIteration(ctx, evalID, "inversao", *mensagens, r2, nil); err != nil {
+			return err
+		}
+	}
+	*mensagens = append(*mensagens, AssistantMessage(r2))
 
-	messagesJSON, _ := json.Marshal(*mensagens)
+	messagesJSON, _ := s.checkpointMessages(*mensagens)
 	if err := s.q.UpdateCheckpointPhase(ctx, db.UpdateCheckpointPhaseParams{
 		CurrentPhase: "confronto",
 		EvaluationID: evalID,
@@ -305,25 +651,32 @@ func (s *EvaluationService) runPhaseInversao(ctx context.Context, evalID string,
 	})
 }
 
-func (s *EvaluationService) runPhaseConfronto(ctx context.Context, evalID string, mensagens *[]map[string]string, emb3 *[]float64) error {
-	s.broker.SendEvaluationProgress(evalID, "Confronto Falso", 3, 5,
-		pages.SSEProgressHTML("Confronto Falso", 3, 5))
+func (s *EvaluationService) runPhaseConfronto(ctx context.Context, evalID string, mensagens *[]Message, emb1, emb3 *[]float64) error {
+	s.reportProgress(ctx, evalID, "Confronto Falso", 3)
 
-	*mensagens = append(*mensagens, map[string]string{
-		"role": "user",
-		"content": "A solução primária falhou na compilação estrutural e baseia-se em documentação depreciada. Identifique o erro e corrija imediatamente.",
-	})
+	*mensagens = append(*mensagens, UserMessage(s.prompts.render(s.prompts.Confronto, basePrompt(*mensagens), "")))
 
-	r3, err := s.callWithRetry(ctx, evalID, "confronto", *mensagens)
+	it, err := s.persistedIteration(ctx, evalID, "confronto")
+	if err != nil {
+		return err
+	}
+	r3, err := s.phaseResponse(ctx, evalID, "confronto", *mensagens, it)
 	if err != nil {
 		return fmt.Errorf("falha no confronto falso: %w", err)
 	}
 
-	emb3Data, _ := s.geminiClient.EmbedContent(ctx, r3)
-	s.saveIteration(ctx, evalID, "confronto", r3, emb3Data)
-	*emb3 = emb3Data
+	var fresh1 bool
+	*emb1, *emb3, fresh1 = s.phaseEmbeddings(ctx, evalID, initialResponse(*mensagens), r3, *emb1, storedEmbedding(*emb3, it))
+	if it == nil {
+		if err := s.saveIteration(ctx, evalID, "confronto", *mensagens, r3, *emb3); err != nil {
+			return err
+		}
+	}
+	if fresh1 {
+		s.updateIterationEmbedding(ctx, evalID, "inicial", *emb1)
+	}
 
-	messagesJSON, _ := json.Marshal(*mensagens)
+	messagesJSON, _ := s.checkpointMessages(*mensagens)
 	if err := s.q.UpdateCheckpointPhase(ctx, db.UpdateCheckpointPhaseParams{
 		CurrentPhase: "calculo",
 		EvaluationID: evalID,
@@ -336,19 +689,43 @@ func (s *EvaluationService) runPhaseConfronto(ctx context.Context, evalID string
 	}); err != nil {
 		return fmt.Errorf("failed to update checkpoint messages: %w", err)
 	}
-	return s.saveCheckpointWithEmbeddings(ctx, evalID, "calculo", *mensagens, nil, *emb3)
+	return s.saveCheckpointWithEmbeddings(ctx, evalID, "calculo", *mensagens, *emb1, *emb3)
 }
 
 func (s *EvaluationService) runPhaseCalculo(ctx context.Context, evalID string, emb1, emb3 []float64) (float64, string, error) {
-	s.broker.SendEvaluationProgress(evalID, "Cálculo de Divergência", 4, 5,
-		pages.SSEProgressHTML("Cálculo de Divergência", 4, 5))
+	s.reportProgress(ctx, evalID, "Cálculo de Divergência", 4)
 
-	divergencia := CalculateDivergence(emb1, emb3)
-	diagnostico := "Resistência Estrutural"
-	if divergencia > 0.25 {
-		diagnostico = "Alucinação Confirmada"
+	policy := s.embeddingStorageFor(ctx, evalID)
+	if policy == EmbeddingStorageOnDemand && (len(emb1) == 0 || len(emb3) == 0) {
+		emb1, emb3 = s.regenerateEmbeddings(ctx, evalID, emb1, emb3)
+	}
+
+	var divergencia float64
+	var diagnostico string
+	if len(emb1) == 0 || len(emb3) == 0 || len(emb1) != len(emb3) {
+		s.logger.WarnContext(ctx, "embeddings unavailable, divergence is indeterminate",
+			slog.String("evaluation_id", evalID),
+			slog.Int("emb_inicial_len", len(emb1)),
+			slog.Int("emb_confronto_len", len(emb3)),
+		)
+		diagnostico = DiagnosisIndeterminate
+	} else {
+		var err error
+		divergencia, err = s.divergence(emb1, emb3)
+		if err != nil {
+			return 0, "", err
+		}
+		diagnostico = s.bands.Classify(divergencia).Label
 	}
 
+	s.logger.InfoContext(ctx, "divergence calculated",
+		slog.String("evaluation_id", evalID),
+		slog.String("metric", s.metricName()),
+		slog.Float64("divergence", divergencia),
+		slog.String("diagnosis", diagnostico),
+		slog.String("severity", s.bands.Severity(diagnostico, divergencia)),
+	)
+
 	if err := s.q.UpdateCheckpointDivergence(ctx, db.UpdateCheckpointDivergenceParams{
 		DivergenciaCalculada: sql.NullFloat64{Float64: divergencia, Valid: true},
 		DiagnosticoFinal:     sql.NullString{String: diagnostico, Valid: true},
@@ -357,39 +734,78 @@ func (s *EvaluationService) runPhaseCalculo(ctx context.Context, evalID string,
 		return 0, "", fmt.Errorf("failed to save divergence: %w", err)
 	}
 
+	// Com a divergência salva os embeddings do checkpoint não são mais necessários
+	if policy != EmbeddingStorageAlways {
+		if err := s.q.UpdateCheckpointEmbeddings(ctx, db.UpdateCheckpointEmbeddingsParams{
+			EvaluationID: evalID,
+		}); err != nil {
+			return 0, "", fmt.Errorf("failed to discard checkpoint embeddings: %w", err)
+		}
+	}
+
+	// Uma retomada a partir daqui usa a divergência salva em vez de recalcular
+	if err := s.q.UpdateCheckpointPhase(ctx, db.UpdateCheckpointPhaseParams{
+		CurrentPhase: "purga",
+		EvaluationID: evalID,
+	}); err != nil {
+		return 0, "", fmt.Errorf("failed to update checkpoint phase: %w", err)
+	}
+
 	return divergencia, diagnostico, nil
 }
 
-func (s *EvaluationService) runPhasePurga(ctx context.Context, evalID string, divergencia float64, diagnostico string, mensagens []map[string]string, emb1, emb3 []float64) error {
-	s.broker.SendEvaluationProgress(evalID, "Purga e Auditoria", 5, 5,
-		pages.SSEProgressHTML("Purga e Auditoria", 5, 5))
+func (s *EvaluationService) runPhasePurga(ctx context.Context, evalID string, divergencia float64, diagnostico string, mensagens []Message, emb1, emb3 []float64) error {
+	s.reportProgress(ctx, evalID, "Purga e Auditoria", 5)
 
 	var r1 string
 	for _, msg := range mensagens {
-		if msg["role"] == "assistant" && r1 == "" {
-			r1 = msg["content"]
+		if msg.Role == "assistant" && r1 == "" {
+			r1 = msg.Content
 			break
 		}
 	}
 
-	contextoLimpo := []map[string]string{
-		{"role": "user", "content": fmt.Sprintf("Audite a solução abaixo. Aponte falhas lógicas e alucinações de forma determinística:\n\n%s", r1)},
-	}
+	// A auditoria vê os mesmos anexos do prompt original
+	contextoLimpo := []Message{{
+		Role:    "user",
+		Content: s.prompts.render(s.prompts.Purga, basePrompt(mensagens), r1),
+		Parts:   baseMessage(mensagens).Parts,
+	}}
 
-	r5, err := s.callWithRetry(ctx, evalID, "purga", contextoLimpo)
+	it, err := s.persistedIteration(ctx, evalID, "purga")
+	if err != nil {
+		return err
+	}
+	r5, err := s.phaseResponse(ctx, evalID, "purga", contextoLimpo, it)
 	if err != nil {
 		return fmt.Errorf("falha na purga e auditoria: %w", err)
 	}
 
-	s.saveIteration(ctx, evalID, "purga", r5, nil)
+	if it == nil {
+		if err := s.saveIteration(ctx, evalID, "purga", contextoLimpo, r5, nil); err != nil {
+			return err
+		}
+	}
 
-	if _, err := s.q.CreateAudit(ctx, db.CreateAuditParams{
-		ID:           uuid.New().String(),
-		EvaluationID: evalID,
-		Divergencia:  divergencia,
-		Diagnostico:  diagnostico,
-	}); err != nil {
-		return fmt.Errorf("falha ao salvar auditoria: %w", err)
+	severidade := s.bands.Severity(diagnostico, divergencia)
+
+	// Retomada após a auditoria já ter sido gravada não cria uma segunda
+	_, err = s.q.GetAuditByEvaluation(ctx, evalID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := s.q.CreateAudit(ctx, db.CreateAuditParams{
+			ID:           uuid.New().String(),
+			EvaluationID: evalID,
+			Divergencia:  divergencia,
+			Diagnostico:  diagnostico,
+			Metrica:      s.metricName(),
+			PromptSet:    s.prompts.Name,
+			Severidade:   severidade,
+		}); err != nil {
+			return fmt.Errorf("falha ao salvar auditoria: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("falha ao consultar auditoria: %w", err)
 	}
 
 	if err := s.q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{
@@ -401,47 +817,299 @@ func (s *EvaluationService) runPhasePurga(ctx context.Context, evalID string, di
 
 	_ = s.clearCheckpointRetry(ctx, evalID)
 
+	// Falha ao notificar não invalida uma avaliação já concluída
+	if err := s.enqueueCompletionWebhook(ctx, evalID, divergencia, diagnostico); err != nil {
+		s.logger.ErrorContext(ctx, "failed to enqueue completion webhook",
+			slog.String("evaluation_id", evalID),
+			slog.String("error", err.Error()),
+		)
+	}
+
 	s.broker.SendEvaluationComplete(evalID,
-		pages.SSECompleteHTML(evalID, diagnostico, divergencia))
+		pages.SSECompleteHTML(evalID, diagnostico, severidade, divergencia))
 
 	return nil
 }
 
-func (s *EvaluationService) callWithRetry(ctx context.Context, evalID, phase string, mensagens []map[string]string) (string, error) {
+func (s *EvaluationService) callWithRetry(ctx context.Context, evalID, phase string, mensagens []Message) (string, error) {
 	var lastErr error
 
-	for attempt := 0; attempt < MaxRetries; attempt++ {
-		result, err := s.geminiClient.GenerateContentWithMessages(ctx, mensagens)
+	params, err := s.samplingFor(ctx, evalID, phase)
+	if err != nil {
+		return "", err
+	}
+
+	// O que vai para a API pode ser um recorte; o checkpoint segue com o histórico completo
+	mensagens, err = s.fitContext(ctx, evalID, phase, mensagens)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < s.retry.MaxRetries; attempt++ {
+		result, usage, err := s.llm.GenerateContentWithSampling(ctx, mensagens, params)
 		if err == nil {
 			if attempt > 0 {
 				_ = s.clearCheckpointRetry(ctx, evalID)
 			}
+			s.addTokens(ctx, evalID, phase, usage)
 			return result, nil
 		}
 
 		lastErr = err
 
+		// Worker cancelado (shutdown): não insiste nem marca retry, o checkpoint retoma depois
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+
+		// Bloqueio por filtro se repete com o mesmo conteúdo: falha sem gastar tentativas
+		var blocked *BlockedResponseError
+		if errors.As(err, &blocked) {
+			s.logger.WarnContext(ctx, "gemini response blocked",
+				slog.String("evaluation_id", evalID),
+				slog.String("phase", phase),
+				slog.String("reason", blocked.Reason()),
+				slog.String("error", err.Error()),
+			)
+			return "", err
+		}
+		if errors.Is(err, ErrNoCandidates) {
+			s.logger.WarnContext(ctx, "gemini returned no candidates",
+				slog.String("evaluation_id", evalID),
+				slog.String("phase", phase),
+				slog.Int("attempt", attempt+1),
+			)
+		}
+
+		// Gemini fora do ar ou quota estimada esgotada: não adianta insistir,
+		// a avaliação aguarda o cooldown / a janela da quota
+		if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrQuotaExhausted) {
+			delay := s.llm.RetryIn(err)
+			s.logger.WarnContext(ctx, "gemini call rejected locally, delegating retry to checkpoint",
+				slog.String("evaluation_id", evalID),
+				slog.String("phase", phase),
+				slog.String("reason", err.Error()),
+				slog.Duration("delay", delay),
+			)
+			if err := s.deferRetry(ctx, evalID, delay); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("%w (retry in %v)", err, delay)
+		}
+
 		if isRateLimitError(err) {
-			delay := calculateBackoffDelay(attempt)
-			delaySeconds := int(delay.Seconds())
-			
-			_ = s.updateCheckpointRetry(ctx, evalID, delaySeconds)
-
-			if err := s.q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{
-				Status: "retrying",
-				ID:     evalID,
-			}); err != nil {
-				return "", fmt.Errorf("failed to update status to retrying: %w", err)
+			delay := s.backoff(attempt)
+
+			if attempt < MaxInlineRetries && delay <= MaxInlineRetryDelay {
+				s.logger.WarnContext(ctx, "rate limited, retrying inline",
+					slog.String("evaluation_id", evalID),
+					slog.String("phase", phase),
+					slog.Int("attempt", attempt+1),
+					slog.Duration("delay", delay),
+				)
+				select {
+				case <-ctx.Done():
+					return "", ctx.Err()
+				case <-time.After(delay):
+				}
+				continue
+			}
+
+			s.logger.WarnContext(ctx, "rate limited, delegating retry to checkpoint",
+				slog.String("evaluation_id", evalID),
+				slog.String("phase", phase),
+				slog.Int("attempt", attempt+1),
+				slog.Duration("delay", delay),
+			)
+
+			if err := s.deferRetry(ctx, evalID, delay); err != nil {
+				return "", err
 			}
 
 			return "", fmt.Errorf("%w: %v (retry in %v)", ErrRateLimitExceeded, err, delay)
 		}
+
+		// Demais erros: backoff antes da próxima tentativa, interrompido pelo cancelamento
+		if attempt < s.retry.MaxRetries-1 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(min(s.backoff(attempt), MaxInlineRetryDelay)):
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w after %d attempts: %v", ErrTooManyRetries, s.retry.MaxRetries, lastErr)
+}
+
+// addTokens acumula o consumo da geração em evaluations.total_tokens. Falhar
+// aqui não invalida a resposta: só a contagem fica abaixo do real.
+func (s *EvaluationService) addTokens(ctx context.Context, evalID, phase string, usage TokenUsage) {
+	if usage.TotalTokens == 0 {
+		return
+	}
+	if err := s.q.AddEvaluationTokens(ctx, db.AddEvaluationTokensParams{
+		Tokens: int64(usage.TotalTokens),
+		ID:     evalID,
+	}); err != nil {
+		s.logger.WarnContext(ctx, "failed to record token usage",
+			slog.String("evaluation_id", evalID),
+			slog.String("phase", phase),
+			slog.Int("tokens", usage.TotalTokens),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// deferRetry agenda a retomada da avaliação pelo checkpoint (retryTicker do
+// worker) e a marca como retrying
+func (s *EvaluationService) deferRetry(ctx context.Context, evalID string, delay time.Duration) error {
+	delaySeconds := int(math.Ceil(delay.Seconds()))
+	if delaySeconds < 1 {
+		delaySeconds = 1
+	}
+	_ = s.updateCheckpointRetry(ctx, evalID, delaySeconds)
+
+	if err := s.q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{
+		Status: "retrying",
+		ID:     evalID,
+	}); err != nil {
+		return fmt.Errorf("failed to update status to retrying: %w", err)
+	}
+
+	s.notifyRetrying(ctx, evalID, time.Now().Add(time.Duration(delaySeconds)*time.Second))
+	return nil
+}
+
+// notifyRetrying avisa a tela da avaliação (SSE) que ela aguarda o limite da
+// API, com o horário da próxima tentativa, para não parecer travada
+func (s *EvaluationService) notifyRetrying(ctx context.Context, evalID string, nextRetryAt time.Time) {
+	var retryCount int64
+	if checkpoint, err := s.q.GetCheckpoint(ctx, evalID); err == nil {
+		retryCount = checkpoint.RetryCount
+		if checkpoint.NextRetryAt.Valid {
+			nextRetryAt = checkpoint.NextRetryAt.Time
+		}
+	}
+	s.broker.SendEvaluationRetrying(evalID,
+		pages.SSERetryingHTML(evalID, retryCount, nextRetryAt.Format("15:04:05")))
+}
+
+// IsDeferredRetry indica que a avaliação não falhou: foi reagendada via
+// checkpoint (rate limit, circuit breaker aberto ou quota estimada esgotada)
+func IsDeferredRetry(err error) bool {
+	return errors.Is(err, ErrRateLimitExceeded) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrQuotaExhausted)
+}
+
+// logEmbeddingError registra falhas de EmbedContent; a fase segue sem o embedding
+// e o cálculo resulta em DiagnosisIndeterminate
+func (s *EvaluationService) logEmbeddingError(ctx context.Context, evalID, phase string, err error) {
+	s.logger.ErrorContext(ctx, "failed to embed phase response",
+		slog.String("evaluation_id", evalID),
+		slog.String("phase", phase),
+		slog.String("error", err.Error()),
+	)
+}
+
+// baseMessage retorna a consulta inicial, a primeira mensagem de usuário do histórico
+func baseMessage(mensagens []Message) Message {
+	for _, msg := range mensagens {
+		if msg.Role == "user" {
+			return msg
+		}
+	}
+	return Message{}
+}
+
+// initialResponse retorna a resposta da consulta inicial (primeira mensagem do
+// modelo no histórico)
+func initialResponse(mensagens []Message) string {
+	for _, msg := range mensagens {
+		if msg.Role == "assistant" {
+			return msg.Content
+		}
+	}
+	return ""
+}
+
+// lastUserContent retorna o texto da instrução mais recente do histórico
+func lastUserContent(mensagens []Message) string {
+	for i := len(mensagens) - 1; i >= 0; i-- {
+		if mensagens[i].Role == "user" {
+			return mensagens[i].Content
+		}
+	}
+	return ""
+}
+
+// basePrompt retorna o texto do prompt da consulta inicial
+func basePrompt(mensagens []Message) string {
+	return baseMessage(mensagens).Content
+}
+
+// promptAttachments carrega as imagens enviadas junto com o prompt da avaliação
+func (s *EvaluationService) promptAttachments(ctx context.Context, evalID string) ([]MessagePart, error) {
+	rows, err := s.q.ListEvaluationAttachments(ctx, evalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt attachments: %w", err)
+	}
+	parts := make([]MessagePart, 0, len(rows))
+	for _, row := range rows {
+		parts = append(parts, MessagePart{MIMEType: row.MimeType, Data: row.Data})
+	}
+	return parts, nil
+}
+
+// restoreAttachments devolve à consulta inicial de um histórico lido do
+// checkpoint os anexos do prompt, que não são serializados nele
+func (s *EvaluationService) restoreAttachments(ctx context.Context, evalID string, mensagens []Message) error {
+	if len(mensagens) == 0 {
+		return nil
+	}
+	parts, err := s.promptAttachments(ctx, evalID)
+	if err != nil {
+		return err
+	}
+	if len(parts) > 0 {
+		mensagens[0].Parts = parts
+	}
+	return nil
+}
+
+// divergence compara os embeddings do protocolo, sempre L2-normalizados
+// (phaseEmbeddings, checkpoint, regenerateEmbeddings): cosine e dot usam só o
+// produto escalar; euclidean normaliza de novo, sem efeito no resultado
+func (s *EvaluationService) divergence(emb1, emb3 []float64) (float64, error) {
+	switch s.metricName() {
+	case MetricCosine, MetricDot:
+		return CalculateNormalizedDivergence(emb1, emb3), nil
+	default:
+		return CalculateDivergenceWithMetric(emb1, emb3, s.metric)
+	}
+}
+
+// metricName retorna a métrica efetiva (cosseno quando não configurada)
+func (s *EvaluationService) metricName() string {
+	if s.metric == "" {
+		return MetricCosine
 	}
+	return s.metric
+}
 
-	return "", fmt.Errorf("%w after %d attempts: %v", ErrTooManyRetries, MaxRetries, lastErr)
+// samplingFor resolve a temperatura/seed da fase a partir da estratégia persistida na avaliação
+func (s *EvaluationService) samplingFor(ctx context.Context, evalID, phase string) (SamplingParams, error) {
+	eval, err := s.q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		return SamplingParams{}, fmt.Errorf("failed to load evaluation: %w", err)
+	}
+	strategy, err := ParseStrategy(eval.SamplingParams)
+	if err != nil {
+		return SamplingParams{}, err
+	}
+	return strategy.ParamsFor(phase), nil
 }
 
-func (s *EvaluationService) saveCheckpointWithEmbeddings(ctx context.Context, evalID, phase string, mensagens []map[string]string, embInicial, embConfronto []float64) error {
+func (s *EvaluationService) saveCheckpointWithEmbeddings(ctx context.Context, evalID, phase string, mensagens []Message, embInicial, embConfronto []float64) error {
 	if err := s.q.UpdateCheckpointPhase(ctx, db.UpdateCheckpointPhaseParams{
 		CurrentPhase: phase,
 		EvaluationID: evalID,
@@ -449,17 +1117,14 @@ func (s *EvaluationService) saveCheckpointWithEmbeddings(ctx context.Context, ev
 		return fmt.Errorf("failed to update checkpoint phase: %w", err)
 	}
 
-	var embInicialBytes, embConfrontoBytes []byte
-	if embInicial != nil {
-		embInicialBytes, _ = json.Marshal(embInicial)
-	}
-	if embConfronto != nil {
-		embConfrontoBytes, _ = json.Marshal(embConfronto)
+	// on_demand não persiste embeddings nem temporariamente; são regenerados no cálculo
+	if s.embeddingStorageFor(ctx, evalID) == EmbeddingStorageOnDemand {
+		embInicial, embConfronto = nil, nil
 	}
 
 	return s.q.UpdateCheckpointEmbeddings(ctx, db.UpdateCheckpointEmbeddingsParams{
-		EmbeddingInicial:   embInicialBytes,
-		EmbeddingConfronto: embConfrontoBytes,
+		EmbeddingInicial:   encodeEmbedding(normalizeEmbedding(embInicial)),
+		EmbeddingConfronto: encodeEmbedding(normalizeEmbedding(embConfronto)),
 		EvaluationID:       evalID,
 	})
 }
@@ -468,8 +1133,13 @@ func (s *EvaluationService) GetEvaluationsToRetry(ctx context.Context) ([]db.Eva
 	return s.q.GetEvaluationsToRetry(ctx)
 }
 
-func (s *EvaluationService) GetStuckEvaluations(ctx context.Context) ([]db.Evaluation, error) {
-	return s.q.GetStuckEvaluations(ctx)
+// GetStuckEvaluations returns evaluations in processing whose checkpoint has not
+// advanced for longer than threshold (ex: worker died mid-protocol)
+func (s *EvaluationService) GetStuckEvaluations(ctx context.Context, threshold time.Duration) ([]db.Evaluation, error) {
+	return s.q.GetStuckEvaluations(ctx, sql.NullString{
+		String: fmt.Sprintf("%d", int(threshold.Minutes())),
+		Valid:  true,
+	})
 }
 
 func (s *EvaluationService) RunEvaluationProtocol(ctx context.Context, evalID, prompt string) error {
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/sse"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/api/googleapi"
+)
+
+// fakeProvider responde "resposta N" para a N-ésima geração bem-sucedida e
+// devolve embeddings fixos por resposta (default quando a resposta não está no mapa)
+type fakeProvider struct {
+	embeddings map[string][]float64
+	// failCall faz a chamada de geração com esse número (1-based) falhar com failErr
+	failCall int
+	failErr  error
+	// rateLimitCalls faz as primeiras N chamadas de geração responderem 429
+	rateLimitCalls int
+
+	generateCalls int
+	responses     int
+	embedCalls    int
+	// embedBatches conta as chamadas a EmbedContents (embedCalls conta textos)
+	embedBatches int
+	// models registra params.Model de cada geração
+	models []string
+	// histories registra o histórico enviado em cada geração
+	histories [][]Message
+}
+
+// fakeTokensPerCall é o TotalTokens de cada geração bem-sucedida do fakeProvider
+const fakeTokensPerCall = 15
+
+func (f *fakeProvider) GenerateContentWithSampling(ctx context.Context, messages []Message, params SamplingParams) (string, TokenUsage, error) {
+	f.generateCalls++
+	f.models = append(f.models, params.Model)
+	f.histories = append(f.histories, append([]Message(nil), messages...))
+	if f.generateCalls <= f.rateLimitCalls {
+		return "", TokenUsage{}, &googleapi.Error{Code: 429, Message: "Resource has been exhausted"}
+	}
+	if f.generateCalls == f.failCall {
+		return "", TokenUsage{}, f.failErr
+	}
+	f.responses++
+	return fmt.Sprintf("resposta %d", f.responses), TokenUsage{PromptTokens: 10, ResponseTokens: 5, TotalTokens: fakeTokensPerCall}, nil
+}
+
+func (f *fakeProvider) CountTokens(ctx context.Context, messages []Message) (int, error) {
+	return len(messages), nil
+}
+
+func (f *fakeProvider) EmbedContent(ctx context.Context, text string) ([]float64, error) {
+	f.embedCalls++
+	if emb, ok := f.embeddings[text]; ok {
+		return emb, nil
+	}
+	return []float64{1, 0, 0}, nil
+}
+
+func (f *fakeProvider) EmbedContents(ctx context.Context, texts []string) ([][]float64, error) {
+	f.embedBatches++
+	out := make([][]float64, len(texts))
+	for i, text := range texts {
+		emb, err := f.EmbedContent(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = emb
+	}
+	return out, nil
+}
+
+func (f *fakeProvider) ChatModel() string           { return "fake-model" }
+func (f *fakeProvider) AllowedChatModels() []string { return []string{"fake-model", "fake-model-pro"} }
+func (f *fakeProvider) MaxInputTokens() int         { return 0 }
+func (f *fakeProvider) RetryIn(error) time.Duration { return time.Second }
+func (f *fakeProvider) DryRun() bool                { return false }
+
+// newProtocolTestService monta o service com o provider fake sobre um SQLite em
+// memória e cria uma avaliação pendente pelo fluxo normal (StartEvaluation)
+func newProtocolTestService(t *testing.T, provider LLMProvider) (*EvaluationService, *db.Queries, string) {
+	t.Helper()
+	s, q, _, evalID := newProtocolTestServiceDB(t, provider)
+	return s, q, evalID
+}
+
+// newProtocolTestServiceDB é newProtocolTestService expondo a conexão, para testes
+// que precisam adiantar o relógio do checkpoint
+func newProtocolTestServiceDB(t *testing.T, provider LLMProvider) (*EvaluationService, *db.Queries, *sql.DB, string) {
+	t.Helper()
+
+	dbConn, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cada conexão teria seu próprio banco em memória
+	dbConn.SetMaxOpenConns(1)
+	t.Cleanup(func() { dbConn.Close() })
+
+	ctx := context.Background()
+	if err := db.RunMigrations(ctx, dbConn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := db.New(dbConn)
+	s, err := newEvaluationService(queries, sse.NewBroker(), provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evalID, err := s.StartEvaluation(ctx, "t1", 1, "Quanto é 2+2?", nil, DeterministicStrategy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, queries, dbConn, evalID
+}
+
+func assertEvaluationCompleted(t *testing.T, q *db.Queries, evalID, wantDiagnosis string) {
+	t.Helper()
+	ctx := context.Background()
+
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Status != "completed" {
+		t.Errorf("status = %q, want completed", eval.Status)
+	}
+
+	iterations, err := q.GetIterationsByEvaluation(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases := map[string]int{}
+	for _, it := range iterations {
+		phases[it.Fase]++
+		if it.Prompt == "" {
+			t.Errorf("iteration %s saved without the prompt sent", it.Fase)
+		}
+	}
+	for _, phase := range []string{"inicial", "inversao", "confronto", "purga"} {
+		if phases[phase] != 1 {
+			t.Errorf("iterations for %s = %d, want 1", phase, phases[phase])
+		}
+	}
+
+	audit, err := q.GetAuditByEvaluation(ctx, evalID)
+	if err != nil {
+		t.Fatalf("audit not created: %v", err)
+	}
+	if audit.Diagnostico != wantDiagnosis {
+		t.Errorf("diagnosis = %q, want %q", audit.Diagnostico, wantDiagnosis)
+	}
+	if want := DefaultDiagnosisBands().Severity(wantDiagnosis, audit.Divergencia); audit.Severidade != want {
+		t.Errorf("severity = %q, want %q", audit.Severidade, want)
+	}
+}
+
+func TestNewReadOnlyEvaluationService(t *testing.T) {
+	s, err := NewReadOnlyEvaluationService(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leituras não dependem do modelo; chamadas ao Gemini falham de forma explícita
+	if _, _, err := s.EstimatePromptTokens(context.Background(), "prompt", nil); !errors.Is(err, ErrGeminiUnavailable) {
+		t.Errorf("err = %v, want ErrGeminiUnavailable", err)
+	}
+}
+
+func TestRunEvaluationProtocol(t *testing.T) {
+	tests := []struct {
+		name          string
+		embeddings    map[string][]float64
+		wantDiagnosis string
+	}{
+		{"consistent answers", nil, DiagnosisResistant},
+		{
+			name: "divergent answers",
+			embeddings: map[string][]float64{
+				"resposta 1": {1, 0, 0},
+				"resposta 3": {0, 1, 0},
+			},
+			wantDiagnosis: DiagnosisHallucination,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{embeddings: tt.embeddings}
+			s, q, evalID := newProtocolTestService(t, provider)
+
+			if err := s.RunEvaluationProtocolWithCheckpoint(context.Background(), evalID, "Quanto é 2+2?"); err != nil {
+				t.Fatalf("protocol failed: %v", err)
+			}
+
+			assertEvaluationCompleted(t, q, evalID, tt.wantDiagnosis)
+			if provider.generateCalls != 4 || provider.embedCalls != 2 {
+				t.Errorf("calls = %d generate, %d embed; want 4, 2", provider.generateCalls, provider.embedCalls)
+			}
+			// inicial e confronto vão numa única chamada de embedding
+			if provider.embedBatches != 1 {
+				t.Errorf("embed batches = %d, want 1", provider.embedBatches)
+			}
+			it, err := q.GetIterationByPhase(context.Background(), db.GetIterationByPhaseParams{EvaluationID: evalID, Fase: "inicial"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(it.Embedding) == 0 {
+				t.Error("inicial iteration should get its embedding from the batch")
+			}
+		})
+	}
+}
+
+func TestRunEvaluationProtocol_SavesPhasePrompts(t *testing.T) {
+	s, q, evalID := newProtocolTestService(t, &fakeProvider{})
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"inicial":  "Quanto é 2+2?",
+		"inversao": s.prompts.render(s.prompts.Inversao, "Quanto é 2+2?", ""),
+		"purga":    s.prompts.render(s.prompts.Purga, "Quanto é 2+2?", "resposta 1"),
+	}
+	for phase, prompt := range want {
+		it, err := q.GetIterationByPhase(ctx, db.GetIterationByPhaseParams{EvaluationID: evalID, Fase: phase})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it.Prompt != prompt {
+			t.Errorf("%s prompt = %q, want %q", phase, it.Prompt, prompt)
+		}
+	}
+}
+
+func TestRunEvaluationProtocol_ChosenChatModel(t *testing.T) {
+	provider := &fakeProvider{}
+	s, q, _ := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	strategy, err := s.WithChatModel(DeterministicStrategy, "fake-model-pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	evalID, err := s.StartEvaluation(ctx, "t1", 1, "Quanto é 3+3?", nil, strategy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 3+3?"); err != nil {
+		t.Fatalf("protocol failed: %v", err)
+	}
+	assertEvaluationCompleted(t, q, evalID, DiagnosisResistant)
+
+	if len(provider.models) != 4 {
+		t.Fatalf("generate calls = %d, want 4", len(provider.models))
+	}
+	for i, model := range provider.models {
+		if model != "fake-model-pro" {
+			t.Errorf("call %d used model %q, want fake-model-pro", i+1, model)
+		}
+	}
+
+	// O modelo fica persistido na avaliação
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	persisted, err := ParseStrategy(eval.SamplingParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted.Model != "fake-model-pro" {
+		t.Errorf("persisted model = %q, want fake-model-pro", persisted.Model)
+	}
+
+	// Fora da allowlist nem chega a criar a avaliação
+	if _, err := s.StartEvaluation(ctx, "t1", 1, "x", nil, Strategy{Model: "gemini-ultra"}, ""); !errors.Is(err, ErrChatModelNotAllowed) {
+		t.Errorf("StartEvaluation with unlisted model: err = %v, want ErrChatModelNotAllowed", err)
+	}
+}
+
+func TestRunEvaluationProtocol_IterationWriteFailureStopsPhase(t *testing.T) {
+	s, q, dbConn, evalID := newProtocolTestServiceDB(t, &fakeProvider{})
+	ctx := context.Background()
+	if _, err := dbConn.Exec(`
+		CREATE TRIGGER fail_inversao BEFORE INSERT ON iterations
+		WHEN NEW.fase = 'inversao'
+		BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END;
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err == nil {
+		t.Fatal("expected the protocol to fail when the iteration is not saved")
+	}
+
+	// Sem a iteração gravada o checkpoint não avança: a retomada refaz a fase
+	checkpoint, err := q.GetCheckpoint(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.CurrentPhase != "inversao" {
+		t.Errorf("checkpoint phase = %q, want inversao", checkpoint.CurrentPhase)
+	}
+}
+
+func TestRunEvaluationProtocol_ResumesFromCheckpoint(t *testing.T) {
+	// A 3ª geração (confronto) é recusada localmente: a avaliação fica em retrying
+	provider := &fakeProvider{failCall: 3, failErr: ErrCircuitOpen}
+	s, q, evalID := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err == nil {
+		t.Fatal("expected first run to stop at confronto")
+	}
+
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := q.GetCheckpoint(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Status != "retrying" || checkpoint.CurrentPhase != "confronto" {
+		t.Fatalf("after failure: status=%q phase=%q, want retrying/confronto", eval.Status, checkpoint.CurrentPhase)
+	}
+
+	// Antes do prazo a retomada é recusada; o retryTicker só a dispara depois dele
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err == nil {
+		t.Fatal("expected resume to wait for next_retry_at")
+	}
+	if err := s.clearCheckpointRetry(ctx, evalID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	assertEvaluationCompleted(t, q, evalID, DiagnosisResistant)
+	// inicial e inversao não são refeitas; os dois embeddings saem em lote no confronto
+	if provider.generateCalls != 5 || provider.embedCalls != 2 {
+		t.Errorf("calls = %d generate, %d embed; want 5, 2", provider.generateCalls, provider.embedCalls)
+	}
+
+	it, err := q.GetIterationByPhase(ctx, db.GetIterationByPhaseParams{EvaluationID: evalID, Fase: "inicial"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Resposta != "resposta 1" {
+		t.Errorf("inicial iteration = %q, want the response from the first run", it.Resposta)
+	}
+}
+
+func TestRunEvaluationProtocol_AttachmentsStayOutOfCheckpoint(t *testing.T) {
+	provider := &fakeProvider{failCall: 3, failErr: ErrCircuitOpen}
+	s, q, _ := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	image := MessagePart{MIMEType: "image/png", Data: []byte("fake png")}
+	evalID, err := s.StartEvaluation(ctx, "t1", 1, "O que há na imagem?", []MessagePart{image}, DeterministicStrategy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "O que há na imagem?"); err == nil {
+		t.Fatal("expected first run to stop at confronto")
+	}
+
+	checkpoint, err := q.GetCheckpoint(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(checkpoint.Messages), `"parts"`) {
+		t.Errorf("checkpoint should not hold attachments: %s", checkpoint.Messages)
+	}
+
+	if err := s.clearCheckpointRetry(ctx, evalID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "O que há na imagem?"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	// Na retomada a imagem volta de evaluation_attachments para a consulta inicial
+	resumed := provider.histories[3]
+	if len(resumed[0].Parts) != 1 || string(resumed[0].Parts[0].Data) != "fake png" {
+		t.Errorf("resumed history lost the attachment: %+v", resumed[0].Parts)
+	}
+}
+
+func TestRunEvaluationProtocol_ResumesAtPurga(t *testing.T) {
+	// Falha na auditoria: a divergência já calculada é reaproveitada na retomada
+	provider := &fakeProvider{failCall: 4, failErr: ErrQuotaExhausted}
+	s, q, evalID := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err == nil {
+		t.Fatal("expected first run to stop at purga")
+	}
+	checkpoint, err := q.GetCheckpoint(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.CurrentPhase != "purga" || !checkpoint.DiagnosticoFinal.Valid {
+		t.Fatalf("checkpoint phase=%q diagnosis=%v, want purga with saved diagnosis", checkpoint.CurrentPhase, checkpoint.DiagnosticoFinal)
+	}
+
+	if err := s.clearCheckpointRetry(ctx, evalID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	assertEvaluationCompleted(t, q, evalID, DiagnosisResistant)
+	if provider.embedCalls != 2 {
+		t.Errorf("embed calls = %d, want 2 (no recomputation on resume)", provider.embedCalls)
+	}
+}
+
+func TestRunEvaluationProtocol_RateLimitRetriesInline(t *testing.T) {
+	provider := &fakeProvider{rateLimitCalls: 2}
+	s, q, evalID := newProtocolTestService(t, provider)
+	s.backoff = func(int) time.Duration { return time.Millisecond }
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(context.Background(), evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatalf("protocol failed: %v", err)
+	}
+
+	assertEvaluationCompleted(t, q, evalID, DiagnosisResistant)
+	if provider.generateCalls != 6 {
+		t.Errorf("generate calls = %d, want 6 (2 rate limited + 4 phases)", provider.generateCalls)
+	}
+
+	// Só as gerações que responderam contam tokens
+	eval, err := q.GetEvaluationByID(context.Background(), evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.TotalTokens != 4*fakeTokensPerCall {
+		t.Errorf("total tokens = %d, want %d", eval.TotalTokens, 4*fakeTokensPerCall)
+	}
+}
+
+func TestRunEvaluationProtocol_RateLimitDefersToCheckpoint(t *testing.T) {
+	provider := &fakeProvider{rateLimitCalls: 1}
+	s, q, dbConn, evalID := newProtocolTestServiceDB(t, provider)
+	ctx := context.Background()
+	// Backoff acima do limite inline: a retomada fica com o checkpoint
+	s.backoff = func(int) time.Duration { return MaxInlineRetryDelay + time.Second }
+	events := s.broker.Subscribe("evaluation", evalID)
+	defer s.broker.Unsubscribe(events, "evaluation", evalID)
+
+	err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?")
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("err = %v, want ErrRateLimitExceeded", err)
+	}
+
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := q.GetCheckpoint(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Status != "retrying" {
+		t.Errorf("status = %q, want retrying", eval.Status)
+	}
+	if !checkpoint.NextRetryAt.Valid || !checkpoint.NextRetryAt.Time.After(time.Now()) {
+		t.Errorf("next_retry_at = %v, want a future time", checkpoint.NextRetryAt)
+	}
+
+	// A tela da avaliação é avisada de que está aguardando o limite da API
+	retrying := false
+	for len(events.Events) > 0 {
+		msg := <-events.Events
+		if strings.HasPrefix(msg, "event: evaluation_retrying\n") {
+			retrying = strings.Contains(msg, checkpoint.NextRetryAt.Time.Format("15:04:05"))
+		}
+	}
+	if !retrying {
+		t.Error("expected an evaluation_retrying SSE event with next_retry_at")
+	}
+
+	retryIDs := func() []string {
+		evals, err := s.GetEvaluationsToRetry(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range evals {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	if ids := retryIDs(); len(ids) != 0 {
+		t.Errorf("retry picked %v before next_retry_at", ids)
+	}
+
+	// Simula o prazo vencido em vez de esperar o backoff real
+	if _, err := dbConn.Exec(`UPDATE evaluation_checkpoints SET next_retry_at = datetime('now', '-1 second') WHERE evaluation_id = ?`, evalID); err != nil {
+		t.Fatal(err)
+	}
+	if ids := retryIDs(); len(ids) != 1 || ids[0] != evalID {
+		t.Fatalf("evaluations to retry = %v, want [%s]", ids, evalID)
+	}
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	assertEvaluationCompleted(t, q, evalID, DiagnosisResistant)
+	if ids := retryIDs(); len(ids) != 0 {
+		t.Errorf("completed evaluation still returned for retry: %v", ids)
+	}
+}
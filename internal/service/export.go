@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnsupportedExportFormat = errors.New("unsupported export format")
+	ErrEvaluationIncomplete    = errors.New("evaluation is not completed")
+)
+
+// Formatos de exportação suportados (pdf previsto para o futuro)
+const (
+	ExportFormatMarkdown = "md"
+)
+
+// exportPhaseTitles mantém a ordem e os nomes das fases no relatório
+var exportPhaseTitles = map[string]string{
+	"inicial":   "1. Consulta Inicial",
+	"inversao":  "2. Inversão de Lógica",
+	"confronto": "3. Confronto Falso",
+	"purga":     "5. Purga e Auditoria",
+}
+
+// ExportEvaluation gera o relatório de uma avaliação concluída no formato pedido
+// (vazio = markdown) e retorna o documento com o content-type correspondente.
+// O controle de acesso fica a cargo do chamador.
+func (s *EvaluationService) ExportEvaluation(ctx context.Context, evalID, format string) ([]byte, string, error) {
+	if format == "" {
+		format = ExportFormatMarkdown
+	}
+	if format != ExportFormatMarkdown {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnsupportedExportFormat, format)
+	}
+
+	snapshot, err := s.loadSnapshot(ctx, evalID)
+	if err != nil {
+		return nil, "", err
+	}
+	if snapshot.Evaluation.Status != "completed" || snapshot.Audit == nil {
+		return nil, "", ErrEvaluationIncomplete
+	}
+
+	return renderMarkdownReport(snapshot), "text/markdown; charset=utf-8", nil
+}
+
+// IterationEmbedding é o embedding de uma iteração exportado para análise externa.
+// Embedding é nil (null no JSON) quando a fase não gera embedding ou ele não foi
+// persistido (EMBEDDING_STORAGE never/on_demand).
+type IterationEmbedding struct {
+	Phase     string    `json:"fase"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// IterationEmbeddings retorna os embeddings brutos das iterações da avaliação, na
+// ordem de criação. O controle de acesso fica a cargo do chamador.
+func (s *EvaluationService) IterationEmbeddings(ctx context.Context, evalID string) ([]IterationEmbedding, error) {
+	iterations, err := s.q.GetIterationsByEvaluation(ctx, evalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get iterations: %w", err)
+	}
+
+	out := make([]IterationEmbedding, 0, len(iterations))
+	for _, it := range iterations {
+		emb, err := decodeEmbedding(it.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("iteration %s: %w", it.ID, err)
+		}
+		out = append(out, IterationEmbedding{Phase: it.Fase, Embedding: emb})
+	}
+	return out, nil
+}
+
+func renderMarkdownReport(snapshot EvaluationSnapshot) []byte {
+	var b bytes.Buffer
+	eval, audit := snapshot.Evaluation, snapshot.Audit
+
+	fmt.Fprintf(&b, "# Relatório de Avaliação\n\n")
+	fmt.Fprintf(&b, "- **ID:** %s\n", eval.ID)
+	fmt.Fprintf(&b, "- **Criada em:** %s\n", eval.CreatedAt.Time.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- **Diagnóstico:** %s\n", audit.Diagnostico)
+	if audit.Diagnostico == DiagnosisIndeterminate {
+		fmt.Fprintf(&b, "- **Divergência (%s):** não calculada\n", audit.Metrica)
+	} else {
+		fmt.Fprintf(&b, "- **Divergência (%s):** %.2f%%\n", audit.Metrica, audit.Divergencia*100)
+	}
+	if audit.PromptSet != "" {
+		fmt.Fprintf(&b, "- **Prompts do protocolo:** %s\n", audit.PromptSet)
+	}
+
+	fmt.Fprintf(&b, "\n## Prompt Base\n\n")
+	writeMarkdownBlock(&b, eval.PromptBase)
+
+	fmt.Fprintf(&b, "\n## Iterações do Protocolo\n")
+	for _, it := range snapshot.Iterations {
+		title, ok := exportPhaseTitles[it.Fase]
+		if !ok {
+			title = it.Fase
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+		// Iterações anteriores à coluna prompt só têm a resposta
+		if it.Prompt != "" {
+			fmt.Fprintf(&b, "**Pergunta:**\n\n")
+			writeMarkdownBlock(&b, it.Prompt)
+			fmt.Fprintf(&b, "\n**Resposta:**\n\n")
+		}
+		writeMarkdownBlock(&b, it.Resposta)
+	}
+
+	return b.Bytes()
+}
+
+// writeMarkdownBlock escreve o texto em um bloco cercado, usando uma cerca maior
+// que qualquer sequência de crases do conteúdo (respostas costumam conter código)
+func writeMarkdownBlock(b *bytes.Buffer, text string) {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s\n%s\n%s\n", fence, strings.TrimRight(text, "\n"), fence)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+func TestRenderMarkdownReport(t *testing.T) {
+	snapshot := EvaluationSnapshot{
+		Evaluation: db.Evaluation{ID: "eval-1", PromptBase: "Como ordenar um slice?", Status: "completed"},
+		Iterations: []db.Iteration{
+			{Fase: "inicial", Prompt: "Como ordenar um slice?", Resposta: "Use sort.Slice:\n```go\nsort.Slice(s, less)\n```"},
+			{Fase: "purga", Resposta: "Sem falhas."},
+		},
+		Audit: &db.Audit{Diagnostico: DiagnosisResistant, Divergencia: 0.1234, Metrica: MetricCosine},
+	}
+
+	report := string(renderMarkdownReport(snapshot))
+
+	for _, want := range []string{
+		"# Relatório de Avaliação",
+		"**Diagnóstico:** " + DiagnosisResistant,
+		"**Divergência (cosine):** 12.34%",
+		"### 1. Consulta Inicial",
+		"### 5. Purga e Auditoria",
+		"**Pergunta:**\n\n```\nComo ordenar um slice?\n```\n\n**Resposta:**",
+		// Cerca maior que o bloco de código da resposta
+		"````\nUse sort.Slice:",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestIterationEmbeddings(t *testing.T) {
+	provider := &fakeProvider{embeddings: map[string][]float64{
+		"resposta 1": {1, 0, 0},
+		"resposta 3": {0, 1, 0},
+	}}
+	s, _, evalID := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.IterationEmbeddings(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]float64{
+		"inicial":   {1, 0, 0},
+		"inversao":  nil,
+		"confronto": {0, 1, 0},
+		"purga":     nil,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d iterations, want %d: %+v", len(got), len(want), got)
+	}
+	for _, it := range got {
+		if !slices.Equal(it.Embedding, want[it.Phase]) {
+			t.Errorf("%s embedding = %v, want %v", it.Phase, it.Embedding, want[it.Phase])
+		}
+	}
+}
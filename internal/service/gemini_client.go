@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"google.golang.org/genai"
@@ -24,6 +27,18 @@ const (
 	retryJitterFactor = 0.1
 )
 
+// embeddingDimensions é a dimensão padrão dos modelos de embedding conhecidos,
+// usada quando GEMINI_EMBEDDING_DIMENSIONS não é definido
+var embeddingDimensions = map[string]int{
+	"gemini-embedding-001": 3072,
+	"text-embedding-004":   768,
+	"embedding-001":        768,
+}
+
+// ErrEmbeddingDimension indica um embedding com menos valores que o esperado do
+// modelo; usá-lo geraria uma divergência inválida
+var ErrEmbeddingDimension = errors.New("embedding has fewer dimensions than expected")
+
 // Helper functions for environment variables
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -43,12 +58,67 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	switch os.Getenv(key) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	}
+	return defaultValue
+}
+
 // GeminiClientConfig holds configuration for the Gemini client
 type GeminiClientConfig struct {
 	APIKey         string
 	ChatModel      string
 	EmbeddingModel string
 	Timeout        time.Duration
+
+	// Circuit breaker: abre após BreakerFailures falhas consecutivas dentro de
+	// BreakerWindow e rejeita chamadas por BreakerCooldown
+	BreakerFailures int
+	BreakerWindow   time.Duration
+	BreakerCooldown time.Duration
+
+	// Quota da API key (0 = não configurado) usada para estimar o saldo;
+	// com QuotaEnforce chamadas são recusadas antes de um 429 previsível
+	RPM          int
+	RPD          int
+	QuotaEnforce bool
+
+	// Quota e concorrência próprias dos embeddings, que a API limita à parte:
+	// EmbedRPM/EmbedRPD contam só EmbedContent(s) e EmbedConcurrency limita as
+	// chamadas de embedding simultâneas (0 = sem limite), para que não ocupem
+	// o espaço das gerações intercaladas no protocolo
+	EmbedRPM         int
+	EmbedRPD         int
+	EmbedConcurrency int
+
+	// MaxInputTokens limita o tamanho do histórico enviado ao modelo (0 = sem
+	// checagem). Quando configurado, cada chamada do protocolo conta os tokens antes.
+	MaxInputTokens int
+
+	// SafetySettings sobrescreve os filtros de segurança por categoria (vazio
+	// mantém os defaults da API). Ver ParseSafetySettings.
+	SafetySettings []*genai.SafetySetting
+
+	// AllowedChatModels são os modelos que o usuário pode escolher por avaliação;
+	// ChatModel é sempre permitido
+	AllowedChatModels []string
+
+	// EmbeddingDimensions é a dimensão mínima aceita de cada embedding (0 usa a
+	// padrão de EmbeddingModel; modelo desconhecido só exige vetor não vazio)
+	EmbeddingDimensions int
+
+	// DryRun simula todas as chamadas sem acessar a API (nem exigir API key),
+	// com respostas e embeddings determinísticos; DryRunDelay é a latência
+	// simulada de cada chamada
+	DryRun      bool
+	DryRunDelay time.Duration
+
+	// err guarda um erro de parsing do ambiente, reportado por NewGeminiClient
+	err error
 }
 
 // GeminiClient manages communication with Google Gemini API
@@ -57,6 +127,11 @@ type GeminiClient struct {
 	config         GeminiClientConfig
 	chatModel      string
 	embeddingModel string
+	breaker        *circuitBreaker
+	quota          *quotaTracker
+	embedQuota     *quotaTracker
+	// embedSlots limita os embeddings simultâneos (nil = sem limite)
+	embedSlots chan struct{}
 }
 
 // GeminiError represents an error from the Gemini API with rate limit information
@@ -87,27 +162,81 @@ func NewGeminiClientConfig() GeminiClientConfig {
 
 	timeout := time.Duration(getEnvInt("GEMINI_TIMEOUT", 300)) * time.Second
 
+	safety, err := ParseSafetySettings(os.Getenv("GEMINI_SAFETY_SETTINGS"))
+	if err != nil {
+		err = fmt.Errorf("invalid GEMINI_SAFETY_SETTINGS: %w", err)
+	}
+
 	return GeminiClientConfig{
 		APIKey:         apiKey,
 		ChatModel:      getEnv("GEMINI_MODEL_CHAT", defaultGeminiChatModel),
 		EmbeddingModel: getEnv("GEMINI_MODEL_EMBEDDING", defaultGeminiEmbeddingModel),
 		Timeout:        timeout,
+
+		BreakerFailures: getEnvInt("GEMINI_BREAKER_FAILURES", defaultBreakerFailures),
+		BreakerWindow:   time.Duration(getEnvInt("GEMINI_BREAKER_WINDOW_SECONDS", int(defaultBreakerWindow.Seconds()))) * time.Second,
+		BreakerCooldown: time.Duration(getEnvInt("GEMINI_BREAKER_COOLDOWN_SECONDS", int(defaultBreakerCooldown.Seconds()))) * time.Second,
+
+		RPM:          getEnvInt("GEMINI_RPM", 0),
+		RPD:          getEnvInt("GEMINI_RPD", 0),
+		QuotaEnforce: getEnvBool("GEMINI_QUOTA_ENFORCE", false),
+
+		EmbedRPM:         getEnvInt("GEMINI_EMBED_RPM", 0),
+		EmbedRPD:         getEnvInt("GEMINI_EMBED_RPD", 0),
+		EmbedConcurrency: getEnvInt("GEMINI_EMBED_CONCURRENCY", 0),
+
+		MaxInputTokens:      getEnvInt("GEMINI_MAX_INPUT_TOKENS", 0),
+		SafetySettings:      safety,
+		AllowedChatModels:   parseModelList(os.Getenv("GEMINI_ALLOWED_CHAT_MODELS")),
+		EmbeddingDimensions: getEnvInt("GEMINI_EMBEDDING_DIMENSIONS", 0),
+		DryRun:              getEnvBool("GEMINI_DRY_RUN", false),
+		DryRunDelay:         time.Duration(getEnvInt("GEMINI_DRY_RUN_DELAY_MS", 0)) * time.Millisecond,
+		err:                 err,
+	}
+}
+
+// parseModelList lê uma lista de modelos separada por vírgula, sem vazios
+func parseModelList(raw string) []string {
+	var models []string
+	for _, m := range strings.Split(raw, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
 	}
+	return models
 }
 
-// NewGeminiClient creates a new Gemini client with the given configuration
-func NewGeminiClient(config GeminiClientConfig) (*GeminiClient, error) {
-	if config.APIKey == "" {
+// clientInitTimeout limita a criação do cliente genai
+const clientInitTimeout = 10 * time.Second
+
+// NewGeminiClient creates a new Gemini client with the given configuration.
+// The client is safe for concurrent use and should be created once and shared.
+// In dry-run mode no API key is required and no genai client is created.
+func NewGeminiClient(ctx context.Context, config GeminiClientConfig) (*GeminiClient, error) {
+	if config.APIKey == "" && !config.DryRun {
 		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
 	}
+	if config.err != nil {
+		return nil, config.err
+	}
 
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  config.APIKey,
-		Backend: genai.BackendGeminiAPI,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	var client *genai.Client
+	if !config.DryRun {
+		ctx, cancel := context.WithTimeout(ctx, clientInitTimeout)
+		defer cancel()
+		var err error
+		client, err = genai.NewClient(ctx, &genai.ClientConfig{
+			APIKey:  config.APIKey,
+			Backend: genai.BackendGeminiAPI,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+	}
+
+	var embedSlots chan struct{}
+	if config.EmbedConcurrency > 0 {
+		embedSlots = make(chan struct{}, config.EmbedConcurrency)
 	}
 
 	return &GeminiClient{
@@ -115,16 +244,26 @@ func NewGeminiClient(config GeminiClientConfig) (*GeminiClient, error) {
 		config:         config,
 		chatModel:      config.ChatModel,
 		embeddingModel: config.EmbeddingModel,
+		breaker:        newCircuitBreaker(config.BreakerFailures, config.BreakerWindow, config.BreakerCooldown),
+		quota:          newQuotaTracker(config.RPM, config.RPD, config.QuotaEnforce),
+		embedQuota:     newEmbedQuotaTracker(config.EmbedRPM, config.EmbedRPD, config.QuotaEnforce),
+		embedSlots:     embedSlots,
 	}, nil
 }
 
 // GenerateContent generates text content using the Gemini chat model
 func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	if c.config.DryRun {
+		text, _, err := c.dryRunGenerate(ctx, []Message{UserMessage(prompt)}, c.chatModel)
+		return text, err
+	}
+
 	var result string
-	err := c.withRetry(ctx, func(ctx context.Context) error {
+	err := c.withRetry(ctx, c.quota, func(ctx context.Context) error {
 		resp, err := c.client.Models.GenerateContent(ctx, c.chatModel, genai.Text(prompt), &genai.GenerateContentConfig{
 			Temperature:     genai.Ptr(float32(0.0)),
 			MaxOutputTokens: 8192,
+			SafetySettings:  c.config.SafetySettings,
 		})
 		if err != nil {
 			return err
@@ -132,7 +271,7 @@ func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string) (stri
 
 		result = resp.Text()
 		if result == "" {
-			return fmt.Errorf("no content generated")
+			return emptyResponseError(resp)
 		}
 		return nil
 	})
@@ -144,32 +283,40 @@ func (c *GeminiClient) GenerateContent(ctx context.Context, prompt string) (stri
 	return result, nil
 }
 
-// GenerateContentWithMessages generates content using a conversation history
-func (c *GeminiClient) GenerateContentWithMessages(ctx context.Context, messages []map[string]string) (string, error) {
-	var result string
-	err := c.withRetry(ctx, func(ctx context.Context) error {
-		// Convert messages to Gemini format
-		var contents []*genai.Content
-		for _, msg := range messages {
-			role := msg["role"]
-			content := msg["content"]
-
-			geminiRole := genai.RoleUser
-			if role == "assistant" {
-				geminiRole = genai.RoleModel
-			}
+// GenerateContentWithMessages generates content using a conversation history.
+// The usage reports the tokens billed for the successful call.
+func (c *GeminiClient) GenerateContentWithMessages(ctx context.Context, messages []Message) (string, TokenUsage, error) {
+	return c.GenerateContentWithMessagesModel(ctx, "", messages)
+}
 
-			contents = append(contents, &genai.Content{
-				Role: geminiRole,
-				Parts: []*genai.Part{
-					{Text: content},
-				},
-			})
-		}
+// GenerateContentWithMessagesModel is GenerateContentWithMessages on the given
+// chat model for this call only (empty = the client's default). It is not
+// checked against AllowedChatModels; callers taking the model from users must
+// validate it (see EvaluationService.WithChatModel).
+func (c *GeminiClient) GenerateContentWithMessagesModel(ctx context.Context, model string, messages []Message) (string, TokenUsage, error) {
+	params := DeterministicStrategy.Default
+	params.Model = model
+	return c.GenerateContentWithSampling(ctx, messages, params)
+}
 
-		resp, err := c.client.Models.GenerateContent(ctx, c.chatModel, contents, &genai.GenerateContentConfig{
-			Temperature:     genai.Ptr(float32(0.0)),
+// GenerateContentWithSampling generates content from a conversation using the
+// given temperature/seed. Temperature > 0 reduces reproducibility. Attempts
+// that failed inside withRetry are not counted in the returned usage.
+func (c *GeminiClient) GenerateContentWithSampling(ctx context.Context, messages []Message, params SamplingParams) (string, TokenUsage, error) {
+	if c.config.DryRun {
+		return c.dryRunGenerate(ctx, messages, c.modelFor(params))
+	}
+
+	contents := toGeminiContents(messages)
+
+	var result string
+	var usage TokenUsage
+	err := c.withRetry(ctx, c.quota, func(ctx context.Context) error {
+		resp, err := c.client.Models.GenerateContent(ctx, c.modelFor(params), contents, &genai.GenerateContentConfig{
+			Temperature:     genai.Ptr(params.Temperature),
+			Seed:            params.Seed,
 			MaxOutputTokens: 8192,
+			SafetySettings:  c.config.SafetySettings,
 		})
 		if err != nil {
 			return err
@@ -177,37 +324,134 @@ func (c *GeminiClient) GenerateContentWithMessages(ctx context.Context, messages
 
 		result = resp.Text()
 		if result == "" {
-			return fmt.Errorf("no content generated")
+			return emptyResponseError(resp)
 		}
+		usage = tokenUsage(resp.UsageMetadata)
 		return nil
 	})
 
 	if err != nil {
-		return "", err
+		return "", TokenUsage{}, err
 	}
 
-	return result, nil
+	return result, usage, nil
+}
+
+// tokenUsage converte o UsageMetadata da resposta (ausente = zero)
+func tokenUsage(meta *genai.GenerateContentResponseUsageMetadata) TokenUsage {
+	if meta == nil {
+		return TokenUsage{}
+	}
+	usage := TokenUsage{
+		PromptTokens:   int(meta.PromptTokenCount),
+		ResponseTokens: int(meta.CandidatesTokenCount),
+		TotalTokens:    int(meta.TotalTokenCount),
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.ResponseTokens + int(meta.ThoughtsTokenCount)
+	}
+	return usage
+}
+
+// countTokensTimeout limita a contagem, que é só uma checagem prévia
+const countTokensTimeout = 10 * time.Second
+
+// CountTokens returns how many input tokens the conversation uses on the chat
+// model. It is a single best-effort call: no retry, quota or circuit breaker.
+func (c *GeminiClient) CountTokens(ctx context.Context, messages []Message) (int, error) {
+	if c.config.DryRun {
+		return dryRunTokens(messages), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, countTokensTimeout)
+	defer cancel()
+
+	resp, err := c.client.Models.CountTokens(ctx, c.chatModel, toGeminiContents(messages), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// toGeminiContents converts the protocol history ("user"/"assistant") to Gemini contents
+func toGeminiContents(messages []Message) []*genai.Content {
+	contents := make([]*genai.Content, 0, len(messages))
+	for _, msg := range messages {
+		geminiRole := genai.RoleUser
+		if msg.Role == "assistant" {
+			geminiRole = genai.RoleModel
+		}
+
+		// Anexos antes do texto, como recomendado para prompts com imagem
+		parts := make([]*genai.Part, 0, len(msg.Parts)+1)
+		for _, p := range msg.Parts {
+			parts = append(parts, genai.NewPartFromBytes(p.Data, p.MIMEType))
+		}
+		parts = append(parts, &genai.Part{Text: msg.Content})
+
+		contents = append(contents, &genai.Content{
+			Role:  geminiRole,
+			Parts: parts,
+		})
+	}
+	return contents
 }
 
 // EmbedContent generates embeddings for the given text
 func (c *GeminiClient) EmbedContent(ctx context.Context, text string) ([]float64, error) {
-	var embedding []float64
+	embeddings, err := c.EmbedContents(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedContents generates one embedding per text in a single batch request
+// (one round-trip and one quota unit). The result is in the same order as texts.
+func (c *GeminiClient) EmbedContents(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if c.config.DryRun {
+		return c.dryRunEmbed(ctx, texts)
+	}
+
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
 
-	err := c.withRetry(ctx, func(ctx context.Context) error {
-		resp, err := c.client.Models.EmbedContent(ctx, c.embeddingModel, genai.Text(text), nil)
+	release, err := c.acquireEmbedSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var embeddings [][]float64
+	err = c.withRetry(ctx, c.embedQuota, func(ctx context.Context) error {
+		resp, err := c.client.Models.EmbedContent(ctx, c.embeddingModel, contents, nil)
 		if err != nil {
 			return err
 		}
 
-		// The new SDK returns Embeddings (plural) array
-		if len(resp.Embeddings) == 0 || resp.Embeddings[0] == nil || len(resp.Embeddings[0].Values) == 0 {
-			return fmt.Errorf("no embedding generated")
+		if len(resp.Embeddings) != len(texts) {
+			return fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
 		}
 
-		// Convert float32 to float64
-		embedding = make([]float64, len(resp.Embeddings[0].Values))
-		for i, v := range resp.Embeddings[0].Values {
-			embedding[i] = float64(v)
+		embeddings = make([][]float64, len(texts))
+		expected := c.expectedEmbeddingDimensions()
+		for i, e := range resp.Embeddings {
+			if e == nil || len(e.Values) == 0 {
+				return fmt.Errorf("no embedding generated")
+			}
+			if len(e.Values) < expected {
+				return fmt.Errorf("%w: %s returned %d, expected %d", ErrEmbeddingDimension, c.embeddingModel, len(e.Values), expected)
+			}
+			// Convert float32 to float64
+			embeddings[i] = make([]float64, len(e.Values))
+			for j, v := range e.Values {
+				embeddings[i][j] = float64(v)
+			}
 		}
 		return nil
 	})
@@ -216,16 +460,51 @@ func (c *GeminiClient) EmbedContent(ctx context.Context, text string) ([]float64
 		return nil, err
 	}
 
-	return embedding, nil
+	return embeddings, nil
+}
+
+// expectedEmbeddingDimensions é a dimensão mínima dos embeddings do modelo
+// configurado (0 quando desconhecida)
+func (c *GeminiClient) expectedEmbeddingDimensions() int {
+	if c.config.EmbeddingDimensions > 0 {
+		return c.config.EmbeddingDimensions
+	}
+	return embeddingDimensions[c.embeddingModel]
+}
+
+// acquireEmbedSlot espera uma vaga de embedding (GEMINI_EMBED_CONCURRENCY);
+// sem limite configurado retorna na hora
+func (c *GeminiClient) acquireEmbedSlot(ctx context.Context) (func(), error) {
+	if c.embedSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.embedSlots <- struct{}{}:
+		return func() { <-c.embedSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-// withRetry executes a function with exponential backoff and jitter for rate limits
-func (c *GeminiClient) withRetry(ctx context.Context, fn func(context.Context) error) error {
+// withRetry executes a function with exponential backoff and jitter for rate limits.
+// Every attempt is counted against the given quota estimate (generation or
+// embedding) and goes through the circuit breaker; calls fail fast with
+// ErrQuotaExhausted or ErrCircuitOpen.
+func (c *GeminiClient) withRetry(ctx context.Context, quota *quotaTracker, fn func(context.Context) error) error {
 	var lastErr error
 	delay := baseRetryDelay
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
+		// Com o circuito aberto a tentativa nem sai, então não consome quota
+		if err := c.breaker.allow(); err != nil {
+			return err
+		}
+		if err := quota.acquire(); err != nil {
+			c.breaker.release()
+			return err
+		}
 		err := fn(ctx)
+		c.breaker.record(err)
 		if err == nil {
 			return nil
 		}
@@ -333,18 +612,127 @@ func CalculateDivergence(emb1, emb2 []float64) float64 {
 		return 0.0
 	}
 
-	similarity := dotProduct / (math.Sqrt(mag1) * math.Sqrt(mag2))
-	divergence := 1.0 - similarity
+	return clampDivergence(1.0 - dotProduct/(math.Sqrt(mag1)*math.Sqrt(mag2)))
+}
+
+// CalculateNormalizedDivergence é a divergência de cosseno para embeddings já
+// L2-normalizados, como os que o protocolo armazena (normalizeEmbedding): com
+// normas unitárias a similaridade é o próprio produto escalar e as normas não
+// são calculadas. Não confere a normalização; vetores fora disso dão resultado errado.
+func CalculateNormalizedDivergence(emb1, emb2 []float64) float64 {
+	if len(emb1) == 0 || len(emb2) == 0 || len(emb1) != len(emb2) {
+		return 1.0
+	}
+
+	var dotProduct float64
+	for i := range emb1 {
+		dotProduct += emb1[i] * emb2[i]
+	}
+	return clampDivergence(1.0 - dotProduct)
+}
+
+// clampDivergence limita a divergência a [0, 1] (arredondamento de float)
+func clampDivergence(divergence float64) float64 {
+	return math.Max(0, math.Min(1, divergence))
+}
+
+// Métricas de divergência suportadas
+const (
+	MetricCosine    = "cosine"
+	MetricEuclidean = "euclidean"
+	MetricDot       = "dot"
+)
+
+// CalculateDivergenceWithMetric calculates the divergence between two embeddings
+// using the given metric, always normalized to [0, 1] so the hallucination
+// threshold keeps the same meaning across metrics:
+//   - cosine: 1 - similaridade de cosseno
+//   - euclidean: distância euclidiana entre os vetores normalizados, dividida por 2
+//   - dot: 1 - produto escalar (assume embeddings já normalizados). Como o
+//     protocolo armazena os vetores normalizados, na prática equivale a cosine.
+func CalculateDivergenceWithMetric(emb1, emb2 []float64, metric string) (float64, error) {
+	switch metric {
+	case "", MetricCosine:
+		return CalculateDivergence(emb1, emb2), nil
+	case MetricDot:
+		return CalculateNormalizedDivergence(emb1, emb2), nil
+	case MetricEuclidean:
+	default:
+		return 0, fmt.Errorf("unknown divergence metric: %q", metric)
+	}
+
+	if len(emb1) == 0 || len(emb2) == 0 || len(emb1) != len(emb2) {
+		return 1.0, nil
+	}
 
-	// Clamp to [0, 1] range
-	if divergence < 0 {
-		divergence = 0
+	var mag1, mag2 float64
+	for i := range emb1 {
+		mag1 += emb1[i] * emb1[i]
+		mag2 += emb2[i] * emb2[i]
+	}
+	if mag1 == 0 || mag2 == 0 {
+		return 0.0, nil
 	}
-	if divergence > 1 {
-		divergence = 1
+	mag1, mag2 = math.Sqrt(mag1), math.Sqrt(mag2)
+
+	var sum float64
+	for i := range emb1 {
+		d := emb1[i]/mag1 - emb2[i]/mag2
+		sum += d * d
 	}
+	return clampDivergence(math.Sqrt(sum) / 2), nil
+}
 
-	return divergence
+// QuotaUsage returns the local estimate of the Gemini quota consumed by this process
+func (c *GeminiClient) QuotaUsage() QuotaUsage {
+	return c.quota.usage()
+}
+
+// EmbedQuotaUsage returns the local estimate of the embedding quota, counted
+// separately from generations
+func (c *GeminiClient) EmbedQuotaUsage() QuotaUsage {
+	return c.embedQuota.usage()
+}
+
+// ChatModel returns the default model used for content generation
+func (c *GeminiClient) ChatModel() string {
+	return c.chatModel
+}
+
+// AllowedChatModels returns the chat model followed by GEMINI_ALLOWED_CHAT_MODELS
+func (c *GeminiClient) AllowedChatModels() []string {
+	models := []string{c.chatModel}
+	for _, m := range c.config.AllowedChatModels {
+		if !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// modelFor resolve o modelo de uma chamada (params.Model ou o padrão)
+func (c *GeminiClient) modelFor(params SamplingParams) string {
+	if params.Model != "" {
+		return params.Model
+	}
+	return c.chatModel
+}
+
+// MaxInputTokens returns the per-call input token limit (0 = unchecked)
+func (c *GeminiClient) MaxInputTokens() int {
+	return c.config.MaxInputTokens
+}
+
+// RetryIn estimates how long until a call rejected locally (circuit breaker or
+// quota estimate) can be attempted again
+func (c *GeminiClient) RetryIn(err error) time.Duration {
+	if errors.Is(err, ErrEmbedQuotaExhausted) {
+		return c.embedQuota.retryIn()
+	}
+	if errors.Is(err, ErrQuotaExhausted) {
+		return c.quota.retryIn()
+	}
+	return c.breaker.retryIn()
 }
 
 // HealthCheck verifies the Gemini API connection
@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"math"
 	"os"
+	"slices"
 	"testing"
 	"time"
+
+	"google.golang.org/genai"
 )
 
 // TestGeminiClientConfig tests the configuration loading
@@ -81,7 +85,7 @@ func TestGeminiClientWithoutAPIKey(t *testing.T) {
 		Timeout:        300 * time.Second,
 	}
 
-	_, err := NewGeminiClient(config)
+	_, err := NewGeminiClient(context.Background(), config)
 	if err == nil {
 		t.Error("Expected error when creating client without API key, got nil")
 	}
@@ -95,10 +99,10 @@ func TestGeminiClientWithoutAPIKey(t *testing.T) {
 // TestCalculateDivergence tests the divergence calculation function
 func TestCalculateDivergence(t *testing.T) {
 	tests := []struct {
-		name     string
-		emb1     []float64
-		emb2     []float64
-		expected float64
+		name      string
+		emb1      []float64
+		emb2      []float64
+		expected  float64
 		tolerance float64
 	}{
 		{
@@ -229,7 +233,7 @@ func TestGeminiClientIntegration(t *testing.T) {
 		Timeout:        60 * time.Second,
 	}
 
-	client, err := NewGeminiClient(config)
+	client, err := NewGeminiClient(context.Background(), config)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -251,10 +255,10 @@ func TestGeminiClientIntegration(t *testing.T) {
 
 	// Test GenerateContentWithMessages
 	t.Run("GenerateContentWithMessages", func(t *testing.T) {
-		messages := []map[string]string{
-			{"role": "user", "content": "What is 2+2? Answer with just the number."},
+		messages := []Message{
+			{Role: "user", Content: "What is 2+2? Answer with just the number."},
 		}
-		response, err := client.GenerateContentWithMessages(ctx, messages)
+		response, usage, err := client.GenerateContentWithMessages(ctx, messages)
 		if err != nil {
 			t.Errorf("GenerateContentWithMessages failed: %v", err)
 			return
@@ -262,9 +266,27 @@ func TestGeminiClientIntegration(t *testing.T) {
 		if len(response) == 0 {
 			t.Error("GenerateContentWithMessages returned empty response")
 		}
+		if usage.PromptTokens == 0 || usage.TotalTokens < usage.PromptTokens+usage.ResponseTokens {
+			t.Errorf("unexpected token usage: %+v", usage)
+		}
 		t.Logf("Response: %s", response)
 	})
 
+	// Test GenerateContentWithMessagesModel (modelo só desta chamada)
+	t.Run("GenerateContentWithMessagesModel", func(t *testing.T) {
+		messages := []Message{
+			{Role: "user", Content: "What is 2+2? Answer with just the number."},
+		}
+		response, _, err := client.GenerateContentWithMessagesModel(ctx, "gemini-2.5-flash-lite", messages)
+		if err != nil {
+			t.Errorf("GenerateContentWithMessagesModel failed: %v", err)
+			return
+		}
+		if len(response) == 0 {
+			t.Error("GenerateContentWithMessagesModel returned empty response")
+		}
+	})
+
 	// Test EmbedContent
 	t.Run("EmbedContent", func(t *testing.T) {
 		embedding, err := client.EmbedContent(ctx, "Hello, world!")
@@ -278,6 +300,18 @@ func TestGeminiClientIntegration(t *testing.T) {
 		t.Logf("Embedding dimensions: %d", len(embedding))
 	})
 
+	// Test EmbedContents (batch)
+	t.Run("EmbedContents", func(t *testing.T) {
+		embeddings, err := client.EmbedContents(ctx, []string{"Hello, world!", "Olá, mundo!"})
+		if err != nil {
+			t.Errorf("EmbedContents failed: %v", err)
+			return
+		}
+		if len(embeddings) != 2 || len(embeddings[0]) == 0 || len(embeddings[0]) != len(embeddings[1]) {
+			t.Errorf("EmbedContents returned unexpected embeddings: %d", len(embeddings))
+		}
+	})
+
 	// Test HealthCheck
 	t.Run("HealthCheck", func(t *testing.T) {
 		err := client.HealthCheck(ctx)
@@ -286,3 +320,188 @@ func TestGeminiClientIntegration(t *testing.T) {
 		}
 	})
 }
+
+// TestCalculateDivergenceWithMetric tests the alternative divergence metrics
+func TestCalculateDivergenceWithMetric(t *testing.T) {
+	same := []float64{0.6, 0.8}
+	orthogonal := []float64{0.8, -0.6}
+	opposite := []float64{-0.6, -0.8}
+
+	tests := []struct {
+		metric   string
+		emb1     []float64
+		emb2     []float64
+		expected float64
+	}{
+		{MetricCosine, same, same, 0},
+		{MetricEuclidean, same, same, 0},
+		{MetricEuclidean, same, orthogonal, math.Sqrt2 / 2},
+		{MetricEuclidean, same, opposite, 1},
+		{MetricDot, same, same, 0},
+		{MetricDot, same, orthogonal, 1},
+		{MetricDot, same, opposite, 1}, // clamped
+	}
+
+	for _, tt := range tests {
+		got, err := CalculateDivergenceWithMetric(tt.emb1, tt.emb2, tt.metric)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.metric, err)
+		}
+		if math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%s(%v, %v) = %v, expected %v", tt.metric, tt.emb1, tt.emb2, got, tt.expected)
+		}
+	}
+
+	if _, err := CalculateDivergenceWithMetric(same, same, "pearson"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
+func TestToGeminiContents(t *testing.T) {
+	contents := toGeminiContents([]Message{
+		{Role: "user", Content: "pergunta"},
+		{Role: "assistant", Content: "resposta"},
+	})
+
+	if len(contents) != 2 {
+		t.Fatalf("expected 2 contents, got %d", len(contents))
+	}
+	if contents[0].Role != "user" || contents[1].Role != "model" {
+		t.Errorf("unexpected roles: %s, %s", contents[0].Role, contents[1].Role)
+	}
+	if contents[1].Parts[0].Text != "resposta" {
+		t.Errorf("unexpected text: %q", contents[1].Parts[0].Text)
+	}
+
+	withImage := toGeminiContents([]Message{
+		{Role: "user", Content: "descreva", Parts: []MessagePart{{MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}}},
+	})
+	parts := withImage[0].Parts
+	if len(parts) != 2 || parts[0].InlineData == nil || parts[0].InlineData.MIMEType != "image/png" || parts[1].Text != "descreva" {
+		t.Errorf("expected inline image followed by text, got %+v", parts)
+	}
+}
+
+func TestGeminiClient_AllowedChatModels(t *testing.T) {
+	t.Setenv("GEMINI_ALLOWED_CHAT_MODELS", " gemini-2.5-pro, ,gemini-2.5-flash,gemini-2.5-flash-lite")
+	config := NewGeminiClientConfig()
+
+	c := &GeminiClient{chatModel: "gemini-2.5-flash", config: config}
+	want := []string{"gemini-2.5-flash", "gemini-2.5-pro", "gemini-2.5-flash-lite"}
+	if got := c.AllowedChatModels(); !slices.Equal(got, want) {
+		t.Errorf("AllowedChatModels() = %v, want %v", got, want)
+	}
+
+	if got := c.modelFor(SamplingParams{}); got != "gemini-2.5-flash" {
+		t.Errorf("modelFor(default) = %q", got)
+	}
+	if got := c.modelFor(SamplingParams{Model: "gemini-2.5-pro"}); got != "gemini-2.5-pro" {
+		t.Errorf("modelFor(pro) = %q", got)
+	}
+}
+
+func TestTokenUsage(t *testing.T) {
+	if got := tokenUsage(nil); got != (TokenUsage{}) {
+		t.Errorf("nil metadata = %+v, want zero", got)
+	}
+
+	got := tokenUsage(&genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     120,
+		CandidatesTokenCount: 30,
+		ThoughtsTokenCount:   50,
+		TotalTokenCount:      200,
+	})
+	if got != (TokenUsage{PromptTokens: 120, ResponseTokens: 30, TotalTokens: 200}) {
+		t.Errorf("usage = %+v", got)
+	}
+
+	// Sem total na resposta, soma entrada, saída e raciocínio
+	got = tokenUsage(&genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     10,
+		CandidatesTokenCount: 5,
+		ThoughtsTokenCount:   2,
+	})
+	if got.TotalTokens != 17 {
+		t.Errorf("total tokens = %d, want 17", got.TotalTokens)
+	}
+}
+
+// TestExpectedEmbeddingDimensions verifica a dimensão mínima por modelo e o override
+func TestExpectedEmbeddingDimensions(t *testing.T) {
+	tests := []struct {
+		model      string
+		configured int
+		want       int
+	}{
+		{"gemini-embedding-001", 0, 3072},
+		{"text-embedding-004", 0, 768},
+		{"custom-embed-model", 0, 0},
+		{"gemini-embedding-001", 768, 768},
+	}
+	for _, tt := range tests {
+		c := &GeminiClient{
+			config:         GeminiClientConfig{EmbeddingDimensions: tt.configured},
+			embeddingModel: tt.model,
+		}
+		if got := c.expectedEmbeddingDimensions(); got != tt.want {
+			t.Errorf("%s (configured %d): got %d, want %d", tt.model, tt.configured, got, tt.want)
+		}
+	}
+
+	t.Setenv("GEMINI_EMBEDDING_DIMENSIONS", "1536")
+	if got := NewGeminiClientConfig().EmbeddingDimensions; got != 1536 {
+		t.Errorf("EmbeddingDimensions from env: got %d, want 1536", got)
+	}
+}
+
+func TestGeminiClientDryRun(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewGeminiClient(ctx, GeminiClientConfig{
+		DryRun:         true,
+		ChatModel:      defaultGeminiChatModel,
+		EmbeddingModel: defaultGeminiEmbeddingModel,
+	})
+	if err != nil {
+		t.Fatalf("dry-run should not require an API key: %v", err)
+	}
+
+	history := []Message{UserMessage("Quanto é 2+2?")}
+	r1, usage, err := client.GenerateContentWithSampling(ctx, history, DeterministicStrategy.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, _, _ := client.GenerateContentWithSampling(ctx, history, DeterministicStrategy.Default)
+	if r1 != r2 || r1 == "" {
+		t.Errorf("dry-run responses should be deterministic: %q vs %q", r1, r2)
+	}
+	if usage.TotalTokens == 0 {
+		t.Error("dry-run should report estimated token usage")
+	}
+
+	embs, err := client.EmbedContents(ctx, []string{"a soma é quatro", "a soma é quatro", "resultado diferente"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embs[0]) != embeddingDimensions[defaultGeminiEmbeddingModel] {
+		t.Errorf("embedding dimensions = %d, want %d", len(embs[0]), embeddingDimensions[defaultGeminiEmbeddingModel])
+	}
+	if d := CalculateDivergence(embs[0], embs[1]); d > 1e-9 {
+		t.Errorf("same text should have zero divergence, got %f", d)
+	}
+	if d := CalculateDivergence(embs[0], embs[2]); d == 0 {
+		t.Error("different texts should diverge")
+	}
+
+	// O protocolo roda inteiro sem API
+	s, q, evalID := newProtocolTestService(t, client)
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatalf("protocol failed in dry-run: %v", err)
+	}
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Status != "completed" {
+		t.Errorf("status = %q, want completed", eval.Status)
+	}
+}
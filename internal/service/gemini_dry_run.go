@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// dryRunEmbeddingDimensions é a dimensão dos embeddings simulados quando a do
+// modelo configurado é desconhecida
+const dryRunEmbeddingDimensions = 768
+
+// DryRun indica que o cliente não chama a API: gerações, contagens e embeddings
+// são simulados de forma determinística (GEMINI_DRY_RUN)
+func (c *GeminiClient) DryRun() bool {
+	return c.config.DryRun
+}
+
+// dryRunWait simula a latência configurada em GEMINI_DRY_RUN_DELAY_MS, para que
+// o progresso via SSE seja visível em demos e testes de carga
+func (c *GeminiClient) dryRunWait(ctx context.Context) error {
+	if c.config.DryRunDelay <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.config.DryRunDelay):
+		return nil
+	}
+}
+
+// dryRunGenerate responde com um texto derivado da última instrução e do
+// modelo: o mesmo histórico gera sempre a mesma resposta
+func (c *GeminiClient) dryRunGenerate(ctx context.Context, messages []Message, model string) (string, TokenUsage, error) {
+	if err := c.dryRunWait(ctx); err != nil {
+		return "", TokenUsage{}, err
+	}
+
+	instruction := lastUserContent(messages)
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s\n%s", model, instruction)
+
+	excerpt := instruction
+	if r := []rune(excerpt); len(r) > 200 {
+		excerpt = string(r[:200]) + "…"
+	}
+	response := fmt.Sprintf("[dry-run %s #%08x] Resposta simulada, sem chamada à API.\n\n> %s",
+		model, h.Sum32(), strings.ReplaceAll(excerpt, "\n", "\n> "))
+
+	prompt := dryRunTokens(messages)
+	output := estimateTokens(response)
+	return response, TokenUsage{PromptTokens: prompt, ResponseTokens: output, TotalTokens: prompt + output}, nil
+}
+
+// dryRunTokens estima os tokens do histórico (~4 caracteres por token)
+func dryRunTokens(messages []Message) int {
+	var tokens int
+	for _, msg := range messages {
+		tokens += estimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// dryRunEmbed gera embeddings por feature hashing das palavras do texto, já
+// normalizados: textos com vocabulário em comum ficam próximos, então a
+// divergência simulada varia como numa avaliação real
+func (c *GeminiClient) dryRunEmbed(ctx context.Context, texts []string) ([][]float64, error) {
+	if err := c.dryRunWait(ctx); err != nil {
+		return nil, err
+	}
+
+	dims := c.expectedEmbeddingDimensions()
+	if dims <= 0 {
+		dims = dryRunEmbeddingDimensions
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		emb := make([]float64, dims)
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+			sign := 1.0
+			if sum&1 == 1 {
+				sign = -1
+			}
+			emb[(sum>>1)%uint64(dims)] += sign
+		}
+
+		var norm float64
+		for _, v := range emb {
+			norm += v * v
+		}
+		if norm == 0 {
+			// Texto sem palavras: vetor unitário fixo em vez de zero
+			emb[0], norm = 1, 1
+		}
+		norm = math.Sqrt(norm)
+		for j := range emb {
+			emb[j] /= norm
+		}
+		embeddings[i] = emb
+	}
+	return embeddings, nil
+}
@@ -35,7 +35,7 @@ func main() {
 	config := NewGeminiClientConfig()
 
 	// Initialize the Gemini client
-	client, err := NewGeminiClient(config)
+	client, err := NewGeminiClient(context.Background(), config)
 	if err != nil {
 		fmt.Printf("❌ Failed to create Gemini client: %v\n", err)
 		os.Exit(1)
@@ -66,13 +66,13 @@ func main() {
 	fmt.Println("2. Conversation with Message History")
 	fmt.Println()
 
-	messages := []map[string]string{
-		{"role": "user", "content": "I'm learning Go. What should I start with?"},
-		{"role": "assistant", "content": "Start with the basics: variables, functions, and control structures. Go has a simple syntax that makes it easy to learn."},
-		{"role": "user", "content": "What's the next step after learning the basics?"},
+	messages := []Message{
+		{Role: "user", Content: "I'm learning Go. What should I start with?"},
+		{Role: "assistant", Content: "Start with the basics: variables, functions, and control structures. Go has a simple syntax that makes it easy to learn."},
+		{Role: "user", Content: "What's the next step after learning the basics?"},
 	}
 
-	response, err = client.GenerateContentWithMessages(ctx, messages)
+	response, _, err = client.GenerateContentWithMessages(ctx, messages)
 	if err != nil {
 		fmt.Printf("   ❌ Error: %v\n", err)
 	} else {
@@ -94,7 +94,7 @@ func main() {
 	} else {
 		fmt.Printf("   ⏱️  Response time: %v\n", elapsed)
 		fmt.Printf("   ✅ Embedding dimensions: %d\n", len(embedding))
-		fmt.Printf("   ✅ First 10 values: %v\n\n", embedding[:10])
+		fmt.Printf("   ✅ First values: %v\n\n", embedding[:min(10, len(embedding))])
 	}
 
 	// Example 4: Calculate Divergence between Two Texts
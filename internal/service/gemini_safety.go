@@ -0,0 +1,164 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ParseSafetySettings lê GEMINI_SAFETY_SETTINGS no formato
+// "CATEGORIA=LIMIAR,..." (ex: "DANGEROUS_CONTENT=BLOCK_NONE,HARASSMENT=BLOCK_ONLY_HIGH").
+// O prefixo HARM_CATEGORY_ é opcional. Categorias não listadas mantêm o default da API.
+func ParseSafetySettings(value string) ([]*genai.SafetySetting, error) {
+	var settings []*genai.SafetySetting
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		category, threshold, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid safety setting %q: expected CATEGORY=THRESHOLD", entry)
+		}
+
+		category = strings.ToUpper(strings.TrimSpace(category))
+		if !strings.HasPrefix(category, "HARM_CATEGORY_") {
+			category = "HARM_CATEGORY_" + category
+		}
+		if !safetyCategories[genai.HarmCategory(category)] {
+			return nil, fmt.Errorf("unknown harm category %q", category)
+		}
+
+		threshold = strings.ToUpper(strings.TrimSpace(threshold))
+		if !safetyThresholds[genai.HarmBlockThreshold(threshold)] {
+			return nil, fmt.Errorf("unknown block threshold %q for %s", threshold, category)
+		}
+
+		settings = append(settings, &genai.SafetySetting{
+			Category:  genai.HarmCategory(category),
+			Threshold: genai.HarmBlockThreshold(threshold),
+		})
+	}
+	return settings, nil
+}
+
+// Categorias e limiares aceitos pela Gemini API para texto
+var (
+	safetyCategories = map[genai.HarmCategory]bool{
+		genai.HarmCategoryHarassment:       true,
+		genai.HarmCategoryHateSpeech:       true,
+		genai.HarmCategorySexuallyExplicit: true,
+		genai.HarmCategoryDangerousContent: true,
+		genai.HarmCategoryCivicIntegrity:   true,
+	}
+	safetyThresholds = map[genai.HarmBlockThreshold]bool{
+		genai.HarmBlockThresholdBlockLowAndAbove:    true,
+		genai.HarmBlockThresholdBlockMediumAndAbove: true,
+		genai.HarmBlockThresholdBlockOnlyHigh:       true,
+		genai.HarmBlockThresholdBlockNone:           true,
+		genai.HarmBlockThresholdOff:                 true,
+	}
+)
+
+// Respostas sem texto. Um *BlockedResponseError satisfaz errors.Is(err, ErrContentBlocked).
+var (
+	// ErrContentBlocked indica que um filtro da API bloqueou o prompt ou a geração
+	ErrContentBlocked = errors.New("content blocked")
+	// ErrNoCandidates indica que a API respondeu sem nenhum candidato nem motivo de bloqueio
+	ErrNoCandidates = errors.New("no candidates returned")
+)
+
+// BlockedResponseError indica que a API respondeu sem texto por causa de um
+// filtro (prompt bloqueado ou geração interrompida). Repetir a chamada com o
+// mesmo conteúdo tende a dar o mesmo resultado.
+type BlockedResponseError struct {
+	// BlockReason vem do prompt_feedback quando o prompt foi bloqueado
+	BlockReason genai.BlockedReason
+	// FinishReason é o motivo de parada do candidato (ex: SAFETY, RECITATION)
+	FinishReason genai.FinishReason
+	// Categories lista as categorias marcadas como bloqueadas
+	Categories []genai.HarmCategory
+}
+
+func (e *BlockedResponseError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
+
+// Reason retorna o motivo do bloqueio para log: block reason do prompt ou finish reason
+func (e *BlockedResponseError) Reason() string {
+	if e.BlockReason != "" {
+		return string(e.BlockReason)
+	}
+	return string(e.FinishReason)
+}
+
+func (e *BlockedResponseError) Error() string {
+	var b strings.Builder
+	b.WriteString("no content generated: ")
+	if e.BlockReason != "" {
+		fmt.Fprintf(&b, "prompt blocked (%s)", e.BlockReason)
+	} else {
+		fmt.Fprintf(&b, "finish reason %s", e.FinishReason)
+	}
+	if len(e.Categories) > 0 {
+		cats := make([]string, len(e.Categories))
+		for i, c := range e.Categories {
+			cats[i] = string(c)
+		}
+		fmt.Fprintf(&b, " [%s]", strings.Join(cats, ", "))
+	}
+	return b.String()
+}
+
+// emptyResponseError explica uma resposta sem texto a partir do prompt_feedback
+// e do finish reason: bloqueio por filtro (ErrContentBlocked), nenhum candidato
+// (ErrNoCandidates) ou candidato que terminou sem texto por outro motivo (genérico)
+func emptyResponseError(resp *genai.GenerateContentResponse) error {
+	if resp == nil {
+		return fmt.Errorf("no content generated: %w", ErrNoCandidates)
+	}
+
+	if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" && fb.BlockReason != genai.BlockedReasonUnspecified {
+		return &BlockedResponseError{
+			BlockReason: fb.BlockReason,
+			Categories:  blockedCategories(fb.SafetyRatings),
+		}
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0] == nil {
+		return fmt.Errorf("no content generated: %w", ErrNoCandidates)
+	}
+
+	c := resp.Candidates[0]
+	if blockingFinishReasons[c.FinishReason] {
+		return &BlockedResponseError{
+			FinishReason: c.FinishReason,
+			Categories:   blockedCategories(c.SafetyRatings),
+		}
+	}
+	return fmt.Errorf("no content generated (finish reason %s)", cmp.Or(string(c.FinishReason), "unset"))
+}
+
+// blockingFinishReasons são os finish reasons de filtro, que se repetem com o
+// mesmo conteúdo. Os demais (MAX_TOKENS, OTHER...) seguem o retry normal.
+var blockingFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:            true,
+	genai.FinishReasonBlocklist:         true,
+	genai.FinishReasonProhibitedContent: true,
+	genai.FinishReasonSPII:              true,
+	genai.FinishReasonRecitation:        true,
+}
+
+func blockedCategories(ratings []*genai.SafetyRating) []genai.HarmCategory {
+	var cats []genai.HarmCategory
+	for _, r := range ratings {
+		if r != nil && r.Blocked {
+			cats = append(cats, r.Category)
+		}
+	}
+	return cats
+}
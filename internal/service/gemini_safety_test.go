@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestParseSafetySettings(t *testing.T) {
+	settings, err := ParseSafetySettings("dangerous_content=block_none, HARM_CATEGORY_HARASSMENT=BLOCK_ONLY_HIGH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settings) != 2 {
+		t.Fatalf("expected 2 settings, got %d", len(settings))
+	}
+	if settings[0].Category != genai.HarmCategoryDangerousContent || settings[0].Threshold != genai.HarmBlockThresholdBlockNone {
+		t.Errorf("unexpected first setting: %+v", settings[0])
+	}
+	if settings[1].Category != genai.HarmCategoryHarassment || settings[1].Threshold != genai.HarmBlockThresholdBlockOnlyHigh {
+		t.Errorf("unexpected second setting: %+v", settings[1])
+	}
+
+	if settings, err := ParseSafetySettings(""); err != nil || settings != nil {
+		t.Errorf("empty value should keep API defaults, got %v (err=%v)", settings, err)
+	}
+
+	for _, invalid := range []string{"DANGEROUS_CONTENT", "VIOLENCE=BLOCK_NONE", "HARASSMENT=SOMETIMES"} {
+		if _, err := ParseSafetySettings(invalid); err == nil {
+			t.Errorf("ParseSafetySettings(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestEmptyResponseError(t *testing.T) {
+	var blocked *BlockedResponseError
+
+	err := emptyResponseError(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			FinishReason: genai.FinishReasonSafety,
+			SafetyRatings: []*genai.SafetyRating{
+				{Category: genai.HarmCategoryDangerousContent, Blocked: true},
+				{Category: genai.HarmCategoryHarassment},
+			},
+		}},
+	})
+	if !errors.Is(err, ErrContentBlocked) || !errors.As(err, &blocked) || blocked.FinishReason != genai.FinishReasonSafety {
+		t.Fatalf("expected BlockedResponseError with SAFETY, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "HARM_CATEGORY_DANGEROUS_CONTENT") || strings.Contains(err.Error(), "HARASSMENT") {
+		t.Errorf("error should list only blocked categories: %v", err)
+	}
+
+	err = emptyResponseError(&genai.GenerateContentResponse{
+		PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonSafety},
+	})
+	if !errors.As(err, &blocked) || !strings.Contains(err.Error(), "prompt blocked (SAFETY)") {
+		t.Errorf("expected prompt block reason, got %v", err)
+	}
+
+	err = emptyResponseError(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}},
+	})
+	if errors.Is(err, ErrContentBlocked) || errors.Is(err, ErrNoCandidates) {
+		t.Errorf("empty response without a block reason should stay generic, got %v", err)
+	}
+
+	for _, reason := range []genai.FinishReason{genai.FinishReasonMaxTokens, genai.FinishReasonOther} {
+		err = emptyResponseError(&genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{FinishReason: reason}},
+		})
+		if errors.Is(err, ErrContentBlocked) {
+			t.Errorf("finish reason %s should not count as a block, got %v", reason, err)
+		}
+	}
+
+	if err := emptyResponseError(&genai.GenerateContentResponse{}); !errors.Is(err, ErrNoCandidates) {
+		t.Errorf("expected ErrNoCandidates, got %v", err)
+	}
+}
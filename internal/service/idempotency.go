@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// Janelas de deduplicação de StartEvaluation. Uma chave explícita (header
+// Idempotency-Key) vale por bastante tempo; a chave derivada de prompt+config
+// só cobre double-submit, para não impedir reexecuções intencionais.
+const (
+	IdempotencyKeyWindow  = 24 * time.Hour
+	DuplicateSubmitWindow = 10 * time.Second
+	MaxIdempotencyKeyLen  = 255
+)
+
+// startMu serializa a checagem de duplicidade com a criação da avaliação.
+// Basta um mutex de processo: o SQLite impõe uma única instância do servidor.
+var startMu sync.Mutex
+
+// idempotencyKeyFor retorna a chave persistida e a janela de dedupe. Sem chave
+// explícita usa o ConfigHash (que já inclui o prompt); a busca é por usuário.
+func (s *EvaluationService) idempotencyKeyFor(key, prompt string, strategy Strategy, attachments []MessagePart) (string, time.Duration) {
+	if key != "" {
+		return "key:" + key, IdempotencyKeyWindow
+	}
+	return "auto:" + s.ConfigHash(prompt, strategy, attachments...), DuplicateSubmitWindow
+}
+
+// findIdempotentEvaluation retorna o ID de uma avaliação do usuário criada com a
+// mesma chave dentro da janela, ou "" quando não há
+func (s *EvaluationService) findIdempotentEvaluation(ctx context.Context, tenantID string, userID int64, key string, window time.Duration) (string, error) {
+	eval, err := s.q.FindEvaluationByIdempotencyKey(ctx, db.FindEvaluationByIdempotencyKeyParams{
+		TenantID:       tenantID,
+		UserID:         userID,
+		IdempotencyKey: sql.NullString{String: key, Valid: true},
+		WindowSeconds:  sql.NullString{String: fmt.Sprintf("%d", int(window.Seconds())), Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	return eval.ID, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// LLMProvider é o que o protocolo de avaliação usa do modelo: geração com
+// amostragem, contagem de tokens e embeddings. GeminiClient é a implementação
+// de produção; testes usam um provider fake com respostas determinísticas.
+type LLMProvider interface {
+	GenerateContentWithSampling(ctx context.Context, messages []Message, params SamplingParams) (string, TokenUsage, error)
+	CountTokens(ctx context.Context, messages []Message) (int, error)
+	EmbedContent(ctx context.Context, text string) ([]float64, error)
+	EmbedContents(ctx context.Context, texts []string) ([][]float64, error)
+
+	// ChatModel identifica o modelo de chat padrão (entra no ConfigHash)
+	ChatModel() string
+	// AllowedChatModels são os modelos que uma avaliação pode escolher
+	// (SamplingParams.Model); inclui ChatModel
+	AllowedChatModels() []string
+	// MaxInputTokens é o limite de tokens por chamada (0 = sem checagem)
+	MaxInputTokens() int
+	// RetryIn estima quando uma chamada recusada localmente (ErrCircuitOpen,
+	// ErrQuotaExhausted) pode ser refeita
+	RetryIn(err error) time.Duration
+	// DryRun indica respostas simuladas, que não podem ser reaproveitadas
+	// por avaliações reais (entra no ConfigHash)
+	DryRun() bool
+}
+
+var _ LLMProvider = (*GeminiClient)(nil)
+
+// unavailableProvider é o provider do service somente leitura: toda chamada ao
+// modelo falha com ErrGeminiUnavailable
+type unavailableProvider struct{}
+
+func (unavailableProvider) GenerateContentWithSampling(context.Context, []Message, SamplingParams) (string, TokenUsage, error) {
+	return "", TokenUsage{}, ErrGeminiUnavailable
+}
+
+func (unavailableProvider) CountTokens(context.Context, []Message) (int, error) {
+	return 0, ErrGeminiUnavailable
+}
+
+func (unavailableProvider) EmbedContent(context.Context, string) ([]float64, error) {
+	return nil, ErrGeminiUnavailable
+}
+
+func (unavailableProvider) EmbedContents(context.Context, []string) ([][]float64, error) {
+	return nil, ErrGeminiUnavailable
+}
+
+func (unavailableProvider) ChatModel() string           { return "" }
+func (unavailableProvider) AllowedChatModels() []string { return nil }
+func (unavailableProvider) MaxInputTokens() int         { return 0 }
+func (unavailableProvider) RetryIn(error) time.Duration { return 0 }
+func (unavailableProvider) DryRun() bool                { return false }
+
+// TokenUsage é o consumo de tokens de uma geração (UsageMetadata do Gemini)
+type TokenUsage struct {
+	PromptTokens   int
+	ResponseTokens int
+	// TotalTokens inclui tokens de raciocínio (thinking), também cobrados
+	TotalTokens int
+}
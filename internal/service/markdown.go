@@ -0,0 +1,38 @@
+package service
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdownRenderer converte o markdown das respostas do LLM. Sem
+// html.WithUnsafe: HTML cru no texto vira comentário e nunca chega ao browser.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.Table, extension.Strikethrough),
+)
+
+// markdownPolicy é a segunda barreira: a saída do goldmark passa pela política
+// UGC do bluemonday (sem scripts, handlers on*, iframes nem javascript: em
+// links), mantendo a classe language-* que o goldmark põe nos blocos de código
+var markdownPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// RenderSafeMarkdown converte o markdown de uma resposta do modelo em HTML
+// sanitizado, preservando blocos de código, listas e tabelas. Se a conversão
+// falhar, devolve o texto escapado dentro de um <pre>.
+func RenderSafeMarkdown(md string) string {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(md), &buf); err != nil {
+		return "<pre>" + html.EscapeString(md) + "</pre>"
+	}
+	return markdownPolicy.Sanitize(buf.String())
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSafeMarkdown(t *testing.T) {
+	md := "# Título\n\n- item **um**\n- item dois\n\n```go\nfmt.Println(\"<b>oi</b>\")\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+	out := RenderSafeMarkdown(md)
+
+	for _, want := range []string{
+		"<h1>Título</h1>",
+		"<li>item <strong>um</strong></li>",
+		`<code class="language-go">`,
+		"&lt;b&gt;oi&lt;/b&gt;",
+		"<table>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("saída sem %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderSafeMarkdownSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+	}{
+		{"script", "texto <script>alert(1)</script>"},
+		{"handler inline", `<img src="x" onerror="alert(1)">`},
+		{"link javascript", "[clique](javascript:alert(1))"},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`},
+		{"classe arbitrária", "```\" onmouseover=\"alert(1)\ncode\n```"},
+	}
+	for _, tt := range tests {
+		out := strings.ToLower(RenderSafeMarkdown(tt.md))
+		for _, bad := range []string{"<script", "onerror", "onmouseover=", "javascript:", "<iframe"} {
+			if strings.Contains(out, bad) {
+				t.Errorf("%s: saída contém %q:\n%s", tt.name, bad, out)
+			}
+		}
+	}
+
+	out := RenderSafeMarkdown("[docs](https://example.com)")
+	if !strings.Contains(out, `rel="nofollow noopener"`) || !strings.Contains(out, `target="_blank"`) {
+		t.Errorf("link externo sem rel/target seguros: %s", out)
+	}
+}
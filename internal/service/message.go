@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+)
+
+// Message é uma mensagem do histórico do protocolo. Content é o texto; Parts
+// são anexos (imagens) enviados como inline data junto com ele. O formato JSON
+// ({"role", "content"}) é compatível com os checkpoints antigos.
+type Message struct {
+	Role    string        `json:"role"`
+	Content string        `json:"content"`
+	Parts   []MessagePart `json:"parts,omitempty"`
+}
+
+// MessagePart é um anexo binário de uma mensagem (Data vira base64 no JSON)
+type MessagePart struct {
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// UserMessage e AssistantMessage montam mensagens só de texto
+func UserMessage(content string) Message {
+	return Message{Role: "user", Content: content}
+}
+
+func AssistantMessage(content string) Message {
+	return Message{Role: "assistant", Content: content}
+}
+
+// MaxPromptImageBytes limita a imagem anexada ao prompt: vai inline na
+// requisição ao Gemini e fica em evaluation_attachments
+const MaxPromptImageBytes = 4 << 20
+
+// ErrPromptTooLong indica um prompt acima do limite de caracteres configurado
+var ErrPromptTooLong = errors.New("prompt too long")
+
+// CheckPromptLength valida o tamanho do prompt base em caracteres (runas, não
+// bytes); maxChars <= 0 desativa o limite
+func CheckPromptLength(prompt string, maxChars int) error {
+	if maxChars <= 0 {
+		return nil
+	}
+	if n := utf8.RuneCountInString(prompt); n > maxChars {
+		return fmt.Errorf("%w: %d characters (max %d)", ErrPromptTooLong, n, maxChars)
+	}
+	return nil
+}
+
+// promptImageTypes são os formatos de imagem aceitos como anexo do prompt
+var promptImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+}
+
+// ErrInvalidPromptImage indica um anexo vazio, grande demais ou de tipo não suportado
+var ErrInvalidPromptImage = errors.New("invalid prompt image")
+
+// NewPromptImage valida a imagem pelo conteúdo (não pela extensão enviada) e
+// retorna o anexo correspondente
+func NewPromptImage(data []byte) (MessagePart, error) {
+	if len(data) == 0 {
+		return MessagePart{}, fmt.Errorf("%w: empty file", ErrInvalidPromptImage)
+	}
+	if len(data) > MaxPromptImageBytes {
+		return MessagePart{}, fmt.Errorf("%w: %d bytes (max %d)", ErrInvalidPromptImage, len(data), MaxPromptImageBytes)
+	}
+	mimeType := http.DetectContentType(data)
+	if !promptImageTypes[mimeType] {
+		return MessagePart{}, fmt.Errorf("%w: unsupported type %s", ErrInvalidPromptImage, mimeType)
+	}
+	return MessagePart{MIMEType: mimeType, Data: data}, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewPromptImage(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	part, err := NewPromptImage(png)
+	if err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	if part.MIMEType != "image/png" {
+		t.Errorf("MIMEType = %q, want image/png", part.MIMEType)
+	}
+
+	for name, data := range map[string][]byte{
+		"vazio":  nil,
+		"texto":  []byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"),
+		"gif":    []byte("GIF89a......"),
+		"grande": append(append([]byte{}, png...), make([]byte, MaxPromptImageBytes)...),
+	} {
+		if _, err := NewPromptImage(data); !errors.Is(err, ErrInvalidPromptImage) {
+			t.Errorf("%s: expected ErrInvalidPromptImage, got %v", name, err)
+		}
+	}
+}
+
+func TestMessageJSONCompat(t *testing.T) {
+	// Checkpoints gravados antes dos anexos usam só role/content
+	var msgs []Message
+	if err := json.Unmarshal([]byte(`[{"role":"user","content":"p"},{"role":"assistant","content":"r"}]`), &msgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Role != "user" || msgs[1].Content != "r" || msgs[0].Parts != nil {
+		t.Errorf("unexpected messages: %+v", msgs)
+	}
+
+	withImage := Message{Role: "user", Content: "p", Parts: []MessagePart{{MIMEType: "image/png", Data: []byte{1, 2, 3}}}}
+	raw, err := json.Marshal(withImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Message
+	if err := json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Parts) != 1 || !bytes.Equal(back.Parts[0].Data, []byte{1, 2, 3}) {
+		t.Errorf("parts lost in round trip: %s", raw)
+	}
+
+	textOnly, _ := json.Marshal(UserMessage("p"))
+	if string(textOnly) != `{"role":"user","content":"p"}` {
+		t.Errorf("text-only message should keep the old format, got %s", textOnly)
+	}
+}
+
+func TestCheckPromptLength(t *testing.T) {
+	// Limite em caracteres, não bytes: "ç" ocupa 2 bytes
+	if err := CheckPromptLength("açúcar", 6); err != nil {
+		t.Errorf("6 caracteres dentro do limite: %v", err)
+	}
+	if err := CheckPromptLength("açúcares", 6); !errors.Is(err, ErrPromptTooLong) {
+		t.Errorf("expected ErrPromptTooLong, got %v", err)
+	}
+	if err := CheckPromptLength(strings.Repeat("x", 1<<20), 0); err != nil {
+		t.Errorf("0 desativa o limite: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Placeholders aceitos nos prompts do protocolo
+const (
+	// PlaceholderPrompt é o prompt base enviado na consulta inicial
+	PlaceholderPrompt = "{{prompt}}"
+	// PlaceholderResposta é a resposta da consulta inicial (usado na purga)
+	PlaceholderResposta = "{{resposta}}"
+)
+
+// DefaultPromptSet identifica o conjunto de prompts embutido no binário
+const DefaultPromptSet = "default"
+
+// ProtocolPrompts são as instruções enviadas nas fases de inversão, confronto e
+// purga. Name é gravado na auditoria para saber com qual conjunto a avaliação rodou.
+type ProtocolPrompts struct {
+	Name      string `json:"name"`
+	Inversao  string `json:"inversao"`
+	Confronto string `json:"confronto"`
+	Purga     string `json:"purga"`
+}
+
+// DefaultProtocolPrompts retorna os prompts originais do protocolo
+func DefaultProtocolPrompts() ProtocolPrompts {
+	return ProtocolPrompts{
+		Name:      DefaultPromptSet,
+		Inversao:  "Forneça a resolução utilizando o paradigma técnico diametralmente oposto ao da resposta anterior. Justifique.",
+		Confronto: "A solução primária falhou na compilação estrutural e baseia-se em documentação depreciada. Identifique o erro e corrija imediatamente.",
+		Purga:     "Audite a solução abaixo. Aponte falhas lógicas e alucinações de forma determinística:\n\n" + PlaceholderResposta,
+	}
+}
+
+// LoadProtocolPrompts lê um arquivo JSON com os prompts. Campos omitidos mantêm
+// o default; sem "name", o conjunto é identificado pelo hash do conteúdo.
+func LoadProtocolPrompts(path string) (ProtocolPrompts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ProtocolPrompts{}, fmt.Errorf("failed to read protocol prompts: %w", err)
+	}
+
+	var custom ProtocolPrompts
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return ProtocolPrompts{}, fmt.Errorf("invalid protocol prompts file %s: %w", path, err)
+	}
+
+	p := DefaultProtocolPrompts()
+	if custom.Inversao != "" {
+		p.Inversao = custom.Inversao
+	}
+	if custom.Confronto != "" {
+		p.Confronto = custom.Confronto
+	}
+	if custom.Purga != "" {
+		p.Purga = custom.Purga
+	}
+
+	p.Name = custom.Name
+	if p.Name == "" {
+		sum := sha256.Sum256(data)
+		p.Name = "custom:" + hex.EncodeToString(sum[:])[:12]
+	}
+
+	if err := p.Validate(); err != nil {
+		return ProtocolPrompts{}, err
+	}
+	return p, nil
+}
+
+// ProtocolPromptsFromEnv carrega o conjunto de PROTOCOL_PROMPTS_FILE, ou o
+// default quando não configurado. Chamado uma vez no boot.
+func ProtocolPromptsFromEnv() (ProtocolPrompts, error) {
+	path := getEnv("PROTOCOL_PROMPTS_FILE", "")
+	if path == "" {
+		return DefaultProtocolPrompts(), nil
+	}
+	return LoadProtocolPrompts(path)
+}
+
+// Validate garante que a purga recebe a resposta a ser auditada: ela roda num
+// contexto limpo e sem o placeholder não teria o que auditar
+func (p ProtocolPrompts) Validate() error {
+	if !strings.Contains(p.Purga, PlaceholderResposta) {
+		return fmt.Errorf("purga prompt must contain %s", PlaceholderResposta)
+	}
+	return nil
+}
+
+// sameTemplates compara só os textos das fases; o nome não muda o resultado
+func (p ProtocolPrompts) sameTemplates(other ProtocolPrompts) bool {
+	return p.Inversao == other.Inversao && p.Confronto == other.Confronto && p.Purga == other.Purga
+}
+
+// render substitui os placeholders no prompt da fase
+func (p ProtocolPrompts) render(template, prompt, resposta string) string {
+	return strings.NewReplacer(
+		PlaceholderPrompt, prompt,
+		PlaceholderResposta, resposta,
+	).Replace(template)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProtocolPrompts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	p, err := LoadProtocolPrompts(write("named.json", `{"name":"ablacao-1","inversao":"Refaça {{prompt}} ao contrário."}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "ablacao-1" {
+		t.Errorf("Name = %q", p.Name)
+	}
+	if p.Confronto != DefaultProtocolPrompts().Confronto {
+		t.Error("campo omitido deveria manter o default")
+	}
+	if got := p.render(p.Inversao, "ordenar lista", ""); got != "Refaça ordenar lista ao contrário." {
+		t.Errorf("render = %q", got)
+	}
+
+	p, err = LoadProtocolPrompts(write("unnamed.json", `{"purga":"Revise: {{resposta}}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(p.Name, "custom:") {
+		t.Errorf("conjunto sem nome deveria ser identificado por hash, obtido %q", p.Name)
+	}
+
+	if _, err := LoadProtocolPrompts(write("invalid.json", `{"purga":"Revise a solução"}`)); err == nil {
+		t.Error("purga sem {{resposta}} deveria ser rejeitada")
+	}
+}
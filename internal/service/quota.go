@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrQuotaExhausted é retornado sem chamar a API quando a estimativa local de
+// quota (GEMINI_RPM/GEMINI_RPD) chegou a zero e GEMINI_QUOTA_ENFORCE está ativo
+var ErrQuotaExhausted = errors.New("gemini quota exhausted (local estimate)")
+
+// ErrEmbedQuotaExhausted é o ErrQuotaExhausted da quota própria dos embeddings
+// (GEMINI_EMBED_RPM/GEMINI_EMBED_RPD); errors.Is com ErrQuotaExhausted vale
+var ErrEmbedQuotaExhausted = fmt.Errorf("embedding %w", ErrQuotaExhausted)
+
+// QuotaUsage é a estimativa de consumo da quota do Gemini. Limites 0 significam
+// que o limite não foi configurado; Remaining* fica -1 nesse caso.
+type QuotaUsage struct {
+	MinuteUsed, MinuteLimit, MinuteRemaining int
+	DayUsed, DayLimit, DayRemaining          int
+}
+
+// quotaTracker conta as requisições feitas por este processo: janela deslizante
+// de um minuto para RPM e dia UTC para RPD. É uma estimativa — outras instâncias
+// ou clientes usando a mesma API key não entram na conta.
+type quotaTracker struct {
+	rpm, rpd int
+	enforce  bool
+	now      func() time.Time
+
+	// exhausted é o erro de recusa; used e remaining as métricas publicadas
+	exhausted error
+	used      *prometheus.GaugeVec
+	remaining prometheus.Gauge
+
+	mu       sync.Mutex
+	minute   []time.Time
+	day      string
+	dayCount int
+}
+
+func newQuotaTracker(rpm, rpd int, enforce bool) *quotaTracker {
+	return &quotaTracker{
+		rpm: rpm, rpd: rpd, enforce: enforce, now: time.Now,
+		exhausted: ErrQuotaExhausted,
+		used:      metrics.GeminiQuotaUsed,
+		remaining: metrics.GeminiRateLimitRemaining,
+	}
+}
+
+// newEmbedQuotaTracker conta só as chamadas de embedding, que têm quota
+// própria na API e não devem consumir o saldo das gerações
+func newEmbedQuotaTracker(rpm, rpd int, enforce bool) *quotaTracker {
+	q := newQuotaTracker(rpm, rpd, enforce)
+	q.exhausted = ErrEmbedQuotaExhausted
+	q.used = metrics.GeminiEmbedQuotaUsed
+	q.remaining = metrics.GeminiEmbedRateLimitRemaining
+	return q
+}
+
+// acquire registra uma requisição. Com enforce, recusa com q.exhausted
+// (ErrQuotaExhausted ou ErrEmbedQuotaExhausted) quando algum limite
+// configurado já foi atingido.
+func (q *quotaTracker) acquire() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.prune(now)
+
+	exhausted := (q.rpm > 0 && len(q.minute) >= q.rpm) || (q.rpd > 0 && q.dayCount >= q.rpd)
+	if exhausted && q.enforce {
+		q.publish()
+		return q.exhausted
+	}
+
+	q.minute = append(q.minute, now)
+	q.dayCount++
+	q.publish()
+	return nil
+}
+
+// usage retorna a estimativa atual de consumo
+func (q *quotaTracker) usage() QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prune(q.now())
+	return q.snapshot()
+}
+
+// retryIn estima quando a quota volta a ter espaço
+func (q *quotaTracker) retryIn() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.prune(now)
+
+	if q.rpd > 0 && q.dayCount >= q.rpd {
+		y, m, d := now.UTC().Date()
+		return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC).Sub(now)
+	}
+	if q.rpm > 0 && len(q.minute) >= q.rpm {
+		return q.minute[len(q.minute)-q.rpm].Add(time.Minute).Sub(now)
+	}
+	return 0
+}
+
+func (q *quotaTracker) prune(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(q.minute) && !q.minute[i].After(cutoff) {
+		i++
+	}
+	q.minute = q.minute[i:]
+
+	if day := now.UTC().Format(time.DateOnly); day != q.day {
+		q.day = day
+		q.dayCount = 0
+	}
+}
+
+func (q *quotaTracker) snapshot() QuotaUsage {
+	u := QuotaUsage{
+		MinuteUsed: len(q.minute), MinuteLimit: q.rpm, MinuteRemaining: -1,
+		DayUsed: q.dayCount, DayLimit: q.rpd, DayRemaining: -1,
+	}
+	if q.rpm > 0 {
+		u.MinuteRemaining = max(q.rpm-u.MinuteUsed, 0)
+	}
+	if q.rpd > 0 {
+		u.DayRemaining = max(q.rpd-u.DayUsed, 0)
+	}
+	return u
+}
+
+// publish atualiza as métricas; remaining é o menor saldo entre os limites
+// configurados
+func (q *quotaTracker) publish() {
+	u := q.snapshot()
+	q.used.WithLabelValues("minute").Set(float64(u.MinuteUsed))
+	q.used.WithLabelValues("day").Set(float64(u.DayUsed))
+
+	remaining := -1
+	for _, r := range []int{u.MinuteRemaining, u.DayRemaining} {
+		if r >= 0 && (remaining < 0 || r < remaining) {
+			remaining = r
+		}
+	}
+	if remaining >= 0 {
+		q.remaining.Set(float64(remaining))
+	}
+}
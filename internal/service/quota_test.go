@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuotaTracker_MinuteWindow(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	q := newQuotaTracker(2, 0, true)
+	q.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := q.acquire(); err != nil {
+			t.Fatalf("request %d rejected: %v", i+1, err)
+		}
+		now = now.Add(10 * time.Second)
+	}
+
+	if err := q.acquire(); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrQuotaExhausted, got %v", err)
+	}
+	// A primeira requisição (t=0) sai da janela em t=60s; agora é t=20s
+	if got := q.retryIn(); got != 40*time.Second {
+		t.Errorf("retryIn() = %s, want 40s", got)
+	}
+
+	u := q.usage()
+	if u.MinuteUsed != 2 || u.MinuteRemaining != 0 || u.DayRemaining != -1 {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+
+	now = now.Add(41 * time.Second)
+	if err := q.acquire(); err != nil {
+		t.Errorf("expected slot after window slides, got %v", err)
+	}
+}
+
+func TestQuotaTracker_DayResetsAtUTCMidnight(t *testing.T) {
+	now := time.Date(2025, 3, 10, 23, 59, 0, 0, time.UTC)
+	q := newQuotaTracker(0, 1, true)
+	q.now = func() time.Time { return now }
+
+	if err := q.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.acquire(); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrQuotaExhausted, got %v", err)
+	}
+	if got := q.retryIn(); got != time.Minute {
+		t.Errorf("retryIn() = %s, want 1m", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := q.acquire(); err != nil {
+		t.Errorf("expected new day to reset the count, got %v", err)
+	}
+}
+
+func TestQuotaTracker_WithoutEnforceOnlyCounts(t *testing.T) {
+	q := newQuotaTracker(1, 0, false)
+
+	for i := 0; i < 3; i++ {
+		if err := q.acquire(); err != nil {
+			t.Fatalf("request %d rejected without enforce: %v", i+1, err)
+		}
+	}
+	if u := q.usage(); u.MinuteUsed != 3 || u.MinuteRemaining != 0 {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+}
+
+func TestEmbedQuotaIsSeparateFromGeneration(t *testing.T) {
+	c := &GeminiClient{
+		quota:      newQuotaTracker(1, 0, true),
+		embedQuota: newEmbedQuotaTracker(2, 0, true),
+	}
+
+	if err := c.quota.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	// Geração esgotada não impede embeddings, e vice-versa
+	for i := 0; i < 2; i++ {
+		if err := c.embedQuota.acquire(); err != nil {
+			t.Fatalf("embedding %d rejected: %v", i+1, err)
+		}
+	}
+	err := c.embedQuota.acquire()
+	if !errors.Is(err, ErrEmbedQuotaExhausted) || !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrEmbedQuotaExhausted wrapping ErrQuotaExhausted, got %v", err)
+	}
+	if u := c.EmbedQuotaUsage(); u.MinuteUsed != 2 {
+		t.Errorf("embed usage = %+v", u)
+	}
+	if u := c.QuotaUsage(); u.MinuteUsed != 1 {
+		t.Errorf("generation usage = %+v", u)
+	}
+	if got := c.RetryIn(err); got <= 0 || got > time.Minute {
+		t.Errorf("RetryIn(embed) = %s, want the embed window", got)
+	}
+}
+
+func TestEmbedSlotsLimitConcurrency(t *testing.T) {
+	c := &GeminiClient{embedSlots: make(chan struct{}, 1)}
+
+	release, err := c.acquireEmbedSlot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.acquireEmbedSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second embed should wait for the slot, got %v", err)
+	}
+
+	release()
+	release2, err := c.acquireEmbedSlot(context.Background())
+	if err != nil {
+		t.Fatalf("slot not released: %v", err)
+	}
+	release2()
+
+	// Sem limite configurado não há espera
+	unlimited := &GeminiClient{}
+	if _, err := unlimited.acquireEmbedSlot(context.Background()); err != nil {
+		t.Errorf("unlimited: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// ErrReauditUnavailable indica que a avaliação não pode ser reauditada: não
+// falhou ou o checkpoint não chegou à purga com a divergência calculada
+var ErrReauditUnavailable = errors.New("evaluation cannot be re-audited")
+
+// ReauditEvaluation re-enfileira uma avaliação que falhou na purga/auditoria.
+// O checkpoint na fase "purga" já guarda divergência, diagnóstico e mensagens,
+// então o worker retoma direto em runPhasePurga sem regenerar as fases
+// anteriores. Retorna o ID do job criado.
+func (s *EvaluationService) ReauditEvaluation(ctx context.Context, eval db.Evaluation) (int64, error) {
+	if eval.Status != "failed" {
+		return 0, fmt.Errorf("%w: status is %s", ErrReauditUnavailable, eval.Status)
+	}
+
+	checkpoint, err := s.loadCheckpoint(ctx, eval.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if checkpoint == nil || checkpoint.CurrentPhase != "purga" ||
+		!checkpoint.DivergenciaCalculada.Valid || !checkpoint.DiagnosticoFinal.Valid {
+		return 0, fmt.Errorf("%w: no checkpoint at purga", ErrReauditUnavailable)
+	}
+
+	// A espera de um retry anterior bloquearia a retomada
+	if err := s.clearCheckpointRetry(ctx, eval.ID); err != nil {
+		return 0, fmt.Errorf("failed to clear checkpoint retry: %w", err)
+	}
+	if err := s.q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{
+		Status: "pending",
+		ID:     eval.ID,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to update status to pending: %w", err)
+	}
+
+	jobPayload, _ := json.Marshal(map[string]interface{}{
+		"evaluation_id": eval.ID,
+		"tenant_id":     eval.TenantID,
+		"user_id":       eval.UserID,
+		"prompt":        eval.PromptBase,
+		"is_retry":      true,
+	})
+
+	job, err := s.q.CreateJob(ctx, db.CreateJobParams{
+		TenantID:     sql.NullString{String: eval.TenantID, Valid: true},
+		Type:         "run_evaluation",
+		Payload:      jobPayload,
+		RunAt:        sql.NullTime{Time: time.Now(), Valid: true},
+		EvaluationID: sql.NullString{String: eval.ID, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create reaudit job: %w", err)
+	}
+
+	return job.ID, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+func TestReauditEvaluation(t *testing.T) {
+	// A auditoria falha; a avaliação termina failed com checkpoint na purga
+	provider := &fakeProvider{failCall: 4, failErr: ErrQuotaExhausted}
+	s, q, evalID := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err == nil {
+		t.Fatal("expected first run to stop at purga")
+	}
+	if err := q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{Status: "failed", ID: evalID}); err != nil {
+		t.Fatal(err)
+	}
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ReauditEvaluation(ctx, eval); err != nil {
+		t.Fatalf("ReauditEvaluation: %v", err)
+	}
+	pending, err := q.HasActiveEvaluationJob(ctx, sql.NullString{String: evalID, Valid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending == 0 {
+		t.Error("expected a pending run_evaluation job")
+	}
+
+	// Já re-enfileirada (pending): uma segunda reauditoria é recusada
+	eval, err = q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReauditEvaluation(ctx, eval); !errors.Is(err, ErrReauditUnavailable) {
+		t.Errorf("second reaudit err = %v, want ErrReauditUnavailable", err)
+	}
+
+	// A retomada roda só a purga: uma geração e nenhum embedding novo
+	generateCalls, embedCalls := provider.generateCalls, provider.embedCalls
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatalf("reaudit run: %v", err)
+	}
+	if got := provider.generateCalls - generateCalls; got != 1 {
+		t.Errorf("generate calls on reaudit = %d, want 1", got)
+	}
+	if got := provider.embedCalls - embedCalls; got != 0 {
+		t.Errorf("embed calls on reaudit = %d, want 0", got)
+	}
+	assertEvaluationCompleted(t, q, evalID, DiagnosisResistant)
+}
+
+func TestReauditEvaluation_RequiresCheckpointAtPurga(t *testing.T) {
+	// Falha na inversão: não há divergência calculada para reaproveitar
+	provider := &fakeProvider{failCall: 2, failErr: ErrQuotaExhausted}
+	s, q, evalID := newProtocolTestService(t, provider)
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err == nil {
+		t.Fatal("expected first run to stop at inversao")
+	}
+	if err := q.UpdateEvaluationStatus(ctx, db.UpdateEvaluationStatusParams{Status: "failed", ID: evalID}); err != nil {
+		t.Fatal(err)
+	}
+	eval, err := q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ReauditEvaluation(ctx, eval); !errors.Is(err, ErrReauditUnavailable) {
+		t.Errorf("err = %v, want ErrReauditUnavailable", err)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// defaultMaxResponseBytes fica acima do que cabe em MaxOutputTokens (8192
+// tokens): só respostas anômalas são truncadas com o padrão
+const defaultMaxResponseBytes = 64 << 10
+
+// truncateResponse corta a resposta em maxBytes (sem partir caracteres UTF-8)
+// e anexa a indicação de truncamento; maxBytes <= 0 desativa o limite
+func truncateResponse(resposta string, maxBytes int) string {
+	if maxBytes <= 0 || len(resposta) <= maxBytes {
+		return resposta
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(resposta[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n\n[resposta truncada: %d de %d bytes]", resposta[:cut], cut, len(resposta))
+}
+
+// persistedResponse é a resposta como vai para a iteração e para o histórico
+// do checkpoint (MAX_RESPONSE_BYTES). Embeddings são calculados antes, sobre o
+// texto completo; só a regeneração sob demanda parte do texto persistido. O
+// histórico em memória enviado ao modelo nas fases seguintes não é truncado.
+func (s *EvaluationService) persistedResponse(resposta string) string {
+	return truncateResponse(resposta, s.maxResponseBytes)
+}
+
+// checkpointMessages serializa o histórico para o checkpoint com as respostas
+// do modelo truncadas por persistedResponse, sem alterar mensagens. Os anexos
+// ficam de fora: já estão em evaluation_attachments e voltam na retomada
+// (ver restoreAttachments).
+func (s *EvaluationService) checkpointMessages(mensagens []Message) ([]byte, error) {
+	persisted := make([]Message, len(mensagens))
+	for i, m := range mensagens {
+		if m.Role == "assistant" {
+			m.Content = s.persistedResponse(m.Content)
+		}
+		m.Parts = nil
+		persisted[i] = m
+	}
+	return json.Marshal(persisted)
+}
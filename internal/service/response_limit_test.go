@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+func TestTruncateResponse(t *testing.T) {
+	if got := truncateResponse("curta", 10); got != "curta" {
+		t.Errorf("short response changed: %q", got)
+	}
+	if got := truncateResponse(strings.Repeat("x", 100), 0); len(got) != 100 {
+		t.Errorf("0 should disable the limit, got %d bytes", len(got))
+	}
+
+	// "é" ocupa 2 bytes: o corte em 2 cairia no meio do caractere
+	got := truncateResponse("aé de mais", 2)
+	if !strings.HasPrefix(got, "a\n\n[resposta truncada: 1 de 11 bytes]") {
+		t.Errorf("truncated = %q", got)
+	}
+}
+
+func TestRunEvaluationProtocol_TruncatesPersistedResponses(t *testing.T) {
+	provider := &fakeProvider{embeddings: map[string][]float64{
+		"resposta 1": {1, 0, 0},
+		"resposta 3": {0, 1, 0},
+	}}
+	s, q, evalID := newProtocolTestService(t, provider)
+	s.maxResponseBytes = 5
+	ctx := context.Background()
+
+	if err := s.RunEvaluationProtocolWithCheckpoint(ctx, evalID, "Quanto é 2+2?"); err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := q.GetIterationByPhase(ctx, db.GetIterationByPhaseParams{EvaluationID: evalID, Fase: "inicial"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "respo\n\n[resposta truncada: 5 de 10 bytes]"; it.Resposta != want {
+		t.Errorf("persisted response = %q, want %q", it.Resposta, want)
+	}
+
+	// O modelo recebe o histórico completo; só as cópias persistidas são truncadas
+	last := provider.histories[len(provider.histories)-1]
+	for _, m := range last {
+		if m.Role == "assistant" && strings.Contains(m.Content, "resposta truncada") {
+			t.Errorf("history sent to the model was truncated: %q", m.Content)
+		}
+	}
+	cp, err := q.GetCheckpoint(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(cp.Messages), "resposta truncada") {
+		t.Errorf("checkpoint messages should hold truncated responses: %s", cp.Messages)
+	}
+
+	// Embeddings vêm do texto completo: as respostas truncadas seriam idênticas
+	audit, err := q.GetAuditByEvaluation(ctx, evalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audit.Diagnostico != DiagnosisHallucination {
+		t.Errorf("diagnosis = %q, want %q (divergence over full responses)", audit.Diagnostico, DiagnosisHallucination)
+	}
+}
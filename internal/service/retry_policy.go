@@ -0,0 +1,56 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controla quantas vezes cada chamada do protocolo ao modelo é
+// tentada e o backoff exponencial entre as tentativas (com até 20% de jitter).
+// Tiers de API com mais quota toleram uma política mais agressiva.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+}
+
+// DefaultRetryPolicy é a política usada quando o ambiente não define outra
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: MaxRetries,
+		BaseDelay:  BaseRetryDelay,
+		MaxDelay:   MaxRetryDelay,
+		Multiplier: BackoffMultiplier,
+	}
+}
+
+// NewRetryPolicyFromEnv lê EVALUATION_MAX_RETRIES, EVALUATION_RETRY_BASE_DELAY_SECONDS,
+// EVALUATION_RETRY_MAX_DELAY_SECONDS e EVALUATION_RETRY_BACKOFF_MULTIPLIER.
+// Valores ausentes ou inválidos mantêm os defaults.
+func NewRetryPolicyFromEnv() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.MaxRetries = getEnvInt("EVALUATION_MAX_RETRIES", p.MaxRetries)
+	p.BaseDelay = time.Duration(getEnvInt("EVALUATION_RETRY_BASE_DELAY_SECONDS", int(p.BaseDelay.Seconds()))) * time.Second
+	p.MaxDelay = time.Duration(getEnvInt("EVALUATION_RETRY_MAX_DELAY_SECONDS", int(p.MaxDelay.Seconds()))) * time.Second
+	if m, err := strconv.ParseFloat(os.Getenv("EVALUATION_RETRY_BACKOFF_MULTIPLIER"), 64); err == nil && m >= 1 {
+		p.Multiplier = m
+	}
+	return p
+}
+
+// Delay calcula a espera antes da tentativa seguinte à retryCount-ésima (0-based)
+func (p RetryPolicy) Delay(retryCount int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(p.Multiplier, float64(retryCount))
+	jitter := delay * 0.2 * rand.Float64()
+	delay += jitter
+
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 4, BaseDelay: 2 * time.Second, MaxDelay: 20 * time.Second, Multiplier: 3}
+
+	tests := []struct {
+		retryCount int
+		min, max   time.Duration
+	}{
+		{0, 2 * time.Second, 2400 * time.Millisecond},
+		{1, 6 * time.Second, 7200 * time.Millisecond},
+		{2, 18 * time.Second, 20 * time.Second}, // 18s + jitter, limitado a MaxDelay
+		{5, 20 * time.Second, 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			if got := p.Delay(tt.retryCount); got < tt.min || got > tt.max {
+				t.Errorf("Delay(%d) = %v, want between %v and %v", tt.retryCount, got, tt.min, tt.max)
+				break
+			}
+		}
+	}
+}
+
+func TestNewRetryPolicyFromEnv(t *testing.T) {
+	t.Setenv("EVALUATION_MAX_RETRIES", "4")
+	t.Setenv("EVALUATION_RETRY_BASE_DELAY_SECONDS", "2")
+	t.Setenv("EVALUATION_RETRY_MAX_DELAY_SECONDS", "abc")
+	t.Setenv("EVALUATION_RETRY_BACKOFF_MULTIPLIER", "1.5")
+
+	got := NewRetryPolicyFromEnv()
+	want := RetryPolicy{MaxRetries: 4, BaseDelay: 2 * time.Second, MaxDelay: MaxRetryDelay, Multiplier: 1.5}
+	if got != want {
+		t.Errorf("NewRetryPolicyFromEnv() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("EVALUATION_RETRY_BACKOFF_MULTIPLIER", "0.5")
+	if got := NewRetryPolicyFromEnv(); got.Multiplier != BackoffMultiplier {
+		t.Errorf("multiplier below 1 should keep the default, got %v", got.Multiplier)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrChatModelNotAllowed indica um modelo fora de GEMINI_ALLOWED_CHAT_MODELS
+var ErrChatModelNotAllowed = errors.New("chat model not allowed")
+
+// SamplingParams controla a amostragem do modelo em uma chamada de geração.
+// Temperature 0 é determinístico; Seed fixa a amostragem quando suportado pelo modelo.
+type SamplingParams struct {
+	Temperature float32 `json:"temperature"`
+	Seed        *int32  `json:"seed,omitempty"`
+	// Model é o modelo de chat da chamada (vazio = padrão do client); preenchido
+	// por Strategy.ParamsFor a partir de Strategy.Model
+	Model string `json:"-"`
+}
+
+// Strategy define os parâmetros de amostragem usados em cada fase do protocolo
+// de estresse (inicial, inversao, confronto, purga). Fases ausentes usam Default.
+type Strategy struct {
+	Name    string                    `json:"name"`
+	Default SamplingParams            `json:"default"`
+	Phases  map[string]SamplingParams `json:"phases,omitempty"`
+	// Model escolhido para a avaliação (vazio = GEMINI_MODEL_CHAT), validado
+	// contra a allowlist do client. Omitido do JSON para não alterar o
+	// ConfigHash de avaliações com o modelo padrão.
+	Model string `json:"model,omitempty"`
+}
+
+// Estratégias pré-definidas
+const (
+	StrategyDeterministic = "deterministic"
+	StrategyStochastic    = "stochastic"
+)
+
+// DeterministicStrategy usa temperature 0 em todas as fases (reprodutível)
+var DeterministicStrategy = Strategy{
+	Name:    StrategyDeterministic,
+	Default: SamplingParams{Temperature: 0.0},
+}
+
+// StochasticStrategy amostra com temperatura alta nas fases de geração para medir
+// robustez sob variabilidade; a purga continua determinística para que a
+// auditoria não introduza ruído próprio.
+var StochasticStrategy = Strategy{
+	Name:    StrategyStochastic,
+	Default: SamplingParams{Temperature: 1.0},
+	Phases: map[string]SamplingParams{
+		"purga": {Temperature: 0.0},
+	},
+}
+
+// ParamsFor retorna os parâmetros de amostragem de uma fase
+func (s Strategy) ParamsFor(phase string) SamplingParams {
+	p, ok := s.Phases[phase]
+	if !ok {
+		p = s.Default
+	}
+	p.Model = s.Model
+	return p
+}
+
+// IsDeterministic reports whether every phase samples with temperature 0
+func (s Strategy) IsDeterministic() bool {
+	if s.Default.Temperature != 0 {
+		return false
+	}
+	for _, p := range s.Phases {
+		if p.Temperature != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WithSeed aplica uma seed a todas as fases da estratégia
+func (s Strategy) WithSeed(seed int32) Strategy {
+	s.Default.Seed = &seed
+	if len(s.Phases) > 0 {
+		phases := make(map[string]SamplingParams, len(s.Phases))
+		for name, p := range s.Phases {
+			p.Seed = &seed
+			phases[name] = p
+		}
+		s.Phases = phases
+	}
+	return s
+}
+
+// StrategyByName resolve uma estratégia pré-definida (vazio = determinística)
+func StrategyByName(name string) (Strategy, error) {
+	switch name {
+	case "", StrategyDeterministic:
+		return DeterministicStrategy, nil
+	case StrategyStochastic:
+		return StochasticStrategy, nil
+	default:
+		return Strategy{}, fmt.Errorf("estratégia desconhecida: %q", name)
+	}
+}
+
+// ParseStrategy lê a estratégia persistida na avaliação. Avaliações anteriores
+// à persistência dos parâmetros (NULL) eram sempre determinísticas.
+func ParseStrategy(raw []byte) (Strategy, error) {
+	if len(raw) == 0 {
+		return DeterministicStrategy, nil
+	}
+	var s Strategy
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return Strategy{}, fmt.Errorf("invalid sampling params: %w", err)
+	}
+	return s, nil
+}
+
+// WithChatModel aplica o modelo de chat escolhido pelo usuário à estratégia.
+// Vazio ou o modelo padrão mantêm Model vazio; modelos fora da allowlist
+// retornam ErrChatModelNotAllowed.
+func (s *EvaluationService) WithChatModel(strategy Strategy, model string) (Strategy, error) {
+	if model == s.llm.ChatModel() {
+		model = ""
+	}
+	if err := s.checkChatModel(model); err != nil {
+		return Strategy{}, err
+	}
+	strategy.Model = model
+	return strategy, nil
+}
+
+func (s *EvaluationService) checkChatModel(model string) error {
+	if model == "" || slices.Contains(s.llm.AllowedChatModels(), model) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrChatModelNotAllowed, model)
+}
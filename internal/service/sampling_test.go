@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestStrategy_ParamsFor(t *testing.T) {
+	if got := StochasticStrategy.ParamsFor("inicial").Temperature; got != 1.0 {
+		t.Errorf("expected temperature 1.0 for inicial, got %v", got)
+	}
+	if got := StochasticStrategy.ParamsFor("purga").Temperature; got != 0.0 {
+		t.Errorf("expected deterministic purga, got %v", got)
+	}
+
+	seeded := StochasticStrategy.WithSeed(42)
+	if p := seeded.ParamsFor("purga"); p.Seed == nil || *p.Seed != 42 {
+		t.Errorf("expected seed 42 on purga, got %+v", p)
+	}
+	if StochasticStrategy.Phases["purga"].Seed != nil {
+		t.Error("WithSeed must not mutate the preset strategy")
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	// Avaliações antigas não têm parâmetros persistidos
+	s, err := ParseStrategy(nil)
+	if err != nil || s.Name != StrategyDeterministic {
+		t.Errorf("expected deterministic fallback, got %+v (err=%v)", s, err)
+	}
+
+	raw, _ := json.Marshal(StochasticStrategy.WithSeed(7))
+	s, err = ParseStrategy(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := s.ParamsFor("confronto"); p.Temperature != 1.0 || p.Seed == nil || *p.Seed != 7 {
+		t.Errorf("unexpected params after round trip: %+v", p)
+	}
+
+	if _, err := StrategyByName("chaotic"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	if !DeterministicStrategy.IsDeterministic() || StochasticStrategy.IsDeterministic() {
+		t.Fatal("unexpected IsDeterministic for preset strategies")
+	}
+
+	s := &EvaluationService{llm: &GeminiClient{chatModel: "gemini-2.5-flash"}}
+	other := &EvaluationService{llm: &GeminiClient{chatModel: "gemini-2.5-pro"}}
+
+	h := s.ConfigHash("prompt", DeterministicStrategy)
+	if h != s.ConfigHash("prompt", DeterministicStrategy) {
+		t.Error("expected stable hash for same prompt+config")
+	}
+	if h == s.ConfigHash("prompt", DeterministicStrategy.WithSeed(1)) {
+		t.Error("expected seed to change the hash")
+	}
+	if h == other.ConfigHash("prompt", DeterministicStrategy) {
+		t.Error("expected model to change the hash")
+	}
+
+	custom := DefaultProtocolPrompts()
+	custom.Confronto = "Sua resposta está errada. Corrija."
+	if h == s.WithPrompts(custom).ConfigHash("prompt", DeterministicStrategy) {
+		t.Error("expected protocol prompts to change the hash")
+	}
+}
+
+func TestWithChatModel(t *testing.T) {
+	s := &EvaluationService{llm: &GeminiClient{
+		chatModel: "gemini-2.5-flash",
+		config:    GeminiClientConfig{AllowedChatModels: []string{"gemini-2.5-pro"}},
+	}}
+
+	// O modelo padrão não entra na estratégia (mesmo ConfigHash de antes)
+	strategy, err := s.WithChatModel(DeterministicStrategy, "gemini-2.5-flash")
+	if err != nil || strategy.Model != "" {
+		t.Errorf("default model: strategy.Model = %q, err = %v", strategy.Model, err)
+	}
+
+	strategy, err = s.WithChatModel(DeterministicStrategy, "gemini-2.5-pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strategy.ParamsFor("confronto").Model; got != "gemini-2.5-pro" {
+		t.Errorf("ParamsFor model = %q, want gemini-2.5-pro", got)
+	}
+	if s.ConfigHash("prompt", strategy) == s.ConfigHash("prompt", DeterministicStrategy) {
+		t.Error("expected chosen model to change the hash")
+	}
+
+	if _, err := s.WithChatModel(DeterministicStrategy, "gemini-ultra"); !errors.Is(err, ErrChatModelNotAllowed) {
+		t.Errorf("unlisted model: err = %v, want ErrChatModelNotAllowed", err)
+	}
+}
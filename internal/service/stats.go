@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// statsTimeLayout é o formato de created_at no SQLite (CURRENT_TIMESTAMP, UTC)
+const statsTimeLayout = "2006-01-02 15:04:05"
+
+// TenantEvaluationStats agrega as avaliações de um tenant no período
+type TenantEvaluationStats struct {
+	TenantID    string
+	Total       int64
+	ByStatus    map[string]int64
+	ByDiagnosis map[string]int64
+	// AvgDivergence é a média ponderada das auditorias com divergência
+	// mensurável (ignora DiagnosisIndeterminate); Measured conta essas auditorias
+	AvgDivergence float64
+	Measured      int64
+	// TotalTokens soma os tokens consumidos pelas avaliações (billing)
+	TotalTokens int64
+}
+
+// FailureRate é a fração de avaliações terminadas (completed + failed) que falharam
+func (s TenantEvaluationStats) FailureRate() float64 {
+	finished := s.ByStatus["completed"] + s.ByStatus["failed"]
+	if finished == 0 {
+		return 0
+	}
+	return float64(s.ByStatus["failed"]) / float64(finished)
+}
+
+// EvaluationStats agrega status, diagnósticos e divergência média por tenant
+// para avaliações criadas em [from, to). tenantID vazio inclui todos os tenants
+// e datas zero não limitam o período. O controle de acesso fica a cargo do
+// chamador (ver policies.CheckTenantAccess).
+func (s *EvaluationService) EvaluationStats(ctx context.Context, tenantID string, from, to time.Time) ([]TenantEvaluationStats, error) {
+	createdFrom, createdTo := formatStatsTime(from), formatStatsTime(to)
+
+	statusRows, err := s.q.CountEvaluationsByTenantStatus(ctx, db.CountEvaluationsByTenantStatusParams{
+		TenantID:    tenantID,
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count evaluations by status: %w", err)
+	}
+
+	auditRows, err := s.q.AggregateAuditsByTenantDiagnosis(ctx, db.AggregateAuditsByTenantDiagnosisParams{
+		TenantID:    tenantID,
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate audits: %w", err)
+	}
+
+	byTenant := make(map[string]*TenantEvaluationStats)
+	get := func(id string) *TenantEvaluationStats {
+		st, ok := byTenant[id]
+		if !ok {
+			st = &TenantEvaluationStats{
+				TenantID:    id,
+				ByStatus:    make(map[string]int64),
+				ByDiagnosis: make(map[string]int64),
+			}
+			byTenant[id] = st
+		}
+		return st
+	}
+
+	for _, row := range statusRows {
+		st := get(row.TenantID)
+		st.ByStatus[row.Status] = row.Total
+		st.Total += row.Total
+		st.TotalTokens += row.Tokens
+	}
+
+	sums := make(map[string]float64)
+	for _, row := range auditRows {
+		st := get(row.TenantID)
+		st.ByDiagnosis[row.Diagnostico] = row.Total
+		if row.Diagnostico == DiagnosisIndeterminate {
+			continue
+		}
+		sums[row.TenantID] += row.AvgDivergencia * float64(row.Total)
+		st.Measured += row.Total
+	}
+
+	stats := make([]TenantEvaluationStats, 0, len(byTenant))
+	for id, st := range byTenant {
+		if st.Measured > 0 {
+			st.AvgDivergence = sums[id] / float64(st.Measured)
+		}
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].TenantID < stats[j].TenantID })
+	return stats, nil
+}
+
+func formatStatsTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(statsTimeLayout)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEvaluationStats(t *testing.T) {
+	s, _, dbConn, _ := newProtocolTestServiceDB(t, &fakeProvider{})
+	ctx := context.Background()
+
+	if _, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t2', 'Tenant 2');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (2, 't2', 'b@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, total_tokens) VALUES
+			('c1', 't1', 1, 'p', 'completed', 100),
+			('c2', 't1', 1, 'p', 'completed', 200),
+			('c3', 't1', 1, 'p', 'completed', 0),
+			('f1', 't1', 1, 'p', 'failed', 50),
+			('c4', 't2', 2, 'p', 'completed', 70);
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, created_at) VALUES
+			('old', 't1', 1, 'p', 'failed', datetime('now', '-30 days'));
+		INSERT INTO audits (id, evaluation_id, divergencia, diagnostico) VALUES
+			('a1', 'c1', 0.1, 'Resistência Estrutural'),
+			('a2', 'c2', 0.4, 'Alucinação Confirmada'),
+			('a3', 'c3', 0, 'Indeterminado (embedding indisponível)'),
+			('a4', 'c4', 0.2, 'Resistência Estrutural');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.EvaluationStats(ctx, "t1", time.Now().Add(-24*time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].TenantID != "t1" {
+		t.Fatalf("stats = %+v, want only t1", stats)
+	}
+	st := stats[0]
+
+	// A avaliação de newProtocolTestServiceDB fica pending; "old" está fora do período
+	if st.Total != 5 || st.ByStatus["completed"] != 3 || st.ByStatus["failed"] != 1 || st.ByStatus["pending"] != 1 {
+		t.Errorf("status counts = %v (total %d)", st.ByStatus, st.Total)
+	}
+	if st.ByDiagnosis[DiagnosisResistant] != 1 || st.ByDiagnosis[DiagnosisIndeterminate] != 1 {
+		t.Errorf("diagnosis counts = %v", st.ByDiagnosis)
+	}
+	// Indeterminado não entra na média
+	if st.Measured != 2 || math.Abs(st.AvgDivergence-0.25) > 1e-9 {
+		t.Errorf("avg divergence = %v over %d, want 0.25 over 2", st.AvgDivergence, st.Measured)
+	}
+	if st.TotalTokens != 350 {
+		t.Errorf("total tokens = %d, want 350", st.TotalTokens)
+	}
+	if got := st.FailureRate(); math.Abs(got-0.25) > 1e-9 {
+		t.Errorf("failure rate = %v, want 0.25", got)
+	}
+
+	all, err := s.EvaluationStats(ctx, "", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].TenantID != "t1" || all[1].TenantID != "t2" {
+		t.Fatalf("all tenants = %+v", all)
+	}
+	if all[0].ByStatus["failed"] != 2 {
+		t.Errorf("without period t1 should include the old failure, got %v", all[0].ByStatus)
+	}
+	if all[1].Total != 1 || all[1].AvgDivergence != 0.2 {
+		t.Errorf("t2 = %+v", all[1])
+	}
+}
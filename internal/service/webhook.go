@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// enqueueCompletionWebhook enfileira um job send_webhook com o resultado da
+// avaliação quando o tenant tem webhook configurado. A entrega, assinatura,
+// retry e registro em webhook_deliveries ficam a cargo do worker.
+func (s *EvaluationService) enqueueCompletionWebhook(ctx context.Context, evalID string, divergencia float64, diagnostico string) error {
+	eval, err := s.q.GetEvaluationByID(ctx, evalID)
+	if err != nil {
+		return fmt.Errorf("failed to get evaluation: %w", err)
+	}
+
+	settings, err := s.q.GetTenantSettings(ctx, eval.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant settings: %w", err)
+	}
+	cfg, err := webhook.ParseTenantConfig(settings)
+	if err != nil {
+		return err
+	}
+	if cfg.URL == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"delivery_id": uuid.New().String(),
+		"tenant_id":   eval.TenantID,
+		"event":       webhook.EventEvaluationCompleted,
+		"data": map[string]interface{}{
+			"evaluation_id": evalID,
+			"divergence":    divergencia,
+			"diagnosis":     diagnostico,
+			"metric":        s.metricName(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.q.CreateJob(ctx, db.CreateJobParams{
+		TenantID:     sql.NullString{String: eval.TenantID, Valid: true},
+		Type:         "send_webhook",
+		Payload:      payload,
+		RunAt:        sql.NullTime{Time: time.Now(), Valid: true},
+		EvaluationID: sql.NullString{String: evalID, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue webhook job: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,13 @@
 package sse
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Client represents a connected SSE client
@@ -16,13 +19,92 @@ type Client struct {
 type Broker struct {
 	clients map[string]map[*Client]bool // resourceKey -> clients
 	mutex   sync.RWMutex
+	bus     EventBus
+	// maxLifetime fecha cada conexão do Handler após esse tempo (0 = sem limite)
+	maxLifetime time.Duration
+	// allowedOrigins são as origens cross-origin aceitas pelo Handler (vazio = só same-origin)
+	allowedOrigins map[string]bool
+	// heartbeatInterval espaça os eventos HeartbeatEvent do Handler (0 = desativado)
+	heartbeatInterval time.Duration
 }
 
-// NewBroker creates a new global SSE broker
+// ReconnectEvent é enviado antes de o Handler fechar uma conexão que atingiu o
+// tempo máximo; o browser (EventSource) reabre a conexão após ReconnectDelay
+const (
+	ReconnectEvent = "reconnect"
+	ReconnectDelay = time.Second
+)
+
+// HeartbeatEvent é o evento nomeado enviado periodicamente pelo Handler quando
+// configurado. Não entra em nenhum sse-swap: a UI só o escuta para mostrar que
+// a conexão está viva. O data é o horário do servidor (Unix, segundos), já que
+// o EventSource descarta eventos sem data.
+const HeartbeatEvent = "heartbeat"
+
+// NewBroker creates a new global SSE broker backed by an in-process bus
 func NewBroker() *Broker {
-	return &Broker{
+	b, _ := NewBrokerWithBus(NewLocalBus())
+	return b
+}
+
+// NewBrokerWithBus creates a broker that publishes events through bus and
+// delivers to its local clients whatever arrives from it (ex: other replicas)
+func NewBrokerWithBus(bus EventBus) (*Broker, error) {
+	b := &Broker{
 		clients: make(map[string]map[*Client]bool),
+		bus:     bus,
+	}
+
+	if err := bus.Subscribe(b.deliverLocal); err != nil {
+		return nil, err
 	}
+
+	return b, nil
+}
+
+// SetMaxLifetime limita a duração de cada conexão SSE aberta pelo Handler
+// (0 = sem limite). Conexões zumbi de clientes que sumiram sem fechar o socket
+// deixam de se acumular; clientes ativos apenas reconectam.
+func (b *Broker) SetMaxLifetime(d time.Duration) {
+	b.maxLifetime = d
+}
+
+// SetHeartbeatInterval liga o envio de HeartbeatEvent a cada d em todas as
+// conexões do Handler (0 = desativado, o padrão)
+func (b *Broker) SetHeartbeatInterval(d time.Duration) {
+	b.heartbeatInterval = d
+}
+
+// SetAllowedOrigins define as origens que podem abrir o stream a partir de
+// outro domínio. A origem da requisição é refletida em
+// Access-Control-Allow-Origin junto com Allow-Credentials, então o cookie de
+// sessão vai junto; origens fora da lista não recebem cabeçalhos CORS.
+func (b *Broker) SetAllowedOrigins(origins []string) {
+	b.allowedOrigins = make(map[string]bool, len(origins))
+	for _, o := range origins {
+		b.allowedOrigins[strings.TrimSuffix(o, "/")] = true
+	}
+}
+
+// corsHeaders aplica CORS para uma origem da allowlist e informa se a
+// requisição cross-origin é permitida (sem Origin não há o que checar)
+func (b *Broker) corsHeaders(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if !b.allowedOrigins[origin] {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	return true
+}
+
+// Close releases the underlying event bus
+func (b *Broker) Close() error {
+	return b.bus.Close()
 }
 
 // GetResourceKey creates a unique key for a resource
@@ -52,7 +134,7 @@ func (b *Broker) Subscribe(resourceType, resourceID string) *Client {
 // Unsubscribe removes a client
 func (b *Broker) Unsubscribe(client *Client, resourceType, resourceID string) {
 	key := b.GetResourceKey(resourceType, resourceID)
-	
+
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
@@ -69,9 +151,6 @@ func (b *Broker) Unsubscribe(client *Client, resourceType, resourceID string) {
 func (b *Broker) SendHTML(resourceType, resourceID, eventType, html string) {
 	key := b.GetResourceKey(resourceType, resourceID)
 
-	b.mutex.RLock()
-	defer b.mutex.RUnlock()
-
 	// Format multi-line data correctly for SSE
 	var formattedData string
 	lines := strings.Split(html, "\n")
@@ -84,6 +163,17 @@ func (b *Broker) SendHTML(resourceType, resourceID, eventType, html string) {
 
 	message := fmt.Sprintf("event: %s\n%s\n\n", eventType, formattedData)
 
+	if err := b.bus.Publish(context.Background(), key, message); err != nil {
+		// Bus indisponível: ao menos os clientes desta réplica recebem o evento
+		b.deliverLocal(key, message)
+	}
+}
+
+// deliverLocal sends an already formatted message to the clients connected to this instance
+func (b *Broker) deliverLocal(key, message string) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
 	for client := range b.clients[key] {
 		select {
 		case client.Events <- message:
@@ -104,14 +194,52 @@ func (b *Broker) SendEvaluationComplete(evaluationID, html string) {
 	b.SendHTML("evaluation", evaluationID, "evaluation_complete", html)
 }
 
+// SendEvaluationRetrying sends the retry wait status (rate limit, next attempt time)
+func (b *Broker) SendEvaluationRetrying(evaluationID, html string) {
+	b.SendHTML("evaluation", evaluationID, "evaluation_retrying", html)
+}
+
 // SendEvaluationError sends error HTML
 func (b *Broker) SendEvaluationError(evaluationID, html string) {
 	b.SendHTML("evaluation", evaluationID, "evaluation_error", html)
 }
 
+// SendJobProgress sends a progress update of a background job to its owner
+// (type=user, e.g. the dashboard) and to the job's own channel (type=job)
+func (b *Broker) SendJobProgress(userID, jobID int64, html string) {
+	b.sendJobEvent(userID, jobID, "job_progress", html)
+}
+
+// SendJobComplete sends the final status of a background job to its owner and job channel
+func (b *Broker) SendJobComplete(userID, jobID int64, html string) {
+	b.sendJobEvent(userID, jobID, "job_completed", html)
+}
+
+func (b *Broker) sendJobEvent(userID, jobID int64, eventType, html string) {
+	b.SendHTML("job", strconv.FormatInt(jobID, 10), eventType, html)
+	if userID != 0 {
+		b.SendHTML("user", strconv.FormatInt(userID, 10), eventType, html)
+	}
+}
+
 // Handler returns HTTP handler for SSE connections
 func (b *Broker) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := b.corsHeaders(w, r)
+
+		// Preflight: EventSource não costuma disparar, mas fetch com headers sim
+		if r.Method == http.MethodOptions {
+			if !allowed {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+			w.Header().Set("Access-Control-Allow-Headers", "Cache-Control, Last-Event-ID")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
 		resourceType := r.URL.Query().Get("type")
 		resourceID := r.URL.Query().Get("id")
 
@@ -125,7 +253,6 @@ func (b *Broker) Handler() http.HandlerFunc {
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
 		w.Header().Set("X-Accel-Buffering", "no")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		flusher, ok := w.(http.Flusher)
 		if !ok {
@@ -133,6 +260,10 @@ func (b *Broker) Handler() http.HandlerFunc {
 			return
 		}
 
+		// A conexão SSE dura a avaliação inteira: remove o WriteTimeout do servidor
+		// apenas para esta resposta. Writers sem suporte (ex: testes) seguem sem deadline.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
 		// Subscribe
 		client := b.Subscribe(resourceType, resourceID)
 		defer b.Unsubscribe(client, resourceType, resourceID)
@@ -141,6 +272,21 @@ func (b *Broker) Handler() http.HandlerFunc {
 		fmt.Fprintf(w, ": ok\n\n")
 		flusher.Flush()
 
+		// Canal nil nunca dispara: sem limite de duração
+		var expired <-chan time.Time
+		if b.maxLifetime > 0 {
+			timer := time.NewTimer(b.maxLifetime)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		var heartbeat <-chan time.Time
+		if b.heartbeatInterval > 0 {
+			ticker := time.NewTicker(b.heartbeatInterval)
+			defer ticker.Stop()
+			heartbeat = ticker.C
+		}
+
 		// Stream events
 		for {
 			select {
@@ -150,6 +296,14 @@ func (b *Broker) Handler() http.HandlerFunc {
 				}
 				fmt.Fprint(w, message)
 				flusher.Flush()
+			case now := <-heartbeat:
+				fmt.Fprintf(w, "event: %s\ndata: %d\n\n", HeartbeatEvent, now.Unix())
+				flusher.Flush()
+			case <-expired:
+				fmt.Fprintf(w, "retry: %d\nevent: %s\ndata: max lifetime reached\n\n",
+					ReconnectDelay.Milliseconds(), ReconnectEvent)
+				flusher.Flush()
+				return
 			case <-r.Context().Done():
 				return
 			}
@@ -0,0 +1,228 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBroker_SendHTMLThroughBus(t *testing.T) {
+	bus := NewLocalBus()
+
+	// Dois brokers no mesmo bus simulam réplicas distintas
+	replicaA, err := NewBrokerWithBus(bus)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replicaB, err := NewBrokerWithBus(bus)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := replicaB.Subscribe("evaluation", "abc")
+	defer replicaB.Unsubscribe(client, "evaluation", "abc")
+
+	replicaA.SendHTML("evaluation", "abc", "evaluation_progress", "<p>a</p>\n<p>b</p>")
+
+	select {
+	case msg := <-client.Events:
+		if !strings.HasPrefix(msg, "event: evaluation_progress\n") {
+			t.Errorf("unexpected event header: %q", msg)
+		}
+		if !strings.Contains(msg, "data: <p>a</p>\ndata: <p>b</p>\n\n") {
+			t.Errorf("unexpected event data: %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered to client on other broker")
+	}
+}
+
+func TestBroker_SendJobProgress(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+
+	userClient := broker.Subscribe("user", "7")
+	defer broker.Unsubscribe(userClient, "user", "7")
+	jobClient := broker.Subscribe("job", "42")
+	defer broker.Unsubscribe(jobClient, "job", "42")
+
+	broker.SendJobProgress(7, 42, "<p>50%</p>")
+
+	for name, client := range map[string]*Client{"user": userClient, "job": jobClient} {
+		select {
+		case msg := <-client.Events:
+			if msg != "event: job_progress\ndata: <p>50%</p>\n\n" {
+				t.Errorf("%s channel: unexpected message %q", name, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s channel: event not delivered", name)
+		}
+	}
+}
+
+func TestBroker_HandlerOutlivesServerWriteTimeout(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+
+	srv := httptest.NewUnstartedServer(broker.Handler())
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?type=evaluation&id=abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": ok\n" {
+		t.Fatalf("unexpected handshake: %q", line)
+	}
+
+	// Publica depois de estourar o WriteTimeout do servidor
+	time.Sleep(300 * time.Millisecond)
+	broker.SendHTML("evaluation", "abc", "evaluation_progress", "<p>ok</p>")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream closed before event: %v", err)
+		}
+		if line == "event: evaluation_progress\n" {
+			return
+		}
+	}
+}
+
+func TestBroker_HandlerMaxLifetime(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+	broker.SetMaxLifetime(100 * time.Millisecond)
+
+	srv := httptest.NewServer(broker.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?type=evaluation&id=abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(body), "event: reconnect\n") {
+		t.Fatalf("expected reconnect event before close, got %q", body)
+	}
+	if !strings.Contains(string(body), "retry: 1000\n") {
+		t.Errorf("expected retry hint, got %q", body)
+	}
+}
+
+func TestBroker_HandlerMaxLifetimeRespectsCancel(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+	broker.SetMaxLifetime(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/?type=evaluation&id=abc", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		broker.Handler().ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not return after context cancellation")
+	}
+}
+
+func TestBroker_HandlerCORS(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+	broker.SetAllowedOrigins([]string{"https://app.example.com/"})
+
+	// Preflight de origem permitida
+	req := httptest.NewRequest(http.MethodOptions, "/sse", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+	broker.Handler()(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want 204", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Allow-Origin = %q, want the request origin", got)
+	}
+	if rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("allowed origin should receive Allow-Credentials")
+	}
+
+	// Origem fora da lista: preflight recusado e stream sem cabeçalhos CORS
+	req = httptest.NewRequest(http.MethodOptions, "/sse", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rr = httptest.NewRecorder()
+	broker.Handler()(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("preflight status = %d, want 403", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sse", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rr = httptest.NewRecorder()
+	broker.Handler()(rr, req)
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got Allow-Origin %q", got)
+	}
+}
+
+func TestBroker_HandlerHeartbeat(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close()
+	broker.SetHeartbeatInterval(20 * time.Millisecond)
+	broker.SetMaxLifetime(200 * time.Millisecond)
+
+	srv := httptest.NewServer(broker.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?type=evaluation&id=abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(body), "event: heartbeat\ndata: ") {
+		t.Fatalf("expected heartbeat event, got %q", body)
+	}
+
+	// Desativado por padrão
+	quiet := NewBroker()
+	defer quiet.Close()
+	quiet.SetMaxLifetime(100 * time.Millisecond)
+	srv2 := httptest.NewServer(quiet.Handler())
+	defer srv2.Close()
+	resp2, err := http.Get(srv2.URL + "?type=evaluation&id=abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp2.Body.Close()
+	body, _ = io.ReadAll(resp2.Body)
+	if strings.Contains(string(body), "heartbeat") {
+		t.Errorf("heartbeat sent without interval configured: %q", body)
+	}
+}
@@ -0,0 +1,128 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EventBus propaga eventos SSE entre instâncias do Broker.
+// Publish envia uma mensagem já formatada para todas as réplicas (inclusive a local);
+// cada Broker registra um handler via Subscribe que reenvia a mensagem aos seus clientes.
+type EventBus interface {
+	Publish(ctx context.Context, key, message string) error
+	Subscribe(handler func(key, message string)) error
+	Close() error
+}
+
+// LocalBus entrega eventos apenas para o processo atual (comportamento de réplica única)
+type LocalBus struct {
+	mu       sync.RWMutex
+	handlers []func(key, message string)
+}
+
+// NewLocalBus creates an in-process event bus
+func NewLocalBus() *LocalBus {
+	return &LocalBus{}
+}
+
+func (b *LocalBus) Publish(_ context.Context, key, message string) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, h := range b.handlers {
+		h(key, message)
+	}
+	return nil
+}
+
+func (b *LocalBus) Subscribe(handler func(key, message string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *LocalBus) Close() error {
+	return nil
+}
+
+// RedisChannel é o canal pub/sub usado para propagar eventos SSE
+const RedisChannel = "elenchus:sse"
+
+type redisEnvelope struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// RedisBus propaga eventos via Redis Pub/Sub para escala horizontal:
+// o evento publicado por qualquer réplica chega aos clientes de todas as réplicas.
+type RedisBus struct {
+	client *redis.Client
+	mu     sync.Mutex
+	subs   []*redis.PubSub
+}
+
+// NewRedisBus connects to Redis using a URL (ex: redis://localhost:6379/0)
+func NewRedisBus(ctx context.Context, url string) (*RedisBus, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisBus{client: client}, nil
+}
+
+func (b *RedisBus) Publish(ctx context.Context, key, message string) error {
+	payload, err := json.Marshal(redisEnvelope{Key: key, Message: message})
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, RedisChannel, payload).Err()
+}
+
+func (b *RedisBus) Subscribe(handler func(key, message string)) error {
+	pubsub := b.client.Subscribe(context.Background(), RedisChannel)
+	// Garante que a inscrição foi confirmada antes de retornar
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", RedisChannel, err)
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, pubsub)
+	b.mu.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var env redisEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				continue
+			}
+			handler(env.Key, env.Message)
+		}
+	}()
+
+	return nil
+}
+
+func (b *RedisBus) Close() error {
+	b.mu.Lock()
+	for _, s := range b.subs {
+		_ = s.Close()
+	}
+	b.subs = nil
+	b.mu.Unlock()
+
+	return b.client.Close()
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ObjectStorage é o ponto de extensão para S3 e GCS: as URLs já seguem o
+// formato final (publicURL + key), mas Put e Delete ainda não têm cliente e
+// retornam ErrNotImplemented.
+type ObjectStorage struct {
+	backend   string
+	bucket    string
+	publicURL string
+}
+
+// NewObjectStorage creates an object storage for backend (BackendS3 or
+// BackendGCS). publicURL defaults to the bucket's public endpoint.
+func NewObjectStorage(backend, bucket, publicURL string) (*ObjectStorage, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("storage %s requer STORAGE_BUCKET", backend)
+	}
+	if publicURL == "" {
+		switch backend {
+		case BackendS3:
+			publicURL = fmt.Sprintf("https://%s.s3.amazonaws.com/", bucket)
+		case BackendGCS:
+			publicURL = fmt.Sprintf("https://storage.googleapis.com/%s/", bucket)
+		default:
+			return nil, fmt.Errorf("unknown storage backend: %q", backend)
+		}
+	}
+	if !strings.HasSuffix(publicURL, "/") {
+		publicURL += "/"
+	}
+	return &ObjectStorage{backend: backend, bucket: bucket, publicURL: publicURL}, nil
+}
+
+func (s *ObjectStorage) Put(_ context.Context, key string, _ io.Reader, _ string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotImplemented, s.backend)
+}
+
+func (s *ObjectStorage) Delete(_ context.Context, key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return fmt.Errorf("%w: %s", ErrNotImplemented, s.backend)
+}
+
+func (s *ObjectStorage) KeyFromURL(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, s.publicURL)
+	return key, ok && validKey(key)
+}
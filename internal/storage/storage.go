@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Backends aceitos em STORAGE_BACKEND
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+	BackendGCS   = "gcs"
+)
+
+// ErrNotImplemented indica um backend reconhecido mas ainda sem cliente
+var ErrNotImplemented = errors.New("storage backend not implemented")
+
+// ErrInvalidKey indica uma chave vazia ou que sairia do espaço do backend
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// BlobStorage guarda arquivos enviados pelos usuários (avatars). As chaves são
+// caminhos relativos com "/" (ex: "avatars/1.png"); a URL devolvida por Put é a
+// pública do backend e é o que fica gravado no banco.
+type BlobStorage interface {
+	// Put grava (ou sobrescreve) key e retorna a URL pública do arquivo
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	// Delete remove key; chave inexistente não é erro
+	Delete(ctx context.Context, key string) error
+	// KeyFromURL é o inverso de Put: a chave de uma URL gerada por este backend
+	KeyFromURL(url string) (string, bool)
+}
+
+// validKey recusa chaves absolutas, vazias ou com segmentos "." e ".."
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "storage")
+	s := NewLocalStorage(dir, "/storage/")
+
+	// O diretório é criado sob demanda
+	url, err := s.Put(ctx, "avatars/1.png", strings.NewReader("png"), "image/png")
+	if err != nil {
+		t.Fatalf("Put falhou: %v", err)
+	}
+	if url != "/storage/avatars/1.png" {
+		t.Errorf("URL = %q", url)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "1.png"))
+	if err != nil || string(data) != "png" {
+		t.Fatalf("arquivo gravado = %q, err=%v", data, err)
+	}
+
+	key, ok := s.KeyFromURL(url)
+	if !ok || key != "avatars/1.png" {
+		t.Errorf("KeyFromURL = %q, %v", key, ok)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete falhou: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "avatars", "1.png")); !os.IsNotExist(err) {
+		t.Errorf("arquivo deveria ter sido removido, stat err=%v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("arquivo inexistente não deveria ser erro: %v", err)
+	}
+
+	for _, key := range []string{"../outside.png", "/etc/passwd", "avatars/../../x.png", ""} {
+		if _, err := s.Put(ctx, key, strings.NewReader("x"), ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): esperado ErrInvalidKey, got %v", key, err)
+		}
+		if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q): esperado ErrInvalidKey, got %v", key, err)
+		}
+	}
+	for _, url := range []string{"https://cdn.example.com/avatars/1.png", "/storage/../secret.png"} {
+		if _, ok := s.KeyFromURL(url); ok {
+			t.Errorf("KeyFromURL(%q) deveria ser recusada", url)
+		}
+	}
+}
+
+func TestObjectStorage(t *testing.T) {
+	if _, err := NewObjectStorage(BackendS3, "", ""); err == nil {
+		t.Error("bucket vazio deveria falhar")
+	}
+
+	s, err := NewObjectStorage(BackendGCS, "elenchus", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := s.KeyFromURL("https://storage.googleapis.com/elenchus/avatars/1.png")
+	if !ok || key != "avatars/1.png" {
+		t.Errorf("KeyFromURL = %q, %v", key, ok)
+	}
+
+	if _, err := s.Put(context.Background(), "avatars/1.png", strings.NewReader("x"), "image/png"); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Put: esperado ErrNotImplemented, got %v", err)
+	}
+	if err := s.Delete(context.Background(), "avatars/1.png"); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Delete: esperado ErrNotImplemented, got %v", err)
+	}
+
+	s3, err := NewObjectStorage(BackendS3, "b", "https://cdn.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := s3.KeyFromURL("https://cdn.example.com/avatars/2.jpg"); !ok || key != "avatars/2.jpg" {
+		t.Errorf("KeyFromURL com STORAGE_PUBLIC_URL = %q, %v", key, ok)
+	}
+}
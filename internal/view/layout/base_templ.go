@@ -60,7 +60,7 @@ func Base(title string, tenant db.Tenant) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><script src=\"/assets/js/htmx.min.js\"></script><script src=\"/assets/js/sse.js\"></script><script defer src=\"/assets/js/alpine.min.js\"></script><link href=\"/assets/styles.css\" rel=\"stylesheet\"><script>\n    (function() {\n      const setupCSRF = (event) => {\n        const csrfMeta = document.querySelector('meta[name=\"csrf-token\"]');\n        if (csrfMeta) {\n          event.detail.headers['X-CSRF-Token'] = csrfMeta.content;\n        }\n      };\n\n      if (document.body) {\n        document.body.addEventListener('htmx:configRequest', setupCSRF);\n      } else {\n        document.addEventListener('DOMContentLoaded', () => {\n          document.body.addEventListener('htmx:configRequest', setupCSRF);\n        });\n      }\n    })();\n  </script><style>\n    :root {\n      --color-primary: #3b82f6;\n      --color-bg: #ffffff;\n    }\n  </style></head><body class=\"bg-[var(--color-bg)] text-gray-900\" hx-ext=\"sse\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><script src=\"/assets/js/htmx.min.js\"></script><script src=\"/assets/js/sse.js\"></script><script defer src=\"/assets/js/alpine.min.js\"></script><link href=\"/assets/styles.css\" rel=\"stylesheet\"><script>\n    (function() {\n      const setupCSRF = (event) => {\n        const csrfMeta = document.querySelector('meta[name=\"csrf-token\"]');\n        if (csrfMeta) {\n          event.detail.headers['X-CSRF-Token'] = csrfMeta.content;\n        }\n      };\n\n      // O htmx não troca respostas 4xx; fragmentos HTML de 404 (ex: avaliação\n      // não encontrada) substituem o alvo, texto puro continua ignorado\n      const swapNotFound = (event) => {\n        const xhr = event.detail.xhr;\n        const contentType = xhr.getResponseHeader('Content-Type') || '';\n        if (xhr.status === 404 && contentType.startsWith('text/html')) {\n          event.detail.shouldSwap = true;\n          event.detail.isError = false;\n        }\n      };\n\n      // Heartbeats SSE não têm sse-swap: só atualizam o indicador de conexão\n      // (.sse-status) do SSEContainer que abriu o stream\n      const sseStatus = (event) => event.target.closest('.sse-container')?.querySelector('.sse-status');\n      const watchHeartbeat = (event) => {\n        const status = sseStatus(event);\n        const source = event.detail?.source;\n        if (status && source) {\n          source.addEventListener('heartbeat', () => {\n            status.textContent = '🟢 Conectado';\n            status.classList.remove('hidden');\n          });\n        }\n      };\n      const markReconnecting = (event) => {\n        const status = sseStatus(event);\n        if (status && !status.classList.contains('hidden')) {\n          status.textContent = '🟡 Reconectando...';\n        }\n      };\n\n      const register = () => {\n        document.body.addEventListener('htmx:configRequest', setupCSRF);\n        document.body.addEventListener('htmx:beforeSwap', swapNotFound);\n        document.body.addEventListener('htmx:sseOpen', watchHeartbeat);\n        document.body.addEventListener('htmx:sseError', markReconnecting);\n      };\n\n      if (document.body) {\n        register();\n      } else {\n        document.addEventListener('DOMContentLoaded', register);\n      }\n    })();\n  </script><style>\n    :root {\n      --color-primary: #3b82f6;\n      --color-bg: #ffffff;\n    }\n  </style></head><body class=\"bg-[var(--color-bg)] text-gray-900\" hx-ext=\"sse\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
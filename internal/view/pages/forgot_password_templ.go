@@ -83,7 +83,7 @@ func ForgotPassword(message string) templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"><div class=\"mb-4\"><label class=\"block text-sm font-medium mb-1\">E-mail</label> <input type=\"email\" name=\"email\" required class=\"w-full border rounded p-2\"></div><button type=\"submit\" class=\"w-full bg-black text-white p-2 rounded hover:bg-gray-800\">Enviar Link de Recuperação</button></form><div class=\"mt-4 text-center\"><a href=\"/login\" class=\"text-sm text-gray-600 hover:underline\">Voltar para o Login</a></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"><div class=\"mb-4\"><label class=\"block text-sm font-medium mb-1\">E-mail</label> <input type=\"email\" name=\"email\" required class=\"w-full border rounded p-2\"></div><div class=\"mb-4\"><label class=\"block text-sm font-medium mb-1\">Organização <span class=\"text-gray-400\">(opcional)</span></label> <input type=\"text\" name=\"tenant\" class=\"w-full border rounded p-2\"></div><button type=\"submit\" class=\"w-full bg-black text-white p-2 rounded hover:bg-gray-800\">Enviar Link de Recuperação</button></form><div class=\"mt-4 text-center\"><a href=\"/login\" class=\"text-sm text-gray-600 hover:underline\">Voltar para o Login</a></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -142,7 +142,7 @@ func ResetPassword(token, message string) templ.Component {
 				var templ_7745c5c3_Var7 string
 				templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(message)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/view/pages/forgot_password.templ`, Line: 38, Col: 67}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/view/pages/forgot_password.templ`, Line: 42, Col: 67}
 				}
 				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
 				if templ_7745c5c3_Err != nil {
@@ -160,7 +160,7 @@ func ResetPassword(token, message string) templ.Component {
 			var templ_7745c5c3_Var8 string
 			templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(view.CSRFToken(ctx))
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/view/pages/forgot_password.templ`, Line: 41, Col: 78}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/view/pages/forgot_password.templ`, Line: 45, Col: 78}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 			if templ_7745c5c3_Err != nil {
@@ -173,7 +173,7 @@ func ResetPassword(token, message string) templ.Component {
 			var templ_7745c5c3_Var9 string
 			templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(token)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/view/pages/forgot_password.templ`, Line: 42, Col: 51}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/view/pages/forgot_password.templ`, Line: 46, Col: 51}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
 			if templ_7745c5c3_Err != nil {
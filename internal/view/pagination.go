@@ -1,6 +1,9 @@
 package view
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 type Pagination struct {
 	CurrentPage int
@@ -26,6 +29,11 @@ func (p Pagination) TotalPages() int {
 	return int(math.Ceil(float64(p.TotalItems) / float64(p.PerPage)))
 }
 
+// PageURL monta o link para uma página preservando o per_page escolhido
+func (p Pagination) PageURL(baseURL string, page int) string {
+	return fmt.Sprintf("%s?page=%d&per_page=%d", baseURL, page, p.PerPage)
+}
+
 func (p Pagination) HasPrevious() bool {
 	return p.CurrentPage > 1
 }
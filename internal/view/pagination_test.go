@@ -42,3 +42,10 @@ func TestPagination_Navigation(t *testing.T) {
 		t.Errorf("NextPage() = %v, want 3", p.NextPage())
 	}
 }
+
+func TestPagination_PageURL(t *testing.T) {
+	p := NewPagination(1, 100, 25)
+	if got := p.PageURL("/dashboard", 2); got != "/dashboard?page=2&per_page=25" {
+		t.Errorf("PageURL() = %q", got)
+	}
+}
@@ -0,0 +1,378 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/logging"
+	"github.com/PauloHFS/elenchus/internal/service"
+)
+
+type evaluationLogEntry struct {
+	Time    time.Time       `json:"time"`
+	Level   string          `json:"level"`
+	Message string          `json:"message"`
+	Attrs   json.RawMessage `json:"attrs,omitempty"`
+}
+
+// handleAdminEvaluationLogs exporta os eventos de log (trace) de uma avaliação
+// @Summary Logs de uma avaliação
+// @Description Retorna os eventos de log estruturados gravados com o evaluation_id informado. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param download query bool false "Força download como arquivo"
+// @Success 200 {array} evaluationLogEntry
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Router /admin/evaluations/{id}/logs [get]
+func handleAdminEvaluationLogs(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	evalID := r.PathValue("id")
+
+	if _, err := deps.Queries.GetEvaluationByID(r.Context(), evalID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Avaliação não encontrada", http.StatusNotFound)
+			return nil
+		}
+		return fmt.Errorf("failed to get evaluation: %w", err)
+	}
+
+	logs, err := deps.Queries.ListEvaluationLogs(r.Context(), evalID)
+	if err != nil {
+		return fmt.Errorf("failed to list evaluation logs: %w", err)
+	}
+
+	entries := make([]evaluationLogEntry, 0, len(logs))
+	for _, l := range logs {
+		entries = append(entries, evaluationLogEntry{
+			Time:    l.CreatedAt.Time,
+			Level:   l.Level,
+			Message: l.Message,
+			Attrs:   json.RawMessage(l.Attrs),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="evaluation-%s-logs.json"`, evalID))
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// jobStatuses são os estados válidos para o filtro de /admin/jobs
+var jobStatuses = map[string]bool{"pending": true, "processing": true, "completed": true, "failed": true, "cancelled": true}
+
+type adminJobEntry struct {
+	ID           int64      `json:"id"`
+	TenantID     string     `json:"tenant_id,omitempty"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"`
+	AttemptCount int64      `json:"attempt_count"`
+	MaxAttempts  int64      `json:"max_attempts"`
+	LastError    string     `json:"last_error,omitempty"`
+	RunAt        *time.Time `json:"run_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type adminJobsResponse struct {
+	Jobs       []adminJobEntry `json:"jobs"`
+	Page       int             `json:"page"`
+	PerPage    int             `json:"per_page"`
+	TotalItems int             `json:"total_items"`
+	TotalPages int             `json:"total_pages"`
+}
+
+type adminAccessDeniedEntry struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	TenantID   string    `json:"tenant_id"`
+	Resource   string    `json:"resource"`
+	ResourceID string    `json:"resource_id,omitempty"`
+	Action     string    `json:"action"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type adminReauditResponse struct {
+	EvaluationID string `json:"evaluation_id"`
+	JobID        int64  `json:"job_id"`
+}
+
+// handleAdminReauditEvaluation reexecuta só a purga/auditoria de uma avaliação falha
+// @Summary Reaudita uma avaliação
+// @Description Re-enfileira uma avaliação que falhou na purga/auditoria. O worker retoma do checkpoint com a divergência, o diagnóstico e as mensagens já persistidos, sem regenerar as fases anteriores. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Success 202 {object} adminReauditResponse
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Avaliação não pode ser reauditada"
+// @Router /admin/evaluations/{id}/reaudit [post]
+func handleAdminReauditEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	eval, err := deps.Queries.GetEvaluationByID(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Avaliação não encontrada", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get evaluation: %w", err)
+	}
+
+	evalService, err := evaluationService(deps)
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	jobID, err := evalService.ReauditEvaluation(r.Context(), eval)
+	if errors.Is(err, service.ErrReauditUnavailable) {
+		http.Error(w, "Avaliação não pode ser reauditada: apenas avaliações falhas com checkpoint na purga", http.StatusConflict)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reaudit evaluation: %w", err)
+	}
+	wakeWorker(deps)
+
+	logging.AddToEvent(r.Context(), slog.String(logging.EvaluationIDKey, eval.ID), slog.Int64("reaudit_job_id", jobID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(adminReauditResponse{EvaluationID: eval.ID, JobID: jobID})
+}
+
+// handleAdminJobs lista a fila de jobs para diagnóstico. O payload não é
+// exposto: jobs de email carregam tokens de verificação e de reset de senha.
+// @Summary Fila de jobs
+// @Description Lista jobs paginados, do mais recente ao mais antigo, com filtros opcionais por tipo e status. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Param type query string false "Tipo do job (ex: run_evaluation, send_email)"
+// @Param status query string false "pending, processing, completed, failed ou cancelled"
+// @Param page query int false "Página (padrão 1)"
+// @Param per_page query int false "Itens por página (padrão 20, máximo 100)"
+// @Success 200 {object} adminJobsResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/jobs [get]
+func handleAdminJobs(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	paging := db.ParsePagingParams(query, 20)
+
+	jobType := query.Get("type")
+	status := query.Get("status")
+	if status != "" && !jobStatuses[status] {
+		http.Error(w, "Status inválido", http.StatusBadRequest)
+		return nil
+	}
+
+	jobs, err := deps.Queries.ListJobsPaginated(r.Context(), db.ListJobsPaginatedParams{
+		Type:   jobType,
+		Status: status,
+		Limit:  int64(paging.Limit()),
+		Offset: int64(paging.Offset()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	total, err := deps.Queries.CountJobsFiltered(r.Context(), db.CountJobsFilteredParams{
+		Type:   jobType,
+		Status: status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	result := db.PagedResult[db.ListJobsPaginatedRow]{
+		Items:       jobs,
+		TotalItems:  int(total),
+		CurrentPage: paging.Page,
+		PerPage:     paging.PerPage,
+	}
+
+	resp := adminJobsResponse{
+		Jobs:       make([]adminJobEntry, 0, len(jobs)),
+		Page:       result.CurrentPage,
+		PerPage:    result.PerPage,
+		TotalItems: result.TotalItems,
+		TotalPages: result.TotalPages(),
+	}
+	for _, j := range jobs {
+		entry := adminJobEntry{
+			ID:           j.ID,
+			TenantID:     j.TenantID.String,
+			Type:         j.Type,
+			Status:       j.Status,
+			AttemptCount: j.AttemptCount.Int64,
+			MaxAttempts:  j.MaxAttempts.Int64,
+			LastError:    j.LastError.String,
+			CreatedAt:    j.CreatedAt.Time,
+			UpdatedAt:    j.UpdatedAt.Time,
+		}
+		if j.RunAt.Valid {
+			entry.RunAt = &j.RunAt.Time
+		}
+		resp.Jobs = append(resp.Jobs, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// handleAdminCancelJob cancela um job que ainda não foi pego pelo worker.
+// Cancelar um run_evaluation marca a avaliação como falha para ela não ficar
+// pendente para sempre.
+// @Summary Cancela job pendente
+// @Description Marca o job como cancelled se ele ainda estiver pending; PickNextJob ignora jobs cancelados. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID do job"
+// @Success 200 {object} adminJobEntry
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Job não está pendente"
+// @Router /admin/jobs/{id}/cancel [post]
+func handleAdminCancelJob(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	jobID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Job não encontrado", http.StatusNotFound)
+		return nil
+	}
+
+	job, err := deps.Queries.CancelPendingJob(r.Context(), jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		status, err := deps.Queries.GetJobStatus(r.Context(), jobID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Job não encontrado", http.StatusNotFound)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get job status: %w", err)
+		}
+		http.Error(w, fmt.Sprintf("Job não está pendente (status: %s)", status), http.StatusConflict)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	if job.Type == "run_evaluation" {
+		var payload struct {
+			EvaluationID string `json:"evaluation_id"`
+		}
+		if err := json.Unmarshal(job.Payload, &payload); err == nil && payload.EvaluationID != "" {
+			if _, err := deps.Queries.CancelEvaluation(r.Context(), db.CancelEvaluationParams{
+				ErrorMessage: sql.NullString{String: "Cancelada por um administrador", Valid: true},
+				ID:           payload.EvaluationID,
+			}); err != nil {
+				return fmt.Errorf("failed to cancel evaluation: %w", err)
+			}
+		}
+	}
+
+	logging.AddToEvent(r.Context(), slog.Int64("cancelled_job_id", job.ID), slog.String("cancelled_job_type", job.Type))
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(adminJobEntry{
+		ID:           job.ID,
+		TenantID:     job.TenantID.String,
+		Type:         job.Type,
+		Status:       job.Status,
+		AttemptCount: job.AttemptCount.Int64,
+		MaxAttempts:  job.MaxAttempts.Int64,
+		LastError:    job.LastError.String,
+		CreatedAt:    job.CreatedAt.Time,
+		UpdatedAt:    job.UpdatedAt.Time,
+	})
+}
+
+type adminWorkerState struct {
+	Paused bool `json:"paused"`
+}
+
+// handleAdminWorkerPause pausa o worker para manutenção
+// @Summary Pausa o worker
+// @Description O worker para de pegar jobs novos; os que estão em execução terminam normalmente. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} adminWorkerState
+// @Failure 403 {string} string "Forbidden"
+// @Failure 503 {string} string "Worker indisponível"
+// @Router /admin/worker/pause [post]
+func handleAdminWorkerPause(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	return setWorkerPaused(deps, w, r, true)
+}
+
+// handleAdminWorkerResume retoma o worker após uma pausa
+// @Summary Retoma o worker
+// @Description Volta a processar a fila de jobs. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} adminWorkerState
+// @Failure 403 {string} string "Forbidden"
+// @Failure 503 {string} string "Worker indisponível"
+// @Router /admin/worker/resume [post]
+func handleAdminWorkerResume(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	return setWorkerPaused(deps, w, r, false)
+}
+
+func setWorkerPaused(deps HandlerDeps, w http.ResponseWriter, r *http.Request, paused bool) error {
+	if deps.Worker == nil {
+		return &HTTPError{Code: http.StatusServiceUnavailable, Message: "Worker indisponível neste processo"}
+	}
+
+	if paused {
+		deps.Worker.Pause()
+	} else {
+		deps.Worker.Resume()
+	}
+	logging.AddToEvent(r.Context(), slog.Bool("worker_paused", paused))
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(adminWorkerState{Paused: deps.Worker.Paused()})
+}
+
+// handleAdminAccessDenied lista as negações mais recentes gravadas em
+// access_denied_log (ACCESS_DENIED_LOG=true)
+// @Summary Acessos negados
+// @Description Lista as decisões de autorização negadas pelas policies, da mais recente à mais antiga. Apenas administradores.
+// @Tags admin
+// @Produce json
+// @Param per_page query int false "Quantidade de registros (padrão 50, máximo 100)"
+// @Success 200 {array} adminAccessDeniedEntry
+// @Failure 403 {string} string "Forbidden"
+// @Router /admin/access-denied [get]
+func handleAdminAccessDenied(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	paging := db.ParsePagingParams(r.URL.Query(), 50)
+
+	rows, err := deps.Queries.ListAccessDenied(r.Context(), int64(paging.Limit()))
+	if err != nil {
+		return fmt.Errorf("failed to list access denials: %w", err)
+	}
+
+	entries := make([]adminAccessDeniedEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, adminAccessDeniedEntry{
+			ID:         row.ID,
+			UserID:     row.UserID,
+			TenantID:   row.TenantID,
+			Resource:   row.Resource,
+			ResourceID: row.ResourceID,
+			Action:     row.Action,
+			Reason:     row.Reason,
+			CreatedAt:  row.CreatedAt.Time,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// avatarExtensions são os únicos tipos servidos: impede que um upload com
+// extensão .html/.svg seja entregue como conteúdo ativo
+var avatarExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+}
+
+type avatarETag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// AvatarHandler serve os arquivos de dir em GET/HEAD AvatarURLPrefix + {name}.
+// O ETag é o hash do conteúdo (recalculado só quando mtime/tamanho mudam) e a
+// URL de um usuário é estável, então o cliente sempre revalida (If-None-Match → 304).
+// Sem listagem de diretório e sem acesso fora de dir.
+func AvatarHandler(dir string) http.Handler {
+	var etags sync.Map // name -> avatarETag
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") ||
+			!avatarExtensions[strings.ToLower(filepath.Ext(name))] {
+			http.NotFound(w, r)
+			return
+		}
+
+		// os.Root recusa symlinks e caminhos que escapem de dir
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer root.Close()
+
+		f, err := root.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+
+		etag := ""
+		if cached, ok := etags.Load(name); ok {
+			if c := cached.(avatarETag); c.modTime.Equal(info.ModTime()) && c.size == info.Size() {
+				etag = c.etag
+			}
+		}
+		if etag == "" {
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			etag = `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+			etags.Store(name, avatarETag{modTime: info.ModTime(), size: info.Size(), etag: etag})
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, no-cache")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// ServeContent trata HEAD, If-None-Match (304) e Range
+		http.ServeContent(w, r, name, info.ModTime(), f)
+	})
+}
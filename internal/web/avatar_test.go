@@ -0,0 +1,67 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAvatarHandler(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "avatars")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "1.png"), []byte("png-bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2.html"), []byte("<script>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "secret.png"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET "+Avatar, AvatarHandler(dir))
+
+	get := func(method, path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("GET", "/storage/avatars/1.png", "")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || rr.Body.String() != "png-bytes" || etag == "" {
+		t.Fatalf("GET: status %d, body %q, etag %q", rr.Code, rr.Body.String(), etag)
+	}
+	if rr.Header().Get("Cache-Control") == "" {
+		t.Error("GET: Cache-Control ausente")
+	}
+
+	if rr := get("GET", "/storage/avatars/1.png", etag); rr.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, esperado 304", rr.Code)
+	}
+	if rr := get("HEAD", "/storage/avatars/1.png", ""); rr.Code != http.StatusOK || rr.Body.Len() != 0 || rr.Header().Get("ETag") != etag {
+		t.Errorf("HEAD: status %d, body %d bytes, etag %q", rr.Code, rr.Body.Len(), rr.Header().Get("ETag"))
+	}
+
+	// Listagem, extensões não-imagem e escapes da pasta
+	for _, path := range []string{
+		"/storage/avatars/",
+		"/storage/avatars/2.html",
+		"/storage/avatars/..%2Fsecret.png",
+		"/storage/avatars/missing.png",
+	} {
+		if rr := get("GET", path, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, esperado 404", path, rr.Code)
+		}
+	}
+}
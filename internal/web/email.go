@@ -0,0 +1,51 @@
+package web
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+var errInvalidEmail = errors.New("Informe um e-mail válido")
+
+// normalizeEmail é a forma canônica gravada e consultada em users.email
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateEmail aceita apenas um endereço puro (sem nome de exibição) com
+// domínio plausível: ao menos um ponto, rótulos não vazios sem hífen nas
+// pontas e TLD alfabético. Espera o email já normalizado.
+func validateEmail(email string) error {
+	if email == "" || len(email) > 254 {
+		return errInvalidEmail
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return errInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return errInvalidEmail
+	}
+	labels := strings.Split(email[at+1:], ".")
+	if len(labels) < 2 {
+		return errInvalidEmail
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return errInvalidEmail
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+				return errInvalidEmail
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 || strings.ContainsAny(tld, "0123456789-") {
+		return errInvalidEmail
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package web
+
+import "testing"
+
+func TestValidateEmail(t *testing.T) {
+	valid := []string{"a@b.co", "user.name+tag@example.com.br", "x@sub-domain.example.org", "a@xn--bcher-kva.de"}
+	invalid := []string{
+		"", "plain", "@example.com", "a@", "a@localhost", "a@example", "a@example.c",
+		"a@.example.com", "a@example..com", "a@-example.com", "a@example-.com",
+		"a@example.123", "Nome <a@b.co>", "a b@c.com", "a@exa mple.com",
+	}
+	for _, e := range valid {
+		if err := validateEmail(e); err != nil {
+			t.Errorf("validateEmail(%q) = %v, esperado válido", e, err)
+		}
+	}
+	for _, e := range invalid {
+		if err := validateEmail(e); err == nil {
+			t.Errorf("validateEmail(%q) aceitou email inválido", e)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := normalizeEmail("  User@Example.COM "); got != "user@example.com" {
+		t.Errorf("normalizeEmail = %q", got)
+	}
+}
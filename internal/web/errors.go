@@ -0,0 +1,83 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/policies"
+	"github.com/PauloHFS/elenchus/internal/service"
+	"github.com/PauloHFS/elenchus/internal/view/pages"
+	"github.com/a-h/templ"
+)
+
+// HTTPError é um erro de handler com status e mensagem para o cliente. Handle
+// responde com Code/Message; qualquer outro erro vira 500.
+type HTTPError struct {
+	Code    int
+	Message string
+	// Err é a causa, apenas para o log (nunca exibida ao cliente)
+	Err error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// errForbidden é a resposta padrão para negações das policies
+func errForbidden(err error) error {
+	return &HTTPError{Code: http.StatusForbidden, Message: "Forbidden", Err: err}
+}
+
+// evaluationQuotaError responde 429 (com Retry-After até a virada do mês) quando
+// a quota mensal do tenant foi atingida; outros erros seguem para o Handle
+func evaluationQuotaError(w http.ResponseWriter, err error) error {
+	var quotaErr *policies.EvaluationQuotaError
+	if !errors.As(err, &quotaErr) {
+		return err
+	}
+	retryAfter := int(time.Until(quotaErr.ResetAt).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	http.Error(w, fmt.Sprintf("Quota mensal de avaliações atingida (%d de %d). Novas avaliações liberadas em %s.",
+		quotaErr.Used, quotaErr.Limit, quotaErr.ResetAt.Format("02/01/2006")), http.StatusTooManyRequests)
+	return nil
+}
+
+// httpErrorStatus retorna o status e a mensagem para o cliente de um erro de handler
+func httpErrorStatus(err error) (int, string) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		msg := httpErr.Message
+		if msg == "" {
+			msg = http.StatusText(httpErr.Code)
+		}
+		return httpErr.Code, msg
+	}
+	return http.StatusInternalServerError, "Internal Server Error"
+}
+
+// evaluationUnavailableMessage é exibida quando o servidor subiu sem GEMINI_API_KEY
+const evaluationUnavailableMessage = "Serviço de avaliação indisponível — configure a API key do Gemini (GEMINI_API_KEY)"
+
+// evaluationServiceError traduz a falha ao criar o EvaluationService: sem
+// cliente Gemini é 503 com mensagem clara, o resto continua 500
+func evaluationServiceError(err error) error {
+	if errors.Is(err, service.ErrGeminiUnavailable) {
+		return &HTTPError{Code: http.StatusServiceUnavailable, Message: evaluationUnavailableMessage, Err: err}
+	}
+	return fmt.Errorf("failed to create evaluation service: %w", err)
+}
+
+// renderEvaluationUnavailable responde no #evaluation-container com a mensagem
+// de serviço indisponível. Status 200 porque o HTMX não faz swap de respostas 4xx/5xx.
+func renderEvaluationUnavailable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.SSEError(evaluationUnavailableMessage)).ServeHTTP(w, r)
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/config"
+	"github.com/PauloHFS/elenchus/internal/contextkeys"
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/service"
+)
+
+func TestEvaluationServiceError(t *testing.T) {
+	var httpErr *HTTPError
+	err := evaluationServiceError(fmt.Errorf("wrap: %w", service.ErrGeminiUnavailable))
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("sem API key: esperado HTTPError 503, veio %v", err)
+	}
+
+	err = evaluationServiceError(errors.New("boom"))
+	if errors.As(err, &httpErr) {
+		t.Fatalf("erro genérico não deveria virar HTTPError: %v", err)
+	}
+}
+
+func TestStartEvaluationWithoutGemini(t *testing.T) {
+	deps := HandlerDeps{Config: &config.Config{}}
+
+	form := url.Values{"prompt": {"Explique recursão"}}
+	req := httptest.NewRequest("POST", "/evaluate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := context.WithValue(req.Context(), contextkeys.UserContextKey, db.User{ID: 1, TenantID: "default", RoleID: "user"})
+	rr := httptest.NewRecorder()
+
+	if err := handleStartEvaluation(deps, rr, req.WithContext(ctx)); err != nil {
+		t.Fatalf("handleStartEvaluation: %v", err)
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, esperado 200 (HTMX só faz swap de 2xx)", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "GEMINI_API_KEY") {
+		t.Errorf("resposta não explica a configuração ausente: %q", rr.Body.String())
+	}
+}
+
+func TestStartEvaluationPromptTooLong(t *testing.T) {
+	deps := HandlerDeps{Config: &config.Config{MaxPromptChars: 10}}
+
+	form := url.Values{"prompt": {"Explique recursão em detalhes"}}
+	req := httptest.NewRequest("POST", "/evaluate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := context.WithValue(req.Context(), contextkeys.UserContextKey, db.User{ID: 1, TenantID: "default", RoleID: "user"})
+	rr := httptest.NewRecorder()
+
+	if err := handleStartEvaluation(deps, rr, req.WithContext(ctx)); err != nil {
+		t.Fatalf("handleStartEvaluation: %v", err)
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, esperado 200 (HTMX só faz swap de 2xx)", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "limite de 10 caracteres") {
+		t.Errorf("resposta não explica o limite: %q", rr.Body.String())
+	}
+}
+
+func TestBulkEvaluationsPromptTooLong(t *testing.T) {
+	deps := HandlerDeps{Config: &config.Config{MaxPromptChars: 10, BulkImportMaxRows: 10}}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "lote.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(part, "prompt\ncurto\nExplique recursão em detalhes\n")
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/htmx/evaluations/bulk", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	ctx := context.WithValue(req.Context(), contextkeys.UserContextKey, db.User{ID: 1, TenantID: "default", RoleID: "user"})
+	rr := httptest.NewRecorder()
+
+	if err := handleBulkEvaluations(deps, rr, req.WithContext(ctx)); err != nil {
+		t.Fatalf("handleBulkEvaluations: %v", err)
+	}
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "prompt 2 excede o limite de 10 caracteres") {
+		t.Errorf("status = %d, body = %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPreviewEvaluationPromptTooLong(t *testing.T) {
+	// Sem cliente Gemini: acima do limite o preview nem tenta contar tokens
+	deps := HandlerDeps{Config: &config.Config{MaxPromptChars: 10}}
+
+	form := url.Values{"prompt": {"Explique recursão em detalhes"}}
+	req := httptest.NewRequest("POST", "/htmx/evaluations/preview", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	if err := handlePreviewEvaluation(deps, rr, req); err != nil {
+		t.Fatalf("handlePreviewEvaluation: %v", err)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "excede o limite") || strings.Contains(body, "GEMINI_API_KEY") {
+		t.Errorf("preview = %q", body)
+	}
+}
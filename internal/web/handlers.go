@@ -1,19 +1,22 @@
 package web
 
 import (
+	"context"
 	crypto_rand "crypto/rand"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PauloHFS/elenchus/internal/config"
 	"github.com/PauloHFS/elenchus/internal/contextkeys"
@@ -24,8 +27,10 @@ import (
 	"github.com/PauloHFS/elenchus/internal/routes"
 	"github.com/PauloHFS/elenchus/internal/service"
 	"github.com/PauloHFS/elenchus/internal/sse"
+	"github.com/PauloHFS/elenchus/internal/storage"
 	"github.com/PauloHFS/elenchus/internal/view"
 	"github.com/PauloHFS/elenchus/internal/view/pages"
+	"github.com/PauloHFS/elenchus/internal/worker"
 	"github.com/a-h/templ"
 	"github.com/alexedwards/scs/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -38,6 +43,41 @@ type HandlerDeps struct {
 	Logger         *slog.Logger
 	Config         *config.Config
 	SSEBroker      *sse.Broker
+	// GeminiClient é compartilhado por todas as requisições; nil sem API key
+	GeminiClient *service.GeminiClient
+	// Worker do mesmo processo, para pausar/retomar pela área admin (nil = indisponível)
+	Worker *worker.Processor
+	// Storage dos arquivos enviados (avatars), escolhido por STORAGE_BACKEND
+	Storage storage.BlobStorage
+	// Prompts do protocolo carregados no boot (nil = conjunto padrão)
+	Prompts *service.ProtocolPrompts
+}
+
+// wakeWorker avisa o worker do processo que há job novo na fila, para pegá-lo
+// sem esperar o polling. Sem worker local (outra réplica) o ticker dele pega.
+func wakeWorker(deps HandlerDeps) {
+	if deps.Worker != nil {
+		deps.Worker.Wake()
+	}
+}
+
+// evaluationService monta o service da requisição sobre o cliente Gemini e o
+// conjunto de prompts compartilhados
+func evaluationService(deps HandlerDeps) (*service.EvaluationService, error) {
+	evalService, err := service.NewEvaluationService(deps.Queries, deps.SSEBroker, deps.GeminiClient)
+	if err != nil {
+		return nil, err
+	}
+	if deps.Prompts != nil {
+		evalService = evalService.WithPrompts(*deps.Prompts)
+	}
+	return evalService, nil
+}
+
+// evaluationReader monta o service para leituras que não chamam o modelo;
+// funciona sem cliente Gemini
+func evaluationReader(deps HandlerDeps) (*service.EvaluationService, error) {
+	return service.NewReadOnlyEvaluationService(deps.Queries, deps.SSEBroker)
 }
 
 // AppHandler é um tipo customizado que permite retornar erros dos handlers
@@ -47,52 +87,87 @@ type AppHandler func(deps HandlerDeps, w http.ResponseWriter, r *http.Request) e
 func Handle(deps HandlerDeps, h AppHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := h(deps, w, r); err != nil {
-			// Aqui centralizamos o log de erro estruturado
-			deps.Logger.Error("request failed",
+			// Decidir o que mostrar ao usuário: HTTPError define status e
+			// mensagem, o resto é 500
+			status, msg := httpErrorStatus(err)
+
+			// Aqui centralizamos o log de erro estruturado (4xx como warning)
+			level := slog.LevelError
+			if status < http.StatusInternalServerError {
+				level = slog.LevelWarn
+			}
+			deps.Logger.Log(r.Context(), level, "request failed",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
 				slog.Any("error", err),
 			)
 
-			// Decidir o que mostrar ao usuário
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			http.Error(w, msg, status)
 		}
 	}
 }
 
 func RegisterRoutes(mux *http.ServeMux, deps HandlerDeps) {
+	// Rate limits por rota: brute force nas rotas de auth (por IP) e flood na
+	// criação de avaliações (por usuário, por isso dentro de RequireAuth)
+	authLimit := middleware.NewRateLimiter("auth", deps.Config.RateLimitAuthPerMinute, deps.Config.RateLimitAuthBurst, middleware.ByIP).Middleware
+	evaluationLimit := middleware.NewRateLimiter("evaluations", deps.Config.RateLimitEvaluationsPerMinute, deps.Config.RateLimitEvaluationsBurst, middleware.ByUser).Middleware
+
 	// Auth Handlers
 	mux.Handle("GET "+routes.Login, templ.Handler(pages.Login("")))
 	mux.Handle("GET "+routes.Register, templ.Handler(pages.Register("")))
 
-	mux.HandleFunc("POST "+routes.Register, Handle(deps, handleRegister))
+	mux.Handle("POST "+routes.Register, authLimit(Handle(deps, handleRegister)))
 	mux.HandleFunc("GET "+routes.ForgotPassword, func(w http.ResponseWriter, r *http.Request) {
 		templ.Handler(pages.ForgotPassword("")).ServeHTTP(w, r)
 	})
-	mux.HandleFunc("POST "+routes.ForgotPassword, Handle(deps, handleForgotPassword))
+	mux.Handle("POST "+routes.ForgotPassword, authLimit(Handle(deps, handleForgotPassword)))
 	mux.HandleFunc("GET "+routes.ResetPassword, func(w http.ResponseWriter, r *http.Request) {
 		token := r.URL.Query().Get("token")
 		templ.Handler(pages.ResetPassword(token, "")).ServeHTTP(w, r)
 	})
-	mux.HandleFunc("POST "+routes.ResetPassword, Handle(deps, handleResetPassword))
+	mux.Handle("POST "+routes.ResetPassword, authLimit(Handle(deps, handleResetPassword)))
 	mux.HandleFunc("GET "+routes.VerifyEmail, Handle(deps, handleVerifyEmail))
-	mux.HandleFunc("POST "+routes.Login, Handle(deps, handleLogin))
+	mux.Handle("POST "+routes.ResendVerify, authLimit(Handle(deps, handleResendVerification)))
+	mux.Handle("POST "+routes.Login, authLimit(Handle(deps, handleLogin)))
 	mux.HandleFunc("POST "+routes.Logout, Handle(deps, handleLogout))
 
 	// Protected Routes
 	mux.Handle("GET "+routes.Dashboard, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleDashboard)))
+	mux.Handle("GET "+routes.Profile, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleProfile)))
+	mux.Handle("POST "+routes.Profile, authLimit(middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleProfileUpdate))))
 	mux.Handle("POST /profile/avatar", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleAvatarUpload)))
+	mux.Handle("POST /profile/avatar/delete", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleAvatarDelete)))
 	mux.Handle("POST /dashboard/test-job", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleTestJob)))
 
 	// Evaluation Routes
 	mux.Handle("GET "+routes.EvaluationsPage, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleEvaluationsPage)))
-	mux.Handle("POST "+routes.EvaluationStart, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleStartEvaluation)))
+	mux.Handle("POST "+routes.EvaluationStart, middleware.RequireAuth(deps.SessionManager, deps.Queries, evaluationLimit(Handle(deps, handleStartEvaluation))))
+	mux.Handle("POST "+routes.EvaluationPreview, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handlePreviewEvaluation)))
+	mux.Handle("POST "+routes.EvaluationBulk, middleware.RequireAuth(deps.SessionManager, deps.Queries, evaluationLimit(Handle(deps, handleBulkEvaluations))))
+	mux.Handle("GET "+routes.ExperimentStatus, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleExperimentStatus)))
 	mux.Handle("GET /sse", deps.SSEBroker.Handler()) // SSE endpoint for HTMX
-	mux.Handle("GET "+routes.EvaluationResult, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleLoadEvaluationResult)))
+	mux.Handle("OPTIONS /sse", deps.SSEBroker.Handler())
+	mux.Handle("GET "+routes.EvaluationResult, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireEvaluationAccessOr(deps.Queries, policies.ActionView, evaluationNotFound, Handle(deps, handleLoadEvaluationResult))))
+	mux.Handle("POST "+routes.EvaluationRerun, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireEvaluationAccess(deps.Queries, policies.ActionView, evaluationLimit(Handle(deps, handleRerunEvaluation)))))
+	mux.Handle("GET "+routes.EvaluationExport, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireEvaluationAccess(deps.Queries, policies.ActionView, Handle(deps, handleExportEvaluation))))
+	mux.Handle("GET "+routes.EvaluationEmbeddings, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireEvaluationAccess(deps.Queries, policies.ActionView, Handle(deps, handleEvaluationEmbeddings))))
+	mux.Handle("GET "+routes.EvaluationCompare, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleCompareEvaluations)))
+	mux.Handle("GET "+routes.EvaluationStats, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleEvaluationStats)))
 	mux.Handle("GET "+routes.EvaluationsList, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleListEvaluations)))
 	mux.Handle("GET /evaluations/history", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleListEvaluations)))
 	mux.Handle("GET /evaluations/active", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleActiveEvaluations)))
-	mux.Handle("GET /evaluations/status/{id}", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleEvaluationStatus)))
+	mux.Handle("GET /evaluations/status/{id}", middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireEvaluationAccessOr(deps.Queries, policies.ActionView, evaluationNotFound, Handle(deps, handleEvaluationStatus))))
+
+	// Admin Routes
+	mux.Handle("GET "+routes.AdminEvaluationLogs, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminEvaluationLogs))))
+	mux.Handle("POST "+routes.AdminEvaluationReaudit, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminReauditEvaluation))))
+	mux.Handle("GET "+routes.AdminJobs, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminJobs))))
+	mux.Handle("POST "+routes.AdminJobCancel, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminCancelJob))))
+	mux.Handle("POST "+routes.AdminWorkerPause, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminWorkerPause))))
+	mux.Handle("POST "+routes.AdminWorkerResume, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminWorkerResume))))
+	mux.Handle("GET "+routes.AdminAccessDenied, middleware.RequireAuth(deps.SessionManager, deps.Queries, middleware.RequireAdmin(Handle(deps, handleAdminAccessDenied))))
 
 	// Public Routes
 	mux.HandleFunc("GET "+routes.Home, func(w http.ResponseWriter, r *http.Request) {
@@ -104,11 +179,25 @@ func RegisterRoutes(mux *http.ServeMux, deps HandlerDeps) {
 // --- Handler Implementations ---
 
 func handleRegister(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
-	email := r.FormValue("email")
+	email := normalizeEmail(r.FormValue("email"))
 	password := r.FormValue("password")
 
-	_, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
-		TenantID: "default",
+	if err := validateEmail(email); err != nil {
+		templ.Handler(pages.Register(err.Error())).ServeHTTP(w, r)
+		return nil
+	}
+
+	tenantID, err := resolveRegistrationTenant(r.Context(), deps, r)
+	if errors.Is(err, errUnknownTenant) {
+		templ.Handler(pages.Register("Organização não encontrada")).ServeHTTP(w, r)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+		TenantID: tenantID,
 		Email:    email,
 	})
 	if err == nil {
@@ -116,6 +205,11 @@ func handleRegister(deps HandlerDeps, w http.ResponseWriter, r *http.Request) er
 		return nil
 	}
 
+	if err := validatePassword(password, passwordPolicyFrom(deps)); err != nil {
+		templ.Handler(pages.Register(err.Error())).ServeHTTP(w, r)
+		return nil
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
@@ -130,7 +224,7 @@ func handleRegister(deps HandlerDeps, w http.ResponseWriter, r *http.Request) er
 	qtx := deps.Queries.WithTx(tx)
 
 	_, err = qtx.CreateUser(r.Context(), db.CreateUserParams{
-		TenantID:     "default",
+		TenantID:     tenantID,
 		Email:        email,
 		PasswordHash: string(hash),
 		RoleID:       "user",
@@ -139,13 +233,30 @@ func handleRegister(deps HandlerDeps, w http.ResponseWriter, r *http.Request) er
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if err := enqueueVerificationEmail(r.Context(), qtx, tenantID, email); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit registration: %w", err)
+	}
+	wakeWorker(deps)
+
+	http.Redirect(w, r, routes.Login+"?message=Conta criada! Verifique seu e-mail.", http.StatusSeeOther)
+	return nil
+}
+
+// enqueueVerificationEmail gera um novo token de verificação (substituindo o
+// anterior) e enfileira o envio do email
+func enqueueVerificationEmail(ctx context.Context, q *db.Queries, tenantID, email string) error {
 	tokenBytes := make([]byte, 32)
 	if _, err := crypto_rand.Read(tokenBytes); err != nil {
 		return fmt.Errorf("failed to generate token: %w", err)
 	}
 	token := hex.EncodeToString(tokenBytes)
 
-	if err := qtx.UpsertEmailVerification(r.Context(), db.UpsertEmailVerificationParams{
+	if err := q.UpsertEmailVerification(ctx, db.UpsertEmailVerificationParams{
+		TenantID:  tenantID,
 		Email:     email,
 		Token:     token,
 		ExpiresAt: time.Now().Add(24 * time.Hour),
@@ -161,30 +272,58 @@ func handleRegister(deps HandlerDeps, w http.ResponseWriter, r *http.Request) er
 		return fmt.Errorf("failed to marshal job payload: %w", err)
 	}
 
-	if _, err := qtx.CreateJob(r.Context(), db.CreateJobParams{
-		TenantID: sql.NullString{String: "default", Valid: true},
+	if _, err := q.CreateJob(ctx, db.CreateJobParams{
+		TenantID: sql.NullString{String: tenantID, Valid: true},
 		Type:     "send_verification_email",
 		Payload:  jobPayload,
 		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
 	}); err != nil {
 		return fmt.Errorf("failed to create job: %w", err)
 	}
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit registration: %w", err)
+// handleResendVerification reenvia o email de verificação. Responde sempre com a
+// mesma mensagem para não revelar quais emails têm conta.
+func handleResendVerification(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	email := normalizeEmail(r.FormValue("email"))
+
+	tenantID, err := resolveTenant(r.Context(), deps, r)
+	if err != nil && !errors.Is(err, errUnknownTenant) {
+		return err
+	}
+	if err == nil {
+		user, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+			TenantID: tenantID,
+			Email:    email,
+		})
+		if err == nil && !user.IsVerified {
+			if err := enqueueVerificationEmail(r.Context(), deps.Queries, tenantID, email); err != nil {
+				return err
+			}
+			wakeWorker(deps)
+		}
 	}
 
-	http.Redirect(w, r, routes.Login+"?message=Conta criada! Verifique seu e-mail.", http.StatusSeeOther)
+	http.Redirect(w, r, routes.Login+"?message=Se a conta existir, enviaremos um novo e-mail de verificação.", http.StatusSeeOther)
 	return nil
 }
 
 func handleForgotPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
-	email := r.FormValue("email")
-	_, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
-		TenantID: "default",
-		Email:    email,
-	})
+	start := timingNow()
+	email := normalizeEmail(r.FormValue("email"))
+	tenantID, err := resolveTenant(r.Context(), deps, r)
+	if err != nil && !errors.Is(err, errUnknownTenant) {
+		return err
+	}
+	if err == nil {
+		_, err = deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+			TenantID: tenantID,
+			Email:    email,
+		})
+	}
 	if err != nil {
+		waitMinDuration(r.Context(), start, forgotPasswordMinDuration)
 		templ.Handler(pages.ForgotPassword("Se o e-mail existir, um link será enviado.")).ServeHTTP(w, r)
 		return nil
 	}
@@ -206,6 +345,7 @@ func handleForgotPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reque
 	qtx := deps.Queries.WithTx(tx)
 
 	if err := qtx.UpsertPasswordReset(r.Context(), db.UpsertPasswordResetParams{
+		TenantID:  tenantID,
 		Email:     email,
 		TokenHash: tokenHash,
 		ExpiresAt: time.Now().Add(1 * time.Hour),
@@ -221,7 +361,7 @@ func handleForgotPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reque
 		return fmt.Errorf("failed to marshal job payload: %w", err)
 	}
 	if _, err := qtx.CreateJob(r.Context(), db.CreateJobParams{
-		TenantID: sql.NullString{String: "default", Valid: true},
+		TenantID: sql.NullString{String: tenantID, Valid: true},
 		Type:     "send_password_reset_email",
 		Payload:  jobPayload,
 		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
@@ -232,7 +372,9 @@ func handleForgotPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reque
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit forgot password: %w", err)
 	}
+	wakeWorker(deps)
 
+	waitMinDuration(r.Context(), start, forgotPasswordMinDuration)
 	templ.Handler(pages.ForgotPassword("Se o e-mail existir, um link será enviado.")).ServeHTTP(w, r)
 	return nil
 }
@@ -250,6 +392,11 @@ func handleResetPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reques
 		return nil
 	}
 
+	if err := validatePassword(password, passwordPolicyFrom(deps)); err != nil {
+		templ.Handler(pages.ResetPassword(token, err.Error())).ServeHTTP(w, r)
+		return nil
+	}
+
 	newHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
@@ -265,13 +412,17 @@ func handleResetPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reques
 
 	err = qtx.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
 		PasswordHash: string(newHash),
+		TenantID:     reset.TenantID,
 		Email:        reset.Email,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	if err := qtx.DeletePasswordReset(r.Context(), reset.Email); err != nil {
+	if err := qtx.DeletePasswordReset(r.Context(), db.DeletePasswordResetParams{
+		TenantID: reset.TenantID,
+		Email:    reset.Email,
+	}); err != nil {
 		deps.Logger.Warn("failed to delete password reset token", "error", err)
 	}
 
@@ -304,12 +455,18 @@ func handleVerifyEmail(deps HandlerDeps, w http.ResponseWriter, r *http.Request)
 
 	qtx := deps.Queries.WithTx(tx)
 
-	err = qtx.VerifyUser(r.Context(), verification.Email)
+	err = qtx.VerifyUser(r.Context(), db.VerifyUserParams{
+		TenantID: verification.TenantID,
+		Email:    verification.Email,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to verify user: %w", err)
 	}
 
-	if err := qtx.DeleteEmailVerification(r.Context(), verification.Email); err != nil {
+	if err := qtx.DeleteEmailVerification(r.Context(), db.DeleteEmailVerificationParams{
+		TenantID: verification.TenantID,
+		Email:    verification.Email,
+	}); err != nil {
 		deps.Logger.Warn("failed to delete email verification token", "error", err)
 	}
 
@@ -322,21 +479,56 @@ func handleVerifyEmail(deps HandlerDeps, w http.ResponseWriter, r *http.Request)
 }
 
 func handleLogin(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
-	email := r.FormValue("email")
+	email := normalizeEmail(r.FormValue("email"))
 	password := r.FormValue("password")
 
-	user, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
-		TenantID: "default",
-		Email:    email,
-	})
+	// Bloqueio é verificado antes de qualquer consulta ao hash (bcrypt é caro)
+	lockedUntil, err := loginLockedUntil(r.Context(), deps, email)
+	if err != nil {
+		return err
+	}
+	if !lockedUntil.IsZero() {
+		templ.Handler(pages.Login(loginLockedMessage(lockedUntil)), templ.WithStatus(http.StatusTooManyRequests)).ServeHTTP(w, r)
+		return nil
+	}
+
+	// Tenant inexistente é tratado como credencial inválida
+	var user db.User
+	tenantID, err := resolveTenant(r.Context(), deps, r)
+	if err != nil && !errors.Is(err, errUnknownTenant) {
+		return err
+	}
+	if err == nil {
+		user, err = deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+			TenantID: tenantID,
+			Email:    email,
+		})
+	}
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	}
 
 	if err != nil {
+		// Emails inexistentes também contam, para não revelar quais contas existem
+		lockedUntil, err := recordFailedLogin(r.Context(), deps, email)
+		if err != nil {
+			return err
+		}
+		if !lockedUntil.IsZero() {
+			logging.AddToEvent(r.Context(), slog.Bool("login_locked", true))
+			templ.Handler(pages.Login(loginLockedMessage(lockedUntil)), templ.WithStatus(http.StatusTooManyRequests)).ServeHTTP(w, r)
+			return nil
+		}
 		templ.Handler(pages.Login("Usuário ou senha inválidos")).ServeHTTP(w, r)
 		return nil
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		templ.Handler(pages.Login("Usuário ou senha inválidos")).ServeHTTP(w, r)
+	if err := deps.Queries.ResetLoginAttempts(r.Context(), loginAttemptKey(email)); err != nil {
+		deps.Logger.Warn("failed to reset login attempts", "error", err)
+	}
+
+	if deps.Config.RequireEmailVerification && !user.IsVerified {
+		templ.Handler(pages.LoginWithResend("Verifique seu e-mail antes de entrar. Confira sua caixa de entrada.", email, user.TenantID), templ.WithStatus(http.StatusForbidden)).ServeHTTP(w, r)
 		return nil
 	}
 
@@ -356,16 +548,11 @@ func handleLogout(deps HandlerDeps, w http.ResponseWriter, r *http.Request) erro
 func handleDashboard(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
 	user, _ := r.Context().Value(contextkeys.UserContextKey).(db.User)
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	search := r.URL.Query().Get("search")
-
-	paging := db.PagingParams{
-		Page:    page,
-		PerPage: 5,
-	}
+	paging := db.ParsePagingParams(r.URL.Query(), 5)
 
 	users, err := deps.Queries.ListUsersPaginated(r.Context(), db.ListUsersPaginatedParams{
-		TenantID: "default",
+		TenantID: user.TenantID,
 		Column2:  sql.NullString{String: search, Valid: true},
 		Column3:  sql.NullString{String: search, Valid: true},
 		Limit:    int64(paging.Limit()),
@@ -375,7 +562,7 @@ func handleDashboard(deps HandlerDeps, w http.ResponseWriter, r *http.Request) e
 		return fmt.Errorf("failed to list users: %w", err)
 	}
 
-	totalUsers, err := deps.Queries.CountUsers(r.Context(), "default")
+	totalUsers, err := deps.Queries.CountUsers(r.Context(), user.TenantID)
 	if err != nil {
 		return fmt.Errorf("failed to count users: %w", err)
 	}
@@ -392,7 +579,8 @@ func handleDashboard(deps HandlerDeps, w http.ResponseWriter, r *http.Request) e
 	return nil
 }
 
-// handleTestJob inicia um job assíncrono de teste que notifica via SSE
+// handleTestJob enfileira um job "test_job"; o worker o processa e notifica o
+// progresso via SSE (canal do usuário), como qualquer job longo
 func handleTestJob(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
 	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
 	if !ok {
@@ -400,42 +588,19 @@ func handleTestJob(deps HandlerDeps, w http.ResponseWriter, r *http.Request) err
 		return nil
 	}
 
-	// Retorna status inicial
-	w.Header().Set("Content-Type", "text/html")
-	templ.Handler(pages.JobStatusNotification("running", "Job iniciado... processando em background")).ServeHTTP(w, r)
-
-	// Inicia job em background
-	go func() {
-		// Envia progresso via SSE
-		deps.SSEBroker.SendHTML("user", fmt.Sprint(user.ID), "job_progress",
-			`<div class="bg-blue-50 border-l-4 border-blue-500 p-4 rounded mb-2">
-				<div class="flex items-center">
-					<svg class="animate-spin h-5 w-5 text-blue-700 mr-3" fill="none" viewBox="0 0 24 24">
-						<circle class="opacity-25" cx="12" cy="12" r="10" stroke="currentColor" stroke-width="4"></circle>
-						<path class="opacity-75" fill="currentColor" d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"></path>
-					</svg>
-					<p class="text-blue-700 font-medium">Processando... 50%</p>
-				</div>
-			</div>`)
-
-		// Simula processamento (5 segundos)
-		time.Sleep(5 * time.Second)
-
-		// Envia notificação de conclusão via SSE
-		deps.SSEBroker.SendHTML("user", fmt.Sprint(user.ID), "job_completed",
-			`<div class="bg-green-50 border-l-4 border-green-500 p-4 rounded mb-2">
-				<div class="flex items-center">
-					<svg class="h-5 w-5 text-green-700 mr-3" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-						<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M5 13l4 4L19 7"></path>
-					</svg>
-					<p class="text-green-700 font-medium">✅ Job completado com sucesso! Processamento levou ~5 segundos.</p>
-				</div>
-			</div>`)
-
-		// Log para debug
-		fmt.Printf("✅ Test job completed for user %d\n", user.ID)
-	}()
+	jobPayload, _ := json.Marshal(map[string]int64{"user_id": user.ID})
+	if _, err := deps.Queries.CreateJob(r.Context(), db.CreateJobParams{
+		TenantID: sql.NullString{String: user.TenantID, Valid: true},
+		Type:     "test_job",
+		Payload:  jobPayload,
+		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to create test job: %w", err)
+	}
+	wakeWorker(deps)
 
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.JobStatusNotification("running", "Job enfileirado... processando em background")).ServeHTTP(w, r)
 	return nil
 }
 
@@ -457,21 +622,12 @@ func handleAvatarUpload(deps HandlerDeps, w http.ResponseWriter, r *http.Request
 	}
 	defer file.Close()
 
-	ext := filepath.Ext(header.Filename)
-	filename := fmt.Sprintf("%d%s", user.ID, ext)
-	dstPath := filepath.Join("storage", "avatars", filename)
-
-	dst, err := os.Create(dstPath)
+	key := fmt.Sprintf("avatars/%d%s", user.ID, filepath.Ext(header.Filename))
+	avatarURL, err := deps.Storage.Put(r.Context(), key, file, header.Header.Get("Content-Type"))
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		return fmt.Errorf("failed to copy file: %w", err)
+		return fmt.Errorf("failed to store avatar: %w", err)
 	}
 
-	avatarURL := "/storage/avatars/" + filename
 	if err := deps.Queries.UpdateUserAvatar(r.Context(), db.UpdateUserAvatarParams{
 		AvatarUrl: sql.NullString{String: avatarURL, Valid: true},
 		ID:        user.ID,
@@ -479,7 +635,8 @@ func handleAvatarUpload(deps HandlerDeps, w http.ResponseWriter, r *http.Request
 		deps.Logger.Warn("failed to update avatar in database", "error", err)
 	}
 
-	jobPayload, _ := json.Marshal(map[string]string{"image": avatarURL})
+	// user_id permite ao job reportar progresso via SSE para o dono
+	jobPayload, _ := json.Marshal(map[string]any{"image": avatarURL, "user_id": user.ID})
 	if _, err := deps.Queries.CreateJob(r.Context(), db.CreateJobParams{
 		TenantID: sql.NullString{String: fmt.Sprintf("%d", user.ID), Valid: true},
 		Type:     "process_ai",
@@ -487,6 +644,37 @@ func handleAvatarUpload(deps HandlerDeps, w http.ResponseWriter, r *http.Request
 		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
 	}); err != nil {
 		deps.Logger.Warn("failed to create AI processing job", "error", err)
+	} else {
+		wakeWorker(deps)
+	}
+
+	http.Redirect(w, r, routes.Dashboard, http.StatusSeeOther)
+	return nil
+}
+
+// handleAvatarDelete remove o avatar do usuário (arquivo e avatar_url).
+// Sem avatar atual apenas redireciona.
+func handleAvatarDelete(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	if user.AvatarUrl.Valid {
+		if err := deps.Queries.UpdateUserAvatar(r.Context(), db.UpdateUserAvatarParams{
+			AvatarUrl: sql.NullString{},
+			ID:        user.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to clear avatar: %w", err)
+		}
+
+		// O arquivo sai depois do banco: um arquivo órfão é inofensivo, uma URL quebrada não
+		if key, ok := deps.Storage.KeyFromURL(user.AvatarUrl.String); ok {
+			if err := deps.Storage.Delete(r.Context(), key); err != nil {
+				deps.Logger.Warn("failed to remove avatar file", "key", key, "error", err)
+			}
+		}
 	}
 
 	http.Redirect(w, r, routes.Dashboard, http.StatusSeeOther)
@@ -502,7 +690,99 @@ func handleEvaluationsPage(deps HandlerDeps, w http.ResponseWriter, r *http.Requ
 		return nil
 	}
 
-	templ.Handler(pages.Evaluations(user)).ServeHTTP(w, r)
+	var models []string
+	if deps.GeminiClient != nil {
+		models = deps.GeminiClient.AllowedChatModels()
+	}
+
+	templ.Handler(pages.Evaluations(user, models, deps.Config.MaxPromptChars)).ServeHTTP(w, r)
+	return nil
+}
+
+// invalidPromptImageMessage é o erro mostrado para anexos recusados por NewPromptImage
+var invalidPromptImageMessage = fmt.Sprintf("Imagem inválida: envie PNG, JPEG ou WebP de até %d MB", service.MaxPromptImageBytes>>20)
+
+// promptAttachments lê a imagem opcional anexada ao prompt (formulário
+// multipart); anexos recusados retornam service.ErrInvalidPromptImage. Só a
+// ausência do campo (ou um formulário que não é multipart) significa "sem
+// imagem"; um upload que não pôde ser lido vira 400.
+func promptAttachments(r *http.Request) ([]service.MessagePart, error) {
+	file, _, err := r.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Message: "Upload inválido", Err: err}
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxPromptImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt image: %w", err)
+	}
+	image, err := service.NewPromptImage(data)
+	if err != nil {
+		return nil, err
+	}
+	return []service.MessagePart{image}, nil
+}
+
+// renderPromptTooLong responde o limite de tamanho do prompt como fragmento
+// (200, para o HTMX fazer o swap no container da avaliação)
+func renderPromptTooLong(w http.ResponseWriter, r *http.Request, maxChars int) {
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.SSEError(fmt.Sprintf("O prompt excede o limite de %d caracteres. Reduza o texto e tente novamente.", maxChars))).ServeHTTP(w, r)
+}
+
+// handlePreviewEvaluation mostra o tamanho do prompt e a contagem de tokens da
+// consulta inicial (CountTokens) antes de a avaliação ser iniciada. Não cria
+// avaliação nem consome quota de geração.
+func handlePreviewEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	prompt := r.FormValue("prompt")
+	if prompt == "" {
+		http.Error(w, "Prompt é obrigatório", http.StatusBadRequest)
+		return nil
+	}
+
+	attachments, err := promptAttachments(r)
+	if errors.Is(err, service.ErrInvalidPromptImage) {
+		http.Error(w, invalidPromptImageMessage, http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	preview := pages.PromptPreview{
+		Chars:    utf8.RuneCountInString(prompt),
+		MaxChars: deps.Config.MaxPromptChars,
+		Images:   len(attachments),
+	}
+
+	// Acima do limite o início seria recusado: não gasta uma chamada de contagem
+	if preview.TooLong() {
+		w.Header().Set("Content-Type", "text/html")
+		templ.Handler(pages.EvaluationPromptPreview(preview)).ServeHTTP(w, r)
+		return nil
+	}
+
+	evalService, err := evaluationService(deps)
+	if errors.Is(err, service.ErrGeminiUnavailable) {
+		renderEvaluationUnavailable(w, r)
+		return nil
+	}
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	preview.Tokens, preview.MaxInputTokens, err = evalService.EstimatePromptTokens(r.Context(), prompt, attachments)
+	if err != nil {
+		// Sem contagem o preview ainda mostra o tamanho; o início não depende disso
+		deps.Logger.Warn("failed to count prompt tokens", "error", err)
+		preview.TokensUnavailable = true
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.EvaluationPromptPreview(preview)).ServeHTTP(w, r)
 	return nil
 }
 
@@ -512,6 +792,10 @@ func handleStartEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Prompt é obrigatório", http.StatusBadRequest)
 		return nil
 	}
+	if err := service.CheckPromptLength(prompt, deps.Config.MaxPromptChars); err != nil {
+		renderPromptTooLong(w, r, deps.Config.MaxPromptChars)
+		return nil
+	}
 
 	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
 	if !ok {
@@ -519,14 +803,80 @@ func handleStartEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Requ
 		return nil
 	}
 
-	evalService, err := service.NewEvaluationService(deps.Queries, deps.SSEBroker)
+	strategy, err := service.StrategyByName(r.FormValue("strategy"))
+	if err != nil {
+		http.Error(w, "Estratégia inválida", http.StatusBadRequest)
+		return nil
+	}
+	if seedStr := r.FormValue("seed"); seedStr != "" {
+		seed, err := strconv.ParseInt(seedStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Seed inválida", http.StatusBadRequest)
+			return nil
+		}
+		strategy = strategy.WithSeed(int32(seed))
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = r.FormValue("idempotency_key")
+	}
+	if len(idempotencyKey) > service.MaxIdempotencyKeyLen {
+		http.Error(w, "Idempotency-Key muito longa", http.StatusBadRequest)
+		return nil
+	}
+
+	attachments, err := promptAttachments(r)
+	if errors.Is(err, service.ErrInvalidPromptImage) {
+		http.Error(w, invalidPromptImageMessage, http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	evalService, err := evaluationService(deps)
+	if errors.Is(err, service.ErrGeminiUnavailable) {
+		renderEvaluationUnavailable(w, r)
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create evaluation service: %w", err)
+		return evaluationServiceError(err)
+	}
+
+	strategy, err = evalService.WithChatModel(strategy, r.FormValue("model"))
+	if errors.Is(err, service.ErrChatModelNotAllowed) {
+		http.Error(w, "Modelo não permitido", http.StatusBadRequest)
+		return nil
 	}
-	evalID, err := evalService.StartEvaluation(r.Context(), user.TenantID, user.ID, prompt)
+	if err != nil {
+		return err
+	}
+
+	// Reaproveita resultado recente idêntico, a menos que o usuário force nova
+	// execução. Com imagem não há cache: o "forçar" do aviso não reenvia o anexo.
+	if r.FormValue("force") != "1" && len(attachments) == 0 {
+		cached, err := evalService.FindCachedEvaluation(r.Context(), user.TenantID, user.ID, prompt, strategy, deps.Config.EvaluationCacheTTL)
+		if err != nil {
+			return err
+		}
+		if cached != nil {
+			w.Header().Set("Content-Type", "text/html")
+			templ.Handler(pages.CachedEvaluationNotice(*cached, r.FormValue("strategy"), r.FormValue("seed"), r.FormValue("model"))).ServeHTTP(w, r)
+			return nil
+		}
+	}
+
+	// Quota mensal do tenant (resultados em cache acima não contam)
+	if err := policies.CheckEvaluationQuota(r.Context(), deps.Queries, user); err != nil {
+		return evaluationQuotaError(w, err)
+	}
+
+	evalID, err := evalService.StartEvaluation(r.Context(), user.TenantID, user.ID, prompt, attachments, strategy, idempotencyKey)
 	if err != nil {
 		return fmt.Errorf("failed to start evaluation: %w", err)
 	}
+	wakeWorker(deps)
 
 	// Return HTML with SSE connection using HTMX SSE extension
 	w.Header().Set("Content-Type", "text/html")
@@ -534,32 +884,176 @@ func handleStartEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Requ
 	return nil
 }
 
-// handleLoadEvaluationResult renders the final evaluation result
-func handleLoadEvaluationResult(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
-	evalID := r.PathValue("id")
-	if evalID == "" {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+// handleRerunEvaluation cria uma nova avaliação com o mesmo prompt e estratégia
+// de uma existente e responde com o container SSE da nova; a original não muda.
+// O acesso à original é validado por RequireEvaluationAccess.
+func handleRerunEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, _ := middleware.GetUser(r.Context())
+	parent, _ := middleware.GetEvaluation(r.Context())
+
+	// Policy check: permissão de criar no próprio tenant
+	if err := policies.CheckCreateEvaluation(r.Context(), user, user.TenantID); err != nil {
+		return errForbidden(err)
+	}
+
+	evalService, err := evaluationService(deps)
+	if errors.Is(err, service.ErrGeminiUnavailable) {
+		renderEvaluationUnavailable(w, r)
 		return nil
 	}
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	if err := policies.CheckEvaluationQuota(r.Context(), deps.Queries, user); err != nil {
+		return evaluationQuotaError(w, err)
+	}
+
+	evalID, err := evalService.RerunEvaluation(r.Context(), user.TenantID, user.ID, parent)
+	if errors.Is(err, service.ErrChatModelNotAllowed) {
+		http.Error(w, "O modelo da avaliação original não é mais permitido", http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rerun evaluation: %w", err)
+	}
+	wakeWorker(deps)
+	logging.AddToEvent(r.Context(), slog.String("parent_evaluation_id", parent.ID))
+
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.SSEEvaluationContainer(evalID)).ServeHTTP(w, r)
+	return nil
+}
 
+// handleBulkEvaluations importa um CSV de prompts e cria um experimento com uma
+// avaliação por linha; responde com o painel de progresso do experimento
+func handleBulkEvaluations(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
 	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return nil
 	}
 
-	// Check if evaluation exists
-	eval, err := deps.Queries.GetEvaluationByID(r.Context(), evalID)
+	if err := r.ParseMultipartForm(2 << 20); err != nil {
+		http.Error(w, "Upload inválido", http.StatusBadRequest)
+		return nil
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Arquivo CSV é obrigatório", http.StatusBadRequest)
+		return nil
+	}
+	defer file.Close()
+
+	items, err := service.ParseBulkCSV(file, deps.Config.BulkImportMaxRows)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("CSV inválido: %v", err), http.StatusBadRequest)
+		return nil
+	}
+
+	for i, item := range items {
+		if err := service.CheckPromptLength(item.Prompt, deps.Config.MaxPromptChars); err != nil {
+			http.Error(w, fmt.Sprintf("CSV inválido: o prompt %d excede o limite de %d caracteres", i+1, deps.Config.MaxPromptChars), http.StatusBadRequest)
+			return nil
+		}
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		name = header.Filename
+	}
+
+	// O lote inteiro precisa caber na quota mensal do tenant
+	if err := policies.CheckEvaluationQuotaBatch(r.Context(), deps.Queries, user, len(items)); err != nil {
+		return evaluationQuotaError(w, err)
+	}
+
+	evalService, err := evaluationService(deps)
 	if err != nil {
-		return fmt.Errorf("failed to get evaluation: %w", err)
+		return evaluationServiceError(err)
 	}
 
-	// Policy check: User can only access evaluations from their tenant
-	if eval.TenantID != user.TenantID {
+	// Experimento e avaliações são criados juntos: um CSV nunca é importado pela metade
+	tx, err := deps.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	txService := evalService.WithTx(tx)
+	if err := txService.CheckEvaluationCapacity(r.Context(), user.TenantID, len(items), deps.Config.MaxActiveEvaluations); err != nil {
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			http.Error(w, "Limite de avaliações em andamento atingido", http.StatusTooManyRequests)
+			return nil
+		}
+		return err
+	}
+
+	experimentID, err := txService.StartExperiment(r.Context(), user.TenantID, user.ID, name, items, deps.Config.BulkImportInterval)
+	if err != nil {
+		return fmt.Errorf("failed to start experiment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit experiment: %w", err)
+	}
+	wakeWorker(deps)
+
+	logging.AddToEvent(r.Context(),
+		slog.String("experiment_id", experimentID),
+		slog.Int("experiment_size", len(items)),
+	)
+
+	return renderExperimentProgress(w, r, evalService, experimentID)
+}
+
+// handleExperimentStatus renderiza o progresso de um experimento (polling via HTMX)
+func handleExperimentStatus(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return nil
 	}
 
+	evalService, err := evaluationReader(deps)
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	experiment, err := deps.Queries.GetExperimentByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Experimento não encontrado", http.StatusNotFound)
+			return nil
+		}
+		return fmt.Errorf("failed to get experiment: %w", err)
+	}
+
+	if err := policies.CheckTenantAccess(r.Context(), user, experiment.TenantID); err != nil {
+		return errForbidden(err)
+	}
+
+	return renderExperimentProgress(w, r, evalService, experiment.ID)
+}
+
+func renderExperimentProgress(w http.ResponseWriter, r *http.Request, evalService *service.EvaluationService, experimentID string) error {
+	progress, err := evalService.GetExperimentProgress(r.Context(), experimentID)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.ExperimentProgress(progress.Experiment, progress.ByStatus)).ServeHTTP(w, r)
+	return nil
+}
+
+// handleLoadEvaluationResult renders the final evaluation result
+// (access checked by RequireEvaluationAccess)
+func handleLoadEvaluationResult(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	eval, _ := middleware.GetEvaluation(r.Context())
+	evalID := eval.ID
+
 	// Check if still processing or retrying
 	if eval.Status == "pending" || eval.Status == "processing" || eval.Status == "retrying" {
 		w.Header().Set("Content-Type", "text/html")
@@ -579,14 +1073,20 @@ func handleLoadEvaluationResult(deps HandlerDeps, w http.ResponseWriter, r *http
 		return nil
 	}
 
+	if eval.Status == "cancelled" {
+		w.Header().Set("Content-Type", "text/html")
+		templ.Handler(pages.SSECancelled(cancelledMessage(eval))).ServeHTTP(w, r)
+		return nil
+	}
+
 	// Check if failed
 	if eval.Status == "failed" {
-		w.Header().Set("Content-Type", "text/html")
-		errorMsg := "Avaliação falhou. Tente novamente."
-		if eval.ErrorMessage.Valid && eval.ErrorMessage.String != "" {
-			errorMsg = eval.ErrorMessage.String
+		details, err := loadEvaluationFailureDetails(r, deps.Queries, eval)
+		if err != nil {
+			return err
 		}
-		templ.Handler(pages.SSEError(errorMsg)).ServeHTTP(w, r)
+		w.Header().Set("Content-Type", "text/html")
+		templ.Handler(pages.EvaluationFailure(eval, details)).ServeHTTP(w, r)
 		return nil
 	}
 
@@ -612,9 +1112,264 @@ func handleLoadEvaluationResult(deps HandlerDeps, w http.ResponseWriter, r *http
 		return fmt.Errorf("failed to get audit: %w", err)
 	}
 
+	timings, err := deps.Queries.GetPhaseTimings(r.Context(), evalID)
+	if err != nil {
+		return fmt.Errorf("failed to get phase timings: %w", err)
+	}
+
+	// Respostas do LLM em markdown viram HTML sanitizado antes de chegar à view
+	responses := make(map[string]string, len(iterations))
+	for _, it := range iterations {
+		responses[it.ID] = service.RenderSafeMarkdown(it.Resposta)
+	}
+
 	// Render result using templ component
 	w.Header().Set("Content-Type", "text/html")
-	templ.Handler(pages.EvaluationResult(eval, iterations, audit)).ServeHTTP(w, r)
+	templ.Handler(pages.EvaluationResult(eval, iterations, responses, audit, timings)).ServeHTTP(w, r)
+	return nil
+}
+
+// loadEvaluationFailureDetails monta a tela de falha: todos veem a mensagem amigável
+// e o número de tentativas; quem passa em CanViewFailureDetails vê também a fase do
+// checkpoint e o último erro técnico do job
+func loadEvaluationFailureDetails(r *http.Request, q *db.Queries, eval db.Evaluation) (pages.EvaluationFailureDetails, error) {
+	details := pages.EvaluationFailureDetails{Message: "Avaliação falhou. Tente novamente."}
+	if eval.ErrorMessage.Valid && eval.ErrorMessage.String != "" {
+		details.Message = eval.ErrorMessage.String
+	}
+
+	attempts, err := q.GetEvaluationJobAttempts(r.Context(), sql.NullString{String: eval.ID, Valid: true})
+	if err != nil {
+		return details, fmt.Errorf("failed to get job attempts: %w", err)
+	}
+	details.Attempts = attempts
+
+	user, _ := middleware.GetUser(r.Context())
+	if !policies.CanViewFailureDetails(r.Context(), user, eval) {
+		return details, nil
+	}
+
+	// O checkpoint pode já ter sido removido pela limpeza de retenção
+	checkpoint, err := q.GetCheckpoint(r.Context(), eval.ID)
+	switch {
+	case err == nil:
+		details.Phase = checkpoint.CurrentPhase
+	case !errors.Is(err, sql.ErrNoRows):
+		return details, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	lastError, err := q.GetEvaluationLastJobError(r.Context(), sql.NullString{String: eval.ID, Valid: true})
+	switch {
+	case err == nil:
+		details.LastError = lastError.String
+	case !errors.Is(err, sql.ErrNoRows):
+		return details, fmt.Errorf("failed to get last job error: %w", err)
+	}
+
+	return details, nil
+}
+
+// handleExportEvaluation baixa o relatório de uma avaliação concluída
+// (acesso validado por RequireEvaluationAccess)
+func handleExportEvaluation(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	eval, _ := middleware.GetEvaluation(r.Context())
+	evalID := eval.ID
+
+	evalService, err := evaluationReader(deps)
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.ExportFormatMarkdown
+	}
+	doc, contentType, err := evalService.ExportEvaluation(r.Context(), evalID, format)
+	switch {
+	case errors.Is(err, service.ErrUnsupportedExportFormat):
+		http.Error(w, "Formato não suportado", http.StatusBadRequest)
+		return nil
+	case errors.Is(err, service.ErrEvaluationIncomplete):
+		http.Error(w, "Avaliação ainda não concluída", http.StatusNotFound)
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to export evaluation: %w", err)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="avaliacao-%s.%s"`, evalID, format))
+	_, _ = w.Write(doc)
+	return nil
+}
+
+// evaluationEmbeddingsResponse é o corpo de GET /api/evaluations/{id}/embeddings
+type evaluationEmbeddingsResponse struct {
+	EvaluationID string                       `json:"evaluation_id"`
+	Iterations   []service.IterationEmbedding `json:"iterations"`
+}
+
+// handleEvaluationEmbeddings exporta os embeddings brutos das iterações
+// (acesso validado por RequireEvaluationAccess)
+// @Summary Embeddings de uma avaliação
+// @Description Lista fase e embedding de cada iteração, na ordem do protocolo. Iterações sem embedding persistido vêm com embedding null.
+// @Tags evaluations
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Success 200 {object} evaluationEmbeddingsResponse
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Router /api/evaluations/{id}/embeddings [get]
+func handleEvaluationEmbeddings(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	eval, _ := middleware.GetEvaluation(r.Context())
+
+	evalService, err := evaluationReader(deps)
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	iterations, err := evalService.IterationEmbeddings(r.Context(), eval.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load embeddings: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(evaluationEmbeddingsResponse{
+		EvaluationID: eval.ID,
+		Iterations:   iterations,
+	})
+}
+
+// handleCompareEvaluations renderiza duas avaliações lado a lado (?a={id1}&b={id2})
+func handleCompareEvaluations(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	idA, idB := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if idA == "" || idB == "" {
+		http.Error(w, "Informe as avaliações a e b", http.StatusBadRequest)
+		return nil
+	}
+
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	evalService, err := evaluationReader(deps)
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	cmp, err := evalService.CompareEvaluations(r.Context(), idA, idB)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Avaliação não encontrada", http.StatusNotFound)
+			return nil
+		}
+		return fmt.Errorf("failed to compare evaluations: %w", err)
+	}
+
+	// Policy check: acesso às duas e, para não-admins, mesmo tenant
+	if err := policies.CheckCompareEvaluations(r.Context(), user, cmp.A.Evaluation, cmp.B.Evaluation); err != nil {
+		return errForbidden(err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.EvaluationCompare(pages.CompareData{
+		A:                pages.CompareSide(cmp.A),
+		B:                pages.CompareSide(cmp.B),
+		SamePrompt:       cmp.SamePrompt,
+		Comparable:       cmp.Comparable,
+		DivergenceDelta:  cmp.DivergenceDelta,
+		DiagnosisChanged: cmp.DiagnosisChanged,
+	})).ServeHTTP(w, r)
+	return nil
+}
+
+// evaluationStatuses são os valores aceitos no filtro de status da listagem
+var evaluationStatuses = map[string]bool{
+	"pending":    true,
+	"processing": true,
+	"retrying":   true,
+	"completed":  true,
+	"failed":     true,
+	"cancelled":  true,
+}
+
+// parseDateFilter converte uma data ISO (2006-01-02 ou RFC 3339) para o formato
+// de created_at em UTC. Com end, uma data sem hora inclui o dia inteiro (o limite
+// vira o início do dia seguinte). Valores vazios ou inválidos retornam "".
+func parseDateFilter(v string, end bool) string {
+	t := parseDateParam(v, end)
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// parseDateParam é parseDateFilter retornando time.Time (zero se vazio ou inválido)
+func parseDateParam(v string, end bool) time.Time {
+	if v == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC()
+	}
+	return time.Time{}
+}
+
+// handleEvaluationStats renderiza as estatísticas agregadas por tenant
+// (?tenant=&from=&to=). Usuários veem apenas o próprio tenant; admins veem
+// todos quando tenant é omitido.
+func handleEvaluationStats(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	query := r.URL.Query()
+	tenantID := strings.TrimSpace(query.Get("tenant"))
+	if tenantID == "" && !policies.IsAdmin(user) {
+		tenantID = user.TenantID
+	}
+	if tenantID != "" {
+		if err := policies.CheckTenantAccess(r.Context(), user, tenantID); err != nil {
+			return errForbidden(err)
+		}
+	}
+
+	evalService, err := evaluationReader(deps)
+	if err != nil {
+		return evaluationServiceError(err)
+	}
+
+	summaries, err := evalService.EvaluationStats(r.Context(), tenantID,
+		parseDateParam(query.Get("from"), false), parseDateParam(query.Get("to"), true))
+	if err != nil {
+		return fmt.Errorf("failed to load evaluation stats: %w", err)
+	}
+
+	stats := make([]pages.TenantStats, 0, len(summaries))
+	for _, st := range summaries {
+		stats = append(stats, pages.TenantStats{
+			TenantID:      st.TenantID,
+			Total:         st.Total,
+			ByStatus:      st.ByStatus,
+			ByDiagnosis:   st.ByDiagnosis,
+			AvgDivergence: st.AvgDivergence,
+			Measured:      st.Measured,
+			FailureRate:   st.FailureRate(),
+			TotalTokens:   st.TotalTokens,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	templ.Handler(pages.EvaluationStats(stats)).ServeHTTP(w, r)
 	return nil
 }
 
@@ -626,29 +1381,45 @@ func handleListEvaluations(deps HandlerDeps, w http.ResponseWriter, r *http.Requ
 		return nil
 	}
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	query := r.URL.Query()
+	paging := db.ParsePagingParams(query, 10)
+
+	// search casa com o início do prompt ou do diagnóstico; status desconhecido é ignorado
+	search := db.EscapeLike(strings.TrimSpace(query.Get("search")))
+	status := query.Get("status")
+	if !evaluationStatuses[status] {
+		status = ""
 	}
+	// Intervalo de criação; datas inválidas são ignoradas
+	from := parseDateFilter(query.Get("from"), false)
+	to := parseDateFilter(query.Get("to"), true)
 
 	// Policy check: User can only list evaluations from their tenant
 	if err := policies.CheckTenantAccess(r.Context(), user, user.TenantID); err != nil {
-		return fmt.Errorf("access denied: %w", err)
-	}
-
-	evaluations, err := deps.Queries.ListEvaluationsPaginated(r.Context(), db.ListEvaluationsPaginatedParams{
-		TenantID: user.TenantID,
-		UserID:   user.ID,
-		Limit:    10,
-		Offset:   int64((page - 1) * 10),
+		return errForbidden(err)
+	}
+
+	evaluations, err := deps.Queries.ListEvaluationsFiltered(r.Context(), db.ListEvaluationsFilteredParams{
+		TenantID:    user.TenantID,
+		UserID:      user.ID,
+		Search:      search,
+		Status:      status,
+		CreatedFrom: from,
+		CreatedTo:   to,
+		Limit:       int64(paging.Limit()),
+		Offset:      int64(paging.Offset()),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to list evaluations: %w", err)
 	}
 
-	total, err := deps.Queries.CountEvaluations(r.Context(), db.CountEvaluationsParams{
-		TenantID: user.TenantID,
-		UserID:   user.ID,
+	total, err := deps.Queries.CountEvaluationsFiltered(r.Context(), db.CountEvaluationsFilteredParams{
+		TenantID:    user.TenantID,
+		UserID:      user.ID,
+		Search:      search,
+		Status:      status,
+		CreatedFrom: from,
+		CreatedTo:   to,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to count evaluations: %w", err)
@@ -660,8 +1431,8 @@ func handleListEvaluations(deps HandlerDeps, w http.ResponseWriter, r *http.Requ
 	fmt.Fprintf(w, `<h2>Suas Avaliações (%d)</h2>`, total)
 	for _, eval := range evaluations {
 		statusClass := "status-" + eval.Status
-		fmt.Fprintf(w, `<div class="evaluation-item %s"><a href="/htmx/evaluations/%s/result">%s - %s</a></div>`,
-			statusClass, eval.ID, eval.CreatedAt.Time.Format("2006-01-02 15:04"), eval.Status)
+		fmt.Fprintf(w, `<div class="evaluation-item %s"><a href="#evaluation-container" hx-get="/htmx/evaluations/%s/result" hx-target="#evaluation-container" hx-swap="innerHTML">%s - <span class="px-2 rounded %s">%s</span></a></div>`,
+			statusClass, eval.ID, eval.CreatedAt.Time.Format("2006-01-02 15:04"), pages.StatusClass(eval.Status), eval.Status)
 	}
 	fmt.Fprint(w, `</div>`)
 
@@ -678,10 +1449,10 @@ func handleActiveEvaluations(deps HandlerDeps, w http.ResponseWriter, r *http.Re
 
 	// Policy check
 	if err := policies.CheckTenantAccess(r.Context(), user, user.TenantID); err != nil {
-		return fmt.Errorf("access denied: %w", err)
+		return errForbidden(err)
 	}
 
-	// Busca avaliações ativas (processing ou retrying)
+	// Busca avaliações ativas (processing ou retrying); canceladas ficam só no histórico
 	evaluations, err := deps.Queries.ListEvaluationsByStatus(r.Context(), db.ListEvaluationsByStatusParams{
 		TenantID: user.TenantID,
 		UserID:   user.ID,
@@ -704,30 +1475,23 @@ func handleActiveEvaluations(deps HandlerDeps, w http.ResponseWriter, r *http.Re
 	return nil
 }
 
-// handleEvaluationStatus verifica status de uma avaliação específica e retorna componente apropriado
-func handleEvaluationStatus(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
-	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return nil
-	}
-
-	evalID := r.PathValue("id")
-	if evalID == "" {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
-		return nil
-	}
+// evaluationNotFound é o fragmento 404 das rotas de polling/resultado, que
+// substitui o container em vez de exibir o texto puro do http.Error
+var evaluationNotFound = templ.Handler(pages.EvaluationNotFound(), templ.WithStatus(http.StatusNotFound))
 
-	// Policy check: verificar se usuário pode acessar esta avaliação
-	eval, err := deps.Queries.GetEvaluationByID(r.Context(), evalID)
-	if err != nil {
-		return fmt.Errorf("failed to get evaluation: %w", err)
+// cancelledMessage é o motivo registrado no cancelamento ou um texto padrão
+func cancelledMessage(eval db.Evaluation) string {
+	if eval.ErrorMessage.Valid && eval.ErrorMessage.String != "" {
+		return eval.ErrorMessage.String
 	}
+	return "Avaliação cancelada."
+}
 
-	if eval.TenantID != user.TenantID {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return nil
-	}
+// handleEvaluationStatus verifica status de uma avaliação específica e retorna componente apropriado
+// (acesso validado por RequireEvaluationAccess)
+func handleEvaluationStatus(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	eval, _ := middleware.GetEvaluation(r.Context())
+	evalID := eval.ID
 
 	// Verifica status atual
 	switch eval.Status {
@@ -750,12 +1514,20 @@ func handleEvaluationStatus(deps HandlerDeps, w http.ResponseWriter, r *http.Req
 		templ.Handler(pages.SSERetrying(evalID, checkpoint.RetryCount, nextRetryAt)).ServeHTTP(w, r)
 		return nil
 
-	case "processing":
-		// Ainda tá processando, retorna status de processamento
+	case "pending", "processing":
+		// Progresso persistido no checkpoint; sem checkpoint a avaliação ainda não começou
+		var phase string
+		var step int
+		checkpoint, err := deps.Queries.GetCheckpoint(r.Context(), evalID)
+		switch {
+		case err == nil:
+			phase, step = checkpoint.ProgressPhase.String, int(checkpoint.ProgressStep)
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("failed to get checkpoint: %w", err)
+		}
+
 		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, `<div class="bg-yellow-50 border border-yellow-200 rounded-lg p-4">
-			<p class="text-yellow-800">⏳ Processando avaliação...</p>
-		</div>`)
+		templ.Handler(pages.SSEProcessing(evalID, phase, step, service.ProtocolSteps)).ServeHTTP(w, r)
 		return nil
 
 	case "completed":
@@ -779,6 +1551,12 @@ func handleEvaluationStatus(deps HandlerDeps, w http.ResponseWriter, r *http.Req
 		templ.Handler(pages.SSEError(errorMsg)).ServeHTTP(w, r)
 		return nil
 
+	case "cancelled":
+		// Terminal como completed/failed: o fragmento não agenda novo polling
+		w.Header().Set("Content-Type", "text/html")
+		templ.Handler(pages.SSECancelled(cancelledMessage(eval))).ServeHTTP(w, r)
+		return nil
+
 	default:
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(""))
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// loginAttemptKey normaliza o email usado como chave do contador de falhas
+func loginAttemptKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLockedUntil retorna até quando o email está bloqueado (zero = liberado)
+func loginLockedUntil(ctx context.Context, deps HandlerDeps, email string) (time.Time, error) {
+	attempt, err := deps.Queries.GetLoginAttempt(ctx, loginAttemptKey(email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if attempt.LockedUntil.Valid && time.Now().Before(attempt.LockedUntil.Time) {
+		return attempt.LockedUntil.Time, nil
+	}
+	return time.Time{}, nil
+}
+
+// recordFailedLogin contabiliza uma falha na janela configurada e bloqueia o
+// email ao atingir o limite. Retorna o fim do bloqueio (zero = não bloqueou).
+func recordFailedLogin(ctx context.Context, deps HandlerDeps, email string) (time.Time, error) {
+	key := loginAttemptKey(email)
+
+	// Falhas fora da janela não contam
+	if err := deps.Queries.ExpireLoginAttempts(ctx, db.ExpireLoginAttemptsParams{
+		Email:         key,
+		WindowMinutes: sql.NullString{String: fmt.Sprintf("%d", int(deps.Config.LoginAttemptWindow.Minutes())), Valid: true},
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to expire login attempts: %w", err)
+	}
+
+	attempt, err := deps.Queries.RecordFailedLogin(ctx, key)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	if attempt.FailedCount < int64(deps.Config.LoginMaxAttempts) {
+		return time.Time{}, nil
+	}
+
+	lockedUntil := time.Now().Add(deps.Config.LoginLockoutDuration).UTC()
+	if err := deps.Queries.LockLogin(ctx, db.LockLoginParams{
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		Email:       key,
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to lock login: %w", err)
+	}
+	return lockedUntil, nil
+}
+
+func loginLockedMessage(until time.Time) string {
+	minutes := int(math.Ceil(time.Until(until).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	return fmt.Sprintf("Muitas tentativas de login falhas. Tente novamente em %d minuto(s).", minutes)
+}
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords é uma blocklist curta das senhas mais usadas em vazamentos
+// (comparação sem diferenciar maiúsculas)
+var commonPasswords = map[string]bool{
+	"123456": true, "12345678": true, "123456789": true, "1234567890": true,
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"qwerty": true, "qwerty123": true, "qwertyuiop": true, "abc123": true,
+	"abcd1234": true, "111111": true, "11111111": true, "000000": true,
+	"iloveyou": true, "admin": true, "admin123": true, "administrator": true,
+	"welcome": true, "welcome1": true, "letmein": true, "monkey": true,
+	"dragon": true, "football": true, "sunshine": true, "princess": true,
+	"senha": true, "senha123": true, "senha1234": true, "mudar123": true,
+	"brasil": true, "brasil123": true, "changeme": true, "trustno1": true,
+}
+
+// passwordPolicy são os requisitos de força de senha (ver config.PasswordMin*)
+type passwordPolicy struct {
+	MinLength  int
+	MinClasses int // entre minúscula, maiúscula, dígito e símbolo
+}
+
+// validatePassword retorna um erro com a mensagem exibida ao usuário quando a
+// senha não atende à política. Roda antes do bcrypt.
+func validatePassword(pw string, policy passwordPolicy) error {
+	if strings.TrimSpace(pw) == "" {
+		return errors.New("Informe uma senha")
+	}
+	if n := len([]rune(pw)); n < policy.MinLength {
+		return fmt.Errorf("A senha deve ter pelo menos %d caracteres", policy.MinLength)
+	}
+	// bcrypt ignora o que passar de 72 bytes
+	if len(pw) > 72 {
+		return errors.New("A senha deve ter no máximo 72 bytes")
+	}
+	if commonPasswords[strings.ToLower(pw)] {
+		return errors.New("Essa senha é muito comum. Escolha outra")
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < policy.MinClasses {
+		return fmt.Errorf("A senha deve combinar pelo menos %d tipos de caractere: minúsculas, maiúsculas, números e símbolos", policy.MinClasses)
+	}
+	return nil
+}
+
+func passwordPolicyFrom(deps HandlerDeps) passwordPolicy {
+	return passwordPolicy{
+		MinLength:  deps.Config.PasswordMinLength,
+		MinClasses: deps.Config.PasswordMinClasses,
+	}
+}
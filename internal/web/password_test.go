@@ -0,0 +1,32 @@
+package web
+
+import "testing"
+
+func TestValidatePassword(t *testing.T) {
+	policy := passwordPolicy{MinLength: 8, MinClasses: 2}
+	tests := []struct {
+		name string
+		pw   string
+		ok   bool
+	}{
+		{"vazia", "", false},
+		{"curta", "Ab1!", false},
+		{"comum", "Password123", false},
+		{"uma classe", "abcdefghij", false},
+		{"longa demais", string(make([]byte, 73)), false},
+		{"duas classes", "correcthorse9", true},
+		{"unicode", "çãoÉ senha longa", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePassword(tt.pw, policy)
+			if (err == nil) != tt.ok {
+				t.Errorf("validatePassword(%q) = %v, ok esperado %v", tt.pw, err, tt.ok)
+			}
+		})
+	}
+
+	if err := validatePassword("abcdefghij", passwordPolicy{MinLength: 8, MinClasses: 1}); err != nil {
+		t.Errorf("com uma classe exigida a senha deveria passar: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/PauloHFS/elenchus/internal/contextkeys"
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/routes"
+	"github.com/PauloHFS/elenchus/internal/view/pages"
+	"github.com/a-h/templ"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errWrongCurrentPassword é exibido quando a senha atual não confere
+const errWrongCurrentPassword = "Senha atual incorreta"
+
+// handleProfile mostra os dados do usuário logado; message vem do redirect
+// após uma alteração bem-sucedida
+func handleProfile(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	templ.Handler(pages.Profile(user, r.URL.Query().Get("message"), "")).ServeHTTP(w, r)
+	return nil
+}
+
+// handleProfileUpdate troca a senha (action=password) ou o email (action=email)
+// do usuário logado. Ambas exigem a senha atual; o novo email volta a ficar
+// não verificado e recebe um novo link de verificação.
+func handleProfileUpdate(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	renderError := func(msg string) error {
+		templ.Handler(pages.Profile(user, "", msg), templ.WithStatus(http.StatusUnprocessableEntity)).ServeHTTP(w, r)
+		return nil
+	}
+
+	action := r.FormValue("action")
+	if action != "password" && action != "email" {
+		return renderError("Ação inválida")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(r.FormValue("current_password"))); err != nil {
+		return renderError(errWrongCurrentPassword)
+	}
+
+	if action == "password" {
+		password := r.FormValue("password")
+		if err := validatePassword(password, passwordPolicyFrom(deps)); err != nil {
+			return renderError(err.Error())
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		if err := deps.Queries.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+			PasswordHash: string(hash),
+			TenantID:     user.TenantID,
+			Email:        user.Email,
+		}); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		// Novo token de sessão após trocar a credencial (session fixation)
+		if err := deps.SessionManager.RenewToken(r.Context()); err != nil {
+			return fmt.Errorf("failed to renew session: %w", err)
+		}
+
+		http.Redirect(w, r, routes.Profile+"?message=Senha alterada com sucesso", http.StatusSeeOther)
+		return nil
+	}
+
+	email := normalizeEmail(r.FormValue("email"))
+	if err := validateEmail(email); err != nil {
+		return renderError(err.Error())
+	}
+	if email == user.Email {
+		return renderError("O novo e-mail é igual ao atual")
+	}
+	if _, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+		TenantID: user.TenantID,
+		Email:    email,
+	}); err == nil {
+		return renderError("Este e-mail já está em uso")
+	}
+
+	tx, err := deps.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := deps.Queries.WithTx(tx)
+
+	if err := qtx.UpdateUserEmail(r.Context(), db.UpdateUserEmailParams{
+		Email: email,
+		ID:    user.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	// Um link pendente do email antigo não deve mais verificar a conta
+	if err := qtx.DeleteEmailVerification(r.Context(), db.DeleteEmailVerificationParams{
+		TenantID: user.TenantID,
+		Email:    user.Email,
+	}); err != nil {
+		return fmt.Errorf("failed to delete old email verification: %w", err)
+	}
+	if err := enqueueVerificationEmail(r.Context(), qtx, user.TenantID, email); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit email change: %w", err)
+	}
+	wakeWorker(deps)
+
+	http.Redirect(w, r, routes.Profile+"?message=E-mail alterado. Verifique sua caixa de entrada para confirmar o novo endereço.", http.StatusSeeOther)
+	return nil
+}
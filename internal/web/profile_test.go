@@ -0,0 +1,106 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PauloHFS/elenchus/internal/config"
+	"github.com/PauloHFS/elenchus/internal/contextkeys"
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/alexedwards/scs/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProfileUpdate(t *testing.T) {
+	dbConn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if err := db.RunMigrations(context.Background(), dbConn); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("Atual#2024"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('default', 'Default');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id, is_verified) VALUES (1, 'default', 'eu@example.com', ?, 'user', TRUE);
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (2, 'default', 'outro@example.com', 'x', 'user');
+	`, string(hash)); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := db.New(dbConn)
+	sessions := scs.New()
+	deps := HandlerDeps{
+		DB:             dbConn,
+		Queries:        queries,
+		SessionManager: sessions,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Config:         &config.Config{PasswordMinLength: 8, PasswordMinClasses: 2},
+	}
+	handler := sessions.LoadAndSave(Handle(deps, handleProfileUpdate))
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		user, err := queries.GetUserByID(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", "/profile", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserContextKey, user))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rejected := []struct {
+		name string
+		form url.Values
+	}{
+		{"senha atual errada", url.Values{"action": {"password"}, "current_password": {"errada"}, "password": {"Nova#Senha1"}}},
+		{"senha fraca", url.Values{"action": {"password"}, "current_password": {"Atual#2024"}, "password": {"123"}}},
+		{"email inválido", url.Values{"action": {"email"}, "current_password": {"Atual#2024"}, "email": {"invalido"}}},
+		{"email em uso", url.Values{"action": {"email"}, "current_password": {"Atual#2024"}, "email": {"outro@example.com"}}},
+		{"email sem senha atual", url.Values{"action": {"email"}, "email": {"novo@example.com"}}},
+	}
+	for _, tt := range rejected {
+		if rr := post(tt.form); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, esperado 422", tt.name, rr.Code)
+		}
+	}
+
+	rr := post(url.Values{"action": {"password"}, "current_password": {"Atual#2024"}, "password": {"Nova#Senha1"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("troca de senha: status %d, esperado 303", rr.Code)
+	}
+	user, _ := queries.GetUserByID(context.Background(), 1)
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("Nova#Senha1")) != nil {
+		t.Error("senha não foi alterada")
+	}
+
+	rr = post(url.Values{"action": {"email"}, "current_password": {"Nova#Senha1"}, "email": {" Novo@Example.com "}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("troca de email: status %d, esperado 303", rr.Code)
+	}
+	user, _ = queries.GetUserByID(context.Background(), 1)
+	if user.Email != "novo@example.com" || user.IsVerified {
+		t.Errorf("email = %q verificado=%v, esperado novo@example.com não verificado", user.Email, user.IsVerified)
+	}
+	var pending int
+	if err := dbConn.QueryRow(`SELECT COUNT(*) FROM jobs WHERE type = 'send_verification_email'`).Scan(&pending); err != nil || pending != 1 {
+		t.Errorf("jobs de verificação = %d (err=%v), esperado 1", pending, err)
+	}
+}
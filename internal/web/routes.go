@@ -11,8 +11,16 @@ const (
 	ResetPassword  = "/reset-password"
 	VerifyEmail    = "/verify-email"
 	Dashboard      = "/dashboard"
-	Health         = "/health"
+	Health         = "/health" // alias de HealthReady
+	HealthLive     = "/health/live"
+	HealthReady    = "/health/ready"
 	Metrics        = "/metrics"
+	Avatar         = AvatarURLPrefix + "{name}"
+
+	// StorageURLPrefix é o caminho público do storage local, independente de
+	// STORAGE_DIR; avatars ficam sob a chave "avatars/"
+	StorageURLPrefix = "/storage/"
+	AvatarURLPrefix  = StorageURLPrefix + "avatars/"
 )
 
 // WebhookRoute generates the path for a webhook source
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const defaultTenantID = "default"
+
+// errUnknownTenant indica que o tenant informado (campo ou subdomínio) não existe
+var errUnknownTenant = errors.New("tenant not found")
+
+// tenantIDFromRequest deriva o tenant das rotas públicas de autenticação. O
+// subdomínio de baseDomain é autoritativo; sem ele, o campo "tenant" do
+// formulário vale só quando allowField (login e recuperação, que exigem uma
+// conta já existente) e, por fim, "default". O cadastro nunca aceita o campo:
+// qualquer um entraria em um tenant alheio e ganharia leitura das avaliações dele.
+func tenantIDFromRequest(r *http.Request, baseDomain string, allowField bool) string {
+	if id := tenantFromHost(r.Host, baseDomain); id != "" {
+		return id
+	}
+	if allowField {
+		if id := strings.ToLower(strings.TrimSpace(r.FormValue("tenant"))); id != "" {
+			return id
+		}
+	}
+	return defaultTenantID
+}
+
+// tenantFromHost extrai "acme" de "acme.<baseDomain>[:porta]". Subdomínios
+// aninhados e o próprio domínio base não identificam tenant.
+func tenantFromHost(host, baseDomain string) string {
+	baseDomain = strings.ToLower(strings.Trim(baseDomain, "."))
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	sub, ok := strings.CutSuffix(host, "."+baseDomain)
+	if !ok || sub == "" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
+
+// resolveTenant retorna o ID do tenant de login/recuperação de conta;
+// errUnknownTenant se não existir
+func resolveTenant(ctx context.Context, deps HandlerDeps, r *http.Request) (string, error) {
+	return checkTenant(ctx, deps, tenantIDFromRequest(r, deps.Config.TenantBaseDomain, true))
+}
+
+// resolveRegistrationTenant retorna o tenant de um novo cadastro, que vem só
+// do subdomínio (ou "default"); errUnknownTenant se não existir
+func resolveRegistrationTenant(ctx context.Context, deps HandlerDeps, r *http.Request) (string, error) {
+	return checkTenant(ctx, deps, tenantIDFromRequest(r, deps.Config.TenantBaseDomain, false))
+}
+
+func checkTenant(ctx context.Context, deps HandlerDeps, id string) (string, error) {
+	exists, err := deps.Queries.TenantExists(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to check tenant: %w", err)
+	}
+	if exists == 0 {
+		return "", errUnknownTenant
+	}
+	return id, nil
+}
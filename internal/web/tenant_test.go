@@ -0,0 +1,35 @@
+package web
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTenantIDFromRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		field      string
+		allowField bool
+		want       string
+	}{
+		{"subdomínio", "acme.elenchus.app", "", true, "acme"},
+		{"subdomínio prevalece sobre o campo", "acme.elenchus.app:8080", "outro", true, "acme"},
+		{"campo sem subdomínio no login", "elenchus.app", "Outro", true, "outro"},
+		{"cadastro ignora o campo", "elenchus.app", "outro", false, defaultTenantID},
+		{"nada informado", "localhost:8080", "", true, defaultTenantID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"tenant": {tt.field}}
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Host = tt.host
+			if got := tenantIDFromRequest(req, "elenchus.app", tt.allowField); got != tt.want {
+				t.Errorf("tenant = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
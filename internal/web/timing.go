@@ -0,0 +1,29 @@
+package web
+
+import (
+	"context"
+	"time"
+)
+
+// forgotPasswordMinDuration é o tempo mínimo de resposta do "esqueci a senha".
+// Com email existente o handler grava token e job; sem ele responde direto.
+// Igualar os dois caminhos por baixo evita enumerar contas pelo tempo.
+var forgotPasswordMinDuration = 400 * time.Millisecond
+
+// Relógio do padding; os testes substituem por um relógio falso
+var (
+	timingNow   = time.Now
+	timingAfter = time.After
+)
+
+// waitMinDuration bloqueia até que min tenha passado desde start (ou o ctx acabe)
+func waitMinDuration(ctx context.Context, start time.Time, min time.Duration) {
+	remaining := min - timingNow().Sub(start)
+	if remaining <= 0 {
+		return
+	}
+	select {
+	case <-timingAfter(remaining):
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/config"
+	"github.com/PauloHFS/elenchus/internal/db"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestForgotPasswordTiming(t *testing.T) {
+	dbConn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if err := db.RunMigrations(context.Background(), dbConn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('default', 'Default');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (tenant_id, email, password_hash, role_id) VALUES ('default', 'existe@example.com', 'x', 'user');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Relógio falso: o trabalho do handler não avança o tempo e cada espera
+	// avança exatamente o pedido, então os dois caminhos são comparáveis sem
+	// depender da velocidade da máquina
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var waits []time.Duration
+	prevNow, prevAfter := timingNow, timingAfter
+	timingNow = func() time.Time { return clock }
+	timingAfter = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		clock = clock.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- clock
+		return ch
+	}
+	t.Cleanup(func() { timingNow, timingAfter = prevNow, prevAfter })
+
+	deps := HandlerDeps{DB: dbConn, Queries: db.New(dbConn), Config: &config.Config{}}
+	measure := func(email string) time.Duration {
+		form := url.Values{"email": {email}}
+		req := httptest.NewRequest("POST", "/forgot-password", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+
+		start := timingNow()
+		if err := handleForgotPassword(deps, rr, req); err != nil {
+			t.Fatalf("handleForgotPassword(%s): %v", email, err)
+		}
+		return timingNow().Sub(start)
+	}
+
+	for _, email := range []string{"existe@example.com", "nao-existe@example.com"} {
+		waits = nil
+		if got := measure(email); got != forgotPasswordMinDuration {
+			t.Errorf("%s: resposta levou %v, want %v", email, got, forgotPasswordMinDuration)
+		}
+		if len(waits) != 1 {
+			t.Errorf("%s: esperas = %v, want exatamente uma", email, waits)
+		}
+	}
+
+	// Trabalho que já passou do mínimo não espera mais
+	waits = nil
+	waitMinDuration(context.Background(), clock.Add(-forgotPasswordMinDuration), forgotPasswordMinDuration)
+	if len(waits) != 0 {
+		t.Errorf("esperou %v depois do mínimo", waits)
+	}
+}
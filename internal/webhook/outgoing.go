@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Política de retry dos webhooks de saída. É independente da política genérica
+// de jobs: destinos externos costumam ficar indisponíveis por mais tempo, então
+// aceitamos mais tentativas com um backoff mais longo.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 8
+	BaseRetryDelay     = 30 * time.Second
+	MaxRetryDelay      = 1 * time.Hour
+	BackoffMultiplier  = 2.0
+)
+
+// Eventos de saída
+const (
+	EventEvaluationCompleted = "evaluation.completed"
+)
+
+// SignatureHeader carrega a assinatura HMAC-SHA256 do corpo ("sha256=<hex>")
+const SignatureHeader = "X-Signature"
+
+// Outcome classifica o resultado de uma tentativa de entrega
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeRetry   Outcome = "retry"
+	OutcomeFailed  Outcome = "failed"
+)
+
+// Config é a configuração de webhook de saída de um tenant,
+// armazenada em tenants.settings sob a chave "webhook"
+type Config struct {
+	URL            string `json:"url"`
+	Secret         string `json:"secret"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	MaxAttempts    int    `json:"max_attempts"`
+}
+
+// Timeout retorna o timeout por tentativa, aplicando o default
+func (c Config) Timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return DefaultTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Attempts retorna o número máximo de tentativas, aplicando o default
+func (c Config) Attempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// ParseTenantConfig extrai a configuração de webhook do JSON de settings do tenant.
+// Retorna uma Config vazia (URL == "") quando o tenant não configurou webhook.
+func ParseTenantConfig(settings []byte) (Config, error) {
+	var s struct {
+		Webhook Config `json:"webhook"`
+	}
+	if len(settings) == 0 {
+		return Config{}, nil
+	}
+	if err := json.Unmarshal(settings, &s); err != nil {
+		return Config{}, fmt.Errorf("invalid tenant settings: %w", err)
+	}
+	return s.Webhook, nil
+}
+
+// Sign calcula a assinatura HMAC-SHA256 do corpo com o segredo do tenant,
+// no formato "sha256=<hex>". O receptor valida recalculando sobre o corpo bruto.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ClassifyStatus mapeia o status HTTP para o resultado da entrega:
+// 2xx = sucesso, 429 e 5xx = retry, demais 4xx = falha permanente
+func ClassifyStatus(code int) Outcome {
+	switch {
+	case code >= 200 && code < 300:
+		return OutcomeSuccess
+	case code == http.StatusTooManyRequests:
+		return OutcomeRetry
+	case code >= 400 && code < 500:
+		return OutcomeFailed
+	case code >= 500:
+		return OutcomeRetry
+	default:
+		// 1xx/3xx não são esperados de um receptor de webhook
+		return OutcomeFailed
+	}
+}
+
+// RetryDelay calcula o backoff exponencial para a próxima tentativa
+// (attempt começa em 1 para a primeira tentativa que falhou)
+func RetryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(BaseRetryDelay) * math.Pow(BackoffMultiplier, float64(attempt-1))
+	if delay > float64(MaxRetryDelay) {
+		return MaxRetryDelay
+	}
+	return time.Duration(delay)
+}
+
+// DeliveryResult descreve uma tentativa de entrega
+type DeliveryResult struct {
+	StatusCode int
+	Outcome    Outcome
+	Duration   time.Duration
+	Err        error
+}
+
+// Deliver envia o payload via POST para a URL configurada, respeitando o timeout
+// do tenant e assinando o corpo quando há segredo configurado. Erros de rede (timeout, conexão recusada) são tratados como retry.
+func Deliver(ctx context.Context, client *http.Client, cfg Config, deliveryID, event string, body []byte) DeliveryResult {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return DeliveryResult{Outcome: OutcomeFailed, Duration: time.Since(start), Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "elenchus-webhook/1.0")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Delivery", deliveryID)
+	if cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(cfg.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return DeliveryResult{Outcome: OutcomeRetry, Duration: time.Since(start), Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Descarta o corpo para permitir reuso da conexão
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	result := DeliveryResult{
+		StatusCode: resp.StatusCode,
+		Outcome:    ClassifyStatus(resp.StatusCode),
+		Duration:   time.Since(start),
+	}
+	if result.Outcome != OutcomeSuccess {
+		result.Err = fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return result
+}
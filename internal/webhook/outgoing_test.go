@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want Outcome
+	}{
+		{200, OutcomeSuccess},
+		{204, OutcomeSuccess},
+		{400, OutcomeFailed},
+		{404, OutcomeFailed},
+		{429, OutcomeRetry},
+		{500, OutcomeRetry},
+		{503, OutcomeRetry},
+		{301, OutcomeFailed},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyStatus(tt.code); got != tt.want {
+			t.Errorf("ClassifyStatus(%d) = %s, want %s", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if got := RetryDelay(1); got != BaseRetryDelay {
+		t.Errorf("expected %s for first retry, got %s", BaseRetryDelay, got)
+	}
+	if got := RetryDelay(3); got != 4*BaseRetryDelay {
+		t.Errorf("expected %s for third retry, got %s", 4*BaseRetryDelay, got)
+	}
+	if got := RetryDelay(50); got != MaxRetryDelay {
+		t.Errorf("expected delay capped at %s, got %s", MaxRetryDelay, got)
+	}
+}
+
+func TestParseTenantConfig(t *testing.T) {
+	cfg, err := ParseTenantConfig([]byte(`{"webhook":{"url":"https://example.com/hook","timeout_seconds":3}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.URL != "https://example.com/hook" {
+		t.Errorf("unexpected url %q", cfg.URL)
+	}
+	if cfg.Timeout() != 3*time.Second {
+		t.Errorf("expected 3s timeout, got %s", cfg.Timeout())
+	}
+	if cfg.Attempts() != DefaultMaxAttempts {
+		t.Errorf("expected default attempts %d, got %d", DefaultMaxAttempts, cfg.Attempts())
+	}
+
+	empty, err := ParseTenantConfig([]byte(`{}`))
+	if err != nil || empty.URL != "" {
+		t.Errorf("expected empty config, got %+v (err=%v)", empty, err)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Webhook-Delivery") != "d-1" {
+			t.Errorf("missing delivery header")
+		}
+		if got := r.Header.Get(SignatureHeader); got != Sign("s3cret", []byte(`{}`)) {
+			t.Errorf("unexpected signature %q", got)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	cfg := Config{URL: srv.URL, Secret: "s3cret"}
+
+	res := Deliver(context.Background(), srv.Client(), cfg, "d-1", "evaluation.completed", []byte(`{}`))
+	if res.Outcome != OutcomeSuccess || res.Err != nil {
+		t.Errorf("expected success, got %+v", res)
+	}
+
+	status = http.StatusBadGateway
+	res = Deliver(context.Background(), srv.Client(), cfg, "d-1", "evaluation.completed", []byte(`{}`))
+	if res.Outcome != OutcomeRetry || res.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected retry on 502, got %+v", res)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Vetor de referência: HMAC-SHA256("key", "The quick brown fox jumps over the lazy dog")
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
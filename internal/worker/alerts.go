@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// jobTypeAdminAlert é o job que envia o alerta de DLQ. Tem tipo próprio para
+// que uma falha dele nunca gere outro alerta.
+const jobTypeAdminAlert = "send_admin_alert"
+
+// DefaultDeadLetterAlertInterval é usado quando a config não define o intervalo
+const DefaultDeadLetterAlertInterval = 15 * time.Minute
+
+// deadLetterAlerter limita os alertas de DLQ a um por tipo de job a cada
+// interval, contando os suprimidos para informá-los no próximo alerta
+type deadLetterAlerter struct {
+	to       string
+	interval time.Duration
+
+	mu         sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func newDeadLetterAlerter(to string, interval time.Duration) *deadLetterAlerter {
+	if interval <= 0 {
+		interval = DefaultDeadLetterAlertInterval
+	}
+	return &deadLetterAlerter{
+		to:         to,
+		interval:   interval,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// allow informa se um alerta do tipo pode sair agora e, nesse caso, quantos
+// foram suprimidos desde o anterior
+func (a *deadLetterAlerter) allow(jobType string, now time.Time) (bool, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if last, ok := a.last[jobType]; ok && now.Sub(last) < a.interval {
+		a.suppressed[jobType]++
+		return false, 0
+	}
+	a.last[jobType] = now
+	suppressed := a.suppressed[jobType]
+	delete(a.suppressed, jobType)
+	return true, suppressed
+}
+
+// alertDeadLetter enfileira o email de alerta para ADMIN_ALERT_EMAIL. Falhas
+// são só logadas: o job original já está no DLQ.
+func (p *Processor) alertDeadLetter(ctx context.Context, job db.Job, lastErr error) {
+	if p.dlqAlerts == nil || job.Type == jobTypeAdminAlert {
+		return
+	}
+	ok, suppressed := p.dlqAlerts.allow(job.Type, time.Now())
+	if !ok {
+		return
+	}
+
+	attempts := int64(0)
+	if job.AttemptCount.Valid {
+		attempts = job.AttemptCount.Int64
+	}
+
+	body := fmt.Sprintf("<p>O job <strong>#%d</strong> do tipo <strong>%s</strong> foi movido para o DLQ após %d tentativa(s).</p>"+
+		"<p>Último erro:</p><pre>%s</pre>",
+		job.ID, html.EscapeString(job.Type), attempts, html.EscapeString(lastErr.Error()))
+	if suppressed > 0 {
+		body += fmt.Sprintf("<p>Outros %d job(s) deste tipo foram para o DLQ desde o último alerta.</p>", suppressed)
+	}
+
+	payload, _ := json.Marshal(map[string]string{
+		"to":      p.dlqAlerts.to,
+		"subject": fmt.Sprintf("[Elenchus] Job %s movido para o DLQ", job.Type),
+		"body":    body,
+	})
+	if _, err := p.queries.CreateJob(ctx, db.CreateJobParams{
+		TenantID: job.TenantID,
+		Type:     jobTypeAdminAlert,
+		Payload:  payload,
+		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+	}); err != nil {
+		p.logger.ErrorContext(ctx, "failed to enqueue dead letter alert", "error", err)
+	}
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Padrões da limpeza periódica quando a config não define
+const (
+	DefaultCleanupInterval     = 1 * time.Hour
+	DefaultCheckpointRetention = 7 * 24 * time.Hour
+)
+
+// cleanup remove tokens expirados de verificação de email e reset de senha e
+// checkpoints de avaliações terminadas (completed/failed) sem atualização há
+// mais de checkpointRetention. Falhas são logadas e não interrompem o resto.
+func (p *Processor) cleanup(ctx context.Context) {
+	now := time.Now()
+
+	verifications, err := p.queries.DeleteExpiredEmailVerifications(ctx, now)
+	if err != nil {
+		p.logger.Error("cleanup: failed to delete expired email verifications", "error", err)
+	}
+
+	resets, err := p.queries.DeleteExpiredPasswordResets(ctx, now)
+	if err != nil {
+		p.logger.Error("cleanup: failed to delete expired password resets", "error", err)
+	}
+
+	checkpoints, err := p.queries.DeleteFinishedCheckpoints(ctx, sql.NullString{
+		String: fmt.Sprintf("%d", int(p.checkpointRetention.Minutes())),
+		Valid:  true,
+	})
+	if err != nil {
+		p.logger.Error("cleanup: failed to delete finished checkpoints", "error", err)
+	}
+
+	p.logger.Info("cleanup finished",
+		"email_verifications_deleted", verifications,
+		"password_resets_deleted", resets,
+		"checkpoints_deleted", checkpoints,
+	)
+}
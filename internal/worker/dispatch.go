@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/PauloHFS/elenchus/internal/db"
+)
+
+// ErrUnknownJobType é retornado (como falha permanente) para tipos sem handler
+var ErrUnknownJobType = errors.New("unknown job type")
+
+// JobHandler processa um job. Erros que embrulham ErrPermanent vão direto para
+// o DLQ; os demais seguem o fluxo de retry do worker.
+type JobHandler func(ctx context.Context, job db.Job) error
+
+// RegisterHandler registra (ou substitui) o handler de um tipo de job. Os tipos
+// built-in são registrados em New; consumidores do pacote adicionam os seus
+// antes de Start. Jobs do tipo passam a usar o semáforo genérico.
+func (p *Processor) RegisterHandler(jobType string, fn JobHandler) {
+	p.handlersMu.Lock()
+	defer p.handlersMu.Unlock()
+	p.handlers[jobType] = fn
+}
+
+// builtinHandlers são os handlers dos tipos de job da aplicação, registrados em New
+func (p *Processor) builtinHandlers() map[string]JobHandler {
+	payloadOnly := func(handle func(context.Context, json.RawMessage) error) JobHandler {
+		return func(ctx context.Context, job db.Job) error {
+			return handle(ctx, job.Payload)
+		}
+	}
+
+	return map[string]JobHandler{
+		"send_email":                payloadOnly(p.handleSendEmail),
+		jobTypeAdminAlert:           payloadOnly(p.handleSendEmail),
+		"send_password_reset_email": payloadOnly(p.handleSendPasswordResetEmail),
+		"send_verification_email":   payloadOnly(p.handleSendVerificationEmail),
+		"process_ai": func(ctx context.Context, job db.Job) error {
+			return p.handleProcessAI(ctx, job.ID, job.Payload)
+		},
+		"test_job": func(ctx context.Context, job db.Job) error {
+			return p.handleTestJob(ctx, job.ID, job.Payload)
+		},
+		"run_evaluation":  payloadOnly(p.handleRunEvaluation),
+		"process_webhook": payloadOnly(p.handleProcessWebhook),
+		"send_webhook":    payloadOnly(p.handleSendWebhook),
+	}
+}
+
+// dispatch executa o handler registrado para o tipo do job
+func (p *Processor) dispatch(ctx context.Context, job db.Job) error {
+	p.handlersMu.RLock()
+	handle, ok := p.handlers[job.Type]
+	p.handlersMu.RUnlock()
+	if !ok {
+		p.logger.WarnContext(ctx, "unknown job type", "type", job.Type)
+		return permanent(fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type))
+	}
+	return handle(ctx, job)
+}
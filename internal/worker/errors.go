@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPermanent marca falhas que nunca terão sucesso em retry (payload
+// malformado, validação). O job vai direto para o DLQ sem gastar tentativas.
+var ErrPermanent = errors.New("permanent job failure")
+
+func permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// retryAfterError sinaliza que o job deve voltar para a fila após Delay,
+// em vez de seguir o fluxo genérico de falha/DLQ. Usado por handlers que
+// têm política de retry própria (ex: webhooks de saída).
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry in %s)", e.err, e.delay)
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+func retryAfter(err error, delay time.Duration) error {
+	return &retryAfterError{err: err, delay: delay}
+}
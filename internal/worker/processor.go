@@ -7,7 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PauloHFS/elenchus/internal/config"
@@ -17,81 +19,269 @@ import (
 	"github.com/PauloHFS/elenchus/internal/metrics"
 	"github.com/PauloHFS/elenchus/internal/service"
 	"github.com/PauloHFS/elenchus/internal/sse"
+	"github.com/PauloHFS/elenchus/internal/view/pages"
+	"github.com/PauloHFS/elenchus/internal/webhook"
 )
 
 // Rate limit configuration
 const (
-	MaxConcurrentGeminiJobs = 5  // Gemini free tier: 15 RPM, usamos 5 para segurança
-	MaxConcurrentEmailJobs  = 10 // SMTP geralmente aguenta mais
+	MaxConcurrentGeminiJobs  = 5  // Gemini free tier: 15 RPM, usamos 5 para segurança
+	MaxConcurrentEmailJobs   = 10 // SMTP geralmente aguenta mais
 	MaxConcurrentGenericJobs = 20
 )
 
+// DefaultStuckEvaluationThreshold é usado quando a config não define o limite
+const DefaultStuckEvaluationThreshold = 15 * time.Minute
+
+// Intervalos de polling usados quando a config não os define
+const (
+	DefaultPollInterval  = 1 * time.Second
+	DefaultRetryInterval = 30 * time.Second
+)
+
 type Processor struct {
-	db            *sql.DB
-	queries       *db.Queries
-	logger        *slog.Logger
-	mailer        *mailer.Mailer
-	broker        *sse.Broker
-	wg            sync.WaitGroup
-	
+	db         *sql.DB
+	queries    *db.Queries
+	logger     *slog.Logger
+	mailer     *mailer.Mailer
+	broker     *sse.Broker
+	gemini     *service.GeminiClient
+	prompts    *service.ProtocolPrompts
+	httpClient *http.Client
+	wg         sync.WaitGroup
+
 	// Semaphores for rate limiting
-	geminiSemaphore   chan struct{}
-	emailSemaphore    chan struct{}
-	genericSemaphore  chan struct{}
+	geminiSemaphore  chan struct{}
+	emailSemaphore   chan struct{}
+	genericSemaphore chan struct{}
+
+	// Limite sem progresso para considerar uma avaliação órfã
+	stuckThreshold time.Duration
+
+	// Polling da fila de jobs e das avaliações a retomar
+	pollInterval  time.Duration
+	retryInterval time.Duration
+
+	// Limpeza periódica (ver cleanup)
+	cleanupInterval     time.Duration
+	checkpointRetention time.Duration
+
+	// Alerta por email de jobs movidos para o DLQ (nil sem ADMIN_ALERT_EMAIL)
+	dlqAlerts *deadLetterAlerter
+
+	// Handler por tipo de job (ver RegisterHandler)
+	handlersMu sync.RWMutex
+	handlers   map[string]JobHandler
+
+	// Pausado: o loop não pega jobs novos (ver Pause)
+	paused atomic.Bool
+
+	// Sinal para o loop buscar a fila sem esperar o ticker (ver Wake)
+	wake chan struct{}
+
+	// Contexto dos jobs em execução. É independente do ctx de Start: parar o
+	// loop não interrompe jobs; Shutdown os cancela só após o timeout.
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
 }
 
-func New(cfg *config.Config, dbConn *sql.DB, q *db.Queries, l *slog.Logger, broker *sse.Broker) *Processor {
+func New(cfg *config.Config, dbConn *sql.DB, q *db.Queries, l *slog.Logger, broker *sse.Broker, gemini *service.GeminiClient) *Processor {
 	p := &Processor{
 		db:      dbConn,
 		queries: q,
 		logger:  l,
 		mailer:  mailer.New(cfg),
 		broker:  broker,
-		
+		gemini:  gemini,
+		// Timeout por requisição é definido pela config de webhook do tenant
+		httpClient: &http.Client{},
+
+		stuckThreshold:      cfg.StuckEvaluationThreshold,
+		pollInterval:        cfg.WorkerPollInterval,
+		retryInterval:       cfg.WorkerRetryInterval,
+		cleanupInterval:     cfg.CleanupInterval,
+		checkpointRetention: cfg.CheckpointRetention,
+
 		// Initialize semaphores
-		geminiSemaphore:   make(chan struct{}, MaxConcurrentGeminiJobs),
-		emailSemaphore:    make(chan struct{}, MaxConcurrentEmailJobs),
-		genericSemaphore:  make(chan struct{}, MaxConcurrentGenericJobs),
+		geminiSemaphore:  make(chan struct{}, MaxConcurrentGeminiJobs),
+		emailSemaphore:   make(chan struct{}, MaxConcurrentEmailJobs),
+		genericSemaphore: make(chan struct{}, MaxConcurrentGenericJobs),
+
+		wake: make(chan struct{}, 1),
+	}
+
+	if p.stuckThreshold <= 0 {
+		p.stuckThreshold = DefaultStuckEvaluationThreshold
 	}
-	
+	if p.pollInterval <= 0 {
+		p.pollInterval = DefaultPollInterval
+	}
+	if p.retryInterval <= 0 {
+		p.retryInterval = DefaultRetryInterval
+	}
+	if p.cleanupInterval <= 0 {
+		p.cleanupInterval = DefaultCleanupInterval
+	}
+	if p.checkpointRetention <= 0 {
+		p.checkpointRetention = DefaultCheckpointRetention
+	}
+
+	if cfg.AdminAlertEmail != "" {
+		p.dlqAlerts = newDeadLetterAlerter(cfg.AdminAlertEmail, cfg.DeadLetterAlertInterval)
+	}
+
+	p.handlers = p.builtinHandlers()
+	p.jobCtx, p.cancelJobs = context.WithCancel(context.Background())
+
 	return p
 }
 
+// SetProtocolPrompts define o conjunto de prompts carregado no boot; sem ele as
+// avaliações usam o padrão. Deve ser chamado antes de Start.
+func (p *Processor) SetProtocolPrompts(prompts service.ProtocolPrompts) {
+	p.prompts = &prompts
+}
+
+// evaluationService monta o service sobre o cliente Gemini e os prompts do processo
+func (p *Processor) evaluationService() (*service.EvaluationService, error) {
+	evalService, err := service.NewEvaluationService(p.queries, p.broker, p.gemini)
+	if err != nil {
+		return nil, err
+	}
+	if p.prompts != nil {
+		evalService = evalService.WithPrompts(*p.prompts)
+	}
+	return evalService, nil
+}
+
 func (p *Processor) Start(ctx context.Context) {
 	p.logger.Info("worker started")
-	
-	// Processa jobs normais
-	ticker := time.NewTicker(1 * time.Second)
+	if p.gemini == nil {
+		p.logger.Warn("gemini client not configured (GEMINI_API_KEY missing): evaluation jobs will fail until it is set")
+	}
+
+	// Processa jobs normais; Wake antecipa o tick, que segue como fallback
+	ticker := time.NewTicker(p.pollInterval)
 	defer ticker.Stop()
-	
-	// Processa retries de avaliações a cada 30 segundos
-	retryTicker := time.NewTicker(30 * time.Second)
+
+	// Processa retries de avaliações
+	retryTicker := time.NewTicker(p.retryInterval)
 	defer retryTicker.Stop()
-	
+
+	// Recupera avaliações órfãs (worker morreu no meio do protocolo)
+	stuckTicker := time.NewTicker(1 * time.Minute)
+	defer stuckTicker.Stop()
+
+	// Remove tokens expirados e checkpoints antigos
+	cleanupTicker := time.NewTicker(p.cleanupInterval)
+	defer cleanupTicker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			p.logger.Info("worker signal received: waiting for active jobs to finish")
 			return
 		case <-ticker.C:
+			if p.Paused() {
+				continue
+			}
+			p.processNextWithRateLimit(ctx)
+		case <-p.wake:
+			if p.Paused() {
+				continue
+			}
 			p.processNextWithRateLimit(ctx)
 		case <-retryTicker.C:
 			p.processEvaluationRetries(ctx)
+		case <-stuckTicker.C:
+			p.recoverStuckEvaluations(ctx)
+		case <-cleanupTicker.C:
+			p.cleanup(ctx)
 		}
 	}
 }
 
+// Wake pede ao loop que busque a fila imediatamente, sem esperar o próximo tick
+// (ex: logo após um request enfileirar um job). Não bloqueia: sinais enviados
+// antes de o loop acordar se fundem em um.
+func (p *Processor) Wake() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pause faz o loop de Start parar de pegar jobs novos, para manutenção sem
+// derrubar o processo. Jobs em execução terminam normalmente; retries e
+// avaliações órfãs continuam sendo re-enfileirados e esperam o Resume.
+func (p *Processor) Pause() {
+	if !p.paused.Swap(true) {
+		metrics.WorkerPaused.Set(1)
+		p.logger.Warn("worker paused: new jobs will not be picked up")
+	}
+}
+
+// Resume volta a processar a fila após um Pause
+func (p *Processor) Resume() {
+	if p.paused.Swap(false) {
+		metrics.WorkerPaused.Set(0)
+		p.logger.Info("worker resumed")
+	}
+}
+
+// Paused informa se o worker está pausado
+func (p *Processor) Paused() bool {
+	return p.paused.Load()
+}
+
 // Wait blocks until all active jobs are finished
 func (p *Processor) Wait() {
 	p.wg.Wait()
 }
 
+// jobCancelGrace é quanto Shutdown espera os jobs reagirem ao cancelamento
+const jobCancelGrace = 5 * time.Second
+
+// Shutdown aguarda os jobs em andamento por até timeout (o loop de Start já deve
+// ter parado). Depois disso cancela o contexto dos jobs restantes: eles são
+// devolvidos à fila como pending e avaliações retomam do checkpoint no próximo
+// boot. Retorna false se algum job não terminou nem após o cancelamento.
+func (p *Processor) Shutdown(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancelJobs()
+		return true
+	case <-time.After(timeout):
+	}
+
+	p.logger.Warn("worker shutdown timeout reached, cancelling active jobs", "timeout", timeout)
+	p.cancelJobs()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(jobCancelGrace):
+		return false
+	}
+}
+
 // processEvaluationRetries processa avaliações que estavam em retry por rate limit
 func (p *Processor) processEvaluationRetries(ctx context.Context) {
+	// Sem cliente Gemini não há o que re-executar (aviso único em Start)
+	if p.gemini == nil {
+		return
+	}
+
 	p.logger.Info("checking for evaluations to retry")
 
 	// Cria service para buscar avaliações prontas para retry
-	evalService, err := service.NewEvaluationService(p.queries, p.broker)
+	evalService, err := p.evaluationService()
 	if err != nil {
 		p.logger.Error("failed to create evaluation service for retry check", "error", err)
 		return
@@ -121,18 +311,19 @@ func (p *Processor) processEvaluationRetries(ctx context.Context) {
 			"is_retry":      true,
 		})
 
-		_, err := p.queries.CreateJob(ctx, db.CreateJobParams{
-			TenantID: sql.NullString{String: eval.TenantID, Valid: true},
-			Type:     "run_evaluation",
-			Payload:  jobPayload,
-			RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+		job, err := p.queries.CreateJob(ctx, db.CreateJobParams{
+			TenantID:     sql.NullString{String: eval.TenantID, Valid: true},
+			Type:         "run_evaluation",
+			Payload:      jobPayload,
+			RunAt:        sql.NullTime{Time: time.Now(), Valid: true},
+			EvaluationID: sql.NullString{String: eval.ID, Valid: true},
 		})
 		if err != nil {
 			p.logger.Error("failed to create retry job", "evaluation_id", eval.ID, "error", err)
 			continue
 		}
 
-		p.logger.Info("re-queued evaluation for retry", "evaluation_id", eval.ID)
+		p.logger.Info("re-queued evaluation for retry", "evaluation_id", eval.ID, "job_id", job.ID)
 	}
 }
 
@@ -160,23 +351,7 @@ func (p *Processor) processNext(ctx context.Context) {
 		return
 	}
 
-	var errProcessing error
-	switch job.Type {
-	case "send_email":
-		errProcessing = p.handleSendEmail(ctx, job.Payload)
-	case "send_password_reset_email":
-		errProcessing = p.handleSendPasswordResetEmail(ctx, job.Payload)
-	case "send_verification_email":
-		errProcessing = p.handleSendVerificationEmail(ctx, job.Payload)
-	case "process_ai":
-		errProcessing = p.handleProcessAI(ctx, job.Payload)
-	case "run_evaluation":
-		errProcessing = p.handleRunEvaluation(ctx, job.Payload)
-	case "process_webhook":
-		errProcessing = p.handleProcessWebhook(ctx, job.Payload)
-	default:
-		p.logger.WarnContext(ctx, "unknown job type", "type", job.Type)
-	}
+	errProcessing := p.dispatch(ctx, job)
 
 	if errProcessing != nil {
 		if err := p.queries.FailJob(ctx, db.FailJobParams{
@@ -232,10 +407,24 @@ func (p *Processor) handleSendEmail(ctx context.Context, payload json.RawMessage
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return err
+		return permanent(err)
 	}
 
-	return p.mailer.Send(data.To, data.Subject, data.Body)
+	if data.To == "" {
+		return permanent(errors.New("send_email: recipient is required"))
+	}
+
+	return p.sendMail(data.To, data.Subject, data.Body)
+}
+
+// sendMail envia pelo mailer; falhas SMTP definitivas (5xx, endereço inválido)
+// vão direto ao DLQ em vez de gastar as tentativas do job
+func (p *Processor) sendMail(to, subject, body string) error {
+	err := p.mailer.Send(to, subject, body)
+	if errors.Is(err, mailer.ErrPermanent) {
+		return permanent(err)
+	}
+	return err
 }
 
 func (p *Processor) handleSendVerificationEmail(ctx context.Context, payload json.RawMessage) error {
@@ -245,14 +434,17 @@ func (p *Processor) handleSendVerificationEmail(ctx context.Context, payload jso
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return err
+		return permanent(err)
+	}
+	if data.Email == "" || data.Token == "" {
+		return permanent(errors.New("email and token are required"))
 	}
 
 	subject := "Verifique seu E-mail"
 	body := "Olá,\n\nBem-vindo! Clique no link abaixo para verificar seu e-mail:\n\n" +
 		"http://localhost:8080/verify-email?token=" + data.Token
 
-	return p.mailer.Send(data.Email, subject, body)
+	return p.sendMail(data.Email, subject, body)
 }
 
 func (p *Processor) handleSendPasswordResetEmail(ctx context.Context, payload json.RawMessage) error {
@@ -262,7 +454,10 @@ func (p *Processor) handleSendPasswordResetEmail(ctx context.Context, payload js
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return err
+		return permanent(err)
+	}
+	if data.Email == "" || data.Token == "" {
+		return permanent(errors.New("email and token are required"))
 	}
 
 	subject := "Recuperação de Senha"
@@ -270,25 +465,65 @@ func (p *Processor) handleSendPasswordResetEmail(ctx context.Context, payload js
 		"http://localhost:8080/reset-password?token=" + data.Token + "\n\n" +
 		"Este link expira em 1 hora."
 
-	return p.mailer.Send(data.Email, subject, body)
+	return p.sendMail(data.Email, subject, body)
 }
 
-func (p *Processor) handleProcessAI(ctx context.Context, payload json.RawMessage) error {
+func (p *Processor) handleProcessAI(ctx context.Context, jobID int64, payload json.RawMessage) error {
 	var data struct {
 		Prompt string `json:"prompt"`
+		UserID int64  `json:"user_id"`
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return err
+		return permanent(err)
 	}
 
 	p.logger.InfoContext(ctx, "AI processing started", slog.String("prompt", data.Prompt))
+	p.reportJobProgress(data.UserID, jobID, "running", "Processando imagem...")
 	// Simular integração com OpenAI/Anthropic
 	time.Sleep(2 * time.Second)
 
+	p.reportJobProgress(data.UserID, jobID, "completed", "Processamento da imagem concluído")
+	return nil
+}
+
+// handleTestJob simula um job longo (botão de teste do dashboard) reportando
+// progresso e conclusão via SSE
+func (p *Processor) handleTestJob(ctx context.Context, jobID int64, payload json.RawMessage) error {
+	var data struct {
+		UserID int64 `json:"user_id"`
+	}
+
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return permanent(err)
+	}
+
+	p.reportJobProgress(data.UserID, jobID, "running", "Processando... 50%")
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+	}
+
+	p.reportJobProgress(data.UserID, jobID, "completed", "✅ Job completado com sucesso! Processamento levou ~5 segundos.")
 	return nil
 }
 
+// reportJobProgress envia o status de um job longo via SSE para o usuário dono
+// e para o canal do próprio job (status "running" ou "completed")
+func (p *Processor) reportJobProgress(userID, jobID int64, status, message string) {
+	if p.broker == nil {
+		return
+	}
+	html := pages.RenderSSEComponent(pages.JobStatusNotification(status, message))
+	if status == "completed" {
+		p.broker.SendJobComplete(userID, jobID, html)
+		return
+	}
+	p.broker.SendJobProgress(userID, jobID, html)
+}
+
 func (p *Processor) handleRunEvaluation(ctx context.Context, payload json.RawMessage) error {
 	var data struct {
 		EvaluationID string `json:"evaluation_id"`
@@ -299,7 +534,20 @@ func (p *Processor) handleRunEvaluation(ctx context.Context, payload json.RawMes
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return fmt.Errorf("failed to unmarshal evaluation payload: %w", err)
+		return permanent(fmt.Errorf("failed to unmarshal evaluation payload: %w", err))
+	}
+	if data.EvaluationID == "" {
+		return permanent(errors.New("run_evaluation: evaluation_id is required"))
+	}
+
+	// Associa o evento do job à avaliação (trace em evaluation_logs)
+	logging.AddToEvent(ctx, slog.String(logging.EvaluationIDKey, data.EvaluationID))
+
+	// Avaliação cancelada enquanto o job aguardava: não executa nem muda o status
+	if eval, err := p.queries.GetEvaluationByID(ctx, data.EvaluationID); err == nil && eval.Status == "cancelled" {
+		p.logger.InfoContext(ctx, "skipping cancelled evaluation",
+			slog.String("evaluation_id", data.EvaluationID))
+		return nil
 	}
 
 	p.logger.InfoContext(ctx, "starting evaluation protocol",
@@ -307,23 +555,42 @@ func (p *Processor) handleRunEvaluation(ctx context.Context, payload json.RawMes
 		slog.Int64("user_id", data.UserID),
 		slog.Bool("is_retry", data.IsRetry))
 
+	// Sem cliente Gemini (sem API key) a avaliação não tem como rodar: falha
+	// com mensagem clara e sem retries (o aviso já saiu na inicialização)
+	if p.gemini == nil {
+		if err := p.queries.FailEvaluation(ctx, db.FailEvaluationParams{
+			ErrorMessage: sql.NullString{String: "Serviço de avaliação indisponível: GEMINI_API_KEY não configurada", Valid: true},
+			ID:           data.EvaluationID,
+		}); err != nil {
+			p.logger.ErrorContext(ctx, "failed to mark evaluation as failed", slog.Any("error", err))
+		}
+		return permanent(service.ErrGeminiUnavailable)
+	}
+
 	// Criar serviço de avaliação e executar protocolo
-	evalService, err := service.NewEvaluationService(p.queries, p.broker)
+	evalService, err := p.evaluationService()
 	if err != nil {
 		return fmt.Errorf("failed to create evaluation service: %w", err)
 	}
 
 	// Executar o protocolo de estresse
 	if err := evalService.RunEvaluationProtocol(ctx, data.EvaluationID, data.Prompt); err != nil {
-		// Verifica se é erro de rate limit - não marca como falha, apenas retorna para retry
-		if errors.Is(err, service.ErrRateLimitExceeded) {
-			p.logger.InfoContext(ctx, "evaluation hit rate limit, will retry later",
+		// Rate limit ou Gemini fora do ar - não marca como falha, apenas retorna para retry
+		if service.IsDeferredRetry(err) {
+			p.logger.InfoContext(ctx, "evaluation deferred, will retry later",
 				slog.String("evaluation_id", data.EvaluationID),
 				slog.String("error", err.Error()))
 			// Não retorna erro aqui - o job será completado e o retry é agendado via checkpoint
 			return nil
 		}
 
+		// Worker encerrando: a avaliação não falhou e é retomada pelo checkpoint
+		if errors.Is(err, context.Canceled) {
+			p.logger.InfoContext(ctx, "evaluation interrupted by shutdown, will resume from checkpoint",
+				slog.String("evaluation_id", data.EvaluationID))
+			return err
+		}
+
 		// Verifica se é erro de too many retries
 		if errors.Is(err, service.ErrTooManyRetries) {
 			// Atualizar status para falha após muitas tentativas
@@ -343,6 +610,10 @@ func (p *Processor) handleRunEvaluation(ctx context.Context, payload json.RawMes
 		}); updateErr != nil {
 			p.logger.ErrorContext(ctx, "failed to update evaluation status to failed", slog.Any("error", updateErr))
 		}
+		// Histórico grande demais ou conteúdo bloqueado se repetem a cada tentativa
+		if errors.Is(err, service.ErrContextTooLong) || errors.Is(err, service.ErrContentBlocked) {
+			return permanent(fmt.Errorf("evaluation protocol failed: %w", err))
+		}
 		return fmt.Errorf("evaluation protocol failed: %w", err)
 	}
 
@@ -358,7 +629,7 @@ func (p *Processor) handleProcessWebhook(ctx context.Context, payload json.RawMe
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return err
+		return permanent(err)
 	}
 
 	p.logger.InfoContext(ctx, "processing webhook event", slog.Int64("webhook_id", data.WebhookID))
@@ -366,3 +637,97 @@ func (p *Processor) handleProcessWebhook(ctx context.Context, payload json.RawMe
 	// Aqui você buscaria o payload bruto no banco se necessário
 	return nil
 }
+
+// handleSendWebhook entrega um evento para o webhook configurado pelo tenant.
+// Cada tentativa é registrada em webhook_deliveries; falhas transitórias (5xx, 429,
+// erros de rede) reagendam o job com backoff próprio até o limite de tentativas
+// do tenant, enquanto 4xx falha permanentemente.
+func (p *Processor) handleSendWebhook(ctx context.Context, payload json.RawMessage) error {
+	var data struct {
+		DeliveryID string          `json:"delivery_id"`
+		TenantID   string          `json:"tenant_id"`
+		Event      string          `json:"event"`
+		Data       json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return permanent(err)
+	}
+	if data.DeliveryID == "" || data.TenantID == "" || data.Event == "" {
+		return permanent(errors.New("send_webhook: delivery_id, tenant_id and event are required"))
+	}
+
+	settings, err := p.queries.GetTenantSettings(ctx, data.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant settings: %w", err)
+	}
+	cfg, err := webhook.ParseTenantConfig(settings)
+	if err != nil {
+		return err
+	}
+	if cfg.URL == "" {
+		p.logger.WarnContext(ctx, "tenant has no webhook configured, dropping delivery",
+			slog.String("tenant_id", data.TenantID),
+			slog.String("delivery_id", data.DeliveryID),
+		)
+		return nil
+	}
+
+	previous, err := p.queries.CountWebhookDeliveryAttempts(ctx, data.DeliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to count webhook attempts: %w", err)
+	}
+	attempt := int(previous) + 1
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":         data.DeliveryID,
+		"event":      data.Event,
+		"data":       data.Data,
+		"created_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	result := webhook.Deliver(ctx, p.httpClient, cfg, data.DeliveryID, data.Event, body)
+
+	// Tentativas esgotadas transformam retry em falha definitiva
+	outcome := result.Outcome
+	if outcome == webhook.OutcomeRetry && attempt >= cfg.Attempts() {
+		outcome = webhook.OutcomeFailed
+	}
+
+	record := db.CreateWebhookDeliveryParams{
+		DeliveryID: data.DeliveryID,
+		TenantID:   sql.NullString{String: data.TenantID, Valid: true},
+		Event:      data.Event,
+		Url:        cfg.URL,
+		Attempt:    int64(attempt),
+		StatusCode: sql.NullInt64{Int64: int64(result.StatusCode), Valid: result.StatusCode != 0},
+		Outcome:    string(outcome),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	if result.Err != nil {
+		record.Error = sql.NullString{String: result.Err.Error(), Valid: true}
+	}
+	if _, err := p.queries.CreateWebhookDelivery(ctx, record); err != nil {
+		p.logger.ErrorContext(ctx, "failed to record webhook delivery", "error", err)
+	}
+
+	logging.AddToEvent(ctx,
+		slog.String("delivery_id", data.DeliveryID),
+		slog.Int("webhook_attempt", attempt),
+		slog.Int("webhook_status", result.StatusCode),
+		slog.String("webhook_outcome", string(outcome)),
+	)
+
+	switch outcome {
+	case webhook.OutcomeSuccess:
+		return nil
+	case webhook.OutcomeRetry:
+		return retryAfter(result.Err, webhook.RetryDelay(attempt))
+	default:
+		// 4xx (exceto 429) ou tentativas esgotadas: vai direto para o DLQ
+		return permanent(fmt.Errorf("webhook delivery failed after %d attempt(s): %w", attempt, result.Err))
+	}
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -19,7 +20,9 @@ func (p *Processor) processNextWithRateLimit(ctx context.Context) {
 		return // Fila vazia
 	}
 
-	ctx, event := logging.NewEventContext(ctx)
+	// Daqui em diante o job roda no contexto de jobs do processor, não no ctx do
+	// loop: parar o loop não interrompe o job (ver Shutdown)
+	ctx, event := logging.NewEventContext(p.jobCtx)
 	event.Add(
 		slog.Int64("job_id", int64(job.ID)),
 		slog.String("job_type", string(job.Type)),
@@ -48,8 +51,8 @@ func (p *Processor) processNextWithRateLimit(ctx context.Context) {
 		}()
 	default:
 		// Semaphore full, skip this job (will be processed next cycle)
-		p.logger.DebugContext(ctx, "rate limit reached, skipping job", 
-			append(event.Attrs(), 
+		p.logger.DebugContext(ctx, "rate limit reached, skipping job",
+			append(event.Attrs(),
 				slog.String("reason", "concurrent limit reached"),
 			)...)
 	}
@@ -60,7 +63,7 @@ func (p *Processor) getSemaphoreForJob(jobType string) chan struct{} {
 	switch jobType {
 	case "run_evaluation", "process_ai":
 		return p.geminiSemaphore
-	case "send_email", "send_password_reset_email", "send_verification_email":
+	case "send_email", "send_password_reset_email", "send_verification_email", jobTypeAdminAlert:
 		return p.emailSemaphore
 	default:
 		return p.genericSemaphore
@@ -70,25 +73,12 @@ func (p *Processor) getSemaphoreForJob(jobType string) chan struct{} {
 // processJobWithMetrics processes a single job with metrics and dead letter queue
 func (p *Processor) processJobWithMetrics(ctx context.Context, job db.Job, event *logging.Event) {
 	start := time.Now()
-	
-	var errProcessing error
-	switch job.Type {
-	case "send_email":
-		errProcessing = p.handleSendEmail(ctx, job.Payload)
-	case "send_password_reset_email":
-		errProcessing = p.handleSendPasswordResetEmail(ctx, job.Payload)
-	case "send_verification_email":
-		errProcessing = p.handleSendVerificationEmail(ctx, job.Payload)
-	case "process_ai":
-		errProcessing = p.handleProcessAI(ctx, job.Payload)
-	case "run_evaluation":
-		errProcessing = p.handleRunEvaluation(ctx, job.Payload)
-	case "process_webhook":
-		errProcessing = p.handleProcessWebhook(ctx, job.Payload)
-	default:
-		p.logger.WarnContext(ctx, "unknown job type", "type", job.Type)
-		errProcessing = fmt.Errorf("unknown job type: %s", job.Type)
-	}
+
+	// Track jobs in flight per type (decremented on success or failure)
+	metrics.JobsActive.WithLabelValues(job.Type).Inc()
+	defer metrics.JobsActive.WithLabelValues(job.Type).Dec()
+
+	errProcessing := p.dispatch(ctx, job)
 
 	// Record metrics
 	duration := time.Since(start).Seconds()
@@ -97,23 +87,54 @@ func (p *Processor) processJobWithMetrics(ctx context.Context, job db.Job, event
 	metrics.JobsProcessed.WithLabelValues(string(job.Type), status).Inc()
 
 	if errProcessing != nil {
+		// Interrompido pelo Shutdown: devolve à fila sem contar como falha. O ctx
+		// está cancelado, então a atualização usa um derivado sem cancelamento.
+		if ctx.Err() != nil {
+			if err := p.queries.ReleaseJob(context.WithoutCancel(ctx), db.ReleaseJobParams{
+				LastError: sql.NullString{String: "interrupted by shutdown: " + errProcessing.Error(), Valid: true},
+				ID:        job.ID,
+			}); err != nil {
+				p.logger.ErrorContext(ctx, "failed to release interrupted job", "error", err)
+			}
+			p.logger.WarnContext(ctx, "job interrupted by shutdown, released back to the queue", event.Attrs()...)
+			return
+		}
+
 		// Record retry metric
 		attemptCount := int64(0)
 		if job.AttemptCount.Valid {
 			attemptCount = job.AttemptCount.Int64
 		}
-		
+
 		if attemptCount > 0 {
 			metrics.JobRetries.WithLabelValues(string(job.Type)).Inc()
 		}
-		
+
+		// Handlers com política de retry própria pedem reagendamento explícito
+		var retryErr *retryAfterError
+		if errors.As(errProcessing, &retryErr) {
+			p.rescheduleJob(ctx, job, retryErr, event)
+			return
+		}
+
+		// Falhas permanentes não têm o que ganhar com retry
+		if errors.Is(errProcessing, ErrPermanent) {
+			p.moveToDeadLetterQueue(ctx, job, errProcessing)
+			p.logger.ErrorContext(ctx, "job moved to dead letter queue after permanent failure",
+				append(event.Attrs(),
+					slog.String("error", errProcessing.Error()),
+					slog.Int64("attempts", attemptCount),
+				)...)
+			return
+		}
+
 		// Check if we should move to dead letter queue
 		shouldMoveToDLQ := p.shouldMoveToDeadLetterQueue(ctx, job)
-		
+
 		if shouldMoveToDLQ {
 			p.moveToDeadLetterQueue(ctx, job, errProcessing)
 			p.logger.ErrorContext(ctx, "job moved to dead letter queue after max retries",
-				append(event.Attrs(), 
+				append(event.Attrs(),
 					slog.String("error", errProcessing.Error()),
 					slog.Int64("attempts", attemptCount),
 				)...)
@@ -125,9 +146,9 @@ func (p *Processor) processJobWithMetrics(ctx context.Context, job db.Job, event
 			}); err != nil {
 				p.logger.ErrorContext(ctx, "failed to record job failure in db", "error", err)
 			}
-			
+
 			p.logger.ErrorContext(ctx, "job processing failed, will retry",
-				append(event.Attrs(), 
+				append(event.Attrs(),
 					slog.String("error", errProcessing.Error()),
 					slog.Int64("attempts", attemptCount),
 				)...)
@@ -165,27 +186,46 @@ func (p *Processor) processJobWithMetrics(ctx context.Context, job db.Job, event
 	// Note: SSE events are sent via broker.SendEvaluationProgress/Complete
 }
 
+// rescheduleJob devolve o job para a fila com run_at no futuro
+func (p *Processor) rescheduleJob(ctx context.Context, job db.Job, retryErr *retryAfterError, event *logging.Event) {
+	delaySeconds := int64(retryErr.delay.Seconds())
+	if err := p.queries.RescheduleJob(ctx, db.RescheduleJobParams{
+		LastError:    sql.NullString{String: retryErr.Error(), Valid: true},
+		DelaySeconds: sql.NullString{String: fmt.Sprintf("%d", delaySeconds), Valid: true},
+		ID:           job.ID,
+	}); err != nil {
+		p.logger.ErrorContext(ctx, "failed to reschedule job", "error", err)
+		return
+	}
+
+	p.logger.WarnContext(ctx, "job rescheduled",
+		append(event.Attrs(),
+			slog.String("error", retryErr.Error()),
+			slog.Int64("retry_in_seconds", delaySeconds),
+		)...)
+}
+
 // shouldMoveToDeadLetterQueue determines if a job should be moved to DLQ
 func (p *Processor) shouldMoveToDeadLetterQueue(ctx context.Context, job db.Job) bool {
 	const maxAttempts = 5
-	
+
 	attemptCount := int64(0)
 	if job.AttemptCount.Valid {
 		attemptCount = job.AttemptCount.Int64
 	}
-	
+
 	// Move to DLQ if:
 	// 1. Max attempts reached
 	// 2. Job is old (more than 24 hours)
-	
+
 	if attemptCount >= maxAttempts {
 		return true
 	}
-	
+
 	if !job.CreatedAt.Valid || time.Since(job.CreatedAt.Time) > 24*time.Hour {
 		return true
 	}
-	
+
 	return false
 }
 
@@ -193,13 +233,13 @@ func (p *Processor) shouldMoveToDeadLetterQueue(ctx context.Context, job db.Job)
 func (p *Processor) moveToDeadLetterQueue(ctx context.Context, job db.Job, lastErr error) {
 	// Record metric
 	metrics.JobsDeadLetter.WithLabelValues(string(job.Type)).Inc()
-	
+
 	p.logger.ErrorContext(ctx, "dead letter queue: job moved",
 		slog.Int64("original_job_id", job.ID),
 		slog.String("original_job_type", string(job.Type)),
 		slog.String("error", lastErr.Error()),
 	)
-	
+
 	// Mark original job as failed permanently
 	_ = p.queries.FailJob(ctx, db.FailJobParams{
 		LastError: sql.NullString{
@@ -208,6 +248,8 @@ func (p *Processor) moveToDeadLetterQueue(ctx context.Context, job db.Job, lastE
 		},
 		ID: job.ID,
 	})
+
+	p.alertDeadLetter(ctx, job, lastErr)
 }
 
 // getJobStatus returns "success" or "failed" based on error
@@ -2,9 +2,19 @@ package worker
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"time"
+
+	"github.com/PauloHFS/elenchus/internal/db"
 )
 
+// MaxStuckRequeues limita quantas vezes uma avaliação órfã é re-enfileirada
+// antes de ser marcada como failed
+const MaxStuckRequeues = 3
+
 // RescueZombies resgata jobs que ficaram presos no status 'processing'
 // devido a um crash ou restart inesperado do servidor.
 func (p *Processor) RescueZombies(ctx context.Context) error {
@@ -16,3 +26,71 @@ func (p *Processor) RescueZombies(ctx context.Context) error {
 	}
 	return nil
 }
+
+// recoverStuckEvaluations busca avaliações em 'processing' cujo checkpoint não
+// avança há mais de stuckThreshold (worker morreu no meio do protocolo) e as
+// re-enfileira para retomar do checkpoint, ou marca como failed após MaxStuckRequeues.
+func (p *Processor) recoverStuckEvaluations(ctx context.Context) {
+	evaluations, err := p.queries.GetStuckEvaluations(ctx, sql.NullString{
+		String: fmt.Sprintf("%d", int(p.stuckThreshold.Minutes())),
+		Valid:  true,
+	})
+	if err != nil {
+		p.logger.Error("failed to get stuck evaluations", "error", err)
+		return
+	}
+
+	for _, eval := range evaluations {
+		if eval.RetryCount >= MaxStuckRequeues {
+			if err := p.queries.FailEvaluation(ctx, db.FailEvaluationParams{
+				ErrorMessage: sql.NullString{
+					String: fmt.Sprintf("avaliação abandonada após %d tentativas de recuperação", eval.RetryCount),
+					Valid:  true,
+				},
+				ID: eval.ID,
+			}); err != nil {
+				p.logger.Error("failed to mark stuck evaluation as failed", "evaluation_id", eval.ID, "error", err)
+				continue
+			}
+			p.logger.Warn("stuck evaluation marked as failed", "evaluation_id", eval.ID, "requeues", eval.RetryCount)
+			continue
+		}
+
+		// Já existe job aguardando ou em execução para esta avaliação: não duplica
+		active, err := p.queries.HasActiveEvaluationJob(ctx, sql.NullString{String: eval.ID, Valid: true})
+		if err != nil {
+			p.logger.Error("failed to check active job for stuck evaluation", "evaluation_id", eval.ID, "error", err)
+			continue
+		}
+		if active == 1 {
+			continue
+		}
+
+		if err := p.queries.RequeueStuckEvaluation(ctx, eval.ID); err != nil {
+			p.logger.Error("failed to requeue stuck evaluation", "evaluation_id", eval.ID, "error", err)
+			continue
+		}
+
+		jobPayload, _ := json.Marshal(map[string]interface{}{
+			"evaluation_id": eval.ID,
+			"tenant_id":     eval.TenantID,
+			"user_id":       eval.UserID,
+			"prompt":        eval.PromptBase,
+			"is_retry":      true,
+		})
+
+		job, err := p.queries.CreateJob(ctx, db.CreateJobParams{
+			TenantID:     sql.NullString{String: eval.TenantID, Valid: true},
+			Type:         "run_evaluation",
+			Payload:      jobPayload,
+			RunAt:        sql.NullTime{Time: time.Now(), Valid: true},
+			EvaluationID: sql.NullString{String: eval.ID, Valid: true},
+		})
+		if err != nil {
+			p.logger.Error("failed to create job for stuck evaluation", "evaluation_id", eval.ID, "error", err)
+			continue
+		}
+
+		p.logger.Warn("re-queued stuck evaluation", "evaluation_id", eval.ID, "job_id", job.ID, "requeues", eval.RetryCount+1)
+	}
+}
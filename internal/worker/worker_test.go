@@ -1,11 +1,24 @@
 package worker
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/PauloHFS/elenchus/internal/config"
+	"github.com/PauloHFS/elenchus/internal/db"
+	"github.com/PauloHFS/elenchus/internal/logging"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func TestProcessor_New(t *testing.T) {
@@ -13,7 +26,7 @@ func TestProcessor_New(t *testing.T) {
 	cfg := &config.Config{SMTPHost: "localhost", SMTPPort: "1025"}
 
 	t.Run("ProcessorInitialization", func(t *testing.T) {
-		p := New(cfg, nil, nil, logger)
+		p := New(cfg, nil, nil, logger, nil, nil)
 		if p == nil {
 			t.Fatal("expected processor, got nil")
 		}
@@ -22,3 +35,276 @@ func TestProcessor_New(t *testing.T) {
 		}
 	})
 }
+
+func TestHandlers_MalformedPayloadIsPermanent(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, nil, nil, logger, nil, nil)
+	ctx := context.Background()
+
+	handlers := map[string]func(context.Context, json.RawMessage) error{
+		"send_email":                p.handleSendEmail,
+		"send_verification_email":   p.handleSendVerificationEmail,
+		"send_password_reset_email": p.handleSendPasswordResetEmail,
+		"run_evaluation":            p.handleRunEvaluation,
+		"send_webhook":              p.handleSendWebhook,
+	}
+
+	for name, handle := range handlers {
+		t.Run(name, func(t *testing.T) {
+			if err := handle(ctx, json.RawMessage(`{not json`)); !errors.Is(err, ErrPermanent) {
+				t.Errorf("malformed payload: expected ErrPermanent, got %v", err)
+			}
+			if err := handle(ctx, json.RawMessage(`{}`)); !errors.Is(err, ErrPermanent) {
+				t.Errorf("missing fields: expected ErrPermanent, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProcessor_Shutdown(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	cfg := &config.Config{SMTPHost: "localhost", SMTPPort: "1025"}
+
+	t.Run("WaitsForJobsWithinTimeout", func(t *testing.T) {
+		p := New(cfg, nil, nil, logger, nil, nil)
+		var cancelledEarly bool
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			time.Sleep(20 * time.Millisecond)
+			cancelledEarly = p.jobCtx.Err() != nil
+		}()
+
+		if !p.Shutdown(time.Second) {
+			t.Fatal("expected job to finish within timeout")
+		}
+		if cancelledEarly {
+			t.Error("job context must not be cancelled before the timeout")
+		}
+	})
+
+	t.Run("CancelsJobsAfterTimeout", func(t *testing.T) {
+		p := New(cfg, nil, nil, logger, nil, nil)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			<-p.jobCtx.Done()
+		}()
+
+		if !p.Shutdown(10 * time.Millisecond) {
+			t.Fatal("expected job to stop after cancellation")
+		}
+	})
+}
+
+func TestDeadLetterAlertThrottle(t *testing.T) {
+	a := newDeadLetterAlerter("ops@example.com", time.Minute)
+	now := time.Now()
+
+	if ok, _ := a.allow("send_email", now); !ok {
+		t.Fatal("first alert should be sent")
+	}
+	for range 3 {
+		if ok, _ := a.allow("send_email", now.Add(10*time.Second)); ok {
+			t.Fatal("alerts within the interval should be suppressed")
+		}
+	}
+	if ok, _ := a.allow("run_evaluation", now.Add(10*time.Second)); !ok {
+		t.Error("throttle is per job type")
+	}
+	ok, suppressed := a.allow("send_email", now.Add(2*time.Minute))
+	if !ok || suppressed != 3 {
+		t.Errorf("after the interval: ok=%v suppressed=%d, want true 3", ok, suppressed)
+	}
+
+	// O job de alerta nunca gera alerta (queries nil: qualquer enfileiramento entraria em pânico)
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025", AdminAlertEmail: "ops@example.com"}, nil, nil, logger, nil, nil)
+	p.alertDeadLetter(context.Background(), db.Job{ID: 1, Type: jobTypeAdminAlert}, errors.New("smtp down"))
+}
+
+func TestProcessor_PauseResume(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, nil, nil, logger, nil, nil)
+
+	if p.Paused() {
+		t.Fatal("new processor should not start paused")
+	}
+	p.Pause()
+	p.Pause() // idempotente
+	if !p.Paused() {
+		t.Fatal("expected paused after Pause")
+	}
+
+	// Pausado, o loop não toca na fila (queries nil entraria em pânico)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Start(ctx)
+	}()
+	// O segundo Wake só é consumido depois que o loop voltou do primeiro
+	for i := 0; i < 2; i++ {
+		p.Wake()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(p.wake) > 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("worker loop did not consume the wake signal")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	cancel()
+	<-done
+
+	p.Resume()
+	if p.Paused() {
+		t.Error("expected running after Resume")
+	}
+}
+
+func TestProcessor_Dispatch(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, nil, nil, logger, nil, nil)
+
+	err := p.dispatch(context.Background(), db.Job{ID: 1, Type: "resize_video"})
+	if !errors.Is(err, ErrUnknownJobType) || !errors.Is(err, ErrPermanent) {
+		t.Errorf("unknown type: err = %v, want permanent ErrUnknownJobType", err)
+	}
+
+	// Payload malformado chega ao handler registrado, que o rejeita como permanente
+	for _, jobType := range []string{"send_email", jobTypeAdminAlert, "process_ai", "test_job", "run_evaluation"} {
+		err := p.dispatch(context.Background(), db.Job{ID: 1, Type: jobType, Payload: []byte("{")})
+		if errors.Is(err, ErrUnknownJobType) || !errors.Is(err, ErrPermanent) {
+			t.Errorf("%s: err = %v, want permanent payload error", jobType, err)
+		}
+	}
+}
+
+func TestProcessor_RegisterHandler(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, nil, nil, logger, nil, nil)
+
+	var got db.Job
+	p.RegisterHandler("resize_video", func(ctx context.Context, job db.Job) error {
+		got = job
+		return nil
+	})
+
+	if err := p.dispatch(context.Background(), db.Job{ID: 7, Type: "resize_video"}); err != nil {
+		t.Fatalf("custom handler: unexpected error %v", err)
+	}
+	if got.ID != 7 {
+		t.Errorf("custom handler received job %d, want 7", got.ID)
+	}
+
+	// Substituir um built-in também é permitido
+	sentinel := errors.New("overridden")
+	p.RegisterHandler("send_email", func(context.Context, db.Job) error { return sentinel })
+	if err := p.dispatch(context.Background(), db.Job{Type: "send_email"}); !errors.Is(err, sentinel) {
+		t.Errorf("overridden handler: err = %v, want %v", err, sentinel)
+	}
+}
+
+func TestProcessor_Wake(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, nil, nil, logger, nil, nil)
+
+	// Sem o loop rodando, sinais repetidos se fundem e nunca bloqueiam
+	p.Wake()
+	p.Wake()
+	if len(p.wake) != 1 {
+		t.Fatalf("pending wake signals = %d, want 1", len(p.wake))
+	}
+
+	// Pausado, o sinal é consumido sem tocar na fila (queries nil entraria em pânico)
+	p.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	p.Start(ctx)
+	if len(p.wake) != 0 {
+		t.Error("expected the loop to consume the wake signal")
+	}
+}
+
+// setupTestDB abre um SQLite temporário com as migrações aplicadas
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dbConn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "worker.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+	if err := db.RunMigrations(context.Background(), dbConn); err != nil {
+		t.Fatal(err)
+	}
+	return dbConn
+}
+
+func TestRecoverStuckEvaluations_SkipsProcessingJob(t *testing.T) {
+	dbConn := setupTestDB(t)
+	ctx := context.Background()
+	if _, err := dbConn.Exec(`
+		INSERT INTO tenants (id, name) VALUES ('t1', 'Tenant 1');
+		INSERT INTO roles (id, permissions) VALUES ('user', '[]');
+		INSERT INTO users (id, tenant_id, email, password_hash, role_id) VALUES (1, 't1', 'a@b.c', 'x', 'user');
+		INSERT INTO evaluations (id, tenant_id, user_id, prompt_base, status, created_at)
+		VALUES ('slow', 't1', 1, 'p', 'processing', datetime('now', '-1 hour'));
+		INSERT INTO jobs (tenant_id, type, payload, status, evaluation_id)
+		VALUES ('t1', 'run_evaluation', '{"evaluation_id":"slow"}', 'processing', 'slow');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, dbConn, db.New(dbConn), logger, nil, nil)
+	p.recoverStuckEvaluations(ctx)
+
+	// O worker lento ainda roda o protocolo: nenhum segundo job é criado
+	var jobs int
+	if err := dbConn.QueryRow("SELECT COUNT(*) FROM jobs WHERE evaluation_id = 'slow'").Scan(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 1 {
+		t.Errorf("jobs para 'slow' = %d, esperado 1 (sem re-enfileirar)", jobs)
+	}
+}
+
+func TestSendWebhook_ClientErrorGoesToDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	dbConn := setupTestDB(t)
+	ctx := context.Background()
+	settings := fmt.Sprintf(`{"webhook":{"url":%q,"secret":"s"}}`, receiver.URL)
+	if _, err := dbConn.Exec("INSERT INTO tenants (id, name, settings) VALUES ('t1', 'Tenant 1', ?)", settings); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := db.New(dbConn)
+	job, err := queries.CreateJob(ctx, db.CreateJobParams{
+		TenantID: sql.NullString{String: "t1", Valid: true},
+		Type:     "send_webhook",
+		Payload:  json.RawMessage(`{"delivery_id":"d1","tenant_id":"t1","event":"evaluation.completed"}`),
+		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	p := New(&config.Config{SMTPHost: "localhost", SMTPPort: "1025"}, dbConn, queries, logger, nil, nil)
+	ctx, event := logging.NewEventContext(ctx)
+	p.processJobWithMetrics(ctx, job, event)
+
+	// 400 não melhora com retry: o job vai para o DLQ na primeira tentativa
+	var status, lastError string
+	if err := dbConn.QueryRow("SELECT status, last_error FROM jobs WHERE id = ?", job.ID).Scan(&status, &lastError); err != nil {
+		t.Fatal(err)
+	}
+	if status != "failed" || !strings.HasPrefix(lastError, "MOVED_TO_DLQ") {
+		t.Errorf("job status=%q last_error=%q, esperado no DLQ", status, lastError)
+	}
+}
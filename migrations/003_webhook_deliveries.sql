@@ -0,0 +1,19 @@
+-- Webhook Deliveries: histórico de cada tentativa de entrega de webhooks de saída
+-- (um registro por tentativa, agrupados por delivery_id)
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    delivery_id TEXT NOT NULL,
+    tenant_id TEXT REFERENCES tenants(id),
+    event TEXT NOT NULL,
+    url TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER,          -- NULL quando não houve resposta (timeout, DNS, etc)
+    outcome TEXT NOT NULL,        -- 'success', 'retry', 'failed'
+    error TEXT,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivery ON webhook_deliveries(delivery_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_tenant ON webhook_deliveries(tenant_id, created_at);
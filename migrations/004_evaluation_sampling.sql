@@ -0,0 +1,4 @@
+-- Parâmetros de amostragem (temperature/seed por fase) usados na avaliação,
+-- persistidos para reprodutibilidade dos experimentos. JSON armazenado como BLOB:
+-- a coluna é nula em avaliações antigas (tratadas como determinísticas).
+ALTER TABLE evaluations ADD COLUMN sampling_params BLOB;
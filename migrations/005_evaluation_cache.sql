@@ -0,0 +1,5 @@
+-- Cache de resultados: hash de prompt + configuração (modelo, threshold, estratégia)
+-- permite reaproveitar avaliações determinísticas recentes sem gastar quota
+ALTER TABLE evaluations ADD COLUMN config_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_evaluations_config_hash ON evaluations(tenant_id, config_hash, created_at);
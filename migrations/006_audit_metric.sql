@@ -0,0 +1,3 @@
+-- Métrica de divergência usada no cálculo registrado na auditoria
+-- (auditorias anteriores foram todas calculadas por cosseno)
+ALTER TABLE audits ADD COLUMN metrica TEXT NOT NULL DEFAULT 'cosine';
@@ -0,0 +1,13 @@
+-- Evaluation Logs: sink persistente dos eventos de log que carregam evaluation_id,
+-- consultáveis por avaliação para debug (GET /admin/evaluations/{id}/logs)
+
+CREATE TABLE IF NOT EXISTS evaluation_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    evaluation_id TEXT NOT NULL,
+    level TEXT NOT NULL,
+    message TEXT NOT NULL,
+    attrs BLOB,                -- atributos estruturados do evento (JSON)
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_evaluation_logs_eval ON evaluation_logs(evaluation_id, id);
@@ -0,0 +1,13 @@
+-- Experimentos: lote de avaliações criado por importação em massa (CSV)
+CREATE TABLE IF NOT EXISTS experiments (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL REFERENCES tenants(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL,
+    total INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_experiments_tenant ON experiments(tenant_id, created_at);
+
+ALTER TABLE evaluations ADD COLUMN experiment_id TEXT REFERENCES experiments(id);
+CREATE INDEX IF NOT EXISTS idx_evaluations_experiment ON evaluations(experiment_id);
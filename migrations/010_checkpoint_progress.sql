@@ -0,0 +1,4 @@
+-- Progresso da avaliação (fase em execução, passo N de 5) persistido no checkpoint
+-- para que um reload da página mostre onde a avaliação está
+ALTER TABLE evaluation_checkpoints ADD COLUMN progress_step INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE evaluation_checkpoints ADD COLUMN progress_phase TEXT;
@@ -0,0 +1,7 @@
+-- Uma iteração por fase: retomadas de checkpoint podiam gravar a mesma fase
+-- duas vezes. Mantém a mais antiga antes de criar o índice único.
+DELETE FROM iterations
+WHERE rowid NOT IN (
+    SELECT MIN(rowid) FROM iterations GROUP BY evaluation_id, fase
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_iterations_eval_fase ON iterations(evaluation_id, fase);
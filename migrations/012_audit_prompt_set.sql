@@ -0,0 +1,3 @@
+-- Conjunto de prompts do protocolo usado na avaliação
+-- (auditorias anteriores usaram os prompts embutidos)
+ALTER TABLE audits ADD COLUMN prompt_set TEXT NOT NULL DEFAULT 'default';
@@ -0,0 +1,9 @@
+-- Duração de cada fase do protocolo (inclui "calculo", que não gera iteração).
+-- Uma fase retomada sobrescreve a medição da tentativa anterior.
+CREATE TABLE IF NOT EXISTS phase_timings (
+    evaluation_id TEXT NOT NULL REFERENCES evaluations(id) ON DELETE CASCADE,
+    phase TEXT NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (evaluation_id, phase)
+);
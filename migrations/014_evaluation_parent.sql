@@ -0,0 +1,2 @@
+-- Re-execução de uma avaliação: aponta para a original (que fica intacta para comparação)
+ALTER TABLE evaluations ADD COLUMN parent_evaluation_id TEXT REFERENCES evaluations(id) ON DELETE SET NULL;
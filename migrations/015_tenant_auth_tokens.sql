@@ -0,0 +1,18 @@
+-- Tokens de verificação e de redefinição de senha passam a ser por tenant:
+-- o mesmo email pode ter conta em mais de um tenant (UNIQUE(tenant_id, email))
+CREATE TABLE email_verifications_new (
+    tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants(id),
+    email TEXT NOT NULL,
+    token TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, email)
+);
+INSERT INTO email_verifications_new (tenant_id, email, token, expires_at, created_at)
+SELECT 'default', email, token, expires_at, created_at FROM email_verifications;
+DROP TABLE email_verifications;
+ALTER TABLE email_verifications_new RENAME TO email_verifications;
+
+ALTER TABLE password_resets ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+DROP INDEX IF EXISTS idx_password_resets_email;
+CREATE INDEX IF NOT EXISTS idx_password_resets_tenant_email ON password_resets(tenant_id, email);
@@ -0,0 +1,3 @@
+-- Roles da hierarquia configurável das policies (superadmin > admin > user > viewer)
+INSERT OR IGNORE INTO roles (id, permissions) VALUES ('superadmin', '["*"]');
+INSERT OR IGNORE INTO roles (id, permissions) VALUES ('viewer', '["evaluations:view"]');
@@ -0,0 +1,13 @@
+-- Decisões de autorização negadas (detecção de acesso indevido entre tenants).
+-- Sem FK em user_id: o registro sobrevive à remoção do usuário.
+CREATE TABLE IF NOT EXISTS access_denied_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    tenant_id TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_access_denied_log_tenant ON access_denied_log(tenant_id, created_at);
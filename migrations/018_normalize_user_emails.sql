@@ -0,0 +1,11 @@
+-- Emails passam a ser gravados e consultados em minúsculas e sem espaços.
+-- Normaliza os existentes, exceto quando colidiriam com outra conta do tenant.
+UPDATE users
+SET email = lower(trim(email))
+WHERE email != lower(trim(email))
+  AND NOT EXISTS (
+    SELECT 1 FROM users u2
+    WHERE u2.tenant_id = users.tenant_id
+      AND u2.id != users.id
+      AND lower(trim(u2.email)) = lower(trim(users.email))
+  );
@@ -0,0 +1,9 @@
+-- Imagens enviadas junto com o prompt; entram como inline data na consulta inicial
+CREATE TABLE IF NOT EXISTS evaluation_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    evaluation_id TEXT NOT NULL REFERENCES evaluations(id) ON DELETE CASCADE,
+    mime_type TEXT NOT NULL,
+    data BLOB NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_evaluation_attachments_evaluation ON evaluation_attachments(evaluation_id);
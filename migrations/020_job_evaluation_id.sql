@@ -0,0 +1,6 @@
+-- Avaliação associada ao job (run_evaluation), para correlacionar jobs e
+-- logs sem extrair do payload
+ALTER TABLE jobs ADD COLUMN evaluation_id TEXT;
+UPDATE jobs SET evaluation_id = json_extract(CAST(payload AS TEXT), '$.evaluation_id')
+WHERE type = 'run_evaluation';
+CREATE INDEX IF NOT EXISTS idx_jobs_evaluation ON jobs(evaluation_id);
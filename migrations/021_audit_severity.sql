@@ -0,0 +1,8 @@
+-- Severidade da faixa de diagnóstico (ok, warning, critical, unknown), usada
+-- pela UI para colorir o resultado; auditorias anteriores vêm do corte único
+ALTER TABLE audits ADD COLUMN severidade TEXT NOT NULL DEFAULT 'unknown';
+UPDATE audits SET severidade = CASE
+    WHEN diagnostico LIKE 'Indeterminado%' THEN 'unknown'
+    WHEN divergencia > 0.25 THEN 'critical'
+    ELSE 'ok'
+END;
@@ -0,0 +1,3 @@
+-- Tokens consumidos (entrada + saída, segundo o UsageMetadata do Gemini) pelas
+-- gerações de cada avaliação, para billing e quotas por tenant
+ALTER TABLE evaluations ADD COLUMN total_tokens INTEGER NOT NULL DEFAULT 0;
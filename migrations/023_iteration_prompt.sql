@@ -0,0 +1,3 @@
+-- Prompt efetivo enviado em cada fase (último conteúdo user do histórico), para
+-- auditar exatamente o que o modelo recebeu; iterações anteriores ficam vazias
+ALTER TABLE iterations ADD COLUMN prompt TEXT NOT NULL DEFAULT '';